@@ -10,6 +10,8 @@ import (
 	"bezbase/internal/i18n"
 	"bezbase/internal/middleware"
 	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
 	"bezbase/internal/repository"
 	"bezbase/internal/services"
 
@@ -49,6 +51,13 @@ func main() {
 
 	// Initialize Echo
 	e := echo.New()
+	// Client IPs drive rate limits, lockouts and challenge difficulty, so forwarding headers are
+	// only believed from configured proxies
+	ipExtractor, err := middleware.ClientIPExtractor(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatal("Failed to configure trusted proxies:", err)
+	}
+	e.IPExtractor = ipExtractor
 
 	// Swagger route
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -66,8 +75,26 @@ func main() {
 	authProviderRepo := repository.NewAuthProviderRepository(db)
 	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
 	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	webAuthnRepo := repository.NewWebAuthnRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+	personalAccessTokenRepo := repository.NewPersonalAccessTokenRepository(db)
+	accountLockoutRepo := repository.NewAccountLockoutRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	oidcClientRepo := repository.NewOIDCClientRepository(db)
+	oidcGrantRepo := repository.NewOIDCGrantRepository(db)
+	oidcCodeRepo := repository.NewOIDCAuthorizationCodeRepository(db)
+	oidcTokenRepo := repository.NewOIDCTokenRepository(db)
+	samlProviderRepo := repository.NewSAMLProviderRepository(db)
+	deviceAuthorizationRepo := repository.NewDeviceAuthorizationRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	ruleRepo := repository.NewRuleRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	roleAssignmentRepo := repository.NewRoleAssignmentRepository(db)
 
 	// Advanced RBAC repositories
 	roleTemplateRepo := repository.NewRoleTemplateRepository(db)
@@ -76,30 +103,106 @@ func main() {
 
 
 	// Initialize services
-	rbacService, err := services.NewRBACService(roleRepo, ruleRepo, db)
+	rbacService, err := services.NewRBACService(roleRepo, ruleRepo, roleAssignmentRepo, db)
 	if err != nil {
 		log.Fatal("Failed to initialize RBAC service:", err)
 	}
+
+	// Load the JWT signing keys before anything issues or verifies tokens
+	jwtKeys := auth.NewKeySet()
+	keyService := services.NewKeyService(signingKeyRepo, jwtKeys, &cfg.Auth.JWTKeys)
+	if err := keyService.Load(contextx.Background()); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	keyService.Start()
+
+	passwordPolicy, err := services.NewPasswordPolicyService(passwordHistoryRepo, &cfg.Auth.PasswordPolicy)
+	if err != nil {
+		log.Fatal("Failed to load password policy:", err)
+	}
+
+	ldapService, err := services.NewLDAPService(rbacService, &cfg.Auth.LDAP)
+	if err != nil {
+		log.Fatal("Failed to configure LDAP:", err)
+	}
+
 	emailService := services.NewEmailService(emailVerificationRepo, &cfg.Email, cfg.Server.BaseURL)
+	roleExpiryService := services.NewRoleExpiryService(rbacService, roleAssignmentRepo, userRepo, organizationRepo, emailService, &cfg.Auth.RoleAssignments)
+	roleExpiryService.Start()
+	loginHistoryService := services.NewLoginHistoryService(loginEventRepo, userRepo, sessionRepo, authProviderRepo, personalAccessTokenRepo, passwordResetRepo,
+		oidcGrantRepo, oidcTokenRepo, webAuthnRepo, emailService, &cfg.Auth.LoginAlerts, db)
+	sessionService := services.NewSessionService(sessionRepo, userRepo, loginHistoryService, jwtKeys, &cfg.Auth)
+	lockoutService := services.NewLockoutService(accountLockoutRepo, userRepo, emailService, &cfg.Auth.Lockout)
+	mfaService := services.NewMFAService(mfaRepo, userRepo, sessionService, lockoutService, jwtKeys, &cfg.Auth)
+	passkeyService := services.NewPasskeyService(webAuthnRepo, authProviderRepo, userRepo, sessionService, &cfg.WebAuthn)
 	emailVerificationService := services.NewEmailVerificationService(userRepo, emailVerificationRepo, emailService)
-	passwordResetService := services.NewPasswordResetService(userRepo, userInfoRepo, authProviderRepo, passwordResetRepo, emailService)
-	authService := services.NewAuthService(userRepo, userInfoRepo, authProviderRepo, &cfg.Auth, db)
-	userService := services.NewUserService(userRepo, userInfoRepo, authProviderRepo, rbacService, db)
+	passwordResetService := services.NewPasswordResetService(userRepo, userInfoRepo, authProviderRepo, passwordResetRepo, emailService, sessionService, passwordPolicy)
+	authService := services.NewAuthService(userRepo, userInfoRepo, authProviderRepo, sessionService, mfaService, lockoutService, passwordPolicy, ldapService, emailService, &cfg.Auth, db)
+	magicLinkService := services.NewMagicLinkService(magicLinkRepo, userRepo, userInfoRepo, emailService, sessionService, mfaService, &cfg.Auth.MagicLink)
+	oauthService := services.NewOAuthService(authService, userInfoRepo, authProviderRepo, &cfg.OAuth, &cfg.Auth)
+	samlService, err := services.NewSAMLService(authService, authProviderRepo, samlProviderRepo, &cfg.SAML, &cfg.Auth)
+	if err != nil {
+		log.Fatal("Failed to load SAML service provider key:", err)
+	}
+	// Proof-of-work bot protection; any ChallengeVerifier, e.g. a hosted CAPTCHA, can replace it
+	var challengeVerifier services.ChallengeVerifier
+	if cfg.Auth.Challenge.Enabled {
+		powVerifier := services.NewProofOfWorkVerifier(&cfg.Auth.Challenge, &cfg.Auth)
+		powVerifier.Start()
+		challengeVerifier = powVerifier
+	}
+	tokenService := services.NewPersonalAccessTokenService(personalAccessTokenRepo, userRepo, rbacService, &cfg.Auth)
+	impersonationService := services.NewImpersonationService(sessionRepo, userRepo, sessionService, rbacService, jwtKeys, &cfg.Auth)
+	oidcClientService := services.NewOIDCClientService(oidcClientRepo, oidcGrantRepo, oidcTokenRepo)
+	oidcProviderService := services.NewOIDCProviderService(oidcClientRepo, oidcGrantRepo, oidcCodeRepo, oidcTokenRepo, userRepo, sessionRepo, jwtKeys, &cfg.IdentityProvider)
+	deviceAuthorizationService := services.NewDeviceAuthorizationService(deviceAuthorizationRepo, oidcClientRepo, userRepo, sessionService, &cfg.IdentityProvider)
+	emailChangeService := services.NewEmailChangeService(emailChangeRepo, userRepo, userInfoRepo, authProviderRepo, emailService, sessionService, &cfg.Auth.EmailChange, db)
+	linkedAccountService := services.NewLinkedAccountService(authProviderRepo, userRepo, passwordPolicy, emailService)
+	organizationService := services.NewOrganizationService(organizationRepo, userRepo, rbacService)
+	userService := services.NewUserService(userRepo, userInfoRepo, authProviderRepo, rbacService, sessionService, passwordPolicy, emailChangeService, db)
 
 	// Initialize handlers
 	commonHandler := handlers.NewCommonHandler()
+	keyHandler := handlers.NewKeyHandler(keyService)
 	rbacHandler := handlers.NewRBACHandler(rbacService)
 	advancedRbacHandler := handlers.NewAdvancedRBACHandler(rbacService, roleTemplateRepo, contextualPermissionRepo, roleInheritanceRepo, db)
 	userHandler := handlers.NewUserHandler(userService, rbacService)
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, sessionService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	loginHistoryHandler := handlers.NewLoginHistoryHandler(loginHistoryService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	passkeyHandler := handlers.NewPasskeyHandler(passkeyService)
+	oauthHandler := handlers.NewOAuthHandler(oauthService, &cfg.OAuth)
+	linkedAccountHandler := handlers.NewLinkedAccountHandler(linkedAccountService)
+	samlHandler := handlers.NewSAMLHandler(samlService, &cfg.SAML, &cfg.OAuth)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(tokenService)
+	lockoutHandler := handlers.NewLockoutHandler(lockoutService)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService)
+	oidcClientHandler := handlers.NewOIDCClientHandler(oidcClientService)
+	oidcProviderHandler := handlers.NewOIDCProviderHandler(oidcProviderService, deviceAuthorizationService)
+	deviceAuthorizationHandler := handlers.NewDeviceAuthorizationHandler(deviceAuthorizationService)
+	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService, &cfg.Auth.MagicLink)
 	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
 	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+	emailChangeHandler := handlers.NewEmailChangeHandler(emailChangeService)
+	challengeHandler := handlers.NewChallengeHandler(challengeVerifier)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, sessionService)
 
 	// Routes
 
 	// Public routes
 	api := e.Group("/api")
 
+	// OpenID Connect provider protocol routes (clients authenticate per request, not with a JWT)
+	oauth2 := api.Group("/oauth2")
+	oauth2.Use(middleware.APIRateLimit())
+	oauth2.POST("/token", oidcProviderHandler.Token)
+	oauth2.POST("/device_authorization", deviceAuthorizationHandler.Authorize)
+	oauth2.GET("/userinfo", oidcProviderHandler.UserInfo)
+	oauth2.POST("/userinfo", oidcProviderHandler.UserInfo)
+	oauth2.POST("/introspect", oidcProviderHandler.Introspect)
+	oauth2.POST("/revoke", oidcProviderHandler.Revoke)
+
 	// API v1 routes
 	apiV1 := api.Group("/v1")
 	apiV1.Use(middleware.APIRateLimit()) // Add rate limiting for API endpoints
@@ -107,8 +210,30 @@ func main() {
 	// Auth routes (public, but versioned)
 	auth := apiV1.Group("/auth")
 	auth.Use(middleware.AuthRateLimit()) // Add rate limiting for auth endpoints
-	auth.POST("/register", authHandler.Register)
-	auth.POST("/login", authHandler.Login)
+	auth.GET("/challenge", challengeHandler.GetChallenge)
+	auth.POST("/register", authHandler.Register, middleware.RequireChallenge(challengeVerifier))
+	auth.POST("/login", authHandler.Login, middleware.RequireChallenge(challengeVerifier))
+	auth.POST("/change-expired-password", authHandler.ChangeExpiredPassword)
+	auth.POST("/refresh", authHandler.Refresh)
+	auth.POST("/logout", authHandler.Logout)
+	auth.POST("/unlock-account", lockoutHandler.UnlockAccount)
+	auth.POST("/mfa/verify", mfaHandler.Verify)
+	auth.POST("/passkey/login/begin", passkeyHandler.BeginLogin)
+	auth.POST("/passkey/login/finish", passkeyHandler.FinishLogin)
+	auth.POST("/magic-link", magicLinkHandler.RequestLink)
+	auth.POST("/magic-link/login", magicLinkHandler.Login)
+
+	// Social login routes
+	auth.GET("/oauth/providers", oauthHandler.ListProviders)
+	auth.GET("/oauth/:provider/authorize", oauthHandler.Authorize)
+	auth.GET("/oauth/:provider/callback", oauthHandler.Callback)
+	auth.POST("/oauth/link/confirm", oauthHandler.ConfirmLink)
+
+	// SAML single sign-on routes
+	auth.GET("/saml/providers", samlHandler.ListLoginProviders)
+	auth.GET("/saml/:slug/login", samlHandler.Login)
+	auth.POST("/saml/:slug/acs", samlHandler.ACS)
+	auth.GET("/saml/:slug/metadata", samlHandler.Metadata)
 
 	// Email verification routes (public)
 	auth.POST("/send-verification-email", emailVerificationHandler.SendVerificationEmail)
@@ -116,28 +241,128 @@ func main() {
 	auth.GET("/verify-email", emailVerificationHandler.VerifyEmailByToken)
 	auth.POST("/resend-verification-email", emailVerificationHandler.ResendVerificationEmail)
 
+	// Email change routes (public, the emailed token identifies the request)
+	auth.POST("/confirm-email-change", emailChangeHandler.ConfirmChange)
+	auth.POST("/revert-email-change", emailChangeHandler.RevertChange)
+
+	// Login alert route (public, the emailed token identifies the login)
+	auth.POST("/report-login", loginHistoryHandler.ReportLogin)
+
 	// Password reset routes (public)
-	auth.POST("/request-password-reset", passwordResetHandler.RequestPasswordReset)
+	auth.POST("/request-password-reset", passwordResetHandler.RequestPasswordReset, middleware.RequireChallenge(challengeVerifier))
 	auth.POST("/reset-password", passwordResetHandler.ResetPassword)
 	auth.POST("/validate-reset-token", passwordResetHandler.ValidateResetToken)
 	auth.GET("/validate-reset-token", passwordResetHandler.ValidateResetTokenByParam)
 
 	// Protected routes (add JWT middleware after auth routes)
-	apiV1.Use(middleware.JWTMiddleware(cfg.Auth.JWTSecret))
+	apiV1.Use(middleware.JWTMiddleware(jwtKeys, sessionService, tokenService))
+
+	// Active organization from the X-Organization-ID header or the org_id claim
+	apiV1.Use(middleware.ResolveOrganization(organizationService))
+
+	// Step-up re-authentication (refreshes auth_time for sensitive routes)
+	apiV1.POST("/reauthenticate", authHandler.Reauthenticate, middleware.DenyImpersonation(), middleware.RequireSession())
 
 	// Profile routes (users can access their own profile)
 	apiV1.GET("/profile", userHandler.GetProfile, middleware.RequirePermission(rbacService, models.PermissionViewProfile))
-	apiV1.PUT("/profile", userHandler.UpdateProfile, middleware.RequirePermission(rbacService, models.PermissionEditProfile))
-	apiV1.PUT("/profile/password", userHandler.ChangePassword, middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	// The handler checks the permission itself, with the account as resource.*
+	apiV1.PUT("/profile", userHandler.UpdateProfile)
+	apiV1.PUT("/profile/email", emailChangeHandler.RequestChange, middleware.DenyImpersonation(), middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.PUT("/profile/password", userHandler.ChangePassword, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+
+	// Session routes (users manage their own sessions)
+	apiV1.GET("/sessions", sessionHandler.ListSessions, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.DELETE("/sessions", sessionHandler.RevokeOtherSessions, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.DELETE("/sessions/:id", sessionHandler.RevokeSession, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.GET("/login-history", loginHistoryHandler.ListMyHistory, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+
+	// MFA routes (users manage their own second factor)
+	apiV1.GET("/mfa", mfaHandler.GetStatus, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/mfa/totp/enroll", mfaHandler.EnrollTOTP, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.POST("/mfa/totp/disable", mfaHandler.DisableTOTP, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+
+	// Passkey routes (users manage their own passkeys)
+	apiV1.GET("/passkeys", passkeyHandler.ListPasskeys, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/passkeys/register/begin", passkeyHandler.BeginRegistration, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.POST("/passkeys/register/finish", passkeyHandler.FinishRegistration, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.PUT("/passkeys/:id", passkeyHandler.RenamePasskey, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.DELETE("/passkeys/:id", passkeyHandler.DeletePasskey, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+
+	// Linked account routes (sign-in methods of the current user)
+	apiV1.POST("/oauth/:provider/link", oauthHandler.BeginLink, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.POST("/oauth/link/confirm", oauthHandler.ConfirmLinkForCurrentUser, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.GET("/linked-accounts", linkedAccountHandler.ListLinkedAccounts, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/linked-accounts/password", linkedAccountHandler.SetPassword, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.DELETE("/linked-accounts/:provider", linkedAccountHandler.Unlink, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+
+	// Personal access token routes (users manage their own tokens)
+	apiV1.GET("/tokens", tokenHandler.ListTokens, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/tokens", tokenHandler.CreateToken, middleware.RequireSession(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.DELETE("/tokens/:id", tokenHandler.RevokeToken, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
 
 	// User management routes (admin only)
 	userGroup := apiV1.Group("/users")
 	// userGroup.Use(middleware.RequirePermission(rbacService,models.ResourceTypeUser, models.ActionTypeAll))
 	userGroup.GET("", userHandler.GetUsers, middleware.RequirePermission(rbacService, models.PermissionViewUsers))
+	userGroup.GET("/locked", lockoutHandler.ListLockedAccounts, middleware.RequirePermission(rbacService, models.PermissionViewUsers))
 	userGroup.GET("/:id", userHandler.GetUser, middleware.RequirePermission(rbacService, models.PermissionViewUsers))
-	userGroup.POST("", userHandler.CreateUser, middleware.RequirePermission(rbacService, models.PermissionCreateUsers))
-	userGroup.PUT("/:id", userHandler.UpdateUser, middleware.RequirePermission(rbacService, models.PermissionEditUsers))
-	userGroup.DELETE("/:id", userHandler.DeleteUser, middleware.RequirePermission(rbacService, models.PermissionDeleteUsers))
+	userGroup.POST("", userHandler.CreateUser, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreateUsers))
+	userGroup.PUT("/:id", userHandler.UpdateUser) // Checks users:update itself, with the account as resource.*
+	userGroup.DELETE("/:id", userHandler.DeleteUser, middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionDeleteUsers))
+	userGroup.DELETE("/:id/mfa", mfaHandler.ResetUserMFA, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditUsers))
+	userGroup.GET("/:id/tokens", tokenHandler.ListUserTokens, middleware.RequirePermission(rbacService, models.PermissionViewUsers))
+	userGroup.GET("/:id/login-history", loginHistoryHandler.ListUserHistory, middleware.RequirePermission(rbacService, models.PermissionViewUsers))
+	userGroup.DELETE("/:id/tokens/:token_id", tokenHandler.RevokeUserToken, middleware.RequirePermission(rbacService, models.PermissionEditUsers))
+	userGroup.DELETE("/:id/lockout", lockoutHandler.UnlockUser, middleware.RequirePermission(rbacService, models.PermissionEditUsers))
+	userGroup.POST("/:id/impersonate", impersonationHandler.StartImpersonation, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionImpersonateUsers))
+
+	// Impersonation routes
+	apiV1.POST("/impersonation/stop", impersonationHandler.StopImpersonation)
+
+	// OpenID Connect consent and authorized applications (users grant access for themselves only)
+	apiV1.GET("/oidc/authorize", oidcProviderHandler.Authorize, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/oidc/authorize", oidcProviderHandler.Decide, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.GET("/oidc/grants", oidcClientHandler.ListMyGrants, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.DELETE("/oidc/grants/:client_id", oidcClientHandler.RevokeMyGrant, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+
+	// Device flow verification (users approve devices for themselves only)
+	apiV1.GET("/device", deviceAuthorizationHandler.Verify, middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/device", deviceAuthorizationHandler.Decide, middleware.DenyImpersonation(), middleware.RequireSession(), middleware.RequirePermission(rbacService, models.PermissionEditProfile))
+	apiV1.GET("/device-authorizations", deviceAuthorizationHandler.ListAuthorizations, middleware.RequirePermission(rbacService, models.PermissionViewDeviceAuthorizations))
+
+	// OpenID Connect client management routes
+	oidcClientGroup := apiV1.Group("/oidc/clients")
+	oidcClientGroup.GET("", oidcClientHandler.ListClients, middleware.RequirePermission(rbacService, models.PermissionViewOIDCClients))
+	oidcClientGroup.POST("", oidcClientHandler.CreateClient, middleware.RequirePermission(rbacService, models.PermissionCreateOIDCClients))
+	oidcClientGroup.GET("/:id", oidcClientHandler.GetClient, middleware.RequirePermission(rbacService, models.PermissionViewOIDCClients))
+	oidcClientGroup.PUT("/:id", oidcClientHandler.UpdateClient, middleware.RequirePermission(rbacService, models.PermissionEditOIDCClients))
+	oidcClientGroup.DELETE("/:id", oidcClientHandler.DeleteClient, middleware.RequirePermission(rbacService, models.PermissionDeleteOIDCClients))
+	oidcClientGroup.POST("/:id/secret", oidcClientHandler.RotateClientSecret, middleware.RequirePermission(rbacService, models.PermissionEditOIDCClients))
+	oidcClientGroup.GET("/:id/grants", oidcClientHandler.ListClientGrants, middleware.RequirePermission(rbacService, models.PermissionViewOIDCClients))
+	oidcClientGroup.DELETE("/:id/grants/:user_id", oidcClientHandler.RevokeClientGrant, middleware.RequirePermission(rbacService, models.PermissionEditOIDCClients))
+
+	// SAML identity provider management routes
+	samlProviderGroup := apiV1.Group("/saml/providers")
+	samlProviderGroup.GET("", samlHandler.ListProviders, middleware.RequirePermission(rbacService, models.PermissionViewSAMLProviders))
+	samlProviderGroup.POST("", samlHandler.CreateProvider, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreateSAMLProviders))
+	samlProviderGroup.GET("/:id", samlHandler.GetProvider, middleware.RequirePermission(rbacService, models.PermissionViewSAMLProviders))
+	samlProviderGroup.PUT("/:id", samlHandler.UpdateProvider, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditSAMLProviders))
+	samlProviderGroup.DELETE("/:id", samlHandler.DeleteProvider, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionDeleteSAMLProviders))
+
+	// Organization routes (tenants; routes with :id are authorized inside that organization)
+	apiV1.GET("/organizations", organizationHandler.ListOrganizations, middleware.RequirePermission(rbacService, models.PermissionViewProfile))
+	apiV1.POST("/organizations", organizationHandler.CreateOrganization, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreateOrganizations))
+	apiV1.POST("/organizations/switch", organizationHandler.SwitchOrganization, middleware.RequireSession())
+	organizationGroup := apiV1.Group("/organizations/:id", middleware.OrganizationFromParam(organizationService, "id"))
+	organizationGroup.GET("", organizationHandler.GetOrganization, middleware.RequirePermission(rbacService, models.PermissionViewOrganizations))
+	organizationGroup.PUT("", organizationHandler.UpdateOrganization, middleware.RequirePermission(rbacService, models.PermissionEditOrganizations))
+	organizationGroup.DELETE("", organizationHandler.DeleteOrganization, middleware.DenyImpersonation(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionDeleteOrganizations))
+	organizationGroup.GET("/members", organizationHandler.ListMembers, middleware.RequirePermission(rbacService, models.PermissionViewOrganizations))
+	organizationGroup.POST("/members", organizationHandler.AddMember, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditOrganizations))
+	organizationGroup.PUT("/members/:user_id", organizationHandler.UpdateMember, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditOrganizations))
+	organizationGroup.DELETE("/members/:user_id", organizationHandler.RemoveMember, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditOrganizations))
 
 	// RBAC management routes (admin only)
 	rbacGroup := apiV1.Group("/rbac")
@@ -145,24 +370,24 @@ func main() {
 
 	// Role management
 	rbacGroup.POST("/roles", rbacHandler.CreateRole,
-		middleware.RequirePermission(rbacService, models.PermissionCreateRoles))
+		middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreateRoles))
 	rbacGroup.GET("/roles", advancedRbacHandler.GetAllRoles, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 	rbacGroup.GET("/roles/:role_id", rbacHandler.GetRole, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
-	rbacGroup.PUT("/roles/:role_id", rbacHandler.UpdateRole, middleware.RequirePermission(rbacService, models.PermissionEditRoles))
-	rbacGroup.DELETE("/roles/:role_id", rbacHandler.DeleteRole, middleware.RequirePermission(rbacService, models.PermissionDeleteRoles))
+	rbacGroup.PUT("/roles/:role_id", rbacHandler.UpdateRole, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditRoles))
+	rbacGroup.DELETE("/roles/:role_id", rbacHandler.DeleteRole, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionDeleteRoles))
 	rbacGroup.GET("/roles/:role/users", rbacHandler.GetUsersWithRole, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 	rbacGroup.GET("/roles/:role/permissions", rbacHandler.GetRolePermissions, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 
 	// User role management
-	rbacGroup.POST("/users/assign-role", rbacHandler.AssignRole, middleware.RequirePermission(rbacService, models.PermissionEditPermissions))
-	rbacGroup.POST("/users/remove-role", rbacHandler.RemoveRole, middleware.RequirePermission(rbacService, models.PermissionEditPermissions))
+	rbacGroup.POST("/users/assign-role", rbacHandler.AssignRole, middleware.DenyImpersonation(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionEditPermissions))
+	rbacGroup.POST("/users/remove-role", rbacHandler.RemoveRole, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditPermissions))
 	rbacGroup.GET("/users/:user_id/roles", rbacHandler.GetUserRoles, middleware.RequirePermission(rbacService, models.PermissionViewPermissions))
 
 	// Permission management
 	rbacGroup.GET("/permissions", rbacHandler.GetPermissions, middleware.RequirePermission(rbacService, models.PermissionViewPermissions))
 	rbacGroup.GET("/permissions/available", rbacHandler.GetAvailablePermissions, middleware.RequirePermission(rbacService, models.PermissionViewPermissions))
-	rbacGroup.POST("/permissions", rbacHandler.AddPermission, middleware.RequirePermission(rbacService, models.PermissionCreatePermissions))
-	rbacGroup.DELETE("/permissions", rbacHandler.RemovePermission, middleware.RequirePermission(rbacService, models.PermissionDeletePermissions))
+	rbacGroup.POST("/permissions", rbacHandler.AddPermission, middleware.DenyImpersonation(), middleware.RequireRecentAuth(cfg.Auth.ReauthMaxAge), middleware.RequirePermission(rbacService, models.PermissionCreatePermissions))
+	rbacGroup.DELETE("/permissions", rbacHandler.RemovePermission, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionDeletePermissions))
 	rbacGroup.GET("/users/:user_id/check-permission", rbacHandler.CheckPermission)
 
 	// Advanced RBAC endpoints
@@ -170,13 +395,13 @@ func main() {
 	rbacGroup.GET("/role-templates", advancedRbacHandler.GetRoleTemplates, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 
 	// Role hierarchy and template creation
-	rbacGroup.POST("/roles/from-template", advancedRbacHandler.CreateRoleFromTemplate, middleware.RequirePermission(rbacService, models.PermissionCreateRoles))
-	rbacGroup.PUT("/roles/:role_id/parent", advancedRbacHandler.SetRoleParent, middleware.RequirePermission(rbacService, models.PermissionEditRoles))
+	rbacGroup.POST("/roles/from-template", advancedRbacHandler.CreateRoleFromTemplate, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreateRoles))
+	rbacGroup.PUT("/roles/:role_id/parent", advancedRbacHandler.SetRoleParent, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionEditRoles))
 	rbacGroup.GET("/roles/:role_id/hierarchy", advancedRbacHandler.GetRoleHierarchy, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 	rbacGroup.GET("/roles/:role_id/eligible-parents", advancedRbacHandler.GetEligibleParentRoles, middleware.RequirePermission(rbacService, models.PermissionViewRoles))
 
 	// Contextual permissions
-	rbacGroup.POST("/contextual-permissions", advancedRbacHandler.CreateContextualPermission, middleware.RequirePermission(rbacService, models.PermissionCreatePermissions))
+	rbacGroup.POST("/contextual-permissions", advancedRbacHandler.CreateContextualPermission, middleware.DenyImpersonation(), middleware.RequirePermission(rbacService, models.PermissionCreatePermissions))
 	rbacGroup.GET("/users/:user_id/effective-permissions", advancedRbacHandler.GetEffectivePermissions, middleware.RequirePermission(rbacService, models.PermissionViewPermissions))
 	
 	// Current user permissions
@@ -186,12 +411,16 @@ func main() {
 	// API v2 (future version example)
 	apiV2 := api.Group("/v2")
 	apiV2.Use(middleware.APIRateLimit()) // Add rate limiting for API endpoints
-	apiV2.Use(middleware.JWTMiddleware(cfg.Auth.JWTSecret))
+	apiV2.Use(middleware.JWTMiddleware(jwtKeys, sessionService, tokenService))
 	apiV2.Use(middleware.RequireMinVersion(2)) // Require minimum version 2
 
 	// Health check
 	api.GET("/health", commonHandler.HealthCheck)
 
+	// Public keys for token verification
+	e.GET("/.well-known/jwks.json", keyHandler.JWKS)
+	e.GET("/.well-known/openid-configuration", oidcProviderHandler.Discovery)
+
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Server.Port)
 	log.Fatal(e.Start(":" + cfg.Server.Port))
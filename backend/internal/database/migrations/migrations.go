@@ -1,8 +1,10 @@
 package migrations
 
 import (
+	"log"
 	"strings"
 	"bezbase/internal/models"
+	"bezbase/internal/pkg/identifier"
 
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
@@ -761,7 +763,814 @@ func GetMigrations() []*gormigrate.Migration {
 				return nil
 			},
 		},
+		{
+			ID: "20250722_001_add_sessions",
+			Migrate: func(tx *gorm.DB) error {
+				// Create Session table backing refresh tokens
+				type Session struct {
+					ID                       uint         `gorm:"primaryKey"`
+					UserID                   uint         `gorm:"not null;index"`
+					RefreshTokenHash         string       `gorm:"not null;uniqueIndex;size:64"`
+					PreviousRefreshTokenHash string       `gorm:"index;size:64"`
+					UserAgent                string       `gorm:"size:500"`
+					IPAddress                string       `gorm:"size:45"`
+					ExpiresAt                interface{}  `gorm:"type:timestamp;not null"`
+					LastUsedAt               interface{}  `gorm:"type:timestamp"`
+					RevokedAt                *interface{} `gorm:"type:timestamp"`
+					CreatedAt                interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt                interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					DeletedAt                *interface{} `gorm:"type:timestamp;index"`
+				}
+
+				if err := tx.AutoMigrate(&Session{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				if err := tx.Exec("ALTER TABLE sessions ADD CONSTRAINT fk_sessions_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error; err != nil {
+					return err
+				}
+
+				// Add indexes for performance
+				indexes := []string{
+					"CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at)",
+					"CREATE INDEX IF NOT EXISTS idx_sessions_revoked_at ON sessions(revoked_at)",
+				}
+
+				for _, query := range indexes {
+					if err := tx.Exec(query).Error; err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("sessions")
+			},
+		},
+		{
+			ID: "20250723_001_add_mfa",
+			Migrate: func(tx *gorm.DB) error {
+				// Create UserMFA table holding TOTP enrollments
+				type UserMFA struct {
+					ID           uint         `gorm:"primaryKey"`
+					UserID       uint         `gorm:"not null;uniqueIndex"`
+					Secret       string       `gorm:"not null;size:64"`
+					Enabled      bool         `gorm:"default:false"`
+					ConfirmedAt  *interface{} `gorm:"type:timestamp"`
+					LastUsedStep int64        `gorm:"default:0"`
+					CreatedAt    interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt    interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("user_mfa").AutoMigrate(&UserMFA{}); err != nil {
+					return err
+				}
+
+				// Create MFARecoveryCode table
+				type MFARecoveryCode struct {
+					ID        uint         `gorm:"primaryKey"`
+					UserID    uint         `gorm:"not null;index"`
+					CodeHash  string       `gorm:"not null;size:64;index"`
+					UsedAt    *interface{} `gorm:"type:timestamp"`
+					CreatedAt interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.AutoMigrate(&MFARecoveryCode{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraints
+				constraints := []string{
+					"ALTER TABLE user_mfa ADD CONSTRAINT fk_user_mfa_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE",
+					"ALTER TABLE mfa_recovery_codes ADD CONSTRAINT fk_mfa_recovery_codes_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE",
+				}
+
+				for _, query := range constraints {
+					if err := tx.Exec(query).Error; err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("mfa_recovery_codes", "user_mfa")
+			},
+		},
+		{
+			ID: "20250724_001_add_webauthn",
+			Migrate: func(tx *gorm.DB) error {
+				// Create WebAuthnCredential table holding passkeys
+				type WebAuthnCredential struct {
+					ID             uint         `gorm:"primaryKey"`
+					UserID         uint         `gorm:"not null;index"`
+					AuthProviderID uint         `gorm:"not null;index"`
+					CredentialID   string       `gorm:"not null;uniqueIndex;size:1400"`
+					PublicKey      []byte       `gorm:"not null"`
+					AAGUID         string       `gorm:"size:36"`
+					SignCount      int64        `gorm:"default:0"`
+					Transports     string       `gorm:"size:255"`
+					Name           string       `gorm:"not null;size:100"`
+					BackupEligible bool         `gorm:"default:false"`
+					BackupState    bool         `gorm:"default:false"`
+					LastUsedAt     *interface{} `gorm:"type:timestamp"`
+					CreatedAt      interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt      interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("webauthn_credentials").AutoMigrate(&WebAuthnCredential{}); err != nil {
+					return err
+				}
+
+				// Create WebAuthnChallenge table for pending ceremonies
+				type WebAuthnChallenge struct {
+					ID        uint        `gorm:"primaryKey"`
+					Challenge string      `gorm:"not null;uniqueIndex;size:64"`
+					Ceremony  string      `gorm:"not null;size:20"`
+					UserID    uint        `gorm:"index"`
+					ExpiresAt interface{} `gorm:"type:timestamp;not null"`
+					CreatedAt interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("webauthn_challenges").AutoMigrate(&WebAuthnChallenge{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraints
+				constraints := []string{
+					"ALTER TABLE webauthn_credentials ADD CONSTRAINT fk_webauthn_credentials_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE",
+					"ALTER TABLE webauthn_credentials ADD CONSTRAINT fk_webauthn_credentials_auth_provider_id FOREIGN KEY (auth_provider_id) REFERENCES auth_providers(id) ON DELETE CASCADE",
+					"CREATE INDEX IF NOT EXISTS idx_webauthn_challenges_expires_at ON webauthn_challenges(expires_at)",
+				}
+
+				for _, query := range constraints {
+					if err := tx.Exec(query).Error; err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("webauthn_challenges", "webauthn_credentials")
+			},
+		},
+		{
+			ID: "20250725_001_add_signing_keys",
+			Migrate: func(tx *gorm.DB) error {
+				// Create SigningKey table for asymmetric JWT keys
+				type SigningKey struct {
+					ID          uint         `gorm:"primaryKey"`
+					KID         string       `gorm:"column:kid;not null;uniqueIndex;size:64"`
+					Algorithm   string       `gorm:"not null;size:16"`
+					PrivateKey  string       `gorm:"type:text;not null"`
+					PublicKey   string       `gorm:"type:text;not null"`
+					ActivatesAt interface{}  `gorm:"type:timestamp;not null;index"`
+					ExpiresAt   *interface{} `gorm:"type:timestamp;index"`
+					CreatedAt   interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				return tx.Table("signing_keys").AutoMigrate(&SigningKey{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("signing_keys")
+			},
+		},
+		{
+			ID: "20250726_001_add_personal_access_tokens",
+			Migrate: func(tx *gorm.DB) error {
+				// Create PersonalAccessToken table for scoped API tokens
+				type PersonalAccessToken struct {
+					ID         uint         `gorm:"primaryKey"`
+					UserID     uint         `gorm:"not null;index"`
+					Name       string       `gorm:"not null;size:100"`
+					TokenHash  string       `gorm:"not null;uniqueIndex;size:64"`
+					Prefix     string       `gorm:"not null;size:16"`
+					Scopes     string       `gorm:"type:text;not null"`
+					ExpiresAt  interface{}  `gorm:"type:timestamp;not null"`
+					LastUsedAt *interface{} `gorm:"type:timestamp"`
+					LastUsedIP string       `gorm:"size:45"`
+					RevokedAt  *interface{} `gorm:"type:timestamp"`
+					CreatedAt  interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt  interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.AutoMigrate(&PersonalAccessToken{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				return tx.Exec("ALTER TABLE personal_access_tokens ADD CONSTRAINT fk_personal_access_tokens_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("personal_access_tokens")
+			},
+		},
+		{
+			ID: "20250727_001_add_account_lockouts",
+			Migrate: func(tx *gorm.DB) error {
+				// Create AccountLockout table for per-account failed login tracking
+				type AccountLockout struct {
+					ID                   uint         `gorm:"primaryKey"`
+					UserID               uint         `gorm:"not null;uniqueIndex"`
+					FailedAttempts       int          `gorm:"not null;default:0"`
+					LastFailedAt         *interface{} `gorm:"type:timestamp"`
+					LastFailedIP         string       `gorm:"size:45"`
+					BlockedUntil         *interface{} `gorm:"type:timestamp"`
+					LockedAt             *interface{} `gorm:"type:timestamp"`
+					UnlockTokenHash      string       `gorm:"size:64;index"`
+					UnlockTokenExpiresAt *interface{} `gorm:"type:timestamp"`
+					CreatedAt            interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt            interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.AutoMigrate(&AccountLockout{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				return tx.Exec("ALTER TABLE account_lockouts ADD CONSTRAINT fk_account_lockouts_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("account_lockouts")
+			},
+		},
+		{
+			ID: "20250728_001_add_magic_link_tokens",
+			Migrate: func(tx *gorm.DB) error {
+				// Create MagicLinkToken table for passwordless email login
+				type MagicLinkToken struct {
+					ID         uint         `gorm:"primaryKey"`
+					UserID     uint         `gorm:"not null;index"`
+					TokenHash  string       `gorm:"not null;uniqueIndex;size:64"`
+					DeviceHash string       `gorm:"not null;size:64"`
+					Email      string       `gorm:"not null"`
+					RequestIP  string       `gorm:"size:45"`
+					ExpiresAt  interface{}  `gorm:"type:timestamp;not null"`
+					UsedAt     *interface{} `gorm:"type:timestamp"`
+					CreatedAt  interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt  interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.AutoMigrate(&MagicLinkToken{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				return tx.Exec("ALTER TABLE magic_link_tokens ADD CONSTRAINT fk_magic_link_tokens_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("magic_link_tokens")
+			},
+		},
+		{
+			ID: "20250729_001_add_password_histories",
+			Migrate: func(tx *gorm.DB) error {
+				// Create PasswordHistory table for password reuse and expiry checks
+				type PasswordHistory struct {
+					ID           uint        `gorm:"primaryKey"`
+					UserID       uint        `gorm:"not null;index"`
+					PasswordHash string      `gorm:"not null"`
+					CreatedAt    interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.AutoMigrate(&PasswordHistory{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				if err := tx.Exec("ALTER TABLE password_histories ADD CONSTRAINT fk_password_histories_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error; err != nil {
+					return err
+				}
+
+				// Seed the current passwords so reuse checks and password age apply to existing users
+				return tx.Exec("INSERT INTO password_histories (user_id, password_hash, created_at) SELECT user_id, password, updated_at FROM auth_providers WHERE provider = 'email' AND password <> '' AND deleted_at IS NULL").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("password_histories")
+			},
+		},
+		{
+			ID: "20250730_001_add_session_impersonation",
+			Migrate: func(tx *gorm.DB) error {
+				// Record the admin behind impersonation sessions and the admin session to return to
+				if err := tx.Exec("ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER REFERENCES users(id) ON DELETE CASCADE").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("ALTER TABLE sessions ADD COLUMN impersonator_session_id INTEGER").Error; err != nil {
+					return err
+				}
+				return tx.Exec("CREATE INDEX IF NOT EXISTS idx_sessions_impersonator_id ON sessions (impersonator_id)").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				tx.Exec("ALTER TABLE sessions DROP COLUMN IF EXISTS impersonator_session_id")
+				return tx.Exec("ALTER TABLE sessions DROP COLUMN IF EXISTS impersonator_id").Error
+			},
+		},
+		{
+			ID: "20250731_001_add_oidc_provider",
+			Migrate: func(tx *gorm.DB) error {
+				// Create tables for acting as an OpenID Connect provider
+				type OIDCClient struct {
+					ID               uint        `gorm:"primaryKey"`
+					ClientID         string      `gorm:"not null;uniqueIndex;size:64"`
+					ClientSecretHash string      `gorm:"size:64"`
+					Name             string      `gorm:"not null;size:100"`
+					RedirectURIs     string      `gorm:"type:text;not null"`
+					Scopes           string      `gorm:"type:text;not null"`
+					SkipConsent      bool        `gorm:"not null;default:false"`
+					CreatedAt        interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt        interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				type OIDCGrant struct {
+					ID        uint        `gorm:"primaryKey"`
+					UserID    uint        `gorm:"not null;uniqueIndex:idx_oidc_grants_user_client"`
+					ClientID  uint        `gorm:"not null;uniqueIndex:idx_oidc_grants_user_client;index"`
+					Scopes    string      `gorm:"type:text;not null"`
+					CreatedAt interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				type OIDCAuthorizationCode struct {
+					ID            uint        `gorm:"primaryKey"`
+					CodeHash      string      `gorm:"not null;uniqueIndex;size:64"`
+					ClientID      uint        `gorm:"not null;index"`
+					UserID        uint        `gorm:"not null;index"`
+					RedirectURI   string      `gorm:"type:text;not null"`
+					Scopes        string      `gorm:"type:text;not null"`
+					Nonce         string      `gorm:"size:255"`
+					CodeChallenge string      `gorm:"not null;size:128"`
+					AuthTime      interface{} `gorm:"type:timestamp;not null"`
+					ExpiresAt     interface{} `gorm:"type:timestamp;not null"`
+					UsedAt        interface{} `gorm:"type:timestamp"`
+					CreatedAt     interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				type OIDCToken struct {
+					ID                    uint        `gorm:"primaryKey"`
+					ClientID              uint        `gorm:"not null;index"`
+					UserID                uint        `gorm:"not null;index"`
+					AuthorizationCodeID   *uint       `gorm:"index"`
+					Scopes                string      `gorm:"type:text;not null"`
+					AccessTokenHash       string      `gorm:"not null;uniqueIndex;size:64"`
+					AccessTokenExpiresAt  interface{} `gorm:"type:timestamp;not null"`
+					RefreshTokenHash      *string     `gorm:"uniqueIndex;size:64"`
+					RefreshTokenExpiresAt interface{} `gorm:"type:timestamp"`
+					AuthTime              interface{} `gorm:"type:timestamp;not null"`
+					RevokedAt             interface{} `gorm:"type:timestamp"`
+					CreatedAt             interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt             interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("oidc_clients").AutoMigrate(&OIDCClient{}); err != nil {
+					return err
+				}
+				if err := tx.Table("oidc_grants").AutoMigrate(&OIDCGrant{}); err != nil {
+					return err
+				}
+				if err := tx.Table("oidc_authorization_codes").AutoMigrate(&OIDCAuthorizationCode{}); err != nil {
+					return err
+				}
+				if err := tx.Table("oidc_tokens").AutoMigrate(&OIDCToken{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraints; deleting a client or user removes its grants, codes and tokens
+				for _, table := range []string{"oidc_grants", "oidc_authorization_codes", "oidc_tokens"} {
+					if err := tx.Exec("ALTER TABLE " + table + " ADD CONSTRAINT fk_" + table + "_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error; err != nil {
+						return err
+					}
+					if err := tx.Exec("ALTER TABLE " + table + " ADD CONSTRAINT fk_" + table + "_client_id FOREIGN KEY (client_id) REFERENCES oidc_clients(id) ON DELETE CASCADE").Error; err != nil {
+						return err
+					}
+				}
+				return tx.Exec("ALTER TABLE oidc_tokens ADD CONSTRAINT fk_oidc_tokens_authorization_code_id FOREIGN KEY (authorization_code_id) REFERENCES oidc_authorization_codes(id) ON DELETE SET NULL").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("oidc_tokens", "oidc_authorization_codes", "oidc_grants", "oidc_clients")
+			},
+		},
+		{
+			ID: "20250801_001_add_saml_identity_providers",
+			Migrate: func(tx *gorm.DB) error {
+				// Create table for corporate SAML identity providers
+				type SAMLIdentityProvider struct {
+					ID               uint        `gorm:"primaryKey"`
+					Slug             string      `gorm:"not null;uniqueIndex;size:50"`
+					Name             string      `gorm:"not null;size:100"`
+					MetadataXML      string      `gorm:"type:text;not null"`
+					Certificates     string      `gorm:"type:text"`
+					AttributeMapping string      `gorm:"type:jsonb"`
+					Enabled          bool        `gorm:"not null;default:true"`
+					CreatedAt        interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt        interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				return tx.Table("saml_identity_providers").AutoMigrate(&SAMLIdentityProvider{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("saml_identity_providers")
+			},
+		},
+		{
+			ID: "20250802_001_add_device_authorizations",
+			Migrate: func(tx *gorm.DB) error {
+				// Create table for OAuth device authorization requests (RFC 8628)
+				type DeviceAuthorization struct {
+					ID             uint         `gorm:"primaryKey"`
+					ClientID       uint         `gorm:"not null;index"`
+					DeviceCodeHash string       `gorm:"not null;uniqueIndex;size:64"`
+					UserCodeHash   string       `gorm:"not null;index;size:64"`
+					Status         string       `gorm:"not null;index;size:20;default:'pending'"`
+					UserID         *uint        `gorm:"index"`
+					Interval       int          `gorm:"not null"`
+					RequestIP      string       `gorm:"size:45"`
+					UserAgent      string       `gorm:"size:500"`
+					ExpiresAt      interface{}  `gorm:"type:timestamp;not null"`
+					LastPolledAt   *interface{} `gorm:"type:timestamp"`
+					DecidedAt      *interface{} `gorm:"type:timestamp"`
+					CompletedAt    *interface{} `gorm:"type:timestamp"`
+					CreatedAt      interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt      interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("device_authorizations").AutoMigrate(&DeviceAuthorization{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraints; deleting a client or user removes its device requests
+				if err := tx.Exec("ALTER TABLE device_authorizations ADD CONSTRAINT fk_device_authorizations_client_id FOREIGN KEY (client_id) REFERENCES oidc_clients(id) ON DELETE CASCADE").Error; err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE device_authorizations ADD CONSTRAINT fk_device_authorizations_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("device_authorizations")
+			},
+		},
+		{
+			ID: "20250803_001_add_email_change_requests",
+			Migrate: func(tx *gorm.DB) error {
+				// Create table for email changes awaiting confirmation from the new address
+				type EmailChangeRequest struct {
+					ID               uint         `gorm:"primaryKey"`
+					UserID           uint         `gorm:"not null;index"`
+					OldEmail         string       `gorm:"not null"`
+					NewEmail         string       `gorm:"not null"`
+					ConfirmTokenHash string       `gorm:"not null;uniqueIndex;size:64"`
+					RevertTokenHash  string       `gorm:"not null;uniqueIndex;size:64"`
+					RequestIP        string       `gorm:"size:45"`
+					ExpiresAt        interface{}  `gorm:"type:timestamp;not null"`
+					RevertExpiresAt  interface{}  `gorm:"type:timestamp;not null"`
+					ConfirmedAt      *interface{} `gorm:"type:timestamp"`
+					RevertedAt       *interface{} `gorm:"type:timestamp"`
+					CreatedAt        interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt        interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("email_change_requests").AutoMigrate(&EmailChangeRequest{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				return tx.Exec("ALTER TABLE email_change_requests ADD CONSTRAINT fk_email_change_requests_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("email_change_requests")
+			},
+		},
+		{
+			ID: "20250804_001_add_normalized_identifiers",
+			Migrate: func(tx *gorm.DB) error {
+				// Add NFKC case-folded copies of usernames and emails for case-insensitive lookups
+				if err := tx.Exec("ALTER TABLE user_info ADD COLUMN username_normalized TEXT").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("ALTER TABLE user_info ADD COLUMN email_normalized TEXT").Error; err != nil {
+					return err
+				}
+
+				if err := backfillNormalizedIdentifiers(tx); err != nil {
+					return err
+				}
+
+				// NULLs left by collisions do not conflict in a unique index
+				if err := tx.Exec("CREATE UNIQUE INDEX idx_user_info_username_normalized ON user_info (username_normalized)").Error; err != nil {
+					return err
+				}
+				return tx.Exec("CREATE UNIQUE INDEX idx_user_info_email_normalized ON user_info (email_normalized)").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Exec("ALTER TABLE user_info DROP COLUMN IF EXISTS username_normalized").Error; err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE user_info DROP COLUMN IF EXISTS email_normalized").Error
+			},
+		},
+		{
+			ID: "20250805_001_add_login_events",
+			Migrate: func(tx *gorm.DB) error {
+				// Create table for the login history and login alerts
+				type LoginEvent struct {
+					ID                uint         `gorm:"primaryKey"`
+					UserID            uint         `gorm:"not null;index"`
+					SessionID         uint         `gorm:"index"`
+					IPAddress         string       `gorm:"size:45"`
+					UserAgent         string       `gorm:"size:500"`
+					Browser           string       `gorm:"size:50"`
+					OS                string       `gorm:"size:50"`
+					DeviceType        string       `gorm:"size:20"`
+					DeviceFingerprint string       `gorm:"not null;index;size:64"`
+					NewDevice         bool         `gorm:"default:false"`
+					Suspicious        bool         `gorm:"default:false"`
+					ReportTokenHash   *string      `gorm:"uniqueIndex;size:64"`
+					ReportExpiresAt   *interface{} `gorm:"type:timestamp"`
+					ReportedAt        *interface{} `gorm:"type:timestamp"`
+					CreatedAt         interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("login_events").AutoMigrate(&LoginEvent{}); err != nil {
+					return err
+				}
+
+				// Add foreign key constraint
+				return tx.Exec("ALTER TABLE login_events ADD CONSTRAINT fk_login_events_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("login_events")
+			},
+		},
+		{
+			ID: "20250806_001_add_session_authenticated_at",
+			Migrate: func(tx *gorm.DB) error {
+				// Track when the user last proved their credentials, existing sessions count from their login
+				if err := tx.Exec("ALTER TABLE sessions ADD COLUMN authenticated_at TIMESTAMP").Error; err != nil {
+					return err
+				}
+				return tx.Exec("UPDATE sessions SET authenticated_at = created_at").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec("ALTER TABLE sessions DROP COLUMN IF EXISTS authenticated_at").Error
+			},
+		},
+		{
+			ID: "20250807_001_add_organizations",
+			Migrate: func(tx *gorm.DB) error {
+				// Bring organizations back as tenants; roles inside them are Casbin rules in the "org:<id>" domain
+				type Organization struct {
+					ID          uint         `gorm:"primaryKey"`
+					Name        string       `gorm:"not null;size:255"`
+					Slug        string       `gorm:"uniqueIndex;not null;size:100"`
+					Description string       `gorm:"size:500"`
+					IsActive    bool         `gorm:"default:true"`
+					CreatedByID uint         `gorm:"index"`
+					CreatedAt   interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt   interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					DeletedAt   *interface{} `gorm:"type:timestamp;index"`
+				}
+
+				if err := tx.Table("organizations").AutoMigrate(&Organization{}); err != nil {
+					return err
+				}
+
+				type OrganizationMember struct {
+					ID             uint        `gorm:"primaryKey"`
+					OrganizationID uint        `gorm:"not null;uniqueIndex:idx_organization_members_org_user"`
+					UserID         uint        `gorm:"not null;uniqueIndex:idx_organization_members_org_user;index"`
+					CreatedAt      interface{} `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("organization_members").AutoMigrate(&OrganizationMember{}); err != nil {
+					return err
+				}
+
+				constraints := []string{
+					"ALTER TABLE organization_members ADD CONSTRAINT fk_organization_members_organization_id FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE",
+					"ALTER TABLE organization_members ADD CONSTRAINT fk_organization_members_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE",
+					"ALTER TABLE sessions ADD COLUMN organization_id INTEGER",
+					"CREATE INDEX idx_sessions_organization_id ON sessions (organization_id)",
+				}
+				for _, constraint := range constraints {
+					if err := tx.Exec(constraint).Error; err != nil {
+						return err
+					}
+				}
+
+				// The Casbin adapter creates the rules table on first start, fresh databases have none yet
+				if !tx.Migrator().HasTable("rules") {
+					return nil
+				}
+
+				// Move existing policies and role assignments to the global domain:
+				// p, sub, obj, act -> p, sub, *, obj, act and g, user, role -> g, user, role, *
+				statements := []string{
+					"UPDATE rules SET v3 = v2, v2 = v1, v1 = '*' WHERE ptype = 'p'",
+					"UPDATE rules SET v2 = '*' WHERE ptype = 'g'",
+					`INSERT INTO rules (ptype, v0, v1, v2, v3, v4, v5) VALUES
+						('p', 'admin', 'org:*', 'organizations', 'read', '', ''),
+						('p', 'admin', 'org:*', 'organizations', 'update', '', ''),
+						('p', 'admin', 'org:*', 'organizations', 'delete', '', ''),
+						('p', 'user', 'org:*', 'organizations', 'read', '', '')
+					ON CONFLICT DO NOTHING`,
+				}
+				for _, statement := range statements {
+					if err := tx.Exec(statement).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if tx.Migrator().HasTable("rules") {
+					statements := []string{
+						"DELETE FROM rules WHERE (ptype = 'p' AND v1 <> '*') OR (ptype = 'g' AND v2 <> '*')",
+						"UPDATE rules SET v1 = v2, v2 = v3, v3 = '' WHERE ptype = 'p'",
+						"UPDATE rules SET v2 = '' WHERE ptype = 'g'",
+					}
+					for _, statement := range statements {
+						if err := tx.Exec(statement).Error; err != nil {
+							return err
+						}
+					}
+				}
+
+				if err := tx.Exec("ALTER TABLE sessions DROP COLUMN IF EXISTS organization_id").Error; err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("organization_members", "organizations")
+			},
+		},
+		{
+			ID: "20250808_001_add_policy_effect",
+			Migrate: func(tx *gorm.DB) error {
+				// Policies carry an explicit effect in v4 so that deny rules can carve exceptions
+				// out of wildcard grants; every existing policy is an allow rule
+				if !tx.Migrator().HasTable("rules") {
+					return nil
+				}
+				return tx.Exec("UPDATE rules SET v4 = 'allow' WHERE ptype = 'p' AND (v4 IS NULL OR v4 = '')").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if !tx.Migrator().HasTable("rules") {
+					return nil
+				}
+				statements := []string{
+					"DELETE FROM rules WHERE ptype = 'p' AND v4 = 'deny'",
+					"UPDATE rules SET v4 = '' WHERE ptype = 'p'",
+				}
+				for _, statement := range statements {
+					if err := tx.Exec(statement).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20250809_001_add_policy_conditions",
+			Migrate: func(tx *gorm.DB) error {
+				// Policies become (sub, dom, obj, act, cond, eft). The effect moves to v5 so that it stays
+				// the last column: the adapter trims trailing empty columns, and most conditions are empty.
+				if !tx.Migrator().HasTable("rules") {
+					return nil
+				}
+				return tx.Exec("UPDATE rules SET v5 = v4, v4 = '' WHERE ptype = 'p'").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if !tx.Migrator().HasTable("rules") {
+					return nil
+				}
+				statements := []string{
+					"DELETE FROM rules WHERE ptype = 'p' AND v4 <> ''",
+					"UPDATE rules SET v4 = v5, v5 = '' WHERE ptype = 'p'",
+				}
+				for _, statement := range statements {
+					if err := tx.Exec(statement).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20250810_001_add_role_assignments",
+			Migrate: func(tx *gorm.DB) error {
+				// Validity windows of time-bound role assignments; the g rule in "rules" only counts while the window is open
+				type RoleAssignment struct {
+					ID               uint         `gorm:"primaryKey"`
+					UserID           uint         `gorm:"not null;uniqueIndex:idx_role_assignments_grant"`
+					Role             string       `gorm:"not null;size:100;uniqueIndex:idx_role_assignments_grant"`
+					Domain           string       `gorm:"not null;size:100;uniqueIndex:idx_role_assignments_grant"`
+					StartsAt         *interface{} `gorm:"type:timestamp"`
+					ExpiresAt        *interface{} `gorm:"type:timestamp;index"`
+					ExpiryNotifiedAt *interface{} `gorm:"type:timestamp"`
+					CreatedAt        interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+					UpdatedAt        interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("role_assignments").AutoMigrate(&RoleAssignment{}); err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE role_assignments ADD CONSTRAINT fk_role_assignments_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("role_assignments")
+			},
+		},
+		{
+			ID: "20250811_001_add_mfa_challenges",
+			Migrate: func(tx *gorm.DB) error {
+				// Pending MFA logins, so that a challenge token is single-use and accepts a limited number of codes
+				type MFAChallenge struct {
+					ID         uint         `gorm:"primaryKey"`
+					UserID     uint         `gorm:"not null;index"`
+					TokenHash  string       `gorm:"not null;uniqueIndex;size:64"`
+					Attempts   int          `gorm:"not null;default:0"`
+					ExpiresAt  interface{}  `gorm:"type:timestamp;not null"`
+					ConsumedAt *interface{} `gorm:"type:timestamp"`
+					CreatedAt  interface{}  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+				}
+
+				if err := tx.Table("mfa_challenges").AutoMigrate(&MFAChallenge{}); err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE mfa_challenges ADD CONSTRAINT fk_mfa_challenges_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("mfa_challenges")
+			},
+		},
+		{
+			ID: "20250812_001_add_oidc_previous_refresh_token",
+			Migrate: func(tx *gorm.DB) error {
+				// Remember the last rotated-out refresh token, so that presenting it again revokes the grant
+				if err := tx.Exec("ALTER TABLE oidc_tokens ADD COLUMN previous_refresh_token_hash VARCHAR(64)").Error; err != nil {
+					return err
+				}
+				return tx.Exec("CREATE INDEX IF NOT EXISTS idx_oidc_tokens_previous_refresh_token_hash ON oidc_tokens(previous_refresh_token_hash)").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec("ALTER TABLE oidc_tokens DROP COLUMN IF EXISTS previous_refresh_token_hash").Error
+			},
+		},
+	}
+}
+
+// backfillNormalizedIdentifiers fills the normalized username and email columns. Accounts that
+// only differ by case or Unicode form cannot share a value: the oldest active account keeps it,
+// the others are reported and left NULL until an administrator renames them.
+func backfillNormalizedIdentifiers(tx *gorm.DB) error {
+	type userInfoRow struct {
+		ID       uint
+		UserID   uint
+		Username string
+		Email    string
+	}
+
+	var rows []userInfoRow
+	if err := tx.Table("user_info").Select("id, user_id, username, email").Order("deleted_at IS NOT NULL, id").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	usernameOwners := make(map[string]uint)
+	emailOwners := make(map[string]uint)
+	collisions := 0
+	for _, row := range rows {
+		updates := make(map[string]interface{})
+
+		username := identifier.Normalize(row.Username)
+		if owner, ok := usernameOwners[username]; ok {
+			log.Printf("Identifier collision: username %q of user %d matches user %d", row.Username, row.UserID, owner)
+			collisions++
+		} else {
+			usernameOwners[username] = row.UserID
+			updates["username_normalized"] = username
+		}
+
+		email := identifier.Normalize(row.Email)
+		if owner, ok := emailOwners[email]; ok {
+			log.Printf("Identifier collision: email %q of user %d matches user %d", row.Email, row.UserID, owner)
+			collisions++
+		} else {
+			emailOwners[email] = row.UserID
+			updates["email_normalized"] = email
+		}
+
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Table("user_info").Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if collisions > 0 {
+		log.Printf("Found %d username/email collisions; the reported accounts only log in with the exact spelling until renamed", collisions)
 	}
+	return nil
 }
 
 // GetInitialMigration returns the initial migration that creates all tables at once
@@ -777,10 +1586,54 @@ func GetInitialMigration() *gormigrate.Migration {
 				&models.AuthProvider{},
 				&models.EmailVerificationToken{},
 				&models.PasswordResetToken{},
+				&models.Session{},
+				&models.UserMFA{},
+				&models.MFARecoveryCode{},
+				&models.WebAuthnCredential{},
+				&models.WebAuthnChallenge{},
+				&models.SigningKey{},
+				&models.PersonalAccessToken{},
+				&models.AccountLockout{},
+				&models.MagicLinkToken{},
+				&models.PasswordHistory{},
+				&models.OIDCClient{},
+				&models.OIDCGrant{},
+				&models.OIDCAuthorizationCode{},
+				&models.OIDCToken{},
+				&models.SAMLIdentityProvider{},
+				&models.DeviceAuthorization{},
+				&models.EmailChangeRequest{},
+				&models.LoginEvent{},
+				&models.Organization{},
+				&models.OrganizationMember{},
+				&models.RoleAssignment{},
+				&models.MFAChallenge{},
 			)
 		},
 		Rollback: func(tx *gorm.DB) error {
 			return tx.Migrator().DropTable(
+				&models.MFAChallenge{},
+				&models.RoleAssignment{},
+				&models.OrganizationMember{},
+				&models.Organization{},
+				&models.LoginEvent{},
+				&models.EmailChangeRequest{},
+				&models.DeviceAuthorization{},
+				&models.SAMLIdentityProvider{},
+				&models.OIDCToken{},
+				&models.OIDCAuthorizationCode{},
+				&models.OIDCGrant{},
+				&models.OIDCClient{},
+				&models.PasswordHistory{},
+				&models.MagicLinkToken{},
+				&models.AccountLockout{},
+				&models.PersonalAccessToken{},
+				&models.SigningKey{},
+				&models.WebAuthnChallenge{},
+				&models.WebAuthnCredential{},
+				&models.MFARecoveryCode{},
+				&models.UserMFA{},
+				&models.Session{},
 				&models.PasswordResetToken{},
 				&models.EmailVerificationToken{},
 				&models.AuthProvider{},
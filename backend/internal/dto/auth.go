@@ -1,19 +1,50 @@
 package dto
 
+import "time"
+
 type RegisterRequest struct {
 	Username  string `json:"username" validate:"required,min=3,max=30"`
 	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8"`
+	Password  string `json:"password" validate:"required"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
+	Username string `json:"username" validate:"required"` // Username or email, compared case-insensitively
 	Password string `json:"password" validate:"required"`
 }
 
+// ReauthenticateRequest proves the identity of a signed-in user again with the password or a second factor
+type ReauthenticateRequest struct {
+	Password     string `json:"password,omitempty"`
+	Code         string `json:"code,omitempty"`          // TOTP code
+	RecoveryCode string `json:"recovery_code,omitempty"` // MFA recovery code
+}
+
+// ChangeExpiredPasswordRequest replaces a password that is past the maximum age
+type ChangeExpiredPasswordRequest struct {
+	Username        string `json:"username" validate:"required"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// AuthResponse is returned by login flows. When the user has MFA enabled, only
+// MFARequired and ChallengeToken are set and the challenge must be completed at /auth/mfa/verify.
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token          string        `json:"token,omitempty"`
+	RefreshToken   string        `json:"refresh_token,omitempty"`
+	ExpiresAt      *time.Time    `json:"expires_at,omitempty"`
+	MFARequired    bool          `json:"mfa_required,omitempty"`
+	ChallengeToken string        `json:"challenge_token,omitempty"`
+	ImpersonatorID uint          `json:"impersonator_id,omitempty"` // Set on impersonation tokens, which come without a refresh token
+	User           *UserResponse `json:"user,omitempty"`
 }
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// ChallengeResponse tells the client how to prove it is not a bot. With type "pow" the client finds a
+// nonce such that SHA-256(challenge + ":" + nonce) starts with difficulty zero bits and sends
+// "<challenge>:<nonce>" in the X-Challenge-Solution header. Type "none" means no challenge is required.
+type ChallengeResponse struct {
+	Type       string     `json:"type"`
+	Challenge  string     `json:"challenge,omitempty"`
+	Algorithm  string     `json:"algorithm,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,91 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// DeviceAuthorizationRequest is a form-encoded RFC 8628 device authorization request
+type DeviceAuthorizationRequest struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// DeviceAuthorizationResponse gives the device the codes to poll with and to show the user
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceTokenResponse carries the bezbase access and refresh tokens issued to an approved device
+type DeviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// DeviceVerificationResponse describes a pending request on the approval screen, so the user can
+// check that it comes from their device
+type DeviceVerificationResponse struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	RequestIP  string    `json:"request_ip"`
+	UserAgent  string    `json:"user_agent"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// DeviceDecisionRequest is the user's answer for a user code
+type DeviceDecisionRequest struct {
+	UserCode string `json:"user_code" validate:"required"`
+	Approve  bool   `json:"approve"`
+}
+
+// DeviceAuthorizationResponseItem describes a device authorization request for admins
+type DeviceAuthorizationResponseItem struct {
+	ID           uint                             `json:"id"`
+	ClientID     string                           `json:"client_id"`
+	ClientName   string                           `json:"client_name"`
+	Status       models.DeviceAuthorizationStatus `json:"status"`
+	UserID       *uint                            `json:"user_id,omitempty"`
+	Username     string                           `json:"username,omitempty"`
+	RequestIP    string                           `json:"request_ip"`
+	UserAgent    string                           `json:"user_agent"`
+	Interval     int                              `json:"interval"`
+	ExpiresAt    time.Time                        `json:"expires_at"`
+	LastPolledAt *time.Time                       `json:"last_polled_at,omitempty"`
+	DecidedAt    *time.Time                       `json:"decided_at,omitempty"`
+	CompletedAt  *time.Time                       `json:"completed_at,omitempty"`
+	CreatedAt    time.Time                        `json:"created_at"`
+}
+
+// ToDeviceAuthorizationResponses converts device authorization models to DTOs
+func ToDeviceAuthorizationResponses(authorizations []models.DeviceAuthorization) []DeviceAuthorizationResponseItem {
+	responses := make([]DeviceAuthorizationResponseItem, len(authorizations))
+	for i, authorization := range authorizations {
+		responses[i] = DeviceAuthorizationResponseItem{
+			ID:           authorization.ID,
+			ClientID:     authorization.Client.ClientID,
+			ClientName:   authorization.Client.Name,
+			Status:       authorization.CurrentStatus(),
+			UserID:       authorization.UserID,
+			RequestIP:    authorization.RequestIP,
+			UserAgent:    authorization.UserAgent,
+			Interval:     authorization.Interval,
+			ExpiresAt:    authorization.ExpiresAt,
+			LastPolledAt: authorization.LastPolledAt,
+			DecidedAt:    authorization.DecidedAt,
+			CompletedAt:  authorization.CompletedAt,
+			CreatedAt:    authorization.CreatedAt,
+		}
+		if authorization.User != nil && authorization.User.UserInfo != nil {
+			responses[i].Username = authorization.User.UserInfo.Username
+		}
+	}
+	return responses
+}
@@ -0,0 +1,11 @@
+package dto
+
+// EmailChangeTokenRequest confirms or reverts an email change with the token from an emailed link
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ChangeEmailRequest starts a change of the current user's email to Email
+type ChangeEmailRequest struct {
+	Email string `json:"email" validate:"required"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// LinkedAccountResponse describes one sign-in method of the current user
+type LinkedAccountResponse struct {
+	Provider    models.AuthProviderType `json:"provider"`
+	UserName    string                  `json:"user_name"`
+	Verified    bool                    `json:"verified"`
+	HasPassword bool                    `json:"has_password,omitempty"` // Only for the email provider
+	CreatedAt   time.Time               `json:"created_at"`
+}
+
+// SetPasswordRequest adds a password to an account without one
+type SetPasswordRequest struct {
+	NewPassword     string `json:"new_password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+// ToLinkedAccountResponses converts auth providers to DTOs
+func ToLinkedAccountResponses(providers []models.AuthProvider) []LinkedAccountResponse {
+	responses := make([]LinkedAccountResponse, len(providers))
+	for i, provider := range providers {
+		responses[i] = LinkedAccountResponse{
+			Provider:    provider.Provider,
+			UserName:    provider.UserName,
+			Verified:    provider.Verified,
+			HasPassword: provider.Provider == models.ProviderEmail && provider.Password != "",
+			CreatedAt:   provider.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// UnlockAccountRequest lifts a lock with the token from the unlock email
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// LockedAccountResponse describes an account locked after too many failed logins
+type LockedAccountResponse struct {
+	UserID         uint       `json:"user_id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LastFailedAt   *time.Time `json:"last_failed_at,omitempty"`
+	LastFailedIP   string     `json:"last_failed_ip,omitempty"`
+	LockedAt       *time.Time `json:"locked_at"`
+	LockedUntil    *time.Time `json:"locked_until"`
+}
+
+// ToLockedAccountResponses converts lockout models to DTOs
+func ToLockedAccountResponses(lockouts []models.AccountLockout) []LockedAccountResponse {
+	responses := make([]LockedAccountResponse, len(lockouts))
+	for i, lockout := range lockouts {
+		responses[i] = LockedAccountResponse{
+			UserID:         lockout.UserID,
+			Email:          lockout.User.GetPrimaryEmail(),
+			FailedAttempts: lockout.FailedAttempts,
+			LastFailedAt:   lockout.LastFailedAt,
+			LastFailedIP:   lockout.LastFailedIP,
+			LockedAt:       lockout.LockedAt,
+			LockedUntil:    lockout.BlockedUntil,
+		}
+		if lockout.User.UserInfo != nil {
+			responses[i].Username = lockout.User.UserInfo.Username
+		}
+	}
+	return responses
+}
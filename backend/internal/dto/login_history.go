@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+type LoginEventResponse struct {
+	ID                uint       `json:"id"`
+	SessionID         uint       `json:"session_id"`
+	IPAddress         string     `json:"ip_address"`
+	UserAgent         string     `json:"user_agent"`
+	Browser           string     `json:"browser"`
+	OS                string     `json:"os"`
+	DeviceType        string     `json:"device_type"`
+	DeviceFingerprint string     `json:"device_fingerprint"`
+	NewDevice         bool       `json:"new_device"`
+	Suspicious        bool       `json:"suspicious"`
+	Alerted           bool       `json:"alerted"`               // An email with a "this wasn't me" link was sent
+	ReportedAt        *time.Time `json:"reported_at,omitempty"` // The user reported the login as not theirs
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// LoginReportRequest carries the token of the "this wasn't me" link in a login alert
+type LoginReportRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ToLoginEventResponses converts login history entries to DTOs
+func ToLoginEventResponses(events []models.LoginEvent) []LoginEventResponse {
+	responses := make([]LoginEventResponse, len(events))
+	for i, event := range events {
+		responses[i] = LoginEventResponse{
+			ID:                event.ID,
+			SessionID:         event.SessionID,
+			IPAddress:         event.IPAddress,
+			UserAgent:         event.UserAgent,
+			Browser:           event.Browser,
+			OS:                event.OS,
+			DeviceType:        event.DeviceType,
+			DeviceFingerprint: event.DeviceFingerprint,
+			NewDevice:         event.NewDevice,
+			Suspicious:        event.Suspicious,
+			Alerted:           event.ReportTokenHash != nil,
+			ReportedAt:        event.ReportedAt,
+			CreatedAt:         event.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,11 @@
+package dto
+
+// MagicLinkRequest asks for a sign-in link to be emailed
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MagicLinkLoginRequest redeems the token from an emailed sign-in link
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// TOTPEnrollResponse carries the secret and otpauth:// URI to be scanned by an authenticator app
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACodeRequest proves possession of the second factor with a TOTP code or, where allowed, a recovery code
+type MFACodeRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// MFARecoveryCodesResponse returns freshly generated recovery codes; they are only shown once
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse describes the current user's MFA enrollment
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFAVerifyRequest exchanges a login challenge token plus a TOTP or recovery code for a token pair
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+// OAuthProviderResponse describes a configured social login provider
+type OAuthProviderResponse struct {
+	Name         string `json:"name"`
+	AuthorizeURL string `json:"authorize_url"`
+}
+
+// OAuthAuthorizeResponse returns the provider URL the browser must be sent to
+type OAuthAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OAuthLinkConfirmRequest confirms linking a social account to the existing account with the same email.
+// Password is required on the public endpoint and ignored when the caller is already signed in.
+type OAuthLinkConfirmRequest struct {
+	LinkToken string `json:"link_token" validate:"required"`
+	Password  string `json:"password"`
+}
+
+// OAuthCallbackResult is the outcome of a provider callback
+type OAuthCallbackResult struct {
+	Auth         *AuthResponse // Set when the user was signed in (or must complete MFA)
+	LinkRequired bool          // The email belongs to an existing account; the owner must confirm the link
+	LinkToken    string
+	Email        string
+	Linked       bool // A signed-in user linked a new provider
+}
@@ -0,0 +1,200 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// CreateOIDCClientRequest registers an application that signs users in through bezbase
+type CreateOIDCClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1"`
+	Scopes       []string `json:"scopes"`       // Scopes the client may request; defaults to openid, profile and email
+	Public       bool     `json:"public"`       // Public clients (SPAs, native apps) get no secret and rely on PKCE
+	SkipConsent  bool     `json:"skip_consent"` // For first-party apps whose users should not see a consent screen
+}
+
+// UpdateOIDCClientRequest changes a client; omitted fields are left unchanged
+type UpdateOIDCClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	SkipConsent  *bool    `json:"skip_consent"`
+}
+
+// OIDCClientResponse describes a client without its secret
+type OIDCClientResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	SkipConsent  bool      `json:"skip_consent"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OIDCClientCreatedResponse includes the plain client secret, which is only shown once
+type OIDCClientCreatedResponse struct {
+	OIDCClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OIDCGrantResponse describes the scopes a user consented to for a client
+type OIDCGrantResponse struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OIDCAuthorizeRequest carries the parameters of an authorization request, forwarded by the consent page
+type OIDCAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type"`
+	ClientID            string `json:"client_id" query:"client_id"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri"`
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	Nonce               string `json:"nonce" query:"nonce"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+	Prompt              string `json:"prompt" query:"prompt"`
+}
+
+// OIDCAuthorizeDecisionRequest is the user's answer on the consent screen
+type OIDCAuthorizeDecisionRequest struct {
+	OIDCAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OIDCConsentClient is the client information shown on the consent screen
+type OIDCConsentClient struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+}
+
+// OIDCAuthorizeResponse tells the consent page what to show, or where to send the browser next
+type OIDCAuthorizeResponse struct {
+	Client          OIDCConsentClient `json:"client"`
+	Scopes          []string          `json:"scopes"`
+	ConsentRequired bool              `json:"consent_required"`
+	RedirectTo      string            `json:"redirect_to,omitempty"` // Set once there is a code or an error for the client
+}
+
+// OIDCTokenRequest is a form-encoded token endpoint request
+type OIDCTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	DeviceCode   string `form:"device_code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OIDCTokenResponse is the token endpoint response
+type OIDCTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// OIDCTokenActionRequest is a form-encoded introspection or revocation request
+type OIDCTokenActionRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OIDCIntrospectionResponse is an RFC 7662 token introspection response
+type OIDCIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// OAuth2ErrorResponse is the RFC 6749 error format used by the protocol endpoints
+type OAuth2ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OIDCDiscoveryDocument is the OpenID Provider metadata served at /.well-known/openid-configuration
+type OIDCDiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// ToOIDCClientResponse converts a client model to a DTO
+func ToOIDCClientResponse(client *models.OIDCClient) OIDCClientResponse {
+	return OIDCClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIList(),
+		Scopes:       client.ScopeList(),
+		Public:       client.IsPublic(),
+		SkipConsent:  client.SkipConsent,
+		CreatedAt:    client.CreatedAt,
+		UpdatedAt:    client.UpdatedAt,
+	}
+}
+
+// ToOIDCClientResponses converts client models to DTOs
+func ToOIDCClientResponses(clients []models.OIDCClient) []OIDCClientResponse {
+	responses := make([]OIDCClientResponse, len(clients))
+	for i := range clients {
+		responses[i] = ToOIDCClientResponse(&clients[i])
+	}
+	return responses
+}
+
+// ToOIDCGrantResponses converts grants with their client (and user, when loaded) to DTOs
+func ToOIDCGrantResponses(grants []models.OIDCGrant) []OIDCGrantResponse {
+	responses := make([]OIDCGrantResponse, len(grants))
+	for i, grant := range grants {
+		responses[i] = OIDCGrantResponse{
+			ClientID:   grant.Client.ClientID,
+			ClientName: grant.Client.Name,
+			UserID:     grant.UserID,
+			Scopes:     grant.ScopeList(),
+			CreatedAt:  grant.CreatedAt,
+			UpdatedAt:  grant.UpdatedAt,
+		}
+		if grant.User.UserInfo != nil {
+			responses[i].Username = grant.User.UserInfo.Username
+		}
+	}
+	return responses
+}
@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// CreateOrganizationRequest creates an organization; the creator becomes its first admin
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Slug        string `json:"slug" validate:"required,max=100"` // Lowercase letters, digits and hyphens
+	Description string `json:"description" validate:"max=500"`
+}
+
+// UpdateOrganizationRequest changes the fields that are set
+type UpdateOrganizationRequest struct {
+	Name        string `json:"name" validate:"max=255"`
+	Description string `json:"description" validate:"max=500"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// AddOrganizationMemberRequest adds a user with roles held inside the organization
+type AddOrganizationMemberRequest struct {
+	UserID uint     `json:"user_id" validate:"required"`
+	Roles  []string `json:"roles"` // Defaults to the "user" role
+}
+
+// UpdateOrganizationMemberRequest replaces the roles a member holds inside the organization
+type UpdateOrganizationMemberRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1"`
+}
+
+// SwitchOrganizationRequest selects the active organization of the session; 0 clears it
+type SwitchOrganizationRequest struct {
+	OrganizationID uint `json:"organization_id"`
+}
+
+// OrganizationResponse describes an organization
+type OrganizationResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OrganizationMemberResponse describes a member and the roles they hold in the organization
+type OrganizationMemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Roles    []string  `json:"roles"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ToOrganizationResponse converts an organization model to a DTO
+func ToOrganizationResponse(organization *models.Organization) OrganizationResponse {
+	return OrganizationResponse{
+		ID:          organization.ID,
+		Name:        organization.Name,
+		Slug:        organization.Slug,
+		Description: organization.Description,
+		IsActive:    organization.IsActive,
+		CreatedAt:   organization.CreatedAt,
+		UpdatedAt:   organization.UpdatedAt,
+	}
+}
+
+// ToOrganizationResponses converts organization models to DTOs
+func ToOrganizationResponses(organizations []models.Organization) []OrganizationResponse {
+	responses := make([]OrganizationResponse, len(organizations))
+	for i := range organizations {
+		responses[i] = ToOrganizationResponse(&organizations[i])
+	}
+	return responses
+}
+
+// ToOrganizationMemberResponse converts a membership and the member's roles to a DTO
+func ToOrganizationMemberResponse(member *models.OrganizationMember, roles []string) OrganizationMemberResponse {
+	return OrganizationMemberResponse{
+		UserID:   member.UserID,
+		Email:    member.User.GetPrimaryEmail(),
+		Name:     member.User.GetFullName(),
+		Roles:    roles,
+		JoinedAt: member.CreatedAt,
+	}
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/webauthn"
+)
+
+// PasskeyRegistrationRequest finishes a registration ceremony with the output of PublicKeyCredential.toJSON()
+type PasskeyRegistrationRequest struct {
+	Name       string                        `json:"name" validate:"max=100"`
+	Credential webauthn.RegistrationResponse `json:"credential" validate:"required"`
+}
+
+// PasskeyLoginRequest finishes a login ceremony with the output of PublicKeyCredential.toJSON()
+type PasskeyLoginRequest struct {
+	Credential webauthn.AuthenticationResponse `json:"credential" validate:"required"`
+}
+
+// RenamePasskeyRequest changes the display name of a passkey
+type RenamePasskeyRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// PasskeyResponse describes a registered passkey without its key material
+type PasskeyResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports,omitempty"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ToPasskeyResponse converts a WebAuthnCredential model to a PasskeyResponse DTO
+func ToPasskeyResponse(credential *models.WebAuthnCredential) PasskeyResponse {
+	resp := PasskeyResponse{
+		ID:             credential.ID,
+		Name:           credential.Name,
+		BackupEligible: credential.BackupEligible,
+		BackupState:    credential.BackupState,
+		CreatedAt:      credential.CreatedAt,
+		LastUsedAt:     credential.LastUsedAt,
+	}
+	if credential.Transports != "" {
+		resp.Transports = strings.Split(credential.Transports, ",")
+	}
+	return resp
+}
+
+// ToPasskeyResponses converts a slice of WebAuthnCredential models to PasskeyResponse DTOs
+func ToPasskeyResponses(credentials []models.WebAuthnCredential) []PasskeyResponse {
+	responses := make([]PasskeyResponse, 0, len(credentials))
+	for i := range credentials {
+		responses = append(responses, ToPasskeyResponse(&credentials[i]))
+	}
+	return responses
+}
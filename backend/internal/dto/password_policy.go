@@ -0,0 +1,15 @@
+package dto
+
+// PasswordPolicyErrorResponse is returned when a new password breaks the password policy
+type PasswordPolicyErrorResponse struct {
+	Message    string                    `json:"message"`
+	Code       string                    `json:"code"`
+	Violations []PasswordPolicyViolation `json:"violations"`
+}
+
+// PasswordPolicyViolation describes one broken rule. Code is stable for clients; Message is translated.
+type PasswordPolicyViolation struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
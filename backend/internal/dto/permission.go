@@ -1,22 +1,41 @@
 package dto
 
+import "time"
+
 // Role management endpoints
 type AssignRoleRequest struct {
-	UserID uint   `json:"user_id" validate:"required"`
-	Role   string `json:"role" validate:"required"`
+	UserID         uint       `json:"user_id" validate:"required"`
+	Role           string     `json:"role" validate:"required"`
+	OrganizationID uint       `json:"organization_id,omitempty"` // Assign inside an organization instead of globally
+	StartsAt       *time.Time `json:"starts_at,omitempty"`       // The role is granted from this time on; now when omitted
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // The role is revoked at this time; never when omitted
+}
+
+// RoleAssignmentResponse describes a role held by a user and the validity window of the assignment
+type RoleAssignmentResponse struct {
+	UserID    uint       `json:"user_id"`
+	Role      string     `json:"role"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Active    bool       `json:"active"` // False while the assignment has not started yet
 }
 
 type PermissionRequest struct {
-	Role     string `json:"role" validate:"required"`
-	Resource string `json:"resource" validate:"required"`
-	Action   string `json:"action" validate:"required"`
+	Role      string `json:"role" validate:"required"`
+	Resource  string `json:"resource" validate:"required"`
+	Action    string `json:"action" validate:"required"`
+	Effect    string `json:"effect,omitempty" validate:"omitempty,oneof=allow deny"` // Defaults to "allow"
+	Condition string `json:"condition,omitempty" validate:"max=100"`                 // e.g. "subject.id == resource.owner_id"
 }
 
 type PermissionResponse struct {
 	ID         int    `json:"id"`
 	Role       string `json:"role"`
+	Domain     string `json:"domain"` // "*" for global policies, "org:<id>" or "org:*" for organization policies
 	Resource   string `json:"resource"`
 	Action     string `json:"action"`
+	Condition  string `json:"condition,omitempty"` // Expression over subject.*, resource.* and env.*; empty always holds
+	Effect     string `json:"effect"`              // "allow" or "deny"; a matching deny overrides every allow
 	Permission string `json:"permission"`
 }
 
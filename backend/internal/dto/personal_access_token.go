@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// CreatePersonalAccessTokenRequest creates a token limited to a subset of the user's permissions
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"` // resource:action pairs, e.g. "users:read"
+	ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1"`
+}
+
+// PersonalAccessTokenResponse describes a token without its secret
+type PersonalAccessTokenResponse struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PersonalAccessTokenCreatedResponse includes the plain token, which is only shown once
+type PersonalAccessTokenCreatedResponse struct {
+	PersonalAccessTokenResponse
+	Token string `json:"token"`
+}
+
+// ToPersonalAccessTokenResponse converts a token model to a DTO
+func ToPersonalAccessTokenResponse(token *models.PersonalAccessToken) PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:         token.ID,
+		UserID:     token.UserID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		RevokedAt:  token.RevokedAt,
+		Active:     token.IsActive(),
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// ToPersonalAccessTokenResponses converts token models to DTOs
+func ToPersonalAccessTokenResponses(tokens []models.PersonalAccessToken) []PersonalAccessTokenResponse {
+	responses := make([]PersonalAccessTokenResponse, len(tokens))
+	for i := range tokens {
+		responses[i] = ToPersonalAccessTokenResponse(&tokens[i])
+	}
+	return responses
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+// CreateSAMLProviderRequest registers a corporate identity provider
+type CreateSAMLProviderRequest struct {
+	Slug             string            `json:"slug" validate:"required,max=50"` // Lowercase letters, digits and dashes
+	Name             string            `json:"name" validate:"required,max=100"`
+	MetadataXML      string            `json:"metadata_xml" validate:"required"`
+	Certificates     string            `json:"certificates"`      // PEM signing certificates that replace the ones in the metadata
+	AttributeMapping map[string]string `json:"attribute_mapping"` // UserInfo field name -> SAML attribute name
+	Enabled          *bool             `json:"enabled"`           // Defaults to true
+}
+
+// UpdateSAMLProviderRequest changes an identity provider; omitted fields are left unchanged
+type UpdateSAMLProviderRequest struct {
+	Name             string            `json:"name"`
+	MetadataXML      string            `json:"metadata_xml"`
+	Certificates     *string           `json:"certificates"` // An empty string goes back to the metadata certificates
+	AttributeMapping map[string]string `json:"attribute_mapping"`
+	Enabled          *bool             `json:"enabled"`
+}
+
+// SAMLProviderResponse describes an identity provider and the service provider URLs to register with it
+type SAMLProviderResponse struct {
+	ID               uint              `json:"id"`
+	Slug             string            `json:"slug"`
+	Name             string            `json:"name"`
+	MetadataXML      string            `json:"metadata_xml"`
+	Certificates     string            `json:"certificates,omitempty"`
+	AttributeMapping map[string]string `json:"attribute_mapping"`
+	Enabled          bool              `json:"enabled"`
+	EntityID         string            `json:"entity_id"` // Also the service provider metadata URL
+	ACSURL           string            `json:"acs_url"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// SAMLLoginProviderResponse describes an identity provider users can sign in with
+type SAMLLoginProviderResponse struct {
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	LoginURL string `json:"login_url"`
+}
+
+// ToSAMLProviderResponse converts a provider model to a DTO; baseURL is the public URL of the SAML routes
+func ToSAMLProviderResponse(provider *models.SAMLIdentityProvider, baseURL string) SAMLProviderResponse {
+	return SAMLProviderResponse{
+		ID:               provider.ID,
+		Slug:             provider.Slug,
+		Name:             provider.Name,
+		MetadataXML:      provider.MetadataXML,
+		Certificates:     provider.Certificates,
+		AttributeMapping: provider.Mapping(),
+		Enabled:          provider.Enabled,
+		EntityID:         baseURL + "/" + provider.Slug + "/metadata",
+		ACSURL:           baseURL + "/" + provider.Slug + "/acs",
+		CreatedAt:        provider.CreatedAt,
+		UpdatedAt:        provider.UpdatedAt,
+	}
+}
+
+// ToSAMLProviderResponses converts provider models to DTOs
+func ToSAMLProviderResponses(providers []models.SAMLIdentityProvider, baseURL string) []SAMLProviderResponse {
+	responses := make([]SAMLProviderResponse, len(providers))
+	for i := range providers {
+		responses[i] = ToSAMLProviderResponse(&providers[i], baseURL)
+	}
+	return responses
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"time"
+
+	"bezbase/internal/models"
+)
+
+type SessionResponse struct {
+	ID             uint      `json:"id"`
+	UserAgent      string    `json:"user_agent"`
+	IPAddress      string    `json:"ip_address"`
+	Current        bool      `json:"current"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastUsedAt     time.Time `json:"last_used_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	ImpersonatorID *uint     `json:"impersonator_id,omitempty"` // Set when an admin opened the session to act as the user
+}
+
+// ToSessionResponses converts sessions to DTOs, flagging the caller's own session
+func ToSessionResponses(sessions []models.Session, currentSessionID uint) []SessionResponse {
+	responses := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = SessionResponse{
+			ID:             session.ID,
+			UserAgent:      session.UserAgent,
+			IPAddress:      session.IPAddress,
+			Current:        session.ID == currentSessionID,
+			CreatedAt:      session.CreatedAt,
+			LastUsedAt:     session.LastUsedAt,
+			ExpiresAt:      session.ExpiresAt,
+			ImpersonatorID: session.ImpersonatorID,
+		}
+	}
+	return responses
+}
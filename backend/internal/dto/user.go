@@ -10,7 +10,6 @@ type UpdateProfileRequest struct {
 	Username  string `json:"username"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
 	AvatarURL string `json:"avatar_url"`
 	Language  string `json:"language"`
 	Timezone  string `json:"timezone"`
@@ -22,7 +21,7 @@ type UpdateProfileRequest struct {
 
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" binding:"required,min=8"`
+	NewPassword     string `json:"new_password" binding:"required"`
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
 }
 
@@ -31,7 +30,7 @@ type CreateUserRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"`
 	Status    string `json:"status" binding:"required,oneof=active inactive suspended pending"`
 	Language  string `json:"language"`
 	Timezone  string `json:"timezone"`
@@ -59,6 +58,7 @@ type UserResponse struct {
 	ID            uint       `json:"id"`
 	Status        string     `json:"status"`
 	EmailVerified bool       `json:"email_verified"`
+	PendingEmail  string     `json:"pending_email,omitempty"` // New address awaiting confirmation
 	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
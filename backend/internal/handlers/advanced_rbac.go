@@ -200,7 +200,8 @@ func (h *AdvancedRBACHandler) GetRoleTemplates(c echo.Context) error {
 
 // CreateContextualPermission creates a contextual permission
 // @Summary Create contextual permission
-// @Description Create a context-aware permission for a role
+// @Description Create a context-aware permission for a role. Without context_type it applies to every check of
+// @Description the resource and action; with it, only to checks made on that context (and context_value, if set).
 // @Tags Advanced RBAC
 // @Accept json
 // @Produce json
@@ -246,6 +247,12 @@ func (h *AdvancedRBACHandler) CreateContextualPermission(c echo.Context) error {
 			Details: "action is required",
 		})
 	}
+	if req.ContextValue != "" && req.ContextType == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Message: "Validation failed",
+			Details: "context_type is required with context_value",
+		})
+	}
 
 	permission := &models.ContextualPermission{
 		RoleID:       req.RoleID,
@@ -253,7 +260,7 @@ func (h *AdvancedRBACHandler) CreateContextualPermission(c echo.Context) error {
 		Action:       req.Action,
 		ContextType:  req.ContextType,
 		ContextValue: req.ContextValue,
-		IsGranted:    req.IsGranted,
+		IsGranted:    req.IsGranted == nil || *req.IsGranted,
 	}
 
 	if err := h.contextualPermRepo.Create(ctxx, permission); err != nil {
@@ -411,7 +418,7 @@ type CreateContextualPermissionRequest struct {
 	Action       string `json:"action" validate:"required"`
 	ContextType  string `json:"context_type"`
 	ContextValue string `json:"context_value"`
-	IsGranted    bool   `json:"is_granted"`
+	IsGranted    *bool  `json:"is_granted"` // Defaults to true; false makes the permission a deny rule
 }
 
 type RoleHierarchyResponse struct {
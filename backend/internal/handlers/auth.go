@@ -1,22 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"bezbase/internal/dto"
 	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
 	"bezbase/internal/services"
 
 	"github.com/labstack/echo/v4"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	sessionService *services.SessionService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, sessionService *services.SessionService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:    authService,
+		sessionService: sessionService,
 	}
 }
 
@@ -38,9 +44,15 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
 	}
 
-	response, err := h.authService.Register(req)
+	response, err := h.authService.Register(contextx.NewWithRequestContext(c), req)
 	if err != nil {
+		if policyErr := passwordPolicyError(t, err); policyErr != nil {
+			return policyErr
+		}
+
 		switch err.Error() {
+		case "invalid username":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_username"))
 		case "username already registered":
 			return echo.NewHTTPError(http.StatusConflict, t.Error("username_already_registered"))
 		case "username already taken":
@@ -55,7 +67,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	return c.JSON(http.StatusCreated, response)
 }
 
-// @Summary Login with username and password
+// @Summary Login with username or email and password
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -63,7 +75,11 @@ func (h *AuthHandler) Register(c echo.Context) error {
 // @Success 200 {object} dto.AuthResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{} "Password expired; change it at /auth/change-expired-password"
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{} "LDAP directory unavailable"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
 	t := i18n.NewTranslator(c.Request().Context())
@@ -73,11 +89,23 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
 	}
 
-	response, err := h.authService.LoginWithUsername(req)
+	response, err := h.authService.LoginWithUsername(contextx.NewWithRequestContext(c), req)
 	if err != nil {
+		if lockoutErr := lockoutError(c, t, err); lockoutErr != nil {
+			return lockoutErr
+		}
+
 		switch err.Error() {
 		case "invalid credentials":
 			return echo.NewHTTPError(http.StatusUnauthorized, t.InvalidCredentials())
+		case "password expired":
+			return echo.NewHTTPError(http.StatusForbidden, dto.ErrorResponse{Message: t.Error("password_expired"), Code: "password_expired"})
+		case "ldap unavailable":
+			return echo.NewHTTPError(http.StatusServiceUnavailable, t.Error("ldap_unavailable"))
+		case "ldap email missing":
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, t.Error("ldap_email_missing"))
+		case "email already registered":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("email_already_registered"))
 		default:
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 		}
@@ -85,3 +113,170 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// @Summary Replace an expired password and login
+// @Description Used after /auth/login answered with the password_expired code. Returns the same response as login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangeExpiredPasswordRequest true "Current credentials and new password"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} dto.PasswordPolicyErrorResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /auth/change-expired-password [post]
+func (h *AuthHandler) ChangeExpiredPassword(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.ChangeExpiredPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.authService.ChangeExpiredPassword(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		if lockoutErr := lockoutError(c, t, err); lockoutErr != nil {
+			return lockoutErr
+		}
+		if policyErr := passwordPolicyError(t, err); policyErr != nil {
+			return policyErr
+		}
+
+		switch err.Error() {
+		case "invalid credentials":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.InvalidCredentials())
+		case "password not expired":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("password_not_expired"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Exchange a refresh token for a new token pair
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshTokenRequest true "Refresh token request"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.RefreshTokenRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.sessionService.Refresh(contextx.NewWithRequestContext(c), req.RefreshToken)
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "user not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_refresh_token"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Logout and revoke the session owning the refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.LogoutRequest true "Logout request"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.LogoutRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.sessionService.Logout(contextx.NewWithRequestContext(c), req.RefreshToken); err != nil {
+		switch err.Error() {
+		case "invalid refresh token":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_refresh_token"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("logged_out"),
+	})
+}
+
+// @Summary Re-authenticate the current session
+// @Description Confirms the password, an MFA code or a recovery code and returns an access token with a fresh
+// @Description auth_time. Required before sensitive operations once the last authentication is older than REAUTH_MAX_AGE.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.ReauthenticateRequest true "Password, MFA code or recovery code"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/reauthenticate [post]
+func (h *AuthHandler) Reauthenticate(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.ReauthenticateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.authService.Reauthenticate(contextx.NewWithRequestContext(c), claims.UserID, claims.SessionID, req)
+	if err != nil {
+		if lockoutErr := lockoutError(c, t, err); lockoutErr != nil {
+			return lockoutErr
+		}
+
+		switch err.Error() {
+		case "invalid credentials":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.InvalidCredentials())
+		case "invalid mfa code":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_mfa_code"))
+		case "mfa not enabled":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("mfa_not_enabled"))
+		case "session revoked", "user not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("session_revoked"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// lockoutError converts an account lockout into 423 or 429 with a Retry-After header.
+// It returns nil for other errors.
+func lockoutError(c echo.Context, t *i18n.Translator, err error) *echo.HTTPError {
+	var lockoutErr *services.LockoutError
+	if !errors.As(err, &lockoutErr) {
+		return nil
+	}
+
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(lockoutErr.RetryAfter.Seconds())))
+	if lockoutErr.Locked {
+		return echo.NewHTTPError(http.StatusLocked, t.Error("account_locked"))
+	}
+	return echo.NewHTTPError(http.StatusTooManyRequests, t.Error("too_many_login_attempts"))
+}
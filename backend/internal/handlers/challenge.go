@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ChallengeHandler struct {
+	verifier services.ChallengeVerifier
+}
+
+// NewChallengeHandler creates the handler; a nil verifier means challenges are disabled
+func NewChallengeHandler(verifier services.ChallengeVerifier) *ChallengeHandler {
+	return &ChallengeHandler{
+		verifier: verifier,
+	}
+}
+
+// @Summary Get a bot-protection challenge
+// @Description Registration, login and password reset requests must carry a solution in the X-Challenge-Solution
+// @Description header. For type "pow", find a nonce such that SHA-256(challenge + ":" + nonce) starts with
+// @Description difficulty zero bits and send "<challenge>:<nonce>". Each challenge can be used once.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.ChallengeResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/challenge [get]
+func (h *ChallengeHandler) GetChallenge(c echo.Context) error {
+	if h.verifier == nil {
+		return c.JSON(http.StatusOK, dto.ChallengeResponse{Type: "none"})
+	}
+
+	challenge, err := h.verifier.Issue(contextx.NewWithRequestContext(c), c.RealIP())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, challenge)
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"bezbase/internal/dto"
 	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/password"
 
 	"github.com/labstack/echo/v4"
 )
@@ -27,3 +30,30 @@ func (h *CommonHandler) HealthCheck(c echo.Context) error {
 		"message": t.Status("server_running"),
 	})
 }
+
+// passwordPolicyError converts a password policy error into a 400 response listing each translated
+// violation. It returns nil for other errors.
+func passwordPolicyError(t *i18n.Translator, err error) *echo.HTTPError {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	response := dto.PasswordPolicyErrorResponse{
+		Message:    t.Error("password_policy_violation"),
+		Code:       "password_policy_violation",
+		Violations: make([]dto.PasswordPolicyViolation, len(policyErr.Violations)),
+	}
+	for i, violation := range policyErr.Violations {
+		message := t.Error(violation.Code)
+		if violation.Params != nil {
+			message = t.Error(violation.Code, violation.Params)
+		}
+		response.Violations[i] = dto.PasswordPolicyViolation{
+			Code:    violation.Code,
+			Message: message,
+			Params:  violation.Params,
+		}
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, response)
+}
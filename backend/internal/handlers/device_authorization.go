@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DeviceAuthorizationHandler struct {
+	deviceService *services.DeviceAuthorizationService
+}
+
+func NewDeviceAuthorizationHandler(deviceService *services.DeviceAuthorizationService) *DeviceAuthorizationHandler {
+	return &DeviceAuthorizationHandler{
+		deviceService: deviceService,
+	}
+}
+
+// @Summary Device authorization endpoint
+// @Description Starts the device flow (RFC 8628) for a registered client. The device shows the user code and verification URI,
+// @Description then polls the token endpoint with the device_code grant.
+// @Tags OpenID Connect
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string true "Client ID"
+// @Success 200 {object} dto.DeviceAuthorizationResponse
+// @Failure 400 {object} dto.OAuth2ErrorResponse
+// @Failure 401 {object} dto.OAuth2ErrorResponse
+// @Router /oauth2/device_authorization [post]
+func (h *DeviceAuthorizationHandler) Authorize(c echo.Context) error {
+	var req dto.DeviceAuthorizationRequest
+	if err := c.Bind(&req); err != nil {
+		return oauth2Error(c, services.ErrOAuth2InvalidRequest)
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	response, err := h.deviceService.Authorize(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		return oauth2Error(c, err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Look up a device user code
+// @Description Called by the verification page so the user can check which client and device they are signing in
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param user_code query string true "User code shown on the device"
+// @Success 200 {object} dto.DeviceVerificationResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/device [get]
+func (h *DeviceAuthorizationHandler) Verify(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	response, err := h.deviceService.Verify(contextx.NewWithRequestContext(c), c.QueryParam("user_code"))
+	if err != nil {
+		return deviceError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Approve or deny a device
+// @Description An approved device receives a session for the signed-in user on its next poll
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.DeviceDecisionRequest true "User code and decision"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/device [post]
+func (h *DeviceAuthorizationHandler) Decide(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.DeviceDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.deviceService.Decide(contextx.NewWithRequestContext(c), claims.UserID, req.UserCode, req.Approve); err != nil {
+		return deviceError(t, err)
+	}
+
+	if req.Approve {
+		return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("device_approved")})
+	}
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("device_denied")})
+}
+
+// @Summary List device authorization requests (admin)
+// @Description Newest first. Pending and approved requests past their expiry are reported as expired.
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10, max: 100)"
+// @Param status query string false "pending, approved, denied, completed or expired"
+// @Success 200 {object} dto.PaginatedResponse[dto.DeviceAuthorizationResponseItem]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/device-authorizations [get]
+func (h *DeviceAuthorizationHandler) ListAuthorizations(c echo.Context) error {
+	pagination := dto.ParsePagination(c)
+
+	authorizations, total, err := h.deviceService.ListAuthorizations(contextx.NewWithRequestContext(c), pagination.Page, pagination.PageSize, models.DeviceAuthorizationStatus(c.QueryParam("status")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.NewPaginatedResponse(dto.ToDeviceAuthorizationResponses(authorizations), pagination.Page, pagination.PageSize, total))
+}
+
+func deviceError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "invalid user code":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("invalid_user_code"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
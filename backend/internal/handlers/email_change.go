@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService *services.EmailChangeService
+}
+
+func NewEmailChangeHandler(emailChangeService *services.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+	}
+}
+
+// @Summary Change current user email
+// @Description Sends a confirmation link to the new address and a notice with a revert link to the current one.
+// @Description The email stays unchanged until the link is confirmed. Requires recent authentication and is refused
+// @Description while impersonating.
+// @Tags User
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangeEmailRequest true "New email address"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /v1/profile/email [put]
+func (h *EmailChangeHandler) RequestChange(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.ChangeEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.emailChangeService.RequestChange(contextx.NewWithRequestContext(c), claims.UserID, req.Email); err != nil {
+		return accountUpdateError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("email_change_requested")})
+}
+
+// @Summary Confirm an email change
+// @Description Applies a pending email change with the token sent to the new address and marks the address as verified
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailChangeTokenRequest true "Token from the confirmation link"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /auth/confirm-email-change [post]
+func (h *EmailChangeHandler) ConfirmChange(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.emailChangeService.ConfirmChange(contextx.NewWithRequestContext(c), req.Token); err != nil {
+		return accountUpdateError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("email_change_confirmed")})
+}
+
+// @Summary Revert an email change
+// @Description Uses the token sent to the old address. Cancels a pending change, or restores the old address and
+// @Description signs out every session when the change was already confirmed.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailChangeTokenRequest true "Token from the revert link"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /auth/revert-email-change [post]
+func (h *EmailChangeHandler) RevertChange(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.emailChangeService.RevertChange(contextx.NewWithRequestContext(c), req.Token); err != nil {
+		return accountUpdateError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("email_change_reverted")})
+}
+
+// accountUpdateError maps errors of email changes and of the profile updates that change identifiers
+func accountUpdateError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "invalid email change token":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_email_change_token"))
+	case "invalid username":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_username"))
+	case "username already taken":
+		return echo.NewHTTPError(http.StatusConflict, t.UsernameAlreadyTaken())
+	case "invalid email":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_email"))
+	case "email already taken":
+		return echo.NewHTTPError(http.StatusConflict, t.EmailAlreadyTaken())
+	case "email change requested recently":
+		return echo.NewHTTPError(http.StatusTooManyRequests, t.Error("email_change_requested_recently"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
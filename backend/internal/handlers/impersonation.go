@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ImpersonationHandler struct {
+	impersonationService *services.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonationService *services.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+	}
+}
+
+// @Summary Start impersonating a user (admin)
+// @Description Returns a short-lived access token for the user that carries the admin's ID. It has no refresh token and cannot be used to change passwords, MFA or roles.
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/users/{id}/impersonate [post]
+func (h *ImpersonationHandler) StartImpersonation(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	response, err := h.impersonationService.Start(contextx.NewWithRequestContext(c), claims, uint(userID))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		case "cannot impersonate yourself":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("cannot_impersonate_self"))
+		case "cannot impersonate admin":
+			return echo.NewHTTPError(http.StatusForbidden, t.Error("cannot_impersonate_admin"))
+		case "already impersonating":
+			return echo.NewHTTPError(http.StatusForbidden, t.Error("impersonation_forbidden"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Stop impersonating and return to the admin's own session
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/impersonation/stop [post]
+func (h *ImpersonationHandler) StopImpersonation(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	response, err := h.impersonationService.Stop(contextx.NewWithRequestContext(c), claims)
+	if err != nil {
+		switch err.Error() {
+		case "not impersonating":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("not_impersonating"))
+		case "session revoked", "user not found":
+			// Impersonation has ended, but the admin session is gone and they must log in again
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("session_revoked"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type KeyHandler struct {
+	keyService *services.KeyService
+}
+
+func NewKeyHandler(keyService *services.KeyService) *KeyHandler {
+	return &KeyHandler{
+		keyService: keyService,
+	}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying tokens issued by this server, selected by the kid header
+// @Tags System
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *KeyHandler) JWKS(c echo.Context) error {
+	// Keep verifier caches shorter than the window between publishing and using a new key
+	c.Response().Header().Set("Cache-Control", "public, max-age=60")
+	return c.JSON(http.StatusOK, h.keyService.JWKS())
+}
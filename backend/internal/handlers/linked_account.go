@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LinkedAccountHandler struct {
+	linkedAccountService *services.LinkedAccountService
+}
+
+func NewLinkedAccountHandler(linkedAccountService *services.LinkedAccountService) *LinkedAccountHandler {
+	return &LinkedAccountHandler{
+		linkedAccountService: linkedAccountService,
+	}
+}
+
+// @Summary List the sign-in methods of the current user
+// @Description Password (email provider), passkeys and linked social or enterprise accounts
+// @Tags OAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.LinkedAccountResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/linked-accounts [get]
+func (h *LinkedAccountHandler) ListLinkedAccounts(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	providers, err := h.linkedAccountService.ListLinkedAccounts(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToLinkedAccountResponses(providers))
+}
+
+// @Summary Unlink a sign-in method from the current user
+// @Description Unlinking "email" removes the password and unlinking "passkey" deletes every passkey.
+// @Description The last usable sign-in method cannot be removed.
+// @Tags OAuth
+// @Security BearerAuth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Last sign-in method of the account"
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/linked-accounts/{provider} [delete]
+func (h *LinkedAccountHandler) Unlink(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	provider := models.AuthProviderType(c.Param("provider"))
+	if err := h.linkedAccountService.Unlink(contextx.NewWithRequestContext(c), claims.UserID, provider); err != nil {
+		switch err.Error() {
+		case "linked account not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("linked_account_not_found"))
+		case "last sign-in method":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("last_sign_in_method"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("account_unlinked")})
+}
+
+// @Summary Set a password on an account without one
+// @Description For accounts created through social login, SSO or passkeys. Accounts with a password use PUT /v1/profile/password.
+// @Tags OAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.SetPasswordRequest true "New password"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.PasswordPolicyErrorResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/linked-accounts/password [post]
+func (h *LinkedAccountHandler) SetPassword(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.SetPasswordRequest
+	if err := c.Bind(&req); err != nil || req.NewPassword == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+	if req.NewPassword != req.ConfirmPassword {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("new_password_mismatch"))
+	}
+
+	if err := h.linkedAccountService.SetPassword(contextx.NewWithRequestContext(c), claims.UserID, req.NewPassword); err != nil {
+		if policyErr := passwordPolicyError(t, err); policyErr != nil {
+			return policyErr
+		}
+
+		switch err.Error() {
+		case "password already set":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("password_already_set"))
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("password_set")})
+}
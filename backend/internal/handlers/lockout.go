@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LockoutHandler struct {
+	lockoutService *services.LockoutService
+}
+
+func NewLockoutHandler(lockoutService *services.LockoutService) *LockoutHandler {
+	return &LockoutHandler{
+		lockoutService: lockoutService,
+	}
+}
+
+// @Summary Unlock an account with the token from the unlock email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.UnlockAccountRequest true "Unlock token"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /auth/unlock-account [post]
+func (h *LockoutHandler) UnlockAccount(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.UnlockAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.lockoutService.Unlock(contextx.NewWithRequestContext(c), req.Token); err != nil {
+		switch err.Error() {
+		case "invalid unlock token":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_unlock_token"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("account_unlocked")})
+}
+
+// @Summary List accounts locked after too many failed logins (admin)
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.LockedAccountResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/users/locked [get]
+func (h *LockoutHandler) ListLockedAccounts(c echo.Context) error {
+	lockouts, err := h.lockoutService.ListLocked(contextx.NewWithRequestContext(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToLockedAccountResponses(lockouts))
+}
+
+// @Summary Unlock an account and reset its failed login counter (admin)
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/users/{id}/lockout [delete]
+func (h *LockoutHandler) UnlockUser(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	if err := h.lockoutService.UnlockUser(contextx.NewWithRequestContext(c), uint(userID)); err != nil {
+		switch err.Error() {
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("account_unlocked")})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LoginHistoryHandler struct {
+	loginHistoryService *services.LoginHistoryService
+}
+
+func NewLoginHistoryHandler(loginHistoryService *services.LoginHistoryService) *LoginHistoryHandler {
+	return &LoginHistoryHandler{
+		loginHistoryService: loginHistoryService,
+	}
+}
+
+// @Summary List the current user's login history
+// @Tags Session
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.PaginatedResponse[dto.LoginEventResponse]
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/login-history [get]
+func (h *LoginHistoryHandler) ListMyHistory(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+	return h.listHistory(c, claims.UserID)
+}
+
+// @Summary List the login history of a user (admin)
+// @Tags Session
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.PaginatedResponse[dto.LoginEventResponse]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/users/{id}/login-history [get]
+func (h *LoginHistoryHandler) ListUserHistory(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	return h.listHistory(c, uint(userID))
+}
+
+// @Summary Report a login as not made by the account owner
+// @Description Uses the token from the "this wasn't me" link of a login alert. Revokes every session and
+// @Description personal access token, disables the password and emails a password reset link.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.LoginReportRequest true "Token from the login alert"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/report-login [post]
+func (h *LoginHistoryHandler) ReportLogin(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.LoginReportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.loginHistoryService.ReportLogin(contextx.NewWithRequestContext(c), req.Token); err != nil {
+		if err.Error() == "invalid login report token" {
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_login_report_token"))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("login_reported")})
+}
+
+func (h *LoginHistoryHandler) listHistory(c echo.Context, userID uint) error {
+	pagination := dto.ParsePagination(c)
+
+	events, total, err := h.loginHistoryService.ListHistory(contextx.NewWithRequestContext(c), userID, pagination.Page, pagination.PageSize)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.NewPaginatedResponse(dto.ToLoginEventResponses(events), pagination.Page, pagination.PageSize, total))
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// magicLinkDeviceCookie holds the secret a sign-in link is bound to, so the link only works
+	// in the browser that requested it
+	magicLinkDeviceCookie = "magic_link_device"
+	magicLinkCookiePath   = "/api/v1/auth/magic-link"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+	linkConfig       *config.MagicLinkConfig
+}
+
+func NewMagicLinkHandler(magicLinkService *services.MagicLinkService, linkConfig *config.MagicLinkConfig) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		linkConfig:       linkConfig,
+	}
+}
+
+// @Summary Request a passwordless sign-in link
+// @Description Emails a single-use link when the address belongs to an account. The response is the same either way
+// @Description and sets the device cookie the link is bound to.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkRequest true "Email address"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/magic-link [post]
+func (h *MagicLinkHandler) RequestLink(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.MagicLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	deviceSecret, err := h.magicLinkService.RequestLink(contextx.NewWithRequestContext(c), req.Email, h.deviceSecret(c))
+	if err != nil {
+		switch err.Error() {
+		case "magic link login disabled":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("magic_link_disabled"))
+		case "invalid email":
+			return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	h.setDeviceCookie(c, deviceSecret)
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("magic_link_sent")})
+}
+
+// @Summary Sign in with an emailed link
+// @Description Must be called from the browser that requested the link. Returns an MFA challenge when MFA is enabled.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkLoginRequest true "Link token"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/magic-link/login [post]
+func (h *MagicLinkHandler) Login(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.MagicLinkLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.magicLinkService.Login(contextx.NewWithRequestContext(c), req.Token, h.deviceSecret(c))
+	if err != nil {
+		switch err.Error() {
+		case "magic link login disabled":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("magic_link_disabled"))
+		case "invalid magic link":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_magic_link"))
+		case "magic link device mismatch":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("magic_link_device_mismatch"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *MagicLinkHandler) deviceSecret(c echo.Context) string {
+	if cookie, err := c.Cookie(magicLinkDeviceCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func (h *MagicLinkHandler) setDeviceCookie(c echo.Context, value string) {
+	c.SetCookie(&http.Cookie{
+		Name:     magicLinkDeviceCookie,
+		Value:    value,
+		Path:     magicLinkCookiePath,
+		Expires:  time.Now().Add(h.linkConfig.TTL),
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MFAHandler struct {
+	mfaService *services.MFAService
+}
+
+func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// @Summary Get MFA status of the current user
+// @Tags MFA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.MFAStatusResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/mfa [get]
+func (h *MFAHandler) GetStatus(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	status, err := h.mfaService.GetStatus(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// @Summary Start TOTP enrollment for the current user
+// @Tags MFA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.TOTPEnrollResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	response, err := h.mfaService.EnrollTOTP(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "mfa already enabled":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("mfa_already_enabled"))
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Confirm TOTP enrollment and receive recovery codes
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP code"
+// @Success 200 {object} dto.MFARecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.mfaService.ConfirmTOTP(contextx.NewWithRequestContext(c), claims.UserID, req.Code)
+	if err != nil {
+		return h.mfaError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Disable TOTP for the current user
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.MFACodeRequest
+	if err := c.Bind(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.mfaService.DisableTOTP(contextx.NewWithRequestContext(c), claims.UserID, req); err != nil {
+		return h.mfaError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("mfa_disabled"),
+	})
+}
+
+// @Summary Regenerate MFA recovery codes for the current user
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP code"
+// @Success 200 {object} dto.MFARecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.mfaService.RegenerateRecoveryCodes(contextx.NewWithRequestContext(c), claims.UserID, req.Code)
+	if err != nil {
+		return h.mfaError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Complete an MFA login challenge
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAVerifyRequest true "Challenge token with TOTP or recovery code"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil || req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.mfaService.VerifyChallenge(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		if httpErr := lockoutError(c, t, err); httpErr != nil {
+			return httpErr
+		}
+		switch err.Error() {
+		case "invalid challenge token", "user not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_challenge_token"))
+		default:
+			return h.mfaError(t, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Reset MFA for a user (admin only)
+// @Tags MFA
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/users/{id}/mfa [delete]
+func (h *MFAHandler) ResetUserMFA(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	if err := h.mfaService.ResetMFA(contextx.NewWithRequestContext(c), uint(userID)); err != nil {
+		switch err.Error() {
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		case "mfa not enabled":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("mfa_not_enabled"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("mfa_reset"),
+	})
+}
+
+// mfaError maps MFA service errors to HTTP errors
+func (h *MFAHandler) mfaError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "invalid mfa code":
+		return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_mfa_code"))
+	case "mfa not enrolled":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("mfa_not_enrolled"))
+	case "mfa not enabled":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("mfa_not_enabled"))
+	case "mfa already enabled":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("mfa_already_enabled"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
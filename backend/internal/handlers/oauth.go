@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// oauthStateCookie keeps the signed state, nonce and PKCE verifier between the redirect and the callback
+const oauthStateCookie = "oauth_state"
+
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+	oauthConfig  *config.OAuthConfig
+}
+
+func NewOAuthHandler(oauthService *services.OAuthService, oauthConfig *config.OAuthConfig) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		oauthConfig:  oauthConfig,
+	}
+}
+
+// @Summary List configured social login providers
+// @Tags OAuth
+// @Produce json
+// @Success 200 {array} dto.OAuthProviderResponse
+// @Router /auth/oauth/providers [get]
+func (h *OAuthHandler) ListProviders(c echo.Context) error {
+	names := h.oauthService.Providers()
+	providers := make([]dto.OAuthProviderResponse, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, dto.OAuthProviderResponse{
+			Name:         name,
+			AuthorizeURL: h.callbackBase() + "/" + name + "/authorize",
+		})
+	}
+	return c.JSON(http.StatusOK, providers)
+}
+
+// @Summary Start social login
+// @Description Redirects the browser to the provider. The result is delivered to the frontend callback URL in the fragment.
+// @Tags OAuth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/oauth/{provider}/authorize [get]
+func (h *OAuthHandler) Authorize(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	authURL, state, err := h.oauthService.BeginAuthorization(contextx.NewWithRequestContext(c), c.Param("provider"), 0)
+	if err != nil {
+		switch err.Error() {
+		case "oauth provider not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("oauth_provider_not_found"))
+		case "oauth login failed":
+			return echo.NewHTTPError(http.StatusBadGateway, t.Error("oauth_login_failed"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	h.setStateCookie(c, state)
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// @Summary Start linking a social provider to the current user
+// @Description Returns the provider URL to navigate to; the browser must keep the state cookie set by this response.
+// @Tags OAuth
+// @Security BearerAuth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} dto.OAuthAuthorizeResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oauth/{provider}/link [post]
+func (h *OAuthHandler) BeginLink(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	authURL, state, err := h.oauthService.BeginAuthorization(contextx.NewWithRequestContext(c), c.Param("provider"), claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "oauth provider not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("oauth_provider_not_found"))
+		case "oauth login failed":
+			return echo.NewHTTPError(http.StatusBadGateway, t.Error("oauth_login_failed"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	h.setStateCookie(c, state)
+	return c.JSON(http.StatusOK, dto.OAuthAuthorizeResponse{AuthorizationURL: authURL})
+}
+
+// @Summary Social login callback
+// @Description Called by the provider. Redirects to the frontend callback URL with the outcome in the fragment:
+// @Description token/refresh_token/expires_at, mfa_required/challenge_token, link_required/link_token/email, linked, or error.
+// @Tags OAuth
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State"
+// @Success 302
+// @Router /auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c echo.Context) error {
+	var stateCookie string
+	if cookie, err := c.Cookie(oauthStateCookie); err == nil {
+		stateCookie = cookie.Value
+	}
+	// The state is single-use
+	h.setStateCookie(c, "")
+
+	fragment := url.Values{}
+	if providerError := c.QueryParam("error"); providerError != "" {
+		fragment.Set("error", "oauth_login_failed")
+		return h.redirectToFrontend(c, fragment)
+	}
+
+	result, err := h.oauthService.HandleCallback(contextx.NewWithRequestContext(c), c.Param("provider"), stateCookie, c.QueryParam("state"), c.QueryParam("code"))
+	if err != nil {
+		switch err.Error() {
+		case "oauth provider not found":
+			fragment.Set("error", "oauth_provider_not_found")
+		case "invalid oauth state":
+			fragment.Set("error", "invalid_oauth_state")
+		case "social email not verified":
+			fragment.Set("error", "social_email_not_verified")
+		case "social account already linked to another user":
+			fragment.Set("error", "social_account_already_linked")
+		case "provider already linked":
+			fragment.Set("error", "provider_already_linked")
+		default:
+			fragment.Set("error", "oauth_login_failed")
+		}
+		return h.redirectToFrontend(c, fragment)
+	}
+
+	switch {
+	case result.Linked:
+		fragment.Set("linked", "true")
+	case result.LinkRequired:
+		fragment.Set("link_required", "true")
+		fragment.Set("link_token", result.LinkToken)
+		fragment.Set("email", result.Email)
+	case result.Auth.MFARequired:
+		fragment.Set("mfa_required", "true")
+		fragment.Set("challenge_token", result.Auth.ChallengeToken)
+	default:
+		fragment.Set("token", result.Auth.Token)
+		fragment.Set("refresh_token", result.Auth.RefreshToken)
+		if result.Auth.ExpiresAt != nil {
+			fragment.Set("expires_at", strconv.FormatInt(result.Auth.ExpiresAt.Unix(), 10))
+		}
+	}
+	return h.redirectToFrontend(c, fragment)
+}
+
+// @Summary Confirm linking a social account to an existing account
+// @Description Used after a callback returned link_required. The account password proves ownership.
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.OAuthLinkConfirmRequest true "Link token and account password"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /auth/oauth/link/confirm [post]
+func (h *OAuthHandler) ConfirmLink(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.OAuthLinkConfirmRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.oauthService.ConfirmLink(contextx.NewWithRequestContext(c), req, 0)
+	if err != nil {
+		if lockoutErr := lockoutError(c, t, err); lockoutErr != nil {
+			return lockoutErr
+		}
+		return h.linkError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Confirm linking a social account to the current user
+// @Description Used after a callback returned link_required while the account owner is signed in.
+// @Tags OAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.OAuthLinkConfirmRequest true "Link token"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/oauth/link/confirm [post]
+func (h *OAuthHandler) ConfirmLinkForCurrentUser(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.OAuthLinkConfirmRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if _, err := h.oauthService.ConfirmLink(contextx.NewWithRequestContext(c), req, claims.UserID); err != nil {
+		return h.linkError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("account_linked")})
+}
+
+func (h *OAuthHandler) linkError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "invalid link token", "oauth provider not found":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_link_token"))
+	case "invalid credentials":
+		return echo.NewHTTPError(http.StatusUnauthorized, t.InvalidCredentials())
+	case "social account already linked", "social account already linked to another user":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("social_account_already_linked"))
+	case "provider already linked":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("provider_already_linked"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// setStateCookie stores the signed state; an empty value clears it
+func (h *OAuthHandler) setStateCookie(c echo.Context, value string) {
+	cookie := &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     h.cookiePath(),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.oauthConfig.CallbackBaseURL, "https://"),
+		// Lax lets the cookie accompany the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = time.Now().Add(h.oauthConfig.StateTTL)
+	}
+	c.SetCookie(cookie)
+}
+
+func (h *OAuthHandler) redirectToFrontend(c echo.Context, fragment url.Values) error {
+	// The fragment is never sent to servers, keeping tokens out of logs and Referer headers
+	return c.Redirect(http.StatusFound, h.oauthConfig.FrontendCallbackURL+"#"+fragment.Encode())
+}
+
+func (h *OAuthHandler) callbackBase() string {
+	return strings.TrimRight(h.oauthConfig.CallbackBaseURL, "/")
+}
+
+// cookiePath scopes the state cookie to the callback routes
+func (h *OAuthHandler) cookiePath() string {
+	if parsed, err := url.Parse(h.callbackBase()); err == nil && parsed.Path != "" {
+		return parsed.Path
+	}
+	return "/"
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OIDCClientHandler struct {
+	clientService *services.OIDCClientService
+}
+
+func NewOIDCClientHandler(clientService *services.OIDCClientService) *OIDCClientHandler {
+	return &OIDCClientHandler{
+		clientService: clientService,
+	}
+}
+
+// @Summary List OpenID Connect clients (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.OIDCClientResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/oidc/clients [get]
+func (h *OIDCClientHandler) ListClients(c echo.Context) error {
+	clients, err := h.clientService.ListClients(contextx.NewWithRequestContext(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOIDCClientResponses(clients))
+}
+
+// @Summary Register an OpenID Connect client (admin)
+// @Description The client secret is only returned in this response. Public clients get no secret and must use PKCE.
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateOIDCClientRequest true "Client registration"
+// @Success 201 {object} dto.OIDCClientCreatedResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/oidc/clients [post]
+func (h *OIDCClientHandler) CreateClient(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.CreateOIDCClientRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.clientService.CreateClient(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// @Summary Get an OpenID Connect client (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {object} dto.OIDCClientResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id} [get]
+func (h *OIDCClientHandler) GetClient(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+
+	client, err := h.clientService.GetClient(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOIDCClientResponse(client))
+}
+
+// @Summary Update an OpenID Connect client (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Client ID"
+// @Param request body dto.UpdateOIDCClientRequest true "Fields to change"
+// @Success 200 {object} dto.OIDCClientResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id} [put]
+func (h *OIDCClientHandler) UpdateClient(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+
+	var req dto.UpdateOIDCClientRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	client, err := h.clientService.UpdateClient(contextx.NewWithRequestContext(c), uint(id), req)
+	if err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOIDCClientResponse(client))
+}
+
+// @Summary Rotate the secret of a confidential OpenID Connect client (admin)
+// @Description The new secret is only returned in this response; the old one stops working immediately
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {object} dto.OIDCClientCreatedResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id}/secret [post]
+func (h *OIDCClientHandler) RotateClientSecret(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+
+	response, err := h.clientService.RotateClientSecret(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Delete an OpenID Connect client and revoke its tokens (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id} [delete]
+func (h *OIDCClientHandler) DeleteClient(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+
+	if err := h.clientService.DeleteClient(contextx.NewWithRequestContext(c), uint(id)); err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("oidc_client_deleted")})
+}
+
+// @Summary List users who authorized an OpenID Connect client (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {array} dto.OIDCGrantResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id}/grants [get]
+func (h *OIDCClientHandler) ListClientGrants(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+
+	grants, err := h.clientService.ListClientGrants(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOIDCGrantResponses(grants))
+}
+
+// @Summary Revoke a user's authorization of an OpenID Connect client (admin)
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/clients/{id}/grants/{user_id} [delete]
+func (h *OIDCClientHandler) RevokeClientGrant(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	if err := h.clientService.RevokeClientGrant(contextx.NewWithRequestContext(c), uint(id), uint(userID)); err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("oidc_grant_revoked")})
+}
+
+// @Summary List applications the current user has authorized
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.OIDCGrantResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/oidc/grants [get]
+func (h *OIDCClientHandler) ListMyGrants(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	grants, err := h.clientService.ListUserGrants(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOIDCGrantResponses(grants))
+}
+
+// @Summary Revoke the current user's authorization of an application
+// @Description Withdraws consent and revokes every token the application holds for the user
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param client_id path string true "Public client ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/oidc/grants/{client_id} [delete]
+func (h *OIDCClientHandler) RevokeMyGrant(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	if err := h.clientService.RevokeUserGrant(contextx.NewWithRequestContext(c), claims.UserID, c.Param("client_id")); err != nil {
+		return oidcClientError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("oidc_grant_revoked")})
+}
+
+func oidcClientError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "oidc client not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("oidc_client_not_found"))
+	case "oidc grant not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("oidc_grant_not_found"))
+	case "invalid client name":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client_name"))
+	case "invalid redirect uri":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_redirect_uri"))
+	case "invalid scope":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_scope"))
+	case "public client has no secret":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("oidc_public_client_secret"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OIDCProviderHandler struct {
+	providerService *services.OIDCProviderService
+	deviceService   *services.DeviceAuthorizationService
+}
+
+func NewOIDCProviderHandler(providerService *services.OIDCProviderService, deviceService *services.DeviceAuthorizationService) *OIDCProviderHandler {
+	return &OIDCProviderHandler{
+		providerService: providerService,
+		deviceService:   deviceService,
+	}
+}
+
+// @Summary OpenID Provider discovery document
+// @Tags OpenID Connect
+// @Produce json
+// @Success 200 {object} dto.OIDCDiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCProviderHandler) Discovery(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+	return c.JSON(http.StatusOK, h.providerService.Discovery())
+}
+
+// @Summary Check an authorization request for the consent screen
+// @Description Called by the consent page with the query parameters it received. When consent is not required the page should approve right away. redirect_to is set when the browser must go back to the client with an error.
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Redirect URI"
+// @Param response_type query string true "Must be code"
+// @Param scope query string true "Space-separated scopes"
+// @Param state query string false "Client state"
+// @Param nonce query string false "ID token nonce"
+// @Param code_challenge query string true "PKCE S256 challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Param prompt query string false "none or consent"
+// @Success 200 {object} dto.OIDCAuthorizeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/oidc/authorize [get]
+func (h *OIDCProviderHandler) Authorize(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.OIDCAuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.providerService.Authorize(contextx.NewWithRequestContext(c), claims.UserID, req)
+	if err != nil {
+		return authorizeError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Approve or deny an authorization request
+// @Description Returns redirect_to with an authorization code on approval, or with access_denied otherwise
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.OIDCAuthorizeDecisionRequest true "Authorization request parameters and decision"
+// @Success 200 {object} dto.OIDCAuthorizeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/oidc/authorize [post]
+func (h *OIDCProviderHandler) Decide(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.OIDCAuthorizeDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.providerService.Decide(contextx.NewWithRequestContext(c), claims.UserID, claims.SessionID, req)
+	if err != nil {
+		return authorizeError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Token endpoint
+// @Description Exchanges an authorization code (with its PKCE verifier) or a refresh token. Clients authenticate with HTTP Basic or client_id/client_secret form fields; public clients send only client_id.
+// @Description Devices poll with the device_code grant, which returns bezbase session tokens once the user approves.
+// @Tags OpenID Connect
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or urn:ietf:params:oauth:grant-type:device_code"
+// @Success 200 {object} dto.OIDCTokenResponse
+// @Failure 400 {object} dto.OAuth2ErrorResponse
+// @Failure 401 {object} dto.OAuth2ErrorResponse
+// @Router /oauth2/token [post]
+func (h *OIDCProviderHandler) Token(c echo.Context) error {
+	var req dto.OIDCTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauth2Error(c, services.ErrOAuth2InvalidRequest)
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	if req.GrantType == services.DeviceCodeGrantType {
+		response, err := h.deviceService.Token(contextx.NewWithRequestContext(c), req.ClientID, req.ClientSecret, req.DeviceCode)
+		if err != nil {
+			return oauth2Error(c, err)
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+
+	response, err := h.providerService.Token(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		return oauth2Error(c, err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary UserInfo endpoint
+// @Description Returns the claims of the user the access token was issued for, limited by its scopes
+// @Tags OpenID Connect
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} dto.OAuth2ErrorResponse
+// @Failure 403 {object} dto.OAuth2ErrorResponse
+// @Router /oauth2/userinfo [get]
+func (h *OIDCProviderHandler) UserInfo(c echo.Context) error {
+	accessToken := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+	if accessToken == "" {
+		accessToken = c.FormValue("access_token")
+	}
+
+	claims, err := h.providerService.UserInfo(contextx.NewWithRequestContext(c), accessToken)
+	if err != nil {
+		if err.Error() == "insufficient scope" {
+			c.Response().Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			return c.JSON(http.StatusForbidden, dto.OAuth2ErrorResponse{Error: "insufficient_scope"})
+		}
+		c.Response().Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return c.JSON(http.StatusUnauthorized, dto.OAuth2ErrorResponse{Error: "invalid_token"})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, claims)
+}
+
+// @Summary Token introspection (RFC 7662)
+// @Tags OpenID Connect
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} dto.OIDCIntrospectionResponse
+// @Failure 401 {object} dto.OAuth2ErrorResponse
+// @Router /oauth2/introspect [post]
+func (h *OIDCProviderHandler) Introspect(c echo.Context) error {
+	var req dto.OIDCTokenActionRequest
+	if err := c.Bind(&req); err != nil {
+		return oauth2Error(c, services.ErrOAuth2InvalidRequest)
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	response, err := h.providerService.Introspect(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		return oauth2Error(c, err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Token revocation (RFC 7009)
+// @Tags OpenID Connect
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200
+// @Failure 401 {object} dto.OAuth2ErrorResponse
+// @Router /oauth2/revoke [post]
+func (h *OIDCProviderHandler) Revoke(c echo.Context) error {
+	var req dto.OIDCTokenActionRequest
+	if err := c.Bind(&req); err != nil {
+		return oauth2Error(c, services.ErrOAuth2InvalidRequest)
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	if err := h.providerService.Revoke(contextx.NewWithRequestContext(c), req); err != nil {
+		return oauth2Error(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// authorizeError maps errors that must not be redirected to the client, since the client or its
+// redirect URI could not be trusted
+func authorizeError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "invalid client":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_oidc_client"))
+	case "invalid redirect uri":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_redirect_uri"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// clientCredentials prefers HTTP Basic client authentication over form fields. Basic credentials
+// are form-encoded as RFC 6749 section 2.3.1 requires.
+func clientCredentials(c echo.Context, clientID, clientSecret string) (string, string) {
+	username, password, ok := c.Request().BasicAuth()
+	if !ok {
+		return clientID, clientSecret
+	}
+	if decoded, err := url.QueryUnescape(username); err == nil {
+		username = decoded
+	}
+	if decoded, err := url.QueryUnescape(password); err == nil {
+		password = decoded
+	}
+	return username, password
+}
+
+// oauth2Error writes a protocol error in the RFC 6749 format
+func oauth2Error(c echo.Context, err error) error {
+	var oauthErr *services.OAuth2Error
+	if !errors.As(err, &oauthErr) {
+		return c.JSON(http.StatusInternalServerError, dto.OAuth2ErrorResponse{Error: "server_error"})
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		if _, _, ok := c.Request().BasicAuth(); ok {
+			c.Response().Header().Set("WWW-Authenticate", `Basic realm="bezbase"`)
+		}
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(status, dto.OAuth2ErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+	sessionService      *services.SessionService
+}
+
+func NewOrganizationHandler(organizationService *services.OrganizationService, sessionService *services.SessionService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		sessionService:      sessionService,
+	}
+}
+
+// @Summary List the organizations of the current user
+// @Tags Organizations
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.OrganizationResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	organizations, err := h.organizationService.ListUserOrganizations(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOrganizationResponses(organizations))
+}
+
+// @Summary Create an organization
+// @Description The creator becomes a member with the "admin" role inside the organization.
+// @Tags Organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateOrganizationRequest true "Organization"
+// @Success 201 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.CreateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	organization, err := h.organizationService.CreateOrganization(contextx.NewWithRequestContext(c), claims.UserID, req)
+	if err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusCreated, dto.ToOrganizationResponse(organization))
+}
+
+// @Summary Switch the active organization
+// @Description Stores the organization on the session and returns an access token with its org_id claim.
+// @Description Organization 0 switches back to global roles only. A request can also select an
+// @Description organization with the X-Organization-ID header.
+// @Tags Organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.SwitchOrganizationRequest true "Organization to switch to"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/switch [post]
+func (h *OrganizationHandler) SwitchOrganization(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+	ctx := contextx.NewWithRequestContext(c)
+
+	var req dto.SwitchOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if req.OrganizationID != 0 {
+		if err := h.organizationService.CheckAccess(ctx, claims.UserID, req.OrganizationID); err != nil {
+			return organizationError(t, err)
+		}
+	}
+
+	response, err := h.sessionService.SwitchOrganization(ctx, claims.UserID, claims.SessionID, req.OrganizationID)
+	if err != nil {
+		switch err.Error() {
+		case "session revoked", "user not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("session_revoked"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get an organization
+// @Tags Organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+
+	organization, err := h.organizationService.GetOrganization(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOrganizationResponse(organization))
+}
+
+// @Summary Update an organization
+// @Tags Organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param request body dto.UpdateOrganizationRequest true "Fields to change"
+// @Success 200 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+
+	var req dto.UpdateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	organization, err := h.organizationService.UpdateOrganization(contextx.NewWithRequestContext(c), uint(id), req)
+	if err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToOrganizationResponse(organization))
+}
+
+// @Summary Delete an organization
+// @Description Removes its memberships and every role assignment and policy inside it.
+// @Tags Organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+
+	if err := h.organizationService.DeleteOrganization(contextx.NewWithRequestContext(c), uint(id)); err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("organization_deleted")})
+}
+
+// @Summary List the members of an organization
+// @Tags Organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {array} dto.OrganizationMemberResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/organizations/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+
+	members, err := h.organizationService.ListMembers(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+// @Summary Add a member to an organization
+// @Description Roles are held inside the organization only; without roles the member gets "user".
+// @Tags Organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param request body dto.AddOrganizationMemberRequest true "Member"
+// @Success 201 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/organizations/{id}/members [post]
+func (h *OrganizationHandler) AddMember(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+
+	var req dto.AddOrganizationMemberRequest
+	if err := c.Bind(&req); err != nil || req.UserID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.organizationService.AddMember(contextx.NewWithRequestContext(c), uint(id), req); err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusCreated, dto.SuccessResponse{Message: t.Success("organization_member_added")})
+}
+
+// @Summary Replace the roles of an organization member
+// @Tags Organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Param request body dto.UpdateOrganizationMemberRequest true "Roles inside the organization"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/{id}/members/{user_id} [put]
+func (h *OrganizationHandler) UpdateMember(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	var req dto.UpdateOrganizationMemberRequest
+	if err := c.Bind(&req); err != nil || len(req.Roles) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	if err := h.organizationService.UpdateMemberRoles(contextx.NewWithRequestContext(c), uint(id), uint(userID), req); err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("organization_member_updated")})
+}
+
+// @Summary Remove a member from an organization
+// @Description Also revokes every role the user holds inside the organization.
+// @Tags Organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/organizations/{id}/members/{user_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization"))
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	if err := h.organizationService.RemoveMember(contextx.NewWithRequestContext(c), uint(id), uint(userID)); err != nil {
+		return organizationError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("organization_member_removed")})
+}
+
+func organizationError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "organization not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("organization_not_found"))
+	case "not an organization member":
+		return echo.NewHTTPError(http.StatusForbidden, t.Error("not_organization_member"))
+	case "organization name is required":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization_name"))
+	case "invalid organization slug":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization_slug"))
+	case "organization slug taken":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("organization_slug_taken"))
+	case "organization member not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("organization_member_not_found"))
+	case "already an organization member":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("already_organization_member"))
+	case "invalid role":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_organization_role"))
+	case "user not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PasskeyHandler struct {
+	passkeyService *services.PasskeyService
+}
+
+func NewPasskeyHandler(passkeyService *services.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{
+		passkeyService: passkeyService,
+	}
+}
+
+// @Summary Start passkey registration for the current user
+// @Tags Passkey
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} webauthn.CreationOptions
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/passkeys/register/begin [post]
+func (h *PasskeyHandler) BeginRegistration(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	options, err := h.passkeyService.BeginRegistration(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// @Summary Finish passkey registration for the current user
+// @Tags Passkey
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyRegistrationRequest true "Registration response from the authenticator"
+// @Success 201 {object} dto.PasskeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/passkeys/register/finish [post]
+func (h *PasskeyHandler) FinishRegistration(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.PasskeyRegistrationRequest
+	if err := c.Bind(&req); err != nil || len(req.Name) > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	passkey, err := h.passkeyService.FinishRegistration(contextx.NewWithRequestContext(c), claims.UserID, req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid passkey challenge":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_passkey_challenge"))
+		case "passkey verification failed":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("passkey_verification_failed"))
+		case "passkey already registered":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("passkey_already_registered"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusCreated, passkey)
+}
+
+// @Summary List passkeys of the current user
+// @Tags Passkey
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.PasskeyResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/passkeys [get]
+func (h *PasskeyHandler) ListPasskeys(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	passkeys, err := h.passkeyService.ListPasskeys(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, passkeys)
+}
+
+// @Summary Rename one of the current user's passkeys
+// @Tags Passkey
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Passkey ID"
+// @Param request body dto.RenamePasskeyRequest true "New passkey name"
+// @Success 200 {object} dto.PasskeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/passkeys/{id} [put]
+func (h *PasskeyHandler) RenamePasskey(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	passkeyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_passkey_id"))
+	}
+
+	var req dto.RenamePasskeyRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Name) == "" || len(req.Name) > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	passkey, err := h.passkeyService.RenamePasskey(contextx.NewWithRequestContext(c), claims.UserID, uint(passkeyID), req.Name)
+	if err != nil {
+		switch err.Error() {
+		case "passkey not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("passkey_not_found"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, passkey)
+}
+
+// @Summary Delete one of the current user's passkeys
+// @Tags Passkey
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Last sign-in method of the account"
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/passkeys/{id} [delete]
+func (h *PasskeyHandler) DeletePasskey(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	passkeyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_passkey_id"))
+	}
+
+	if err := h.passkeyService.DeletePasskey(contextx.NewWithRequestContext(c), claims.UserID, uint(passkeyID)); err != nil {
+		switch err.Error() {
+		case "passkey not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("passkey_not_found"))
+		case "last sign-in method":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("last_sign_in_method"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("passkey_deleted"),
+	})
+}
+
+// @Summary Start a passwordless passkey login
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} webauthn.RequestOptions
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/passkey/login/begin [post]
+func (h *PasskeyHandler) BeginLogin(c echo.Context) error {
+	options, err := h.passkeyService.BeginLogin(contextx.NewWithRequestContext(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// @Summary Finish a passwordless passkey login
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyLoginRequest true "Authentication response from the authenticator"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/passkey/login/finish [post]
+func (h *PasskeyHandler) FinishLogin(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.PasskeyLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.passkeyService.FinishLogin(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid passkey challenge":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_passkey_challenge"))
+		case "passkey verification failed", "user not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, t.Error("passkey_verification_failed"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -75,6 +75,9 @@ func (h *PasswordResetHandler) ResetPassword(c echo.Context) error {
 	}
 
 	if err := h.passwordResetService.ResetPassword(ctx, req.Token, req.NewPassword); err != nil {
+		if policyErr := passwordPolicyError(t, err); policyErr != nil {
+			return policyErr
+		}
 		if err.Error() == "invalid or expired reset token" {
 			return echo.NewHTTPError(http.StatusNotFound, t.Error("password.invalid_token"))
 		}
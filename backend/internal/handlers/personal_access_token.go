@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PersonalAccessTokenHandler struct {
+	tokenService *services.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(tokenService *services.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		tokenService: tokenService,
+	}
+}
+
+// @Summary List personal access tokens of the current user
+// @Tags Personal Access Tokens
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.PersonalAccessTokenResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/tokens [get]
+func (h *PersonalAccessTokenHandler) ListTokens(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+	return h.listTokens(c, claims.UserID)
+}
+
+// @Summary Create a personal access token
+// @Description The plain token is only returned in this response. Scopes are resource:action pairs the user already holds.
+// @Tags Personal Access Tokens
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CreatePersonalAccessTokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} dto.PersonalAccessTokenCreatedResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/tokens [post]
+func (h *PersonalAccessTokenHandler) CreateToken(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	var req dto.CreatePersonalAccessTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	response, err := h.tokenService.Create(contextx.NewWithRequestContext(c), claims.UserID, req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid token name":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_token_name"))
+		case "invalid token expiry":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_token_expiry"))
+		case "invalid token scope":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_token_scope"))
+		case "token scope not permitted":
+			return echo.NewHTTPError(http.StatusForbidden, t.Error("token_scope_not_permitted"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// @Summary Revoke a personal access token of the current user
+// @Tags Personal Access Tokens
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/tokens/{id} [delete]
+func (h *PersonalAccessTokenHandler) RevokeToken(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+	return h.revokeToken(c, claims.UserID, c.Param("id"))
+}
+
+// @Summary List personal access tokens of a user (admin)
+// @Tags Personal Access Tokens
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} dto.PersonalAccessTokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/users/{id}/tokens [get]
+func (h *PersonalAccessTokenHandler) ListUserTokens(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	return h.listTokens(c, uint(userID))
+}
+
+// @Summary Revoke a personal access token of a user (admin)
+// @Tags Personal Access Tokens
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param token_id path int true "Token ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/users/{id}/tokens/{token_id} [delete]
+func (h *PersonalAccessTokenHandler) RevokeUserToken(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_user_id"))
+	}
+
+	return h.revokeToken(c, uint(userID), c.Param("token_id"))
+}
+
+func (h *PersonalAccessTokenHandler) listTokens(c echo.Context, userID uint) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	tokens, err := h.tokenService.List(contextx.NewWithRequestContext(c), userID)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.UserNotFound())
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.ToPersonalAccessTokenResponses(tokens))
+}
+
+func (h *PersonalAccessTokenHandler) revokeToken(c echo.Context, userID uint, tokenParam string) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	tokenID, err := strconv.ParseUint(tokenParam, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_token_id"))
+	}
+
+	if err := h.tokenService.Revoke(contextx.NewWithRequestContext(c), userID, uint(tokenID)); err != nil {
+		switch err.Error() {
+		case "personal access token not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("personal_access_token_not_found"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("personal_access_token_revoked")})
+}
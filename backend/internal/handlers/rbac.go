@@ -215,14 +215,20 @@ func (h *RBACHandler) AssignRole(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := h.rbacService.AssignRoleToUser(req.UserID, req.Role); err != nil {
+	if err := h.rbacService.AssignRoleToUserWithWindow(req.UserID, req.Role, req.OrganizationID, req.StartsAt, req.ExpiresAt); err != nil {
+		if err.Error() == "invalid role assignment window" {
+			return echo.NewHTTPError(http.StatusBadRequest, "expires_at must be in the future and after starts_at")
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"message": "Role assigned successfully",
-		"user_id": req.UserID,
-		"role":    req.Role,
+		"message":         "Role assigned successfully",
+		"user_id":         req.UserID,
+		"role":            req.Role,
+		"organization_id": req.OrganizationID,
+		"starts_at":       req.StartsAt,
+		"expires_at":      req.ExpiresAt,
 	})
 }
 
@@ -244,14 +250,15 @@ func (h *RBACHandler) RemoveRole(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := h.rbacService.RemoveRoleFromUser(req.UserID, req.Role); err != nil {
+	if err := h.rbacService.RemoveRoleFromUser(req.UserID, req.Role, req.OrganizationID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"message": "Role removed successfully",
-		"user_id": req.UserID,
-		"role":    req.Role,
+		"message":         "Role removed successfully",
+		"user_id":         req.UserID,
+		"role":            req.Role,
+		"organization_id": req.OrganizationID,
 	})
 }
 
@@ -260,6 +267,7 @@ func (h *RBACHandler) RemoveRole(c echo.Context) error {
 // @Security BearerAuth
 // @Produce json
 // @Param user_id path string true "User ID"
+// @Param organization_id query int false "Organization ID, for the roles held inside the organization"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -273,14 +281,28 @@ func (h *RBACHandler) GetUserRoles(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
 	}
 
-	roles, err := h.rbacService.GetUserRoles(uint(userID))
+	orgID, err := parseOrganizationQuery(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid organization ID")
+	}
+
+	assignments, err := h.rbacService.GetUserRoleAssignments(uint(userID), orgID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
+	roles := []string{}
+	for _, assignment := range assignments {
+		if assignment.Active {
+			roles = append(roles, assignment.Role)
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]any{
-		"user_id": userID,
-		"roles":   roles,
+		"user_id":         userID,
+		"organization_id": orgID,
+		"roles":           roles,
+		"assignments":     assignments,
 	})
 }
 
@@ -301,14 +323,22 @@ func (h *RBACHandler) GetUsersWithRole(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Role parameter is required")
 	}
 
-	userIDs, err := h.rbacService.GetUsersWithRole(role)
+	assignments, err := h.rbacService.GetRoleAssignments(role)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
+	userIDs := []uint{}
+	for _, assignment := range assignments {
+		if assignment.Active {
+			userIDs = append(userIDs, assignment.UserID)
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]any{
-		"role":     role,
-		"user_ids": userIDs,
+		"role":        role,
+		"user_ids":    userIDs,
+		"assignments": assignments,
 	})
 }
 
@@ -321,8 +351,9 @@ func (h *RBACHandler) GetUsersWithRole(c echo.Context) error {
 // @Param role query string false "Filter by role"
 // @Param resource query string false "Filter by resource"
 // @Param action query string false "Filter by action"
+// @Param effect query string false "Filter by effect (allow, deny)"
 // @Param permission query string false "Filter by permission"
-// @Param sort query string false "Sort field (role, resource, action, permission)"
+// @Param sort query string false "Sort field (role, domain, resource, action, effect, permission)"
 // @Param order query string false "Sort order (asc, desc)"
 // @Success 200 {object} dto.PermissionsListResponse
 // @Failure 400 {object} map[string]interface{}
@@ -342,6 +373,7 @@ func (h *RBACHandler) GetPermissions(c echo.Context) error {
 	roleFilter := c.QueryParam("role")
 	resourceFilter := c.QueryParam("resource")
 	actionFilter := c.QueryParam("action")
+	effectFilter := c.QueryParam("effect")
 	permissionFilter := c.QueryParam("permission")
 
 	// Parse sort parameters
@@ -351,7 +383,7 @@ func (h *RBACHandler) GetPermissions(c echo.Context) error {
 		sortOrder = "asc"
 	}
 
-	permissions, total, err := h.rbacService.GetAllPermissions(pagination.Page, pagination.PageSize, roleFilter, resourceFilter, actionFilter, permissionFilter, sortField, sortOrder)
+	permissions, total, err := h.rbacService.GetAllPermissions(pagination.Page, pagination.PageSize, roleFilter, resourceFilter, actionFilter, effectFilter, permissionFilter, sortField, sortOrder)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -379,15 +411,25 @@ func (h *RBACHandler) AddPermission(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := h.rbacService.AddPermission(req.Role, req.Resource, req.Action); err != nil {
+	effect, ok := parsePermissionEffect(req.Effect)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Effect must be allow or deny")
+	}
+	if err := services.ValidateCondition(req.Condition); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.rbacService.AddPermission(req.Role, req.Resource, req.Action, effect, req.Condition); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"message":  "Permission added successfully",
-		"role":     req.Role,
-		"resource": req.Resource,
-		"action":   req.Action,
+		"message":   "Permission added successfully",
+		"role":      req.Role,
+		"resource":  req.Resource,
+		"action":    req.Action,
+		"effect":    effect,
+		"condition": req.Condition,
 	})
 }
 
@@ -409,15 +451,25 @@ func (h *RBACHandler) RemovePermission(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := h.rbacService.RemovePermission(req.Role, req.Resource, req.Action); err != nil {
+	effect, ok := parsePermissionEffect(req.Effect)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Effect must be allow or deny")
+	}
+	if err := services.ValidateCondition(req.Condition); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.rbacService.RemovePermission(req.Role, req.Resource, req.Action, effect, req.Condition); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"message":  "Permission removed successfully",
-		"role":     req.Role,
-		"resource": req.Resource,
-		"action":   req.Action,
+		"message":   "Permission removed successfully",
+		"role":      req.Role,
+		"resource":  req.Resource,
+		"action":    req.Action,
+		"effect":    effect,
+		"condition": req.Condition,
 	})
 }
 
@@ -456,6 +508,9 @@ func (h *RBACHandler) GetRolePermissions(c echo.Context) error {
 // @Param user_id path string true "User ID"
 // @Param resource query string true "Resource name"
 // @Param action query string true "Action name"
+// @Param organization_id query int false "Organization ID to check the permission in"
+// @Param context_type query string false "Context type to check the permission on, e.g. project"
+// @Param context_value query string false "Context value to check the permission on, e.g. a project ID"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -476,19 +531,56 @@ func (h *RBACHandler) CheckPermission(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Resource and action parameters are required")
 	}
 
-	allowed, err := h.rbacService.CheckPermission(uint(userID), resource, action)
+	orgID, err := parseOrganizationQuery(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid organization ID")
+	}
+
+	permissionContext := services.PermissionContext{
+		Type:  c.QueryParam("context_type"),
+		Value: c.QueryParam("context_value"),
+	}
+
+	var allowed bool
+	if permissionContext.Type != "" {
+		allowed, err = h.rbacService.CheckPermissionWithContext(uint(userID), orgID, permissionContext, resource, action)
+	} else {
+		allowed, err = h.rbacService.CheckPermissionInOrganization(uint(userID), orgID, resource, action)
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"user_id":  userID,
-		"resource": resource,
-		"action":   action,
-		"allowed":  allowed,
+		"user_id":         userID,
+		"organization_id": orgID,
+		"context_type":    permissionContext.Type,
+		"context_value":   permissionContext.Value,
+		"resource":        resource,
+		"action":          action,
+		"allowed":         allowed,
 	})
 }
 
+// parsePermissionEffect reads the effect of a permission request, allow when omitted
+func parsePermissionEffect(value string) (models.EffectType, bool) {
+	if value == "" {
+		return models.EffectAllow, true
+	}
+	effect := models.EffectType(value)
+	return effect, effect.IsValid()
+}
+
+// parseOrganizationQuery reads the optional organization_id query parameter, 0 when absent
+func parseOrganizationQuery(c echo.Context) (uint, error) {
+	value := c.QueryParam("organization_id")
+	if value == "" {
+		return 0, nil
+	}
+	orgID, err := strconv.ParseUint(value, 10, 32)
+	return uint(orgID), err
+}
+
 // @Summary Get available permissions list
 // @Tags RBAC
 // @Security BearerAuth
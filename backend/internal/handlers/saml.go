@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// samlStateCookie keeps the signed authentication request ID between the redirect and the response
+const samlStateCookie = "saml_state"
+
+type SAMLHandler struct {
+	samlService *services.SAMLService
+	samlConfig  *config.SAMLConfig
+	oauthConfig *config.OAuthConfig
+}
+
+func NewSAMLHandler(samlService *services.SAMLService, samlConfig *config.SAMLConfig, oauthConfig *config.OAuthConfig) *SAMLHandler {
+	return &SAMLHandler{
+		samlService: samlService,
+		samlConfig:  samlConfig,
+		oauthConfig: oauthConfig,
+	}
+}
+
+// @Summary List SAML identity providers users can sign in with
+// @Tags SAML
+// @Produce json
+// @Success 200 {array} dto.SAMLLoginProviderResponse
+// @Router /auth/saml/providers [get]
+func (h *SAMLHandler) ListLoginProviders(c echo.Context) error {
+	providers, err := h.samlService.ListLoginProviders(contextx.NewWithRequestContext(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]dto.SAMLLoginProviderResponse, 0, len(providers))
+	for _, provider := range providers {
+		responses = append(responses, dto.SAMLLoginProviderResponse{
+			Slug:     provider.Slug,
+			Name:     provider.Name,
+			LoginURL: h.samlConfig.BaseURL + "/" + provider.Slug + "/login",
+		})
+	}
+	return c.JSON(http.StatusOK, responses)
+}
+
+// @Summary Start SAML sign-in
+// @Description Redirects the browser to the identity provider with an authentication request.
+// @Description The result is delivered to the frontend callback URL in the fragment, like social login.
+// @Tags SAML
+// @Param slug path string true "Identity provider slug"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/saml/{slug}/login [get]
+func (h *SAMLHandler) Login(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	redirectURL, state, err := h.samlService.BeginLogin(contextx.NewWithRequestContext(c), c.Param("slug"))
+	if err != nil {
+		switch err.Error() {
+		case "saml provider not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("saml_provider_not_found"))
+		case "saml login failed":
+			return echo.NewHTTPError(http.StatusBadGateway, t.Error("saml_login_failed"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	h.setStateCookie(c, state)
+	return c.Redirect(http.StatusFound, redirectURL)
+}
+
+// @Summary SAML assertion consumer service
+// @Description Receives the identity provider's response (HTTP-POST binding). Redirects to the frontend callback URL
+// @Description with token/refresh_token/expires_at, mfa_required/challenge_token, or error in the fragment.
+// @Tags SAML
+// @Accept x-www-form-urlencoded
+// @Param slug path string true "Identity provider slug"
+// @Param SAMLResponse formData string true "Base64 encoded SAML response"
+// @Success 302
+// @Router /auth/saml/{slug}/acs [post]
+func (h *SAMLHandler) ACS(c echo.Context) error {
+	var stateCookie string
+	if cookie, err := c.Cookie(samlStateCookie); err == nil {
+		stateCookie = cookie.Value
+	}
+	// The request ID is single-use
+	h.setStateCookie(c, "")
+
+	fragment := url.Values{}
+	response, err := h.samlService.HandleResponse(contextx.NewWithRequestContext(c), c.Param("slug"), stateCookie, c.Request())
+	if err != nil {
+		switch err.Error() {
+		case "saml provider not found":
+			fragment.Set("error", "saml_provider_not_found")
+		case "invalid saml state":
+			fragment.Set("error", "invalid_saml_state")
+		case "invalid saml response":
+			fragment.Set("error", "invalid_saml_response")
+		case "saml email missing":
+			fragment.Set("error", "saml_email_missing")
+		case "email already registered":
+			fragment.Set("error", "email_already_registered")
+		default:
+			fragment.Set("error", "saml_login_failed")
+		}
+		return h.redirectToFrontend(c, fragment)
+	}
+
+	if response.MFARequired {
+		fragment.Set("mfa_required", "true")
+		fragment.Set("challenge_token", response.ChallengeToken)
+	} else {
+		fragment.Set("token", response.Token)
+		fragment.Set("refresh_token", response.RefreshToken)
+		if response.ExpiresAt != nil {
+			fragment.Set("expires_at", strconv.FormatInt(response.ExpiresAt.Unix(), 10))
+		}
+	}
+	return h.redirectToFrontend(c, fragment)
+}
+
+// @Summary SAML service provider metadata
+// @Description The metadata URL doubles as the service provider entity ID to register with the identity provider
+// @Tags SAML
+// @Produce xml
+// @Param slug path string true "Identity provider slug"
+// @Success 200 {string} string "SAML metadata"
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/saml/{slug}/metadata [get]
+func (h *SAMLHandler) Metadata(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	metadata, err := h.samlService.Metadata(contextx.NewWithRequestContext(c), c.Param("slug"))
+	if err != nil {
+		return samlProviderError(t, err)
+	}
+
+	return c.Blob(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// @Summary List SAML identity providers (admin)
+// @Tags SAML
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.SAMLProviderResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/saml/providers [get]
+func (h *SAMLHandler) ListProviders(c echo.Context) error {
+	providers, err := h.samlService.ListProviders(contextx.NewWithRequestContext(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToSAMLProviderResponses(providers, h.samlConfig.BaseURL))
+}
+
+// @Summary Register a SAML identity provider (admin)
+// @Description The response contains the entity ID and ACS URL to configure at the identity provider
+// @Tags SAML
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateSAMLProviderRequest true "Identity provider"
+// @Success 201 {object} dto.SAMLProviderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/saml/providers [post]
+func (h *SAMLHandler) CreateProvider(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	var req dto.CreateSAMLProviderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	provider, err := h.samlService.CreateProvider(contextx.NewWithRequestContext(c), req)
+	if err != nil {
+		return samlProviderError(t, err)
+	}
+
+	return c.JSON(http.StatusCreated, dto.ToSAMLProviderResponse(provider, h.samlConfig.BaseURL))
+}
+
+// @Summary Get a SAML identity provider (admin)
+// @Tags SAML
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Provider ID"
+// @Success 200 {object} dto.SAMLProviderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/saml/providers/{id} [get]
+func (h *SAMLHandler) GetProvider(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("saml_provider_not_found"))
+	}
+
+	provider, err := h.samlService.GetProvider(contextx.NewWithRequestContext(c), uint(id))
+	if err != nil {
+		return samlProviderError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToSAMLProviderResponse(provider, h.samlConfig.BaseURL))
+}
+
+// @Summary Update a SAML identity provider (admin)
+// @Tags SAML
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Provider ID"
+// @Param request body dto.UpdateSAMLProviderRequest true "Fields to change"
+// @Success 200 {object} dto.SAMLProviderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/saml/providers/{id} [put]
+func (h *SAMLHandler) UpdateProvider(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("saml_provider_not_found"))
+	}
+
+	var req dto.UpdateSAMLProviderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.InvalidRequestBody())
+	}
+
+	provider, err := h.samlService.UpdateProvider(contextx.NewWithRequestContext(c), uint(id), req)
+	if err != nil {
+		return samlProviderError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.ToSAMLProviderResponse(provider, h.samlConfig.BaseURL))
+}
+
+// @Summary Delete a SAML identity provider (admin)
+// @Tags SAML
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Provider ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/saml/providers/{id} [delete]
+func (h *SAMLHandler) DeleteProvider(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("saml_provider_not_found"))
+	}
+
+	if err := h.samlService.DeleteProvider(contextx.NewWithRequestContext(c), uint(id)); err != nil {
+		return samlProviderError(t, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: t.Success("saml_provider_deleted")})
+}
+
+func samlProviderError(t *i18n.Translator, err error) error {
+	switch err.Error() {
+	case "saml provider not found":
+		return echo.NewHTTPError(http.StatusNotFound, t.Error("saml_provider_not_found"))
+	case "saml provider already exists":
+		return echo.NewHTTPError(http.StatusConflict, t.Error("saml_provider_exists"))
+	case "invalid saml slug":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_saml_slug"))
+	case "invalid saml provider name":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_saml_provider_name"))
+	case "invalid saml metadata":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_saml_metadata"))
+	case "invalid saml certificates":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_saml_certificates"))
+	case "invalid attribute mapping":
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_saml_attribute_mapping"))
+	case "saml login failed":
+		return echo.NewHTTPError(http.StatusBadGateway, t.Error("saml_login_failed"))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// setStateCookie stores the signed state; an empty value clears it
+func (h *SAMLHandler) setStateCookie(c echo.Context, value string) {
+	secure := strings.HasPrefix(h.samlConfig.BaseURL, "https://")
+	cookie := &http.Cookie{
+		Name:     samlStateCookie,
+		Value:    value,
+		Path:     h.cookiePath(),
+		HttpOnly: true,
+		Secure:   secure,
+	}
+	// The response arrives as a cross-site POST from the identity provider, which Lax cookies do
+	// not accompany. Browsers only accept SameSite=None on secure cookies, so plain-HTTP development
+	// setups fall back to the browser default.
+	if secure {
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = time.Now().Add(h.samlConfig.StateTTL)
+	}
+	c.SetCookie(cookie)
+}
+
+func (h *SAMLHandler) redirectToFrontend(c echo.Context, fragment url.Values) error {
+	// The fragment is never sent to servers, keeping tokens out of logs and Referer headers
+	return c.Redirect(http.StatusFound, h.oauthConfig.FrontendCallbackURL+"#"+fragment.Encode())
+}
+
+// cookiePath scopes the state cookie to the SAML routes
+func (h *SAMLHandler) cookiePath() string {
+	if parsed, err := url.Parse(h.samlConfig.BaseURL); err == nil && parsed.Path != "" {
+		return parsed.Path
+	}
+	return "/"
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// @Summary List active sessions of the current user
+// @Tags Session
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.SessionResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/sessions [get]
+func (h *SessionHandler) ListSessions(c echo.Context) error {
+	claims := c.Get("user").(*auth.Claims)
+
+	sessions, err := h.sessionService.ListSessions(contextx.NewWithRequestContext(c), claims.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.ToSessionResponses(sessions, claims.SessionID))
+}
+
+// @Summary Revoke one of the current user's sessions
+// @Tags Session
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_session_id"))
+	}
+
+	if err := h.sessionService.RevokeSession(contextx.NewWithRequestContext(c), claims.UserID, uint(sessionID)); err != nil {
+		switch err.Error() {
+		case "session not found":
+			return echo.NewHTTPError(http.StatusNotFound, t.Error("session_not_found"))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("session_revoked"),
+	})
+}
+
+// @Summary Revoke all sessions of the current user except the current one
+// @Tags Session
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c echo.Context) error {
+	t := i18n.NewTranslator(c.Request().Context())
+	claims := c.Get("user").(*auth.Claims)
+
+	if err := h.sessionService.RevokeAllSessions(contextx.NewWithRequestContext(c), claims.UserID, claims.SessionID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: t.Success("sessions_revoked"),
+	})
+}
@@ -6,6 +6,8 @@ import (
 
 	"bezbase/internal/dto"
 	"bezbase/internal/i18n"
+	"bezbase/internal/middleware"
+	"bezbase/internal/models"
 	"bezbase/internal/pkg/auth"
 	"bezbase/internal/pkg/contextx"
 	"bezbase/internal/services"
@@ -81,18 +83,24 @@ func (h *UserHandler) GetProfile(c echo.Context) error {
 // @Success 200 {object} dto.UserResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/profile [put]
 func (h *UserHandler) UpdateProfile(c echo.Context) error {
 	claims := c.Get("user").(*auth.Claims)
 	ctx := contextx.NewWithRequestContext(c)
+	if err := middleware.AuthorizeResource(c, h.rbacService, models.PermissionEditProfile, userAttributes(claims.UserID)); err != nil {
+		return err
+	}
 	var req dto.UpdateProfileRequest
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 	user, err := h.userService.UpdateProfile(ctx, claims.UserID, req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return accountUpdateError(i18n.NewTranslator(c.Request().Context()), err)
 	}
 	return c.JSON(http.StatusOK, user)
 }
@@ -126,6 +134,9 @@ func (h *UserHandler) ChangePassword(c echo.Context) error {
 		if err.Error() == "invalid current password" {
 			return echo.NewHTTPError(http.StatusBadRequest, "Current password is incorrect")
 		}
+		if policyErr := passwordPolicyError(i18n.NewTranslator(c.Request().Context()), err); policyErr != nil {
+			return policyErr
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -202,6 +213,7 @@ func (h *UserHandler) GetUser(c echo.Context) error {
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/users [post]
 func (h *UserHandler) CreateUser(c echo.Context) error {
@@ -217,6 +229,18 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 
 	user, err := h.userService.CreateUser(contextx.NewWithRequestContext(c), req)
 	if err != nil {
+		t := i18n.NewTranslator(c.Request().Context())
+		if policyErr := passwordPolicyError(t, err); policyErr != nil {
+			return policyErr
+		}
+		switch err.Error() {
+		case "invalid username":
+			return echo.NewHTTPError(http.StatusBadRequest, t.Error("invalid_username"))
+		case "username already taken":
+			return echo.NewHTTPError(http.StatusConflict, t.UsernameAlreadyTaken())
+		case "user with this email already exists":
+			return echo.NewHTTPError(http.StatusConflict, t.Error("user_with_email_exists"))
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -234,6 +258,7 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/users/{id} [put]
 func (h *UserHandler) UpdateUser(c echo.Context) error {
@@ -244,6 +269,9 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 	if _, err := fmt.Sscanf(userID, "%d", &id); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
 	}
+	if err := middleware.AuthorizeResource(c, h.rbacService, models.PermissionEditUsers, userAttributes(id)); err != nil {
+		return err
+	}
 
 	var req dto.UpdateUserRequest
 	if err := c.Bind(&req); err != nil {
@@ -252,7 +280,7 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 
 	user, err := h.userService.UpdateUser(contextx.NewWithRequestContext(c), id, req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return accountUpdateError(i18n.NewTranslator(c.Request().Context()), err)
 	}
 
 	return c.JSON(http.StatusOK, user)
@@ -295,3 +323,8 @@ func (h *UserHandler) DeleteUser(c echo.Context) error {
 		"message": "User deleted successfully",
 	})
 }
+
+// userAttributes are the resource.* attributes of a user account, which its user owns
+func userAttributes(userID uint) map[string]interface{} {
+	return map[string]interface{}{"id": userID, "owner_id": userID}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ChallengeSolutionHeader carries the solution of a challenge from GET /auth/challenge
+const ChallengeSolutionHeader = "X-Challenge-Solution"
+
+// RequireChallenge rejects requests without a valid challenge solution with 428 and a
+// challenge_required or invalid_challenge_solution code. Rejected solutions and requests that
+// still fail (4xx/5xx) count against the client IP, which makes its next challenges harder.
+// A nil verifier disables the check.
+func RequireChallenge(verifier services.ChallengeVerifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if verifier == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			clientIP := c.RealIP()
+			ctx := contextx.NewWithRequestContext(c)
+			if err := verifier.Verify(ctx, c.Request().Header.Get(ChallengeSolutionHeader), clientIP); err != nil {
+				verifier.ReportFailure(clientIP)
+
+				t := i18n.NewTranslator(c.Request().Context())
+				code := "invalid_challenge_solution"
+				if err.Error() == "challenge required" {
+					code = "challenge_required"
+				}
+				return echo.NewHTTPError(http.StatusPreconditionRequired, dto.ErrorResponse{Message: t.Error(code), Code: code})
+			}
+
+			err := next(c)
+			if responseStatus(c, err) >= http.StatusBadRequest {
+				verifier.ReportFailure(clientIP)
+			}
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status a handler responded with, including errors not yet written
+func responseStatus(c echo.Context, err error) int {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return c.Response().Status
+}
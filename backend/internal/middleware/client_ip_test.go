@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestClientIPExtractor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		want           string
+	}{
+		{"direct ignores forwarding headers", nil, "203.0.113.7:51000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.1.2.3:443", "198.51.100.1", "198.51.100.1"},
+		{"trusted proxy by address", []string{"10.1.2.3"}, "10.1.2.3:443", "198.51.100.1", "198.51.100.1"},
+		{"spoofed hop before the proxy", []string{"10.0.0.0/8"}, "10.1.2.3:443", "192.0.2.99, 198.51.100.1", "198.51.100.1"},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7:51000", "198.51.100.1", "203.0.113.7"},
+		{"private peer not listed", []string{"10.0.0.0/8"}, "192.168.1.5:51000", "198.51.100.1", "192.168.1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := ClientIPExtractor(tt.trustedProxies)
+			if err != nil {
+				t.Fatalf("ClientIPExtractor: %v", err)
+			}
+			e := echo.New()
+			e.IPExtractor = extractor
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			req.Header.Set(echo.HeaderXRealIP, "192.0.2.1")
+			if got := e.NewContext(req, httptest.NewRecorder()).RealIP(); got != tt.want {
+				t.Errorf("RealIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPExtractorRejectsInvalidProxy(t *testing.T) {
+	if _, err := ClientIPExtractor([]string{"proxy.internal"}); err == nil {
+		t.Error("ClientIPExtractor accepted a host name")
+	}
+}
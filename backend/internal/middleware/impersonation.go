@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DenyImpersonation rejects requests made while an admin is impersonating a user. It guards
+// routes such as role management that must never run on someone else's behalf.
+func DenyImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if claims, ok := c.Get("user").(*auth.Claims); ok && claims.IsImpersonated() {
+				t := i18n.NewTranslator(c.Request().Context())
+				return echo.NewHTTPError(http.StatusForbidden, t.Error("impersonation_forbidden"))
+			}
+			return next(c)
+		}
+	}
+}
+
+// logImpersonatedRequest records an impersonated request under both the admin and the user
+func logImpersonatedRequest(c echo.Context, claims *auth.Claims, err error) {
+	status := responseStatus(c, err)
+	log.Printf("Impersonated request: user %d as user %d (session %d): %s %s %d",
+		claims.ImpersonatorID, claims.UserID, claims.SessionID, c.Request().Method, c.Request().URL.Path, status)
+}
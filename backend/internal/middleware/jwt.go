@@ -6,11 +6,13 @@ import (
 
 	"bezbase/internal/i18n"
 	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/services"
 
 	"github.com/labstack/echo/v4"
 )
 
-func JWTMiddleware(secret string) echo.MiddlewareFunc {
+func JWTMiddleware(keys *auth.KeySet, sessionService *services.SessionService, tokenService *services.PersonalAccessTokenService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			t := i18n.NewTranslator(c.Request().Context())
@@ -25,13 +27,60 @@ func JWTMiddleware(secret string) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_authorization_header"))
 			}
 
-			claims, err := auth.ValidateToken(tokenString, secret)
+			// Personal access tokens are opaque and limited to their scopes by RBACMiddleware
+			if services.IsPersonalAccessToken(tokenString) {
+				claims, err := tokenService.Authenticate(contextx.NewWithRequestContext(c), tokenString)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_token"))
+				}
+
+				c.Set("user", claims)
+				c.Set("user_id", claims.UserID)
+				return next(c)
+			}
+
+			claims, err := auth.ValidateToken(tokenString, keys)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_token"))
 			}
 
+			// Single-purpose tokens (e.g. MFA challenges) never grant API access
+			if claims.Purpose != "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, t.Error("invalid_token"))
+			}
+
+			// Access tokens are only honoured while their session is alive
+			if claims.SessionID == 0 || !sessionService.IsSessionActive(contextx.NewWithRequestContext(c), claims.SessionID) {
+				return echo.NewHTTPError(http.StatusUnauthorized, t.Error("session_revoked"))
+			}
+
 			c.Set("user", claims)
 			c.Set("user_id", claims.UserID)
+			if claims.IsImpersonated() {
+				c.Set("impersonator_id", claims.ImpersonatorID)
+				err := next(c)
+				logImpersonatedRequest(c, claims, err)
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal access token. It guards account
+// security routes (tokens, sessions, MFA, passkeys, password) so a leaked token cannot escalate.
+// Impersonated sessions are rejected as well, since those routes belong to the user alone.
+func RequireSession() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			t := i18n.NewTranslator(c.Request().Context())
+			claims, ok := c.Get("user").(*auth.Claims)
+			if !ok || claims.IsPersonalAccessToken() {
+				return echo.NewHTTPError(http.StatusForbidden, t.Error("session_required"))
+			}
+			if claims.IsImpersonated() {
+				return echo.NewHTTPError(http.StatusForbidden, t.Error("impersonation_forbidden"))
+			}
 			return next(c)
 		}
 	}
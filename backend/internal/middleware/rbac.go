@@ -12,30 +12,73 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// RBACMiddleware requires a permission, evaluated within the organization chosen by ResolveOrganization
 func RBACMiddleware(rbacService *services.RBACService, permission models.Permission) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Get user claims from JWT middleware
-			userClaims, ok := c.Get("user").(*auth.Claims)
-			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid user context")
+			if err := authorize(c, rbacService, permission, services.AccessRequest{}); err != nil {
+				return err
 			}
+			return next(c)
+		}
+	}
+}
 
-			// Check permission
-			allowed, err := rbacService.CheckPermission(userClaims.UserID, permission.Resource.String(), permission.Action.String())
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Permission check failed: %v", err))
+// RequireContextualPermission requires a permission on the object named by a route parameter, so
+// that contextual permissions scoped to it apply. On /projects/:id,
+// RequireContextualPermission(rbacService, permission, "project", "id") checks the permission in
+// the ("project", <id>) context.
+func RequireContextualPermission(rbacService *services.RBACService, permission models.Permission, contextType, param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			value := c.Param(param)
+			if value == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Missing route parameter: %s", param))
 			}
 
-			if !allowed {
-				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Insufficient permissions: %s", permission.Permission))
+			request := services.AccessRequest{Context: &services.PermissionContext{Type: contextType, Value: value}}
+			if err := authorize(c, rbacService, permission, request); err != nil {
+				return err
 			}
-
 			return next(c)
 		}
 	}
 }
 
+// AuthorizeResource checks a permission from inside a handler once the resource has been loaded,
+// so that policy conditions can use its attributes as resource.*, e.g.
+// AuthorizeResource(c, rbacService, permission, map[string]interface{}{"owner_id": post.UserID})
+func AuthorizeResource(c echo.Context, rbacService *services.RBACService, permission models.Permission, attributes map[string]interface{}) error {
+	return authorize(c, rbacService, permission, services.AccessRequest{ResourceAttributes: attributes})
+}
+
+// authorize checks a permission for the current user within the active organization, if any, with
+// the client IP as environment attribute
+func authorize(c echo.Context, rbacService *services.RBACService, permission models.Permission, request services.AccessRequest) error {
+	// Get user claims from JWT middleware
+	userClaims, ok := c.Get("user").(*auth.Claims)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid user context")
+	}
+
+	// Personal access tokens only get the intersection of their scopes and the user's permissions
+	if userClaims.IsPersonalAccessToken() && !userClaims.HasScope(permission.Resource.String(), permission.Action.String()) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Token scope does not include: %s", permission.Permission))
+	}
+
+	request.OrganizationID = ActiveOrganizationID(c)
+	request.IP = c.RealIP()
+	allowed, err := rbacService.Authorize(userClaims.UserID, permission.Resource.String(), permission.Action.String(), request)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Permission check failed: %v", err))
+	}
+
+	if !allowed {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Insufficient permissions: %s", permission.Permission))
+	}
+	return nil
+}
+
 func RequirePermission(rbacService *services.RBACService, permission models.Permission) echo.MiddlewareFunc {
 	return RBACMiddleware(rbacService, permission)
 }
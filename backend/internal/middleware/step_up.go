@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"bezbase/internal/i18n"
+	"bezbase/internal/pkg/auth"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRecentAuth rejects requests whose token was not authenticated within maxAge, so that a
+// stolen token or refresh token alone cannot change credentials or grant privileges. Clients call
+// POST /v1/reauthenticate and retry. The WWW-Authenticate header follows RFC 9470 (step-up).
+func RequireRecentAuth(maxAge time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("user").(*auth.Claims)
+			if !ok || !claims.AuthenticatedWithin(maxAge) {
+				t := i18n.NewTranslator(c.Request().Context())
+				c.Response().Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int(maxAge.Seconds())))
+				return echo.NewHTTPError(http.StatusUnauthorized, t.Error("reauthentication_required"))
+			}
+			return next(c)
+		}
+	}
+}
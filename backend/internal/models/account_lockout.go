@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AccountLockout tracks failed password logins per account. It lives in the database so
+// counters survive restarts and are shared by all replicas.
+type AccountLockout struct {
+	ID                   uint       `json:"id" gorm:"primaryKey"`
+	UserID               uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	FailedAttempts       int        `json:"failed_attempts" gorm:"not null;default:0"`
+	LastFailedAt         *time.Time `json:"last_failed_at,omitempty"`
+	LastFailedIP         string     `json:"last_failed_ip" gorm:"size:45"`
+	BlockedUntil         *time.Time `json:"blocked_until,omitempty"` // No login attempts are accepted before this time
+	LockedAt             *time.Time `json:"locked_at,omitempty"`     // Set when the threshold was reached
+	UnlockTokenHash      string     `json:"-" gorm:"size:64;index"`
+	UnlockTokenExpiresAt *time.Time `json:"-"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (AccountLockout) TableName() string {
+	return "account_lockouts"
+}
+
+// IsLocked checks if the account is locked after reaching the failure threshold
+func (l *AccountLockout) IsLocked() bool {
+	return l.LockedAt != nil && l.IsBlocked()
+}
+
+// IsBlocked checks if login attempts are currently refused, either by backoff or by a lock
+func (l *AccountLockout) IsBlocked() bool {
+	return l.BlockedUntil != nil && time.Now().Before(*l.BlockedUntil)
+}
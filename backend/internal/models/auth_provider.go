@@ -14,6 +14,10 @@ const (
 	ProviderFacebook AuthProviderType = "facebook"
 	ProviderGithub   AuthProviderType = "github"
 	ProviderApple    AuthProviderType = "apple"
+	ProviderOIDC     AuthProviderType = "oidc"    // Default name of the generic OpenID Connect provider
+	ProviderPasskey  AuthProviderType = "passkey" // Credentials live in webauthn_credentials
+	ProviderSAML     AuthProviderType = "saml"    // ProviderID is "<idp slug>:<NameID>"
+	ProviderLDAP     AuthProviderType = "ldap"    // ProviderID is the directory entry ID; passwords stay in the directory
 )
 
 type AuthProvider struct {
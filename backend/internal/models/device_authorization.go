@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending   DeviceAuthorizationStatus = "pending"
+	DeviceAuthorizationApproved  DeviceAuthorizationStatus = "approved"
+	DeviceAuthorizationDenied    DeviceAuthorizationStatus = "denied"
+	DeviceAuthorizationCompleted DeviceAuthorizationStatus = "completed" // Tokens were issued to the device
+	DeviceAuthorizationExpired   DeviceAuthorizationStatus = "expired"   // Never stored; derived from ExpiresAt
+)
+
+// DeviceAuthorization is an RFC 8628 device authorization request. The device polls with the
+// device code while the user approves the user code from a signed-in browser. Only hashes of both
+// codes are stored.
+type DeviceAuthorization struct {
+	ID             uint                      `json:"id" gorm:"primaryKey"`
+	ClientID       uint                      `json:"client_id" gorm:"not null;index"`
+	DeviceCodeHash string                    `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	UserCodeHash   string                    `json:"-" gorm:"not null;index;size:64"`
+	Status         DeviceAuthorizationStatus `json:"status" gorm:"not null;index;size:20;default:'pending'"`
+	UserID         *uint                     `json:"user_id,omitempty" gorm:"index"` // Set once the user approves or denies
+	Interval       int                       `json:"interval" gorm:"not null"`       // Minimum seconds between polls
+	RequestIP      string                    `json:"request_ip" gorm:"size:45"`
+	UserAgent      string                    `json:"user_agent" gorm:"size:500"`
+	ExpiresAt      time.Time                 `json:"expires_at" gorm:"not null"`
+	LastPolledAt   *time.Time                `json:"last_polled_at,omitempty"`
+	DecidedAt      *time.Time                `json:"decided_at,omitempty"`
+	CompletedAt    *time.Time                `json:"completed_at,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	UpdatedAt      time.Time                 `json:"updated_at"`
+
+	// Relationships
+	User   *User      `json:"-" gorm:"foreignKey:UserID"`
+	Client OIDCClient `json:"-" gorm:"foreignKey:ClientID"`
+}
+
+func (DeviceAuthorization) TableName() string {
+	return "device_authorizations"
+}
+
+// IsExpired checks if the device code has expired
+func (d *DeviceAuthorization) IsExpired() bool {
+	return time.Now().After(d.ExpiresAt)
+}
+
+// CurrentStatus returns the status, reporting unfinished requests past their expiry as expired
+func (d *DeviceAuthorization) CurrentStatus() DeviceAuthorizationStatus {
+	if d.IsExpired() && (d.Status == DeviceAuthorizationPending || d.Status == DeviceAuthorizationApproved) {
+		return DeviceAuthorizationExpired
+	}
+	return d.Status
+}
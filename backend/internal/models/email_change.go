@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailChangeRequest holds a new email address until it is confirmed from that address. The old
+// address gets a revert link that cancels the change, or undoes it once confirmed. Only hashes of
+// both link tokens are stored.
+type EmailChangeRequest struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           uint       `json:"user_id" gorm:"not null;index"`
+	OldEmail         string     `json:"old_email" gorm:"not null"`
+	NewEmail         string     `json:"new_email" gorm:"not null"`
+	ConfirmTokenHash string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	RevertTokenHash  string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	RequestIP        string     `json:"request_ip" gorm:"size:45"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`        // Deadline for confirming the new address
+	RevertExpiresAt  time.Time  `json:"revert_expires_at" gorm:"not null"` // Deadline for the revert link
+	ConfirmedAt      *time.Time `json:"confirmed_at"`
+	RevertedAt       *time.Time `json:"reverted_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (EmailChangeRequest) TableName() string {
+	return "email_change_requests"
+}
+
+// IsPending checks if the change still waits for confirmation
+func (r *EmailChangeRequest) IsPending() bool {
+	return r.ConfirmedAt == nil && r.RevertedAt == nil && time.Now().Before(r.ExpiresAt)
+}
+
+// CanRevert checks if the revert link can still be used
+func (r *EmailChangeRequest) CanRevert() bool {
+	return r.RevertedAt == nil && time.Now().Before(r.RevertExpiresAt)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginEvent records a successful login. Events that triggered an alert carry the hash of the
+// token behind the "this wasn't me" link sent to the user.
+type LoginEvent struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	SessionID         uint       `json:"session_id" gorm:"index"`
+	IPAddress         string     `json:"ip_address" gorm:"size:45"`
+	UserAgent         string     `json:"user_agent" gorm:"size:500"`
+	Browser           string     `json:"browser" gorm:"size:50"`
+	OS                string     `json:"os" gorm:"size:50"`
+	DeviceType        string     `json:"device_type" gorm:"size:20"`
+	DeviceFingerprint string     `json:"device_fingerprint" gorm:"not null;index;size:64"` // Hash of browser, OS, device type and language
+	NewDevice         bool       `json:"new_device" gorm:"default:false"`
+	Suspicious        bool       `json:"suspicious" gorm:"default:false"` // Network changed faster than the travel window allows
+	ReportTokenHash   *string    `json:"-" gorm:"uniqueIndex;size:64"`
+	ReportExpiresAt   *time.Time `json:"-"`
+	ReportedAt        *time.Time `json:"reported_at,omitempty"` // The user answered the alert with "this wasn't me"
+	CreatedAt         time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
+
+// CanReport checks if the "this wasn't me" link of the event can still be used
+func (e *LoginEvent) CanReport() bool {
+	return e.ReportTokenHash != nil && e.ReportedAt == nil && e.ReportExpiresAt != nil && time.Now().Before(*e.ReportExpiresAt)
+}
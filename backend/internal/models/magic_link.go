@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// MagicLinkToken is a single-use passwordless login link. Only hashes of the link token and of
+// the secret kept by the requesting device are stored.
+type MagicLinkToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	DeviceHash string     `json:"-" gorm:"not null;size:64"`
+	Email      string     `json:"email" gorm:"not null"`
+	RequestIP  string     `json:"request_ip" gorm:"size:45"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt     *time.Time `json:"used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
+
+// IsExpired checks if the token has expired
+func (mlt *MagicLinkToken) IsExpired() bool {
+	return time.Now().After(mlt.ExpiresAt)
+}
+
+// IsUsed checks if the token has been used
+func (mlt *MagicLinkToken) IsUsed() bool {
+	return mlt.UsedAt != nil
+}
+
+// IsValid checks if the token is valid (not expired and not used)
+func (mlt *MagicLinkToken) IsValid() bool {
+	return !mlt.IsExpired() && !mlt.IsUsed()
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OIDCClient is an application that signs users in with bezbase accounts through OpenID Connect
+type OIDCClient struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	ClientID         string    `json:"client_id" gorm:"not null;uniqueIndex;size:64"`
+	ClientSecretHash string    `json:"-" gorm:"size:64"` // Empty for public clients, which authenticate with PKCE only
+	Name             string    `json:"name" gorm:"not null;size:100"`
+	RedirectURIs     string    `json:"redirect_uris" gorm:"type:text;not null"` // Space-separated, matched exactly
+	Scopes           string    `json:"scopes" gorm:"type:text;not null"`        // Space-separated scopes the client may request
+	SkipConsent      bool      `json:"skip_consent" gorm:"not null;default:false"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (OIDCClient) TableName() string {
+	return "oidc_clients"
+}
+
+// IsPublic reports whether the client has no secret (e.g. a single-page or native app)
+func (c *OIDCClient) IsPublic() bool {
+	return c.ClientSecretHash == ""
+}
+
+// RedirectURIList returns the registered redirect URIs
+func (c *OIDCClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// ScopeList returns the scopes the client may request
+func (c *OIDCClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *OIDCClient) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIList() {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCGrant records the scopes a user consented to for a client
+type OIDCGrant struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_oidc_grants_user_client"`
+	ClientID  uint      `json:"client_id" gorm:"not null;uniqueIndex:idx_oidc_grants_user_client;index"`
+	Scopes    string    `json:"scopes" gorm:"type:text;not null"` // Space-separated
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User   User       `json:"-" gorm:"foreignKey:UserID"`
+	Client OIDCClient `json:"-" gorm:"foreignKey:ClientID"`
+}
+
+func (OIDCGrant) TableName() string {
+	return "oidc_grants"
+}
+
+// ScopeList returns the consented scopes
+func (g *OIDCGrant) ScopeList() []string {
+	return strings.Fields(g.Scopes)
+}
+
+// OIDCAuthorizationCode is a single-use code exchanged for tokens at the token endpoint
+type OIDCAuthorizationCode struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CodeHash      string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	ClientID      uint       `json:"client_id" gorm:"not null;index"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	RedirectURI   string     `json:"redirect_uri" gorm:"type:text;not null"`
+	Scopes        string     `json:"scopes" gorm:"type:text;not null"`
+	Nonce         string     `json:"-" gorm:"size:255"`
+	CodeChallenge string     `json:"-" gorm:"not null;size:128"` // S256 PKCE challenge
+	AuthTime      time.Time  `json:"auth_time" gorm:"not null"`  // When the user last authenticated
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// Relationships
+	User   User       `json:"-" gorm:"foreignKey:UserID"`
+	Client OIDCClient `json:"-" gorm:"foreignKey:ClientID"`
+}
+
+func (OIDCAuthorizationCode) TableName() string {
+	return "oidc_authorization_codes"
+}
+
+// IsValid checks if the code can still be exchanged
+func (c *OIDCAuthorizationCode) IsValid() bool {
+	return c.UsedAt == nil && time.Now().Before(c.ExpiresAt)
+}
+
+// OIDCToken is an access token issued to a client, with an optional refresh token
+type OIDCToken struct {
+	ID                       uint       `json:"id" gorm:"primaryKey"`
+	ClientID                 uint       `json:"client_id" gorm:"not null;index"`
+	UserID                   uint       `json:"user_id" gorm:"not null;index"`
+	AuthorizationCodeID      *uint      `json:"-" gorm:"index"` // Revoked together when the code is replayed
+	Scopes                   string     `json:"scopes" gorm:"type:text;not null"`
+	AccessTokenHash          string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	AccessTokenExpiresAt     time.Time  `json:"access_token_expires_at" gorm:"not null"`
+	RefreshTokenHash         *string    `json:"-" gorm:"uniqueIndex;size:64"`
+	RefreshTokenExpiresAt    *time.Time `json:"refresh_token_expires_at,omitempty"`
+	PreviousRefreshTokenHash *string    `json:"-" gorm:"index;size:64"` // Last rotated-out refresh token, used for reuse detection
+	AuthTime                 time.Time  `json:"auth_time" gorm:"not null"`
+	RevokedAt                *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt                time.Time  `json:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at"`
+
+	// Relationships
+	User   User       `json:"-" gorm:"foreignKey:UserID"`
+	Client OIDCClient `json:"-" gorm:"foreignKey:ClientID"`
+}
+
+func (OIDCToken) TableName() string {
+	return "oidc_tokens"
+}
+
+// ScopeList returns the granted scopes
+func (t *OIDCToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// HasScope reports whether the token was granted scope
+func (t *OIDCToken) HasScope(scope string) bool {
+	for _, granted := range t.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAccessTokenActive checks if the access token is usable
+func (t *OIDCToken) IsAccessTokenActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.AccessTokenExpiresAt)
+}
+
+// IsRefreshTokenActive checks if the refresh token is usable
+func (t *OIDCToken) IsRefreshTokenActive() bool {
+	return t.RevokedAt == nil && t.RefreshTokenExpiresAt != nil && time.Now().Before(*t.RefreshTokenExpiresAt)
+}
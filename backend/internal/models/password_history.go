@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps hashes of the passwords a user has set, newest last. The newest entry
+// also records when the current password was set.
+type PasswordHistory struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;index"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
@@ -8,25 +8,35 @@ type Permission struct {
 }
 
 var (
-	PermissionCreateUsers         = Permission{Resource: ResourceTypeUser, Action: ActionTypeCreate, Permission: "Create Users"}
-	PermissionViewUsers           = Permission{Resource: ResourceTypeUser, Action: ActionTypeRead, Permission: "View Users"}
-	PermissionEditUsers           = Permission{Resource: ResourceTypeUser, Action: ActionTypeUpdate, Permission: "Edit Users"}
-	PermissionDeleteUsers         = Permission{Resource: ResourceTypeUser, Action: ActionTypeDelete, Permission: "Delete Users"}
-	PermissionCreateRoles         = Permission{Resource: ResourceTypeRole, Action: ActionTypeCreate, Permission: "Create Roles"}
-	PermissionViewRoles           = Permission{Resource: ResourceTypeRole, Action: ActionTypeRead, Permission: "View Roles"}
-	PermissionEditRoles           = Permission{Resource: ResourceTypeRole, Action: ActionTypeUpdate, Permission: "Edit Roles"}
-	PermissionDeleteRoles         = Permission{Resource: ResourceTypeRole, Action: ActionTypeDelete, Permission: "Delete Roles"}
-	PermissionCreatePermissions   = Permission{Resource: ResourceTypePermission, Action: ActionTypeCreate, Permission: "Create Permissions"}
-	PermissionViewPermissions     = Permission{Resource: ResourceTypePermission, Action: ActionTypeRead, Permission: "View Permissions"}
-	PermissionEditPermissions     = Permission{Resource: ResourceTypePermission, Action: ActionTypeUpdate, Permission: "Edit Permissions"}
-	PermissionDeletePermissions   = Permission{Resource: ResourceTypePermission, Action: ActionTypeDelete, Permission: "Delete Permissions"}
-	PermissionCreateOrganizations = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeCreate, Permission: "Create Organizations"}
-	PermissionViewOrganizations   = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeRead, Permission: "View Organizations"}
-	PermissionEditOrganizations   = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeUpdate, Permission: "Edit Organizations"}
-	PermissionDeleteOrganizations = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeDelete, Permission: "Delete Organizations"}
-	PermissionViewDashboard       = Permission{Resource: ResourceTypeDashboard, Action: ActionTypeRead, Permission: "View Dashboard"}
-	PermissionViewProfile         = Permission{Resource: ResourceTypeProfile, Action: ActionTypeRead, Permission: "View Profile"}
-	PermissionEditProfile         = Permission{Resource: ResourceTypeProfile, Action: ActionTypeUpdate, Permission: "Edit Profile"}
+	PermissionCreateUsers              = Permission{Resource: ResourceTypeUser, Action: ActionTypeCreate, Permission: "Create Users"}
+	PermissionViewUsers                = Permission{Resource: ResourceTypeUser, Action: ActionTypeRead, Permission: "View Users"}
+	PermissionEditUsers                = Permission{Resource: ResourceTypeUser, Action: ActionTypeUpdate, Permission: "Edit Users"}
+	PermissionDeleteUsers              = Permission{Resource: ResourceTypeUser, Action: ActionTypeDelete, Permission: "Delete Users"}
+	PermissionImpersonateUsers         = Permission{Resource: ResourceTypeUser, Action: ActionTypeImpersonate, Permission: "Impersonate Users"}
+	PermissionCreateRoles              = Permission{Resource: ResourceTypeRole, Action: ActionTypeCreate, Permission: "Create Roles"}
+	PermissionViewRoles                = Permission{Resource: ResourceTypeRole, Action: ActionTypeRead, Permission: "View Roles"}
+	PermissionEditRoles                = Permission{Resource: ResourceTypeRole, Action: ActionTypeUpdate, Permission: "Edit Roles"}
+	PermissionDeleteRoles              = Permission{Resource: ResourceTypeRole, Action: ActionTypeDelete, Permission: "Delete Roles"}
+	PermissionCreatePermissions        = Permission{Resource: ResourceTypePermission, Action: ActionTypeCreate, Permission: "Create Permissions"}
+	PermissionViewPermissions          = Permission{Resource: ResourceTypePermission, Action: ActionTypeRead, Permission: "View Permissions"}
+	PermissionEditPermissions          = Permission{Resource: ResourceTypePermission, Action: ActionTypeUpdate, Permission: "Edit Permissions"}
+	PermissionDeletePermissions        = Permission{Resource: ResourceTypePermission, Action: ActionTypeDelete, Permission: "Delete Permissions"}
+	PermissionCreateOrganizations      = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeCreate, Permission: "Create Organizations"}
+	PermissionViewOrganizations        = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeRead, Permission: "View Organizations"}
+	PermissionEditOrganizations        = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeUpdate, Permission: "Edit Organizations"}
+	PermissionDeleteOrganizations      = Permission{Resource: ResourceTypeOrganization, Action: ActionTypeDelete, Permission: "Delete Organizations"}
+	PermissionViewDashboard            = Permission{Resource: ResourceTypeDashboard, Action: ActionTypeRead, Permission: "View Dashboard"}
+	PermissionCreateOIDCClients        = Permission{Resource: ResourceTypeOIDCClient, Action: ActionTypeCreate, Permission: "Create OIDC Clients"}
+	PermissionViewOIDCClients          = Permission{Resource: ResourceTypeOIDCClient, Action: ActionTypeRead, Permission: "View OIDC Clients"}
+	PermissionEditOIDCClients          = Permission{Resource: ResourceTypeOIDCClient, Action: ActionTypeUpdate, Permission: "Edit OIDC Clients"}
+	PermissionDeleteOIDCClients        = Permission{Resource: ResourceTypeOIDCClient, Action: ActionTypeDelete, Permission: "Delete OIDC Clients"}
+	PermissionCreateSAMLProviders      = Permission{Resource: ResourceTypeSAMLProvider, Action: ActionTypeCreate, Permission: "Create SAML Providers"}
+	PermissionViewSAMLProviders        = Permission{Resource: ResourceTypeSAMLProvider, Action: ActionTypeRead, Permission: "View SAML Providers"}
+	PermissionEditSAMLProviders        = Permission{Resource: ResourceTypeSAMLProvider, Action: ActionTypeUpdate, Permission: "Edit SAML Providers"}
+	PermissionDeleteSAMLProviders      = Permission{Resource: ResourceTypeSAMLProvider, Action: ActionTypeDelete, Permission: "Delete SAML Providers"}
+	PermissionViewDeviceAuthorizations = Permission{Resource: ResourceTypeDeviceAuthorization, Action: ActionTypeRead, Permission: "View Device Authorizations"}
+	PermissionViewProfile              = Permission{Resource: ResourceTypeProfile, Action: ActionTypeRead, Permission: "View Profile"}
+	PermissionEditProfile              = Permission{Resource: ResourceTypeProfile, Action: ActionTypeUpdate, Permission: "Edit Profile"}
 )
 
 // GetHardcodedPermissions returns a hardcoded list of permissions
@@ -36,6 +46,7 @@ func GetHardcodedPermissions() []Permission {
 		PermissionViewUsers,
 		PermissionEditUsers,
 		PermissionDeleteUsers,
+		PermissionImpersonateUsers,
 		PermissionCreateRoles,
 		PermissionViewRoles,
 		PermissionEditRoles,
@@ -49,6 +60,15 @@ func GetHardcodedPermissions() []Permission {
 		PermissionEditOrganizations,
 		PermissionDeleteOrganizations,
 		PermissionViewDashboard,
+		PermissionCreateOIDCClients,
+		PermissionViewOIDCClients,
+		PermissionEditOIDCClients,
+		PermissionDeleteOIDCClients,
+		PermissionCreateSAMLProviders,
+		PermissionViewSAMLProviders,
+		PermissionEditSAMLProviders,
+		PermissionDeleteSAMLProviders,
+		PermissionViewDeviceAuthorizations,
 		PermissionViewProfile,
 		PermissionEditProfile,
 	}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PersonalAccessToken is a long-lived API token for scripts and CI jobs, limited to a set of
+// resource:action scopes on top of the owner's own permissions
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	Prefix     string     `json:"prefix" gorm:"not null;size:16"`   // Leading characters of the token, shown to help identify it
+	Scopes     string     `json:"scopes" gorm:"type:text;not null"` // Comma-separated resource:action pairs
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"size:45"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// ScopeList returns the token scopes as resource:action pairs
+func (t *PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// IsActive checks if the token is usable (not expired and not revoked)
+func (t *PersonalAccessToken) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
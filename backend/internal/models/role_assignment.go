@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// RoleAssignment is the validity window of a role granted to a user in a Casbin domain. The
+// Casbin g rule is in place for the whole assignment, but permission checks only count it while
+// the window is open; expired assignments and their g rules are cleaned up by the sweeper. Roles
+// granted without a window have no RoleAssignment and never expire.
+type RoleAssignment struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_role_assignments_grant"`
+	Role             string     `json:"role" gorm:"not null;size:100;uniqueIndex:idx_role_assignments_grant"`
+	Domain           string     `json:"domain" gorm:"not null;size:100;uniqueIndex:idx_role_assignments_grant"`
+	StartsAt         *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	ExpiryNotifiedAt *time.Time `json:"expiry_notified_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (RoleAssignment) TableName() string {
+	return "role_assignments"
+}
+
+// IsActive reports whether the window is open at the given time
+func (a *RoleAssignment) IsActive(now time.Time) bool {
+	if a.StartsAt != nil && now.Before(*a.StartsAt) {
+		return false
+	}
+	return a.ExpiresAt == nil || now.Before(*a.ExpiresAt)
+}
+
+// IsExpired reports whether the window has closed at the given time
+func (a *RoleAssignment) IsExpired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
@@ -8,20 +8,23 @@ func (o ResourceType) String() string {
 
 // Define resource types for RBAC
 const (
-	ResourceTypeUser         ResourceType = "users"
-	ResourceTypePost         ResourceType = "posts"
-	ResourceTypeProfile      ResourceType = "profile"
-	ResourceTypeAdmin        ResourceType = "admin"
-	ResourceTypePermission   ResourceType = "permissions"
-	ResourceTypeRole         ResourceType = "roles"
-	ResourceTypeOrganization ResourceType = "organizations"
-	ResourceTypeDashboard    ResourceType = "dashboard"
-	ResourceTypeSettings     ResourceType = "settings"
-	ResourceTypeReports      ResourceType = "reports"
-	ResourceTypeAudit        ResourceType = "audit"
-	ResourceTypeSystem       ResourceType = "system"
-	ResourceTypeBackup       ResourceType = "backup"
-	ResourceTypeAll          ResourceType = "*"
+	ResourceTypeUser                ResourceType = "users"
+	ResourceTypePost                ResourceType = "posts"
+	ResourceTypeProfile             ResourceType = "profile"
+	ResourceTypeAdmin               ResourceType = "admin"
+	ResourceTypePermission          ResourceType = "permissions"
+	ResourceTypeRole                ResourceType = "roles"
+	ResourceTypeOrganization        ResourceType = "organizations"
+	ResourceTypeDashboard           ResourceType = "dashboard"
+	ResourceTypeSettings            ResourceType = "settings"
+	ResourceTypeReports             ResourceType = "reports"
+	ResourceTypeAudit               ResourceType = "audit"
+	ResourceTypeSystem              ResourceType = "system"
+	ResourceTypeBackup              ResourceType = "backup"
+	ResourceTypeOIDCClient          ResourceType = "oidc_clients"
+	ResourceTypeSAMLProvider        ResourceType = "saml_providers"
+	ResourceTypeDeviceAuthorization ResourceType = "device_authorizations"
+	ResourceTypeAll                 ResourceType = "*"
 )
 
 type ActionType string
@@ -32,15 +35,33 @@ func (a ActionType) String() string {
 
 // Define action types for RBAC
 const (
-	ActionTypeCreate  ActionType = "create"
-	ActionTypeRead    ActionType = "read"
-	ActionTypeUpdate  ActionType = "update"
-	ActionTypeDelete  ActionType = "delete"
-	ActionTypeExport  ActionType = "export"
-	ActionTypeRestore ActionType = "restore"
-	ActionTypeAll     ActionType = "*"
+	ActionTypeCreate      ActionType = "create"
+	ActionTypeRead        ActionType = "read"
+	ActionTypeUpdate      ActionType = "update"
+	ActionTypeDelete      ActionType = "delete"
+	ActionTypeExport      ActionType = "export"
+	ActionTypeRestore     ActionType = "restore"
+	ActionTypeImpersonate ActionType = "impersonate"
+	ActionTypeAll         ActionType = "*"
 )
 
+type EffectType string
+
+func (e EffectType) String() string {
+	return string(e)
+}
+
+// Define policy effects for RBAC; a matching deny overrides any matching allow
+const (
+	EffectAllow EffectType = "allow"
+	EffectDeny  EffectType = "deny"
+)
+
+// IsValid reports whether the effect is allow or deny
+func (e EffectType) IsValid() bool {
+	return e == EffectAllow || e == EffectDeny
+}
+
 // Apply pagination and get results
 type Rule struct {
 	ID int    `json:"id" gorm:"primaryKey"`
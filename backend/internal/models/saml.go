@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SAMLIdentityProvider is a corporate identity provider users can sign in through with SAML 2.0
+type SAMLIdentityProvider struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Slug             string    `json:"slug" gorm:"not null;uniqueIndex;size:50"` // Used in the SP URLs and in auth provider IDs
+	Name             string    `json:"name" gorm:"not null;size:100"`
+	MetadataXML      string    `json:"-" gorm:"type:text;not null"`
+	Certificates     string    `json:"-" gorm:"type:text"`  // PEM signing certificates; when set they replace the ones in the metadata
+	AttributeMapping string    `json:"-" gorm:"type:jsonb"` // UserInfo field name -> SAML attribute name
+	Enabled          bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (SAMLIdentityProvider) TableName() string {
+	return "saml_identity_providers"
+}
+
+// Mapping returns the configured attribute mapping; fields without an entry use the defaults
+func (p *SAMLIdentityProvider) Mapping() map[string]string {
+	mapping := map[string]string{}
+	if p.AttributeMapping != "" {
+		_ = json.Unmarshal([]byte(p.AttributeMapping), &mapping)
+	}
+	return mapping
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents a server-side login session backing a refresh token
+type Session struct {
+	ID                       uint           `json:"id" gorm:"primaryKey"`
+	UserID                   uint           `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash         string         `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	PreviousRefreshTokenHash string         `json:"-" gorm:"index;size:64"` // Last rotated-out token, used for reuse detection
+	UserAgent                string         `json:"user_agent" gorm:"size:500"`
+	IPAddress                string         `json:"ip_address" gorm:"size:45"`
+	ExpiresAt                time.Time      `json:"expires_at" gorm:"not null"`
+	LastUsedAt               time.Time      `json:"last_used_at"`
+	AuthenticatedAt          time.Time      `json:"authenticated_at"` // Last login or re-authentication; the auth_time of its tokens
+	RevokedAt                *time.Time     `json:"revoked_at,omitempty"`
+	ImpersonatorID           *uint          `json:"impersonator_id,omitempty" gorm:"index"` // Admin acting as the user
+	ImpersonatorSessionID    *uint          `json:"-"`                                      // Admin session resumed when impersonation stops
+	OrganizationID           *uint          `json:"organization_id,omitempty" gorm:"index"` // Active organization, carried as org_id in its tokens
+	CreatedAt                time.Time      `json:"created_at"`
+	UpdatedAt                time.Time      `json:"updated_at"`
+	DeletedAt                gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsExpired checks if the session has expired
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// IsRevoked checks if the session has been revoked
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsImpersonation checks if the session was started by an admin acting as the user
+func (s *Session) IsImpersonation() bool {
+	return s.ImpersonatorID != nil
+}
+
+// IsActive checks if the session is usable (not expired and not revoked)
+func (s *Session) IsActive() bool {
+	return !s.IsExpired() && !s.IsRevoked()
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SigningKey is a JWT signing key pair managed by the database key source
+type SigningKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	KID         string     `json:"kid" gorm:"column:kid;not null;uniqueIndex;size:64"`
+	Algorithm   string     `json:"algorithm" gorm:"not null;size:16"`
+	PrivateKey  string     `json:"-" gorm:"type:text;not null"`          // PKCS#8 PEM
+	PublicKey   string     `json:"public_key" gorm:"type:text;not null"` // PKIX PEM
+	ActivatesAt time.Time  `json:"activates_at" gorm:"not null;index"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // Set once a newer key takes over, after the grace period
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"bezbase/internal/pkg/identifier"
+
 	"gorm.io/gorm"
 )
 
@@ -13,6 +15,10 @@ type UserInfo struct {
 	FirstName   string         `json:"first_name" gorm:"not null"`
 	LastName    string         `json:"last_name" gorm:"not null"`
 	Email       string         `json:"email" gorm:"not null;uniqueIndex"` // Primary email for display
+	// NFKC case-folded copies of Username and Email used for lookups, kept in sync by BeforeSave.
+	// NULL only for accounts that collided with another one when the columns were added.
+	UsernameNormalized *string `json:"-" gorm:"uniqueIndex"`
+	EmailNormalized    *string `json:"-" gorm:"uniqueIndex"`
 	AvatarURL   string         `json:"avatar_url" gorm:""`                 // Profile picture URL
 	Bio         string         `json:"bio" gorm:""`                        // User biography
 	Location    string         `json:"location" gorm:""`                   // User location
@@ -28,8 +34,39 @@ type UserInfo struct {
 	
 	// Relationships
 	User User `json:"-" gorm:"foreignKey:UserID"`
+
+	// Username and email as last loaded or saved, to tell a rename from a save that leaves them alone
+	loadedUsername string
+	loadedEmail    string
 }
 
 func (UserInfo) TableName() string {
 	return "user_info"
-}
\ No newline at end of file
+}
+
+// BeforeSave keeps the normalized identifiers in sync with the username and email. A NULL left by a
+// collision is only filled in once that identifier changes; writing the shared value back on every
+// save would violate the unique index.
+func (u *UserInfo) BeforeSave(tx *gorm.DB) error {
+	if u.UsernameNormalized != nil || u.ID == 0 || u.Username != u.loadedUsername {
+		username := identifier.Normalize(u.Username)
+		u.UsernameNormalized = &username
+	}
+	if u.EmailNormalized != nil || u.ID == 0 || u.Email != u.loadedEmail {
+		email := identifier.Normalize(u.Email)
+		u.EmailNormalized = &email
+	}
+	return nil
+}
+
+// AfterFind remembers the identifiers as stored
+func (u *UserInfo) AfterFind(tx *gorm.DB) error {
+	u.loadedUsername, u.loadedEmail = u.Username, u.Email
+	return nil
+}
+
+// AfterSave remembers the identifiers as stored
+func (u *UserInfo) AfterSave(tx *gorm.DB) error {
+	u.loadedUsername, u.loadedEmail = u.Username, u.Email
+	return nil
+}
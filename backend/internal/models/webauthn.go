@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// WebAuthnCredential is a passkey registered by a user. All of a user's passkeys
+// hang off a single AuthProvider row of type ProviderPasskey.
+type WebAuthnCredential struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	AuthProviderID uint       `json:"-" gorm:"not null;index"`
+	CredentialID   string     `json:"credential_id" gorm:"not null;uniqueIndex;size:1400"` // base64url encoded
+	PublicKey      []byte     `json:"-" gorm:"not null"`                                   // COSE_Key
+	AAGUID         string     `json:"aaguid" gorm:"size:36"`
+	SignCount      uint32     `json:"sign_count" gorm:"default:0"`
+	Transports     string     `json:"transports" gorm:"size:255"` // Comma separated
+	Name           string     `json:"name" gorm:"not null;size:100"`
+	BackupEligible bool       `json:"backup_eligible" gorm:"default:false"`
+	BackupState    bool       `json:"backup_state" gorm:"default:false"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// WebAuthn ceremony types
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnChallenge is a pending, single-use ceremony challenge
+type WebAuthnChallenge struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Challenge string    `json:"-" gorm:"not null;uniqueIndex;size:64"` // base64url encoded
+	Ceremony  string    `json:"ceremony" gorm:"not null;size:20"`
+	UserID    uint      `json:"user_id" gorm:"index"` // Zero for discoverable login
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (WebAuthnChallenge) TableName() string {
+	return "webauthn_challenges"
+}
+
+// IsExpired checks if the challenge has expired
+func (wc *WebAuthnChallenge) IsExpired() bool {
+	return time.Now().After(wc.ExpiresAt)
+}
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -8,11 +11,55 @@ import (
 )
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID uint   `json:"sid,omitempty"`
+	Purpose   string `json:"purpose,omitempty"` // Set on single-purpose tokens (e.g. MFA challenges) that must not grant API access
+
+	// Active organization (tenant) selected for the session; the X-Organization-ID header overrides it
+	OrgID uint `json:"org_id,omitempty"`
+
+	// When the user last proved their identity in this session; refreshes keep it, re-authentication moves it
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+
+	// Set when an admin is acting as the user; UserID is the impersonated user
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+
+	// Set when the request was authenticated with a personal access token instead of a JWT
+	AccessTokenID uint     `json:"-"`
+	Scopes        []string `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// IsPersonalAccessToken reports whether the claims come from a personal access token
+func (c *Claims) IsPersonalAccessToken() bool {
+	return c.AccessTokenID != 0
+}
+
+// IsImpersonated reports whether an admin is acting as the user
+func (c *Claims) IsImpersonated() bool {
+	return c.ImpersonatorID != 0
+}
+
+// AuthenticatedWithin reports whether the user authenticated no longer than maxAge ago. Tokens
+// without auth_time (personal access tokens, impersonation) never count as recently authenticated.
+func (c *Claims) AuthenticatedWithin(maxAge time.Duration) bool {
+	return c.AuthTime != nil && time.Since(c.AuthTime.Time) <= maxAge
+}
+
+// HasScope reports whether a personal access token was granted resource:action
+func (c *Claims) HasScope(resource, action string) bool {
+	for _, scope := range c.Scopes {
+		if scope == resource+":"+action {
+			return true
+		}
+	}
+	return false
+}
+
+// PurposeMFAChallenge marks a token that can only be exchanged at the MFA verification endpoint
+const PurposeMFAChallenge = "mfa_challenge"
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -23,24 +70,74 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken(userID uint, email, secret string) (string, error) {
+// GenerateToken issues a short-lived access token bound to a session and its active organization
+func GenerateToken(userID uint, email string, sessionID uint, orgID uint, authTime time.Time, keys *KeySet, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
+		OrgID:     orgID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+
+	return keys.Sign(claims)
+}
+
+// GenerateImpersonationToken issues an access token that lets an admin act as the user.
+// It is bound to a dedicated session and carries the admin's ID.
+func GenerateImpersonationToken(userID uint, email string, sessionID uint, impersonatorID uint, keys *KeySet, ttl time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:         userID,
+		Email:          email,
+		SessionID:      sessionID,
+		ImpersonatorID: impersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// GeneratePurposeToken issues a short-lived, session-less token restricted to a single purpose
+func GeneratePurposeToken(userID uint, email string, purpose string, keys *KeySet, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// ValidatePurposeToken validates a token and checks that it was issued for the given purpose
+func ValidatePurposeToken(tokenString, purpose string, keys *KeySet) (*Claims, error) {
+	claims, err := ValidateToken(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// ValidateToken verifies a token with the key named by its kid header
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc,
+		jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +148,18 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 
 	return nil, jwt.ErrInvalidKey
 }
+
+// GenerateOpaqueToken returns a random hex token suitable for refresh tokens
+func GenerateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken returns the SHA-256 hex digest used to store opaque tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrNoSigningKey   = errors.New("auth: no active signing key")
+	ErrUnknownKey     = errors.New("auth: unknown signing key")
+	ErrUnsupportedKey = errors.New("auth: unsupported key type")
+)
+
+// SigningKey is an asymmetric JWT key identified by its kid
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer // Nil for verification-only keys
+	PublicKey   crypto.PublicKey
+	ActivatesAt time.Time  // Keys are published before they start signing so every verifier knows them in time
+	ExpiresAt   *time.Time // Tokens signed with the key are rejected after this time; nil means no limit
+}
+
+// KeySet holds the keys used to sign and verify tokens. It is safe for concurrent use
+// and can be swapped atomically when keys are rotated.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*SigningKey
+}
+
+func NewKeySet(keys ...*SigningKey) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// Replace swaps the keys. keys must be ordered by preference, newest first:
+// the first active key with a private part is used for signing.
+func (ks *KeySet) Replace(keys []*SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+}
+
+// SigningKey returns the key new tokens are signed with
+func (ks *KeySet) SigningKey() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.PrivateKey != nil && !key.ActivatesAt.After(now) && !key.expired(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// VerificationKey returns the key with the given kid while it is still accepted
+func (ks *KeySet) VerificationKey(kid string) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.ID == kid && !key.expired(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// Sign signs claims with the current signing key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key, err := ks.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	if key.Algorithm == AlgorithmRS256 {
+		// The RSA signing method only accepts the concrete key type
+		return token.SignedString(key.PrivateKey.(*rsa.PrivateKey))
+	}
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyFunc resolves the verification key from the token's kid header
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := ks.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	// Never let the token header pick a different algorithm than the key was issued for
+	if token.Method.Alg() != key.Algorithm {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.PublicKey, nil
+}
+
+func (k *SigningKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verifiers should currently accept, including
+// keys that are published but not yet signing
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		if key.expired(now) {
+			continue
+		}
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// GenerateSigningKey creates a new RS256 (RSA 2048) or EdDSA (Ed25519) key with a random kid
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	kid, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:         kid[:16],
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}, nil
+}
+
+// ParseKeyPEM parses a PEM encoded private key (PKCS#8 or PKCS#1) or public key (PKIX).
+// Public keys yield verification-only keys.
+func ParseKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("auth: invalid pem data")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.PrivateKey, key.PublicKey = AlgorithmRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Algorithm, key.PrivateKey, key.PublicKey = AlgorithmEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.Algorithm, key.PublicKey = AlgorithmRS256, k
+	case ed25519.PublicKey:
+		key.Algorithm, key.PublicKey = AlgorithmEdDSA, k
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if rsaKey, ok := key.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("auth: rsa keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// EncodeKeyPEM encodes the private (PKCS#8) and public (PKIX) parts of a key as PEM
+func EncodeKeyPEM(key *SigningKey) (string, string, error) {
+	public, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	if key.PrivateKey == nil {
+		return "", publicPEM, nil
+	}
+
+	private, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})), publicPEM, nil
+}
@@ -104,6 +104,15 @@ func WithUserID(ctx Contextx, userID uint) Contextx {
 	}
 }
 
+// WithTransaction returns a context whose repository calls run in the given database transaction
+func WithTransaction(ctx Contextx, tx *gorm.DB) Contextx {
+	return &contextx{
+		Context: ctx,
+		reqCtx:  ctx.ReqContext(),
+		txn:     tx,
+	}
+}
+
 // GetUserID gets user ID from context
 func GetUserID(ctx Contextx) *uint {
 	if userID := ctx.Value(UserIDKey); userID != nil {
@@ -0,0 +1,29 @@
+package identifier
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/secure/precis"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize returns the form stored in the normalized columns: NFKC with full case folding,
+// so "Alice", "ALICE" and the fullwidth "Ａｌｉｃｅ" all compare equal
+func Normalize(value string) string {
+	folded := cases.Fold().String(norm.NFKC.String(strings.TrimSpace(value)))
+	// Case folding can produce sequences that are no longer in NFKC
+	return norm.NFKC.String(folded)
+}
+
+// Username enforces the PRECIS UsernameCasePreserved profile (RFC 8265) on a new username and
+// returns the form to store. Case is kept for display; comparisons use Normalize. "@" is refused
+// so that a username can never shadow an email address at login.
+func Username(value string) (string, error) {
+	username, err := precis.UsernameCasePreserved.String(strings.TrimSpace(value))
+	if err != nil || username == "" || strings.Contains(username, "@") {
+		return "", errors.New("invalid username")
+	}
+	return username, nil
+}
@@ -0,0 +1,221 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	ErrUnavailable        = errors.New("ldap: directory unavailable")
+)
+
+// Config describes how to reach the directory and find users in it
+type Config struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	CACertFile         string
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupAttribute     string
+	IDAttribute        string
+	Attributes         []string // Additional user attributes to read
+	Timeout            time.Duration
+}
+
+// Entry is an authenticated directory user
+type Entry struct {
+	ID         string // Stable identifier used to link the account
+	DN         string
+	Attributes map[string]string // First value of each requested attribute
+	Groups     []string          // Group DNs
+}
+
+// Directory authenticates users with a search-then-bind against an LDAP server
+type Directory struct {
+	config    Config
+	tlsConfig *tls.Config
+}
+
+// NewDirectory checks the configuration and prepares the TLS settings
+func NewDirectory(config Config) (*Directory, error) {
+	if config.URL == "" || config.BaseDN == "" || !strings.Contains(config.UserFilter, "%s") {
+		return nil, errors.New("ldap: URL, base DN and a user filter containing %s are required")
+	}
+	if config.GroupBaseDN != "" && !strings.Contains(config.GroupFilter, "%s") {
+		return nil, errors.New("ldap: the group filter must contain %s")
+	}
+
+	parsed, err := url.Parse(config.URL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, errors.New("ldap: invalid URL")
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         parsed.Hostname(),
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if config.CACertFile != "" {
+		pemData, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, errors.New("ldap: no certificates found in CA file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Directory{config: config, tlsConfig: tlsConfig}, nil
+}
+
+// Authenticate finds the user matching the login name with the service account, then binds as
+// that user to verify the password
+func (d *Directory) Authenticate(username, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which servers accept without checking anything
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	attributes := append([]string{d.config.GroupAttribute}, d.config.Attributes...)
+	if d.config.IDAttribute != "" {
+		attributes = append(attributes, d.config.IDAttribute)
+	}
+	result, err := conn.Search(goldap.NewSearchRequest(
+		d.config.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, int(d.config.Timeout.Seconds()), false,
+		fmt.Sprintf(d.config.UserFilter, goldap.EscapeFilter(username)),
+		attributes, nil,
+	))
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	// Unknown and ambiguous login names are both rejected without revealing which it was
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	userEntry := result.Entries[0]
+
+	if err := conn.Bind(userEntry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, ErrUnavailable
+	}
+
+	entry := &Entry{
+		ID:         d.entryID(userEntry),
+		DN:         userEntry.DN,
+		Attributes: make(map[string]string),
+	}
+	for _, attribute := range d.config.Attributes {
+		if value := strings.TrimSpace(userEntry.GetAttributeValue(attribute)); value != "" {
+			entry.Attributes[attribute] = value
+		}
+	}
+
+	// Group membership is read with the service account; the user may not be allowed to search
+	if d.config.GroupBaseDN != "" {
+		if err := d.bindService(conn); err != nil {
+			return nil, err
+		}
+		groups, err := conn.Search(goldap.NewSearchRequest(
+			d.config.GroupBaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, int(d.config.Timeout.Seconds()), false,
+			fmt.Sprintf(d.config.GroupFilter, goldap.EscapeFilter(userEntry.DN)),
+			[]string{"dn"}, nil,
+		))
+		if err != nil {
+			return nil, ErrUnavailable
+		}
+		for _, group := range groups.Entries {
+			entry.Groups = append(entry.Groups, group.DN)
+		}
+	} else {
+		entry.Groups = userEntry.GetAttributeValues(d.config.GroupAttribute)
+	}
+
+	return entry, nil
+}
+
+func (d *Directory) connect() (*goldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: d.config.Timeout}
+	conn, err := goldap.DialURL(d.config.URL, goldap.DialWithDialer(dialer), goldap.DialWithTLSConfig(d.tlsConfig))
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	conn.SetTimeout(d.config.Timeout)
+
+	if d.config.StartTLS && !strings.HasPrefix(strings.ToLower(d.config.URL), "ldaps://") {
+		if err := conn.StartTLS(d.tlsConfig); err != nil {
+			conn.Close()
+			return nil, ErrUnavailable
+		}
+	}
+	return conn, nil
+}
+
+func (d *Directory) bindService(conn *goldap.Conn) error {
+	var err error
+	if d.config.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(d.config.BindDN, d.config.BindPassword)
+	}
+	if err != nil {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// entryID returns the configured ID attribute, hex encoding binary values such as objectGUID,
+// and falls back to the DN
+func (d *Directory) entryID(entry *goldap.Entry) string {
+	if d.config.IDAttribute != "" {
+		if raw := entry.GetRawAttributeValue(d.config.IDAttribute); len(raw) > 0 {
+			if utf8.Valid(raw) {
+				return string(raw)
+			}
+			return hex.EncodeToString(raw)
+		}
+	}
+	return entry.DN
+}
+
+// SameDN reports whether two distinguished names refer to the same entry, ignoring case and spacing
+func SameDN(a, b string) bool {
+	parsedA, err := goldap.ParseDN(a)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	parsedB, err := goldap.ParseDN(b)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	return parsedA.EqualFold(parsedB)
+}
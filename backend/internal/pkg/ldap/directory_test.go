@@ -0,0 +1,200 @@
+package ldap_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bezbase/internal/pkg/ldap"
+	"bezbase/internal/pkg/ldap/ldaptest"
+)
+
+const (
+	serviceDN       = "cn=bezbase,ou=services,dc=example,dc=com"
+	servicePassword = "service-secret"
+	engineersDN     = "cn=engineers,ou=groups,dc=example,dc=com"
+)
+
+func jane() ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=jane,ou=people,dc=example,dc=com",
+		Password: "jane-secret",
+		Attributes: map[string][]string{
+			"uid":       {"jane"},
+			"mail":      {"jane@example.com"},
+			"givenName": {"Jane"},
+			"entryUUID": {"6f1c2b9e-5d0a-4c43-9b8e-7d1a3e2f4c5b"},
+			"memberOf":  {engineersDN},
+		},
+	}
+}
+
+// startDirectory serves the service account and entries, and returns a directory configured for it
+func startDirectory(t *testing.T, configure func(config *ldap.Config), entries ...ldaptest.Entry) (*ldap.Directory, *ldaptest.Server) {
+	t.Helper()
+	server, err := ldaptest.Start(append([]ldaptest.Entry{{DN: serviceDN, Password: servicePassword}}, entries...)...)
+	if err != nil {
+		t.Fatalf("ldaptest.Start: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	config := ldap.Config{
+		URL:            server.URL,
+		BindDN:         serviceDN,
+		BindPassword:   servicePassword,
+		BaseDN:         "ou=people,dc=example,dc=com",
+		UserFilter:     "(&(objectClass=*)(uid=%s))",
+		GroupAttribute: "memberOf",
+		IDAttribute:    "entryUUID",
+		Attributes:     []string{"mail", "givenName"},
+		Timeout:        5 * time.Second,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+	directory, err := ldap.NewDirectory(config)
+	if err != nil {
+		t.Fatalf("NewDirectory: %v", err)
+	}
+	return directory, server
+}
+
+func TestAuthenticate(t *testing.T) {
+	directory, server := startDirectory(t, nil, jane())
+
+	entry, err := directory.Authenticate("jane", "jane-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.ID != "6f1c2b9e-5d0a-4c43-9b8e-7d1a3e2f4c5b" || entry.DN != jane().DN {
+		t.Errorf("entry = %s (%s), want jane's entryUUID and DN", entry.ID, entry.DN)
+	}
+	if entry.Attributes["mail"] != "jane@example.com" || entry.Attributes["givenName"] != "Jane" {
+		t.Errorf("attributes = %v", entry.Attributes)
+	}
+	if !slices.Equal(entry.Groups, []string{engineersDN}) {
+		t.Errorf("groups = %v, want %v", entry.Groups, []string{engineersDN})
+	}
+
+	// The service account searches, then the user's own bind checks the password
+	if binds := server.Binds(); !slices.Equal(binds, []string{serviceDN, jane().DN}) {
+		t.Errorf("binds = %v, want the service account then the user", binds)
+	}
+}
+
+func TestAuthenticateGroupSearch(t *testing.T) {
+	directory, server := startDirectory(t, func(config *ldap.Config) {
+		config.GroupBaseDN = "ou=groups,dc=example,dc=com"
+		config.GroupFilter = "(member=%s)"
+	}, jane(), ldaptest.Entry{
+		DN:         engineersDN,
+		Attributes: map[string][]string{"cn": {"engineers"}, "member": {jane().DN}},
+	}, ldaptest.Entry{
+		DN:         "cn=admins,ou=groups,dc=example,dc=com",
+		Attributes: map[string][]string{"cn": {"admins"}, "member": {"uid=root,ou=people,dc=example,dc=com"}},
+	})
+
+	entry, err := directory.Authenticate("jane", "jane-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if !slices.Equal(entry.Groups, []string{engineersDN}) {
+		t.Errorf("groups = %v, want %v", entry.Groups, []string{engineersDN})
+	}
+	// Groups are searched as the service account again
+	if binds := server.Binds(); !slices.Equal(binds, []string{serviceDN, jane().DN, serviceDN}) {
+		t.Errorf("binds = %v, want service, user, service", binds)
+	}
+}
+
+func TestAuthenticateWrongPassword(t *testing.T) {
+	directory, _ := startDirectory(t, nil, jane())
+
+	for _, username := range []string{"jane", "nobody"} {
+		if _, err := directory.Authenticate(username, "wrong"); !errors.Is(err, ldap.ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%s) error = %v, want %v", username, err, ldap.ErrInvalidCredentials)
+		}
+	}
+}
+
+func TestAuthenticateEscapesFilter(t *testing.T) {
+	directory, server := startDirectory(t, nil, jane())
+
+	tests := []struct {
+		username string
+		filter   string
+	}{
+		{"*", `(&(objectClass=*)(uid=\2a))`},
+		{"*)(uid=*", `(&(objectClass=*)(uid=\2a\29\28uid=\2a))`},
+		{"jane)(|(uid=*", `(&(objectClass=*)(uid=jane\29\28|\28uid=\2a))`},
+		{`jane\`, `(&(objectClass=*)(uid=jane\5c))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			before := len(server.Filters())
+			if _, err := directory.Authenticate(tt.username, "jane-secret"); !errors.Is(err, ldap.ErrInvalidCredentials) {
+				t.Errorf("Authenticate error = %v, want %v", err, ldap.ErrInvalidCredentials)
+			}
+			if filters := server.Filters()[before:]; !slices.Equal(filters, []string{tt.filter}) {
+				t.Errorf("filters = %v, want %v", filters, []string{tt.filter})
+			}
+		})
+	}
+	if slices.Contains(server.Binds(), jane().DN) {
+		t.Error("a malicious login name matched and bound as jane")
+	}
+}
+
+func TestAuthenticateRejectsEmptyPassword(t *testing.T) {
+	directory, server := startDirectory(t, nil, jane())
+
+	// The server would accept the empty password as an unauthenticated bind
+	if _, err := directory.Authenticate("jane", ""); !errors.Is(err, ldap.ErrInvalidCredentials) {
+		t.Errorf("Authenticate error = %v, want %v", err, ldap.ErrInvalidCredentials)
+	}
+	if binds := server.Binds(); len(binds) != 0 {
+		t.Errorf("binds = %v, want none", binds)
+	}
+}
+
+func TestAuthenticateRejectsAmbiguousEntry(t *testing.T) {
+	contractor := jane()
+	contractor.DN = "uid=jane,ou=contractors,ou=people,dc=example,dc=com"
+	directory, server := startDirectory(t, nil, jane(), contractor)
+
+	if _, err := directory.Authenticate("jane", "jane-secret"); !errors.Is(err, ldap.ErrInvalidCredentials) {
+		t.Errorf("Authenticate error = %v, want %v", err, ldap.ErrInvalidCredentials)
+	}
+	if binds := server.Binds(); !slices.Equal(binds, []string{serviceDN}) {
+		t.Errorf("binds = %v, want only the service account", binds)
+	}
+}
+
+func TestAuthenticateUnavailable(t *testing.T) {
+	directory, _ := startDirectory(t, func(config *ldap.Config) { config.BindPassword = "wrong" }, jane())
+	if _, err := directory.Authenticate("jane", "jane-secret"); !errors.Is(err, ldap.ErrUnavailable) {
+		t.Errorf("Authenticate with a rejected service account error = %v, want %v", err, ldap.ErrUnavailable)
+	}
+
+	directory, server := startDirectory(t, nil, jane())
+	server.Close()
+	if _, err := directory.Authenticate("jane", "jane-secret"); !errors.Is(err, ldap.ErrUnavailable) {
+		t.Errorf("Authenticate with the server down error = %v, want %v", err, ldap.ErrUnavailable)
+	}
+}
+
+func TestSameDN(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"cn=Engineers,ou=Groups,dc=example,dc=com", "CN=engineers, OU=groups, DC=example, DC=com", true},
+		{"cn=engineers,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", false},
+	}
+	for _, tt := range tests {
+		if got := ldap.SameDN(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameDN(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
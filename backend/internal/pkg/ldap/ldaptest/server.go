@@ -0,0 +1,359 @@
+// Package ldaptest runs a minimal LDAPv3 server on the loopback interface, speaking the protocol
+// directly with BER, so that directory logins can be tested without a real directory. It supports
+// simple binds, searches with the common filter types and unbind; everything else is refused.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Entry is a directory entry. Password is the value checked by a simple bind and is never returned
+// by searches.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is an in-process LDAP server
+type Server struct {
+	URL string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	entries  []Entry
+	binds    []string
+	filters  []string
+}
+
+// Start listens on a free loopback port and serves the given entries
+func Start(entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{URL: "ldap://" + listener.Addr().String(), listener: listener, conns: make(map[net.Conn]bool)}
+	for _, entry := range entries {
+		s.Put(entry)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = true
+			s.mu.Unlock()
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+	return s, nil
+}
+
+// Close stops listening, drops open connections and waits for them to finish
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Put adds an entry, replacing the one with the same DN
+func (s *Server) Put(entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].DN, entry.DN) {
+			s.entries[i] = entry
+			return
+		}
+	}
+	s.entries = append(s.entries, entry)
+}
+
+// Binds returns the DNs of all bind requests received so far, in order, successful or not
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+// Filters returns the filters of all search requests received so far, in order, as sent on the
+// wire and decompiled to their string form
+func (s *Server) Filters() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.filters...)
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		request := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch request.Tag {
+		case goldap.ApplicationBindRequest:
+			responses = []*ber.Packet{s.bind(request)}
+		case goldap.ApplicationSearchRequest:
+			responses = s.search(request)
+		case goldap.ApplicationUnbindRequest:
+			return
+		case goldap.ApplicationAbandonRequest:
+			continue
+		default:
+			responses = []*ber.Packet{result(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError, "unsupported operation")}
+		}
+
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			envelope.AppendChild(response)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks a simple bind. Like most servers, a DN with an empty password is accepted as an
+// unauthenticated bind without checking anything.
+func (s *Server) bind(request *ber.Packet) *ber.Packet {
+	if len(request.Children) < 3 || request.Children[2].ClassType != ber.ClassContext || request.Children[2].Tag != 0 {
+		return result(goldap.ApplicationBindResponse, goldap.LDAPResultAuthMethodNotSupported, "only simple binds are supported")
+	}
+	dn := text(request.Children[1])
+	password := text(request.Children[2])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binds = append(s.binds, dn)
+	if password == "" {
+		return result(goldap.ApplicationBindResponse, goldap.LDAPResultSuccess, "")
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return result(goldap.ApplicationBindResponse, goldap.LDAPResultSuccess, "")
+		}
+	}
+	return result(goldap.ApplicationBindResponse, goldap.LDAPResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *Server) search(request *ber.Packet) []*ber.Packet {
+	if len(request.Children) < 8 {
+		return []*ber.Packet{result(goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError, "malformed search request")}
+	}
+	baseDN := text(request.Children[0])
+	scope, _ := request.Children[1].Value.(int64)
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+	var attributes []string
+	for _, attribute := range request.Children[7].Children {
+		attributes = append(attributes, text(attribute))
+	}
+
+	decompiled, err := goldap.DecompileFilter(filter)
+	if err != nil {
+		return []*ber.Packet{result(goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError, err.Error())}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters = append(s.filters, decompiled)
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		if !inScope(entry.DN, baseDN, scope) || !matches(filter, entry) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSizeLimitExceeded, ""))
+		}
+		responses = append(responses, searchEntry(entry, attributes))
+	}
+	return append(responses, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess, ""))
+}
+
+// inScope reports whether dn lies within a search of baseDN with the given scope
+func inScope(dn, baseDN string, scope int64) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+	switch scope {
+	case goldap.ScopeBaseObject:
+		return dn == baseDN
+	case goldap.ScopeSingleLevel:
+		_, parent, ok := strings.Cut(dn, ",")
+		return ok && parent == baseDN
+	default:
+		return dn == baseDN || baseDN == "" || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+// matches evaluates a search filter against an entry, comparing values case-insensitively
+func matches(filter *ber.Packet, entry Entry) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], entry)
+	case goldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := text(filter.Children[1])
+		for _, value := range values(entry, text(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		attribute := text(filter)
+		return strings.EqualFold(attribute, "objectClass") || len(values(entry, attribute)) > 0
+	case goldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, value := range values(entry, text(filter.Children[0])) {
+			if matchesSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchesSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		substring := strings.ToLower(text(part))
+		switch part.Tag {
+		case goldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, substring) {
+				return false
+			}
+			value = value[len(substring):]
+		case goldap.FilterSubstringsAny:
+			i := strings.Index(value, substring)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(substring):]
+		case goldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, substring) {
+				return false
+			}
+			value = ""
+		}
+	}
+	return true
+}
+
+// values returns the values of an attribute, matching its name case-insensitively
+func values(entry Entry, attribute string) []string {
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// searchEntry returns the requested attributes of an entry, or all of them when none or "*" are
+// requested
+func searchEntry(entry Entry, requested []string) *ber.Packet {
+	all := len(requested) == 0
+	for _, attribute := range requested {
+		if attribute == "*" {
+			all = true
+		}
+	}
+
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, attributeValues := range entry.Attributes {
+		if !all && !containsFold(requested, name) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range attributeValues {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	response.AppendChild(attributes)
+	return response
+}
+
+func result(tag ber.Tag, code uint16, message string) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, goldap.ApplicationMap[uint8(tag)])
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return response
+}
+
+// text returns the content octets of a primitive packet
+func text(packet *ber.Packet) string {
+	if packet.Data == nil {
+		return ""
+	}
+	return packet.Data.String()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// discoveryDocument is the subset of the OpenID Provider Metadata we use
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the standard claims read from an ID token
+type idTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // Some providers send "true" as a string
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+	Name          string      `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// keySet caches the provider's JSON Web Key Set by kid
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// discover loads endpoints from the issuer's discovery document once
+func (p *Provider) discover(ctx context.Context) error {
+	if p.Type != TypeOIDC {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return err
+	}
+	if strings.TrimRight(doc.Issuer, "/") != p.Issuer || doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return errors.New("oauth: invalid discovery document")
+	}
+
+	if p.AuthURL == "" {
+		p.AuthURL = doc.AuthorizationEndpoint
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = doc.TokenEndpoint
+	}
+	if p.UserInfoURL == "" {
+		p.UserInfoURL = doc.UserInfoEndpoint
+	}
+	if p.JWKSURL == "" {
+		p.JWKSURL = doc.JWKSURI
+	}
+	p.discovered = true
+	return nil
+}
+
+func (p *Provider) oidcIdentity(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	if token.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	var claims idTokenClaims
+	parsed, err := jwt.ParseWithClaims(token.IDToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !parsed.Valid || claims.ExpiresAt == nil || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+
+	identity := &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}
+
+	// Some providers keep profile claims out of the ID token; fall back to the userinfo endpoint
+	if (identity.Email == "" || identity.FirstName == "") && p.UserInfoURL != "" {
+		var info idTokenClaims
+		if err := p.getJSON(ctx, p.UserInfoURL, token.AccessToken, &info); err == nil && info.Subject == claims.Subject {
+			if identity.Email == "" {
+				identity.Email = info.Email
+				identity.EmailVerified = info.EmailVerified == true || info.EmailVerified == "true"
+			}
+			if identity.FirstName == "" {
+				identity.FirstName, identity.LastName = info.GivenName, info.FamilyName
+				if identity.FirstName == "" {
+					identity.FirstName, identity.LastName = splitName(info.Name)
+				}
+			}
+		}
+	}
+	if identity.FirstName == "" {
+		identity.FirstName, identity.LastName = splitName(claims.Name)
+	}
+
+	return identity, nil
+}
+
+// publicKey returns the signing key for kid, refreshing the JWKS once when the kid is unknown
+func (p *Provider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if key, ok := p.keys.lookup(kid); ok {
+			return key, nil
+		}
+		// Avoid hammering the provider when tokens carry unknown kids
+		if time.Since(p.keys.fetchedAt) < time.Minute {
+			return nil, ErrInvalidIDToken
+		}
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	if key, ok := keys.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrInvalidIDToken
+}
+
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) (*keySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := p.doJSON(req, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := &keySet{keys: make(map[string]crypto.PublicKey), fetchedAt: time.Now()}
+	for _, raw := range jwks.Keys {
+		kid, key, err := ParseJWK(raw)
+		if err != nil {
+			// Skip keys we cannot use (e.g. encryption keys or unsupported curves)
+			continue
+		}
+		keys.keys[kid] = key
+	}
+	return keys, nil
+}
+
+// ParseJWK parses an RSA or P-256 public key in JSON Web Key format
+func ParseJWK(raw []byte) (string, crypto.PublicKey, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return "", nil, err
+	}
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return "", nil, errors.New("oauth: not a signing key")
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return "", nil, errors.New("oauth: invalid rsa key")
+		}
+		return jwk.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return "", nil, errors.New("oauth: unsupported curve")
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return "", nil, errors.New("oauth: invalid ec key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return "", nil, errors.New("oauth: invalid ec key")
+		}
+		return jwk.Kid, key, nil
+	}
+	return "", nil, errors.New("oauth: unsupported key type")
+}
@@ -0,0 +1,260 @@
+// Package oauth implements the client side of the OAuth 2.0 authorization code flow
+// with PKCE (RFC 6749, RFC 7636) and OpenID Connect ID token validation, for social login.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderType selects how the user identity is obtained after the code exchange
+type ProviderType string
+
+const (
+	// TypeOIDC providers return a signed ID token; endpoints come from the discovery document
+	TypeOIDC ProviderType = "oidc"
+	// TypeGitHub is plain OAuth 2.0; the identity comes from the GitHub REST API
+	TypeGitHub ProviderType = "github"
+)
+
+var (
+	ErrExchangeFailed = errors.New("oauth: code exchange failed")
+	ErrInvalidIDToken = errors.New("oauth: invalid id token")
+	ErrIdentityFailed = errors.New("oauth: failed to fetch user identity")
+)
+
+// Provider is a configured identity provider
+type Provider struct {
+	Name         string
+	Type         ProviderType
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// Issuer is the OIDC issuer; AuthURL, TokenURL, UserInfoURL and JWKSURL are discovered from it when empty
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+	EmailsURL   string // GitHub only
+
+	HTTPClient *http.Client
+
+	mu         sync.Mutex
+	discovered bool
+	keys       *keySet
+}
+
+// Token is the token endpoint response
+type Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Identity is the normalized user identity returned by a provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// NewOIDCProvider configures a generic OpenID Connect provider using discovery
+func NewOIDCProvider(name, issuer, clientID, clientSecret, redirectURL string, scopes []string) *Provider {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		Name:         name,
+		Type:         TypeOIDC,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Issuer:       strings.TrimRight(issuer, "/"),
+	}
+}
+
+// NewGoogleProvider configures Google, which is a standard OIDC provider
+func NewGoogleProvider(clientID, clientSecret, redirectURL string) *Provider {
+	return NewOIDCProvider("google", "https://accounts.google.com", clientID, clientSecret, redirectURL, nil)
+}
+
+// NewGitHubProvider configures GitHub OAuth apps
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         "github",
+		Type:         TypeGitHub,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"read:user", "user:email"},
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		EmailsURL:    "https://api.github.com/user/emails",
+	}
+}
+
+// AuthCodeURL returns the authorization endpoint URL for the given state
+func (p *Provider) AuthCodeURL(ctx context.Context, state *State) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state.State)
+	params.Set("code_challenge", CodeChallengeS256(state.Verifier))
+	params.Set("code_challenge_method", "S256")
+	if p.Type == TypeOIDC {
+		params.Set("nonce", state.Nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code and PKCE verifier for tokens
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token Token
+	if err := p.doJSON(req, &token); err != nil || token.Error != "" || token.AccessToken == "" {
+		return nil, ErrExchangeFailed
+	}
+	return &token, nil
+}
+
+// Identity resolves the user behind a token. For OIDC providers the ID token is verified,
+// including the nonce bound to this login attempt.
+func (p *Provider) Identity(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	switch p.Type {
+	case TypeOIDC:
+		return p.oidcIdentity(ctx, token, nonce)
+	case TypeGitHub:
+		return p.githubIdentity(ctx, token)
+	}
+	return nil, fmt.Errorf("oauth: unsupported provider type %q", p.Type)
+}
+
+func (p *Provider) githubIdentity(ctx context.Context, token *Token) (*Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.getJSON(ctx, p.UserInfoURL, token.AccessToken, &user); err != nil || user.ID == 0 {
+		return nil, ErrIdentityFailed
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.EmailsURL, token.AccessToken, &emails); err != nil {
+		return nil, ErrIdentityFailed
+	}
+
+	identity := &Identity{Subject: strconv.FormatInt(user.ID, 10)}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+
+	name := strings.TrimSpace(user.Name)
+	if name == "" {
+		name = user.Login
+	}
+	identity.FirstName, identity.LastName = splitName(name)
+	return identity, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return p.doJSON(req, out)
+}
+
+func (p *Provider) doJSON(req *http.Request, out interface{}) error {
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Token endpoints report errors as JSON with a 400 status; surface them to the caller
+		if json.Unmarshal(body, out) == nil {
+			return nil
+		}
+		return fmt.Errorf("oauth: %s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func (p *Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// splitName splits a display name into first and last name
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
@@ -0,0 +1,126 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxLineLength bounds the bytes read around a search position; corpus lines are a hash plus a count
+const maxLineLength = 128
+
+// BreachList checks passwords against an offline corpus of breached password hashes, such as the
+// Pwned Passwords SHA-1 list ordered by hash. Each line starts with an uppercase hex SHA-1 hash or a
+// prefix of one, optionally followed by ":count", and lines must be sorted. Truncated prefixes
+// shrink the file at the cost of occasionally rejecting a password that was never breached.
+// The file is binary searched in place, so it is never loaded into memory.
+type BreachList struct {
+	path string
+}
+
+// OpenBreachList checks that the corpus file is readable
+func OpenBreachList(path string) (*BreachList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return &BreachList{path: path}, nil
+}
+
+// Contains reports whether the password's SHA-1 hash matches a line of the corpus
+func (b *BreachList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	file, err := os.Open(b.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// lo is always the start of a line; lines starting before hi may still match
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := lineAtOrAfter(file, mid)
+		if err != nil {
+			return false, err
+		}
+		if line == nil || start >= hi {
+			hi = mid
+			continue
+		}
+
+		key := hashPart(line)
+		if key == "" {
+			lo = start + int64(len(line)) + 1
+			continue
+		}
+		if len(key) > len(target) {
+			key = key[:len(target)]
+		}
+		switch prefix := target[:len(key)]; {
+		case key == prefix:
+			return true, nil
+		case key < prefix:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineAtOrAfter returns the first line starting at or after offset, without its newline.
+// line is nil when no line starts there.
+func lineAtOrAfter(file *os.File, offset int64) (int64, []byte, error) {
+	readFrom := offset
+	if offset > 0 {
+		// Read the preceding byte to know whether offset is already a line start
+		readFrom = offset - 1
+	}
+
+	buf := make([]byte, 2*maxLineLength)
+	n, err := file.ReadAt(buf, readFrom)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, nil, err
+	}
+	buf = buf[:n]
+
+	start := int64(0)
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			return 0, nil, nil
+		}
+		buf = buf[newline+1:]
+		start = readFrom + int64(newline) + 1
+	}
+	if len(buf) == 0 {
+		return 0, nil, nil
+	}
+
+	if end := bytes.IndexByte(buf, '\n'); end >= 0 {
+		buf = buf[:end]
+	}
+	return start, buf, nil
+}
+
+// hashPart extracts the normalized hash or hash prefix of a corpus line
+func hashPart(line []byte) string {
+	key := string(bytes.TrimRight(line, "\r"))
+	if colon := strings.IndexByte(key, ':'); colon >= 0 {
+		key = key[:colon]
+	}
+	return strings.ToUpper(strings.TrimSpace(key))
+}
@@ -0,0 +1,101 @@
+package password
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Violation codes double as translation keys under "errors"
+const (
+	CodeTooShort         = "password_too_short"
+	CodeTooLong          = "password_too_long"
+	CodeMissingUppercase = "password_missing_uppercase"
+	CodeMissingLowercase = "password_missing_lowercase"
+	CodeMissingDigit     = "password_missing_digit"
+	CodeMissingSymbol    = "password_missing_symbol"
+	CodeContainsUserInfo = "password_contains_user_info"
+	CodeBreached         = "password_breached"
+	CodeRecentlyUsed     = "password_recently_used"
+)
+
+// minUserInputMatchSize ignores user inputs too short to be a meaningful match
+const minUserInputMatchSize = 4
+
+// Violation is one broken rule. Params fill the placeholders of the translated message.
+type Violation struct {
+	Code   string
+	Params map[string]interface{}
+}
+
+// PolicyError lists every rule a password breaks
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	return "password policy violation"
+}
+
+// Policy holds the length and complexity rules
+type Policy struct {
+	MinLength     int
+	MaxLength     int // In bytes; bcrypt ignores anything beyond 72
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Check returns the rules the password breaks. userInputs are values such as the username or
+// email that must not appear in the password.
+func (p Policy) Check(password string, userInputs ...string) []Violation {
+	var violations []Violation
+
+	if length := len([]rune(password)); length < p.MinLength {
+		violations = append(violations, Violation{Code: CodeTooShort, Params: map[string]interface{}{"Min": p.MinLength}})
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, Violation{Code: CodeTooLong, Params: map[string]interface{}{"Max": p.MaxLength}})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Code: CodeMissingUppercase})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Code: CodeMissingLowercase})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Code: CodeMissingDigit})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Code: CodeMissingSymbol})
+	}
+
+	lowered := strings.ToLower(password)
+	for _, input := range userInputs {
+		// Only the local part of an email address is meaningful
+		if at := strings.LastIndex(input, "@"); at > 0 {
+			input = input[:at]
+		}
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len(input) >= minUserInputMatchSize && strings.Contains(lowered, input) {
+			violations = append(violations, Violation{Code: CodeContainsUserInfo})
+			break
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,246 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	crewsaml "github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+var (
+	ErrInvalidMetadata     = errors.New("saml: invalid identity provider metadata")
+	ErrInvalidCertificates = errors.New("saml: invalid identity provider certificates")
+	ErrInvalidResponse     = errors.New("saml: invalid response")
+)
+
+// Mappable UserInfo fields and the attribute names tried when a provider does not map them explicitly.
+// Names are matched against both the Name and the FriendlyName of an attribute.
+var DefaultAttributes = map[string][]string{
+	"email":      {"email", "mail", "urn:oid:0.9.2342.19200300.100.1.3", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"},
+	"first_name": {"first_name", "givenName", "urn:oid:2.5.4.42", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname"},
+	"last_name":  {"last_name", "sn", "surname", "urn:oid:2.5.4.4", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname"},
+	"phone":      {"phone", "telephoneNumber", "urn:oid:2.5.4.20"},
+	"location":   {"location", "l", "urn:oid:2.5.4.7"},
+	"website":    {"website"},
+	"avatar_url": {"avatar_url"},
+	"timezone":   {"timezone"},
+	"language":   {"language", "preferredLanguage", "urn:oid:2.16.840.1.113730.3.1.39"},
+}
+
+// KeyPair is the service provider key used to sign authentication requests and decrypt assertions
+type KeyPair struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadKeyPair reads a PEM RSA private key and its certificate
+func LoadKeyPair(keyFile, certFile string) (*KeyPair, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("saml: service provider key must be an RSA key")
+	}
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{Key: key, Certificate: certificate}, nil
+}
+
+// Identity is the subject of a validated assertion
+type Identity struct {
+	NameID     string
+	Attributes map[string]string // UserInfo field name -> first attribute value
+}
+
+// Provider is the service provider side of the relationship with one identity provider
+type Provider struct {
+	sp      *crewsaml.ServiceProvider
+	mapping map[string]string
+}
+
+// NewProvider builds the service provider for an identity provider. baseURL is the URL under which
+// this provider's metadata and assertion consumer service endpoints are served. Certificates, when
+// set, replace the signing certificates published in the metadata.
+func NewProvider(metadataXML, certificatesPEM string, mapping map[string]string, baseURL string, keyPair *KeyPair) (*Provider, error) {
+	metadata, err := ParseMetadata(metadataXML)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(certificatesPEM) != "" {
+		certificates, err := ParseCertificates(certificatesPEM)
+		if err != nil {
+			return nil, err
+		}
+		pinSigningCertificates(metadata, certificates)
+	}
+
+	base, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &crewsaml.ServiceProvider{
+		MetadataURL: *base.JoinPath("metadata"),
+		AcsURL:      *base.JoinPath("acs"),
+		IDPMetadata: metadata,
+		// Let the identity provider pick its configured format; transient IDs are rejected on the way back
+		AuthnNameIDFormat: crewsaml.UnspecifiedNameIDFormat,
+	}
+	if keyPair != nil {
+		sp.Key = keyPair.Key
+		sp.Certificate = keyPair.Certificate
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+
+	return &Provider{sp: sp, mapping: mapping}, nil
+}
+
+// ParseMetadata parses identity provider metadata and checks it offers a redirect binding to sign in with
+func ParseMetadata(metadataXML string) (*crewsaml.EntityDescriptor, error) {
+	metadata, err := samlsp.ParseMetadata([]byte(metadataXML))
+	if err != nil || len(metadata.IDPSSODescriptors) == 0 {
+		return nil, ErrInvalidMetadata
+	}
+	sp := crewsaml.ServiceProvider{IDPMetadata: metadata}
+	if sp.GetSSOBindingLocation(crewsaml.HTTPRedirectBinding) == "" {
+		return nil, ErrInvalidMetadata
+	}
+	return metadata, nil
+}
+
+// ParseCertificates parses one or more PEM certificates
+func ParseCertificates(certificatesPEM string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := []byte(certificatesPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, ErrInvalidCertificates
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, ErrInvalidCertificates
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 || strings.TrimSpace(string(rest)) != "" {
+		return nil, ErrInvalidCertificates
+	}
+	return certificates, nil
+}
+
+// pinSigningCertificates makes the given certificates the only ones trusted for signatures
+func pinSigningCertificates(metadata *crewsaml.EntityDescriptor, certificates []*x509.Certificate) {
+	x509Certificates := make([]crewsaml.X509Certificate, len(certificates))
+	for i, certificate := range certificates {
+		x509Certificates[i] = crewsaml.X509Certificate{Data: base64.StdEncoding.EncodeToString(certificate.Raw)}
+	}
+	for i := range metadata.IDPSSODescriptors {
+		metadata.IDPSSODescriptors[i].KeyDescriptors = []crewsaml.KeyDescriptor{{
+			Use:     "signing",
+			KeyInfo: crewsaml.KeyInfo{X509Data: crewsaml.X509Data{X509Certificates: x509Certificates}},
+		}}
+	}
+}
+
+// AuthnRequestURL returns the identity provider URL to redirect the browser to, and the request ID
+// the response must answer
+func (p *Provider) AuthnRequestURL() (string, string, error) {
+	request, err := p.sp.MakeAuthenticationRequest(p.sp.GetSSOBindingLocation(crewsaml.HTTPRedirectBinding), crewsaml.HTTPRedirectBinding, crewsaml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+	redirectURL, err := request.Redirect("", p.sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL.String(), request.ID, nil
+}
+
+// ParseResponse validates a POSTed SAML response: the signature against the identity provider
+// certificates, the audience, the validity window and that it answers requestID
+func (p *Provider) ParseResponse(r *http.Request, requestID string) (*Identity, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, ErrInvalidResponse
+	}
+	// Artifact resolution is not supported; only the POST binding is advertised
+	if r.PostForm.Get("SAMLResponse") == "" {
+		return nil, ErrInvalidResponse
+	}
+
+	assertion, err := p.sp.ParseResponse(r, []string{requestID})
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, ErrInvalidResponse
+	}
+	// Transient identifiers change on every sign-in and cannot identify a returning user
+	if assertion.Subject.NameID.Format == string(crewsaml.TransientNameIDFormat) {
+		return nil, ErrInvalidResponse
+	}
+
+	identity := &Identity{
+		NameID:     assertion.Subject.NameID.Value,
+		Attributes: map[string]string{},
+	}
+	for field, defaults := range DefaultAttributes {
+		names := defaults
+		if name, ok := p.mapping[field]; ok && name != "" {
+			names = []string{name}
+		}
+		if value := attributeValue(assertion, names); value != "" {
+			identity.Attributes[field] = value
+		}
+	}
+	// Identity providers commonly use the email address as the NameID instead of sending an attribute
+	if _, ok := identity.Attributes["email"]; !ok && assertion.Subject.NameID.Format == string(crewsaml.EmailAddressNameIDFormat) {
+		identity.Attributes["email"] = identity.NameID
+	}
+	return identity, nil
+}
+
+// Metadata returns the service provider metadata to register with the identity provider
+func (p *Provider) Metadata() ([]byte, error) {
+	metadata, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), metadata...), nil
+}
+
+func attributeValue(assertion *crewsaml.Assertion, names []string) string {
+	for _, name := range names {
+		for _, statement := range assertion.AttributeStatements {
+			for _, attribute := range statement.Attributes {
+				if attribute.Name != name && attribute.FriendlyName != name {
+					continue
+				}
+				for _, value := range attribute.Values {
+					if value := strings.TrimSpace(value.Value); value != "" {
+						return value
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
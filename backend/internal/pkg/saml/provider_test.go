@@ -0,0 +1,258 @@
+package saml_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bezbase/internal/pkg/saml"
+	"bezbase/internal/pkg/saml/samltest"
+
+	crewsaml "github.com/crewjam/saml"
+)
+
+const testBaseURL = "https://sp.example/api/v1/auth/saml/acme"
+
+func newIdentityProvider(t *testing.T) *samltest.IdentityProvider {
+	t.Helper()
+	idp, err := samltest.New("https://idp.example")
+	if err != nil {
+		t.Fatalf("samltest.New: %v", err)
+	}
+	return idp
+}
+
+// beginLogin builds the service provider for idp and starts an authentication request
+func beginLogin(t *testing.T, idp *samltest.IdentityProvider, certificatesPEM string, mapping map[string]string) (*saml.Provider, string) {
+	t.Helper()
+	provider, err := saml.NewProvider(idp.Metadata(), certificatesPEM, mapping, testBaseURL, nil)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	redirectURL, requestID, err := provider.AuthnRequestURL()
+	if err != nil {
+		t.Fatalf("AuthnRequestURL: %v", err)
+	}
+	if sent, err := idp.RequestID(redirectURL); err != nil || sent != requestID {
+		t.Fatalf("request ID in the redirect = %q, %v; want %q", sent, err, requestID)
+	}
+	return provider, requestID
+}
+
+// validAssertion answers requestID for the service provider at testBaseURL
+func validAssertion(requestID string) samltest.Assertion {
+	return samltest.Assertion{
+		ACSURL:       testBaseURL + "/acs",
+		Audience:     testBaseURL + "/metadata",
+		InResponseTo: requestID,
+		NameID:       "u-1001",
+		Attributes: map[string]string{
+			"mail":      "jane@example.com",
+			"givenName": "Jane",
+			"sn":        "Doe",
+		},
+	}
+}
+
+func postResponse(samlResponse string) *http.Request {
+	form := url.Values{"SAMLResponse": {samlResponse}, "RelayState": {""}}
+	r := httptest.NewRequest(http.MethodPost, testBaseURL+"/acs", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func issue(t *testing.T, idp *samltest.IdentityProvider, assertion samltest.Assertion) *http.Request {
+	t.Helper()
+	response, err := idp.Response(assertion)
+	if err != nil {
+		t.Fatalf("Response: %v", err)
+	}
+	return postResponse(response)
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, "", nil)
+
+	identity, err := provider.ParseResponse(issue(t, idp, validAssertion(requestID)), requestID)
+	if err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+	if identity.NameID != "u-1001" {
+		t.Errorf("NameID = %q, want u-1001", identity.NameID)
+	}
+	want := map[string]string{"email": "jane@example.com", "first_name": "Jane", "last_name": "Doe"}
+	for field, value := range want {
+		if identity.Attributes[field] != value {
+			t.Errorf("attribute %s = %q, want %q", field, identity.Attributes[field], value)
+		}
+	}
+}
+
+func TestParseResponsePinnedCertificate(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, idp.CertificatePEM(), nil)
+	if _, err := provider.ParseResponse(issue(t, idp, validAssertion(requestID)), requestID); err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+
+	// A pinned certificate replaces the one in the metadata
+	other := newIdentityProvider(t)
+	provider, requestID = beginLogin(t, idp, other.CertificatePEM(), nil)
+	if _, err := provider.ParseResponse(issue(t, idp, validAssertion(requestID)), requestID); !errors.Is(err, saml.ErrInvalidResponse) {
+		t.Errorf("ParseResponse error = %v, want %v", err, saml.ErrInvalidResponse)
+	}
+}
+
+func TestParseResponseAttributeMapping(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, "", map[string]string{"email": "workEmail"})
+
+	assertion := validAssertion(requestID)
+	assertion.Attributes["workEmail"] = "jane.doe@corp.example"
+	identity, err := provider.ParseResponse(issue(t, idp, assertion), requestID)
+	if err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+	if identity.Attributes["email"] != "jane.doe@corp.example" {
+		t.Errorf("email = %q, want the mapped attribute", identity.Attributes["email"])
+	}
+}
+
+func TestParseResponseEmailNameID(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, "", nil)
+
+	assertion := validAssertion(requestID)
+	assertion.NameID = "jane@example.com"
+	assertion.NameIDFormat = string(crewsaml.EmailAddressNameIDFormat)
+	assertion.Attributes = nil
+	identity, err := provider.ParseResponse(issue(t, idp, assertion), requestID)
+	if err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+	if identity.Attributes["email"] != "jane@example.com" {
+		t.Errorf("email = %q, want the NameID", identity.Attributes["email"])
+	}
+}
+
+func TestParseResponseRejects(t *testing.T) {
+	other := newIdentityProvider(t)
+
+	tests := []struct {
+		name   string
+		tamper func(a *samltest.Assertion)
+		signer *samltest.IdentityProvider
+	}{
+		{
+			name:   "unsigned",
+			tamper: func(a *samltest.Assertion) { a.Unsigned = true },
+		},
+		{
+			name:   "signed with another certificate",
+			signer: other,
+		},
+		{
+			name: "expired",
+			tamper: func(a *samltest.Assertion) {
+				a.IssueInstant = time.Now().Add(-time.Hour)
+				a.NotOnOrAfter = time.Now().Add(-55 * time.Minute)
+			},
+		},
+		{
+			name:   "audience mismatch",
+			tamper: func(a *samltest.Assertion) { a.Audience = "https://other-sp.example/metadata" },
+		},
+		{
+			name:   "recipient mismatch",
+			tamper: func(a *samltest.Assertion) { a.ACSURL = "https://sp.example/api/v1/auth/saml/other/acs" },
+		},
+		{
+			name:   "wrong InResponseTo",
+			tamper: func(a *samltest.Assertion) { a.InResponseTo = "id-someone-elses-request" },
+		},
+		{
+			name:   "unsolicited",
+			tamper: func(a *samltest.Assertion) { a.InResponseTo = "" },
+		},
+		{
+			name:   "transient NameID",
+			tamper: func(a *samltest.Assertion) { a.NameIDFormat = string(crewsaml.TransientNameIDFormat) },
+		},
+		{
+			name:   "empty NameID",
+			tamper: func(a *samltest.Assertion) { a.NameID = "" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newIdentityProvider(t)
+			provider, requestID := beginLogin(t, idp, "", nil)
+
+			assertion := validAssertion(requestID)
+			if tt.tamper != nil {
+				tt.tamper(&assertion)
+			}
+			signer := idp
+			if tt.signer != nil {
+				// Same entity ID, so only the key differs
+				signer = &samltest.IdentityProvider{EntityID: idp.EntityID, SSOURL: idp.SSOURL, Key: tt.signer.Key, Certificate: tt.signer.Certificate}
+			}
+
+			if _, err := provider.ParseResponse(issue(t, signer, assertion), requestID); !errors.Is(err, saml.ErrInvalidResponse) {
+				t.Errorf("ParseResponse error = %v, want %v", err, saml.ErrInvalidResponse)
+			}
+		})
+	}
+}
+
+func TestParseResponseRejectsTamperedAssertion(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, "", nil)
+
+	response, err := idp.Response(validAssertion(requestID))
+	if err != nil {
+		t.Fatalf("Response: %v", err)
+	}
+	tampered := replaceInResponse(t, response, "u-1001", "u-1002")
+	if _, err := provider.ParseResponse(postResponse(tampered), requestID); !errors.Is(err, saml.ErrInvalidResponse) {
+		t.Errorf("ParseResponse error = %v, want %v", err, saml.ErrInvalidResponse)
+	}
+}
+
+func TestParseResponseRequiresSAMLResponse(t *testing.T) {
+	idp := newIdentityProvider(t)
+	provider, requestID := beginLogin(t, idp, "", nil)
+
+	for name, form := range map[string]url.Values{
+		"empty":    {},
+		"artifact": {"SAMLart": {"AAQAAMh48/1oXIM+sDo7Dh2qMp1HM4IF5DaRNmDj6RdUmllwn9jJHyEgIi8="}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, testBaseURL+"/acs", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if _, err := provider.ParseResponse(r, requestID); !errors.Is(err, saml.ErrInvalidResponse) {
+				t.Errorf("ParseResponse error = %v, want %v", err, saml.ErrInvalidResponse)
+			}
+		})
+	}
+}
+
+// replaceInResponse edits the XML of an encoded SAMLResponse after it was signed
+func replaceInResponse(t *testing.T, samlResponse, old, new string) string {
+	t.Helper()
+	decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		t.Fatalf("decode SAMLResponse: %v", err)
+	}
+	if !strings.Contains(string(decoded), old) {
+		t.Fatalf("SAMLResponse does not contain %q", old)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(decoded), old, new, 1)))
+}
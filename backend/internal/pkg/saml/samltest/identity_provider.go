@@ -0,0 +1,236 @@
+// Package samltest issues SAML responses signed with locally generated keys, so that the service
+// provider can be tested without a real identity provider.
+package samltest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	crewsaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// IdentityProvider signs assertions with its own self-signed certificate
+type IdentityProvider struct {
+	EntityID    string
+	SSOURL      string
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// New creates an identity provider with a fresh RSA key
+func New(entityID string) (*IdentityProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: entityID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &IdentityProvider{
+		EntityID:    entityID,
+		SSOURL:      entityID + "/sso",
+		Key:         key,
+		Certificate: certificate,
+	}, nil
+}
+
+// Metadata returns the identity provider metadata, publishing its signing certificate
+func (idp *IdentityProvider) Metadata() string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="%s">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="%s"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`, idp.EntityID, base64.StdEncoding.EncodeToString(idp.Certificate.Raw), idp.SSOURL)
+}
+
+// CertificatePEM returns the signing certificate, as pinned in a provider's certificates
+func (idp *IdentityProvider) CertificatePEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.Certificate.Raw}))
+}
+
+// RequestID reads the ID of the authentication request sent with the HTTP-Redirect binding, which
+// the response has to answer
+func (idp *IdentityProvider) RequestID(redirectURL string) (string, error) {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(redirectURL, idp.SSOURL+"?") {
+		return "", fmt.Errorf("samltest: redirect to %s, not to the SSO URL", u.Host+u.Path)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	if err != nil {
+		return "", err
+	}
+	requestXML, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		return "", err
+	}
+	var request struct {
+		ID string `xml:"ID,attr"`
+	}
+	if err := xml.Unmarshal(requestXML, &request); err != nil {
+		return "", err
+	}
+	if request.ID == "" {
+		return "", errors.New("samltest: authentication request without ID")
+	}
+	return request.ID, nil
+}
+
+// Assertion describes a response to issue. ACSURL, Audience and InResponseTo must be set; the
+// remaining fields default to a currently valid, signed assertion.
+type Assertion struct {
+	ACSURL       string
+	Audience     string
+	InResponseTo string
+	NameID       string
+	NameIDFormat string            // Persistent when empty
+	Attributes   map[string]string // Name -> value
+	IssueInstant time.Time         // Now when zero
+	NotOnOrAfter time.Time         // Five minutes after IssueInstant when zero
+	Unsigned     bool
+}
+
+// Response returns the base64 encoded SAMLResponse form value for the assertion
+func (idp *IdentityProvider) Response(a Assertion) (string, error) {
+	issueInstant := a.IssueInstant
+	if issueInstant.IsZero() {
+		issueInstant = time.Now()
+	}
+	issueInstant = issueInstant.UTC().Truncate(time.Second)
+	notOnOrAfter := a.NotOnOrAfter
+	if notOnOrAfter.IsZero() {
+		notOnOrAfter = issueInstant.Add(5 * time.Minute)
+	}
+	notOnOrAfter = notOnOrAfter.UTC().Truncate(time.Second)
+	nameIDFormat := a.NameIDFormat
+	if nameIDFormat == "" {
+		nameIDFormat = string(crewsaml.PersistentNameIDFormat)
+	}
+
+	assertion := &crewsaml.Assertion{
+		ID:           randomID(),
+		IssueInstant: issueInstant,
+		Version:      "2.0",
+		Issuer:       crewsaml.Issuer{Format: "urn:oasis:names:tc:SAML:2.0:nameid-format:entity", Value: idp.EntityID},
+		Subject: &crewsaml.Subject{
+			NameID: &crewsaml.NameID{Format: nameIDFormat, Value: a.NameID},
+			SubjectConfirmations: []crewsaml.SubjectConfirmation{{
+				Method: "urn:oasis:names:tc:SAML:2.0:cm:bearer",
+				SubjectConfirmationData: &crewsaml.SubjectConfirmationData{
+					InResponseTo: a.InResponseTo,
+					NotOnOrAfter: notOnOrAfter,
+					Recipient:    a.ACSURL,
+				},
+			}},
+		},
+		Conditions: &crewsaml.Conditions{
+			NotBefore:            issueInstant.Add(-time.Minute),
+			NotOnOrAfter:         notOnOrAfter,
+			AudienceRestrictions: []crewsaml.AudienceRestriction{{Audience: crewsaml.Audience{Value: a.Audience}}},
+		},
+		AuthnStatements: []crewsaml.AuthnStatement{{
+			AuthnInstant: issueInstant,
+			AuthnContext: crewsaml.AuthnContext{
+				AuthnContextClassRef: &crewsaml.AuthnContextClassRef{Value: "urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport"},
+			},
+		}},
+	}
+	if len(a.Attributes) > 0 {
+		statement := crewsaml.AttributeStatement{}
+		for name, value := range a.Attributes {
+			statement.Attributes = append(statement.Attributes, crewsaml.Attribute{
+				Name:       name,
+				NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+				Values:     []crewsaml.AttributeValue{{Type: "xs:string", Value: value}},
+			})
+		}
+		assertion.AttributeStatements = []crewsaml.AttributeStatement{statement}
+	}
+
+	if !a.Unsigned {
+		signature, err := idp.sign(assertion.Element())
+		if err != nil {
+			return "", err
+		}
+		assertion.Signature = signature
+	}
+
+	response := &crewsaml.Response{
+		ID:           randomID(),
+		InResponseTo: a.InResponseTo,
+		Version:      "2.0",
+		IssueInstant: issueInstant,
+		Destination:  a.ACSURL,
+		Issuer:       &crewsaml.Issuer{Format: "urn:oasis:names:tc:SAML:2.0:nameid-format:entity", Value: idp.EntityID},
+		Status:       crewsaml.Status{StatusCode: crewsaml.StatusCode{Value: crewsaml.StatusSuccess}},
+		Assertion:    assertion,
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(response.Element())
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// sign returns an enveloped signature over el, the way identity providers sign assertions
+func (idp *IdentityProvider) sign(el *etree.Element) (*etree.Element, error) {
+	keyStore := dsig.TLSCertKeyStore(tls.Certificate{
+		Certificate: [][]byte{idp.Certificate.Raw},
+		PrivateKey:  idp.Key,
+		Leaf:        idp.Certificate,
+	})
+	signingContext := dsig.NewDefaultSigningContext(keyStore)
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	if err := signingContext.SetSignatureMethod(dsig.RSASHA256SignatureMethod); err != nil {
+		return nil, err
+	}
+	signed, err := signingContext.SignEnveloped(el)
+	if err != nil {
+		return nil, err
+	}
+	return signed.Child[len(signed.Child)-1].(*etree.Element), nil
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("id-%x", b)
+}
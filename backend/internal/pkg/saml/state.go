@@ -0,0 +1,57 @@
+package saml
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidState = errors.New("saml: invalid state")
+
+// RequestState is kept in a signed cookie between the authentication request and the response,
+// so that only responses to requests this browser started are accepted
+type RequestState struct {
+	Provider  string `json:"p"`
+	RequestID string `json:"r"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// Sign serializes the state as payload.signature using HMAC-SHA256
+func (s *RequestState) Sign(secret []byte) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(encoded, secret), nil
+}
+
+// ParseRequestState verifies a signed state value and checks it has not expired
+func ParseRequestState(value string, secret []byte) (*RequestState, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(sign(encoded, secret))) {
+		return nil, ErrInvalidState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	var state RequestState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, ErrInvalidState
+	}
+	if time.Now().Unix() > state.ExpiresAt {
+		return nil, ErrInvalidState
+	}
+	return &state, nil
+}
+
+func sign(payload string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("saml-state:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
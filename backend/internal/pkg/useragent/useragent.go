@@ -0,0 +1,104 @@
+package useragent
+
+import (
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Info is the coarse description of a client derived from its User-Agent header
+type Info struct {
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+}
+
+// browsers are checked in order, since most engines also announce the ones they derive from
+var browsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"Vivaldi/", "Vivaldi"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Chromium/", "Chromium"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+	{"PostmanRuntime/", "Postman"},
+	{"okhttp/", "OkHttp"},
+	{"python-requests/", "Python Requests"},
+	{"Go-http-client/", "Go HTTP client"},
+}
+
+var operatingSystems = []struct {
+	token string
+	name  string
+}{
+	{"Windows Phone", "Windows Phone"},
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"iPod", "iOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+	{"FreeBSD", "FreeBSD"},
+}
+
+// Parse extracts the browser family, operating system and device type. Versions are left out so
+// that browser updates do not look like a different device.
+func Parse(userAgent string) Info {
+	info := Info{Browser: "Unknown", OS: "Unknown", DeviceType: DeviceOther}
+	if userAgent == "" {
+		return info
+	}
+
+	for _, browser := range browsers {
+		if strings.Contains(userAgent, browser.token) {
+			info.Browser = browser.name
+			break
+		}
+	}
+	for _, os := range operatingSystems {
+		if strings.Contains(userAgent, os.token) {
+			info.OS = os.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider"):
+		info.DeviceType = DeviceBot
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") ||
+		(strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		info.DeviceType = DeviceTablet
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "Windows Phone"):
+		info.DeviceType = DeviceMobile
+	case info.OS == "Windows" || info.OS == "macOS" || info.OS == "Linux" || info.OS == "ChromeOS" || info.OS == "FreeBSD":
+		info.DeviceType = DeviceDesktop
+	}
+	return info
+}
+
+// String renders the info for display, for example "Firefox on Windows"
+func (i Info) String() string {
+	return i.Browser + " on " + i.OS
+}
@@ -0,0 +1,429 @@
+// Package webauthn implements the relying party side of the WebAuthn registration and
+// authentication ceremonies (https://www.w3.org/TR/webauthn-3/) for passkey login.
+//
+// Attestation is requested as "none": authenticator provenance is not asserted, so
+// attestation statements are not verified. Everything else the relying party is
+// responsible for (challenge, origin, RP ID hash, flags, signature and sign counter)
+// is checked here. The package is free of storage concerns so that the ceremonies can
+// be driven end to end by a software authenticator.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Authenticator data flags
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackupState            byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+// Client data types
+const (
+	ClientDataTypeCreate = "webauthn.create"
+	ClientDataTypeGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidClientData        = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch        = errors.New("webauthn: challenge mismatch")
+	ErrOriginMismatch           = errors.New("webauthn: origin not allowed")
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+	ErrRPIDMismatch             = errors.New("webauthn: rp id hash mismatch")
+	ErrUserNotPresent           = errors.New("webauthn: user not present")
+	ErrUserNotVerified          = errors.New("webauthn: user not verified")
+	ErrInvalidAttestation       = errors.New("webauthn: invalid attestation object")
+	ErrSignCountRegression      = errors.New("webauthn: sign count did not increase")
+)
+
+// Config describes the relying party
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout time.Duration
+}
+
+// URLEncodedBase64 is a byte slice that marshals to unpadded base64url as used by the WebAuthn JSON encodings
+type URLEncodedBase64 []byte
+
+func (b URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := decodeBase64URL(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingParty is PublicKeyCredentialRpEntity
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity is PublicKeyCredentialUserEntity
+type UserEntity struct {
+	ID          URLEncodedBase64 `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+// CredentialParameter is PublicKeyCredentialParameters
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor is PublicKeyCredentialDescriptor
+type CredentialDescriptor struct {
+	Type       string           `json:"type"`
+	ID         URLEncodedBase64 `json:"id"`
+	Transports []string         `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection is AuthenticatorSelectionCriteria
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is PublicKeyCredentialCreationOptionsJSON, accepted by PublicKeyCredential.parseCreationOptionsFromJSON
+type CreationOptions struct {
+	Challenge              URLEncodedBase64       `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is PublicKeyCredentialRequestOptionsJSON, accepted by PublicKeyCredential.parseRequestOptionsFromJSON
+type RequestOptions struct {
+	Challenge        URLEncodedBase64       `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is RegistrationResponseJSON as produced by PublicKeyCredential.toJSON()
+type RegistrationResponse struct {
+	ID       string                       `json:"id"`
+	RawID    URLEncodedBase64             `json:"rawId"`
+	Type     string                       `json:"type"`
+	Response AuthenticatorAttestationJSON `json:"response"`
+}
+
+// AuthenticatorAttestationJSON is AuthenticatorAttestationResponseJSON
+type AuthenticatorAttestationJSON struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+	AttestationObject URLEncodedBase64 `json:"attestationObject"`
+	Transports        []string         `json:"transports,omitempty"`
+}
+
+// AuthenticationResponse is AuthenticationResponseJSON as produced by PublicKeyCredential.toJSON()
+type AuthenticationResponse struct {
+	ID       string                     `json:"id"`
+	RawID    URLEncodedBase64           `json:"rawId"`
+	Type     string                     `json:"type"`
+	Response AuthenticatorAssertionJSON `json:"response"`
+}
+
+// AuthenticatorAssertionJSON is AuthenticatorAssertionResponseJSON
+type AuthenticatorAssertionJSON struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+	AuthenticatorData URLEncodedBase64 `json:"authenticatorData"`
+	Signature         URLEncodedBase64 `json:"signature"`
+	UserHandle        URLEncodedBase64 `json:"userHandle,omitempty"`
+}
+
+// CollectedClientData is the parsed clientDataJSON
+type CollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// AuthenticatorData is the parsed authenticator data structure
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+// Credential is the outcome of a successful registration ceremony
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key
+	AAGUID         []byte
+	SignCount      uint32
+	Transports     []string
+	BackupEligible bool
+	BackupState    bool
+}
+
+// Assertion is the outcome of a successful authentication ceremony
+type Assertion struct {
+	SignCount   uint32
+	BackupState bool
+}
+
+// NewChallenge returns 32 random bytes for a ceremony challenge
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// EncodeChallenge returns the base64url form in which the challenge appears in clientDataJSON
+func EncodeChallenge(challenge []byte) string {
+	return base64.RawURLEncoding.EncodeToString(challenge)
+}
+
+// NewCreationOptions builds the options for navigator.credentials.create()
+func (c *Config) NewCreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:      user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// NewRequestOptions builds the options for navigator.credentials.get(). An empty allow
+// list lets the authenticator offer any discoverable credential for the RP.
+func (c *Config) NewRequestOptions(challenge []byte, allow []CredentialDescriptor) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout.Milliseconds(),
+		RPID:             c.RPID,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// ParseClientData decodes clientDataJSON without verifying it, so the challenge can be looked up
+func ParseClientData(clientDataJSON []byte) (*CollectedClientData, error) {
+	var clientData CollectedClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, ErrInvalidClientData
+	}
+	return &clientData, nil
+}
+
+// VerifyRegistration runs the registration ceremony checks and returns the new credential
+func (c *Config) VerifyRegistration(challenge []byte, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := c.verifyClientData(clientDataJSON, ClientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrInvalidAttestation
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if format == "" || rawAuthData == nil {
+		return nil, ErrInvalidAttestation
+	}
+	if _, ok := attestation["attStmt"].(map[interface{}]interface{}); !ok {
+		return nil, ErrInvalidAttestation
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.Flags&FlagAttestedCredentialData == 0 || len(authData.CredentialID) == 0 {
+		return nil, ErrInvalidAttestation
+	}
+	if _, err := ParsePublicKey(authData.CredentialPublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             authData.CredentialID,
+		PublicKey:      authData.CredentialPublicKey,
+		AAGUID:         authData.AAGUID,
+		SignCount:      authData.SignCount,
+		BackupEligible: authData.Flags&FlagBackupEligible != 0,
+		BackupState:    authData.Flags&FlagBackupState != 0,
+	}, nil
+}
+
+// VerifyAssertion runs the authentication ceremony checks against a stored credential
+func (c *Config) VerifyAssertion(challenge []byte, clientDataJSON, rawAuthData, signature, publicKey []byte, storedSignCount uint32) (*Assertion, error) {
+	if err := c.verifyClientData(clientDataJSON, ClientDataTypeGet, challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := key.Verify(signed, signature); err != nil {
+		return nil, err
+	}
+
+	// Authenticators that keep a counter must increase it; a regression suggests a cloned credential
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return nil, ErrSignCountRegression
+	}
+
+	return &Assertion{
+		SignCount:   authData.SignCount,
+		BackupState: authData.Flags&FlagBackupState != 0,
+	}, nil
+}
+
+// ParseAuthenticatorData decodes the binary authenticator data structure
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&FlagAttestedCredentialData != 0 {
+		if len(rest) < 18 {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		authData.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The COSE key is followed directly by optional extensions, so decode it to find its length
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		authData.CredentialPublicKey = rest[:len(rest)-len(afterKey)]
+		rest = afterKey
+	}
+
+	if authData.Flags&FlagExtensionData != 0 {
+		_, afterExtensions, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		rest = afterExtensions
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	return authData, nil
+}
+
+func (c *Config) verifyClientData(clientDataJSON []byte, expectedType string, challenge []byte) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != expectedType {
+		return ErrInvalidClientData
+	}
+
+	received, err := decodeBase64URL(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	if clientData.CrossOrigin {
+		return ErrOriginMismatch
+	}
+	for _, origin := range c.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOriginMismatch
+}
+
+func (c *Config) verifyAuthenticatorData(authData *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if authData.Flags&FlagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	// Passkeys replace the password entirely, so user verification is always required
+	if authData.Flags&FlagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// decodeBase64URL accepts base64url with or without padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
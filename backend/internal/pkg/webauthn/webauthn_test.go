@@ -0,0 +1,292 @@
+package webauthn_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"bezbase/internal/pkg/webauthn"
+	"bezbase/internal/pkg/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+var algorithms = map[string]int64{
+	"ES256":   webauthn.AlgES256,
+	"Ed25519": webauthn.AlgEdDSA,
+}
+
+func newRelyingParty() *webauthn.Config {
+	return &webauthn.Config{
+		RPID:    testRPID,
+		RPName:  "Example",
+		Origins: []string{testOrigin},
+		Timeout: time.Minute,
+	}
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return challenge
+}
+
+func newAuthenticator(t *testing.T, algorithm int64) *webauthntest.Authenticator {
+	t.Helper()
+	authenticator, err := webauthntest.New(testRPID, testOrigin, algorithm)
+	if err != nil {
+		t.Fatalf("webauthntest.New: %v", err)
+	}
+	return authenticator
+}
+
+func TestCeremonies(t *testing.T) {
+	for name, algorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			rp := newRelyingParty()
+			authenticator := newAuthenticator(t, algorithm)
+
+			challenge := newChallenge(t)
+			registration, err := authenticator.Register(challenge)
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			credential, err := rp.VerifyRegistration(challenge, registration.Response.ClientDataJSON, registration.Response.AttestationObject)
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if !bytes.Equal(credential.ID, authenticator.CredentialID) {
+				t.Errorf("credential ID = %x, want %x", credential.ID, authenticator.CredentialID)
+			}
+			if !bytes.Equal(credential.PublicKey, authenticator.PublicKey()) {
+				t.Error("credential public key does not match the authenticator key")
+			}
+
+			signCount := credential.SignCount
+			for i := 0; i < 2; i++ {
+				challenge := newChallenge(t)
+				response, err := authenticator.Assert(challenge, nil)
+				if err != nil {
+					t.Fatalf("Assert: %v", err)
+				}
+				assertion, err := rp.VerifyAssertion(challenge, response.Response.ClientDataJSON, response.Response.AuthenticatorData,
+					response.Response.Signature, credential.PublicKey, signCount)
+				if err != nil {
+					t.Fatalf("VerifyAssertion #%d: %v", i+1, err)
+				}
+				if assertion.SignCount != signCount+1 {
+					t.Errorf("sign count = %d, want %d", assertion.SignCount, signCount+1)
+				}
+				signCount = assertion.SignCount
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(a *webauthntest.Authenticator, challenge []byte) []byte
+		wantErr error
+	}{
+		{
+			name:    "wrong challenge",
+			tamper:  func(a *webauthntest.Authenticator, challenge []byte) []byte { return flip(challenge) },
+			wantErr: webauthn.ErrChallengeMismatch,
+		},
+		{
+			name: "wrong origin",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Origin = "https://evil.example"
+				return challenge
+			},
+			wantErr: webauthn.ErrOriginMismatch,
+		},
+		{
+			name: "wrong rp id hash",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.RPID = "evil.example"
+				return challenge
+			},
+			wantErr: webauthn.ErrRPIDMismatch,
+		},
+		{
+			name: "user not present",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserPresent
+				return challenge
+			},
+			wantErr: webauthn.ErrUserNotPresent,
+		},
+		{
+			name: "user not verified",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserVerified
+				return challenge
+			},
+			wantErr: webauthn.ErrUserNotVerified,
+		},
+	}
+
+	for name, algorithm := range algorithms {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				rp := newRelyingParty()
+				authenticator := newAuthenticator(t, algorithm)
+				challenge := newChallenge(t)
+
+				registration, err := authenticator.Register(tt.tamper(authenticator, challenge))
+				if err != nil {
+					t.Fatalf("Register: %v", err)
+				}
+				_, err = rp.VerifyRegistration(challenge, registration.Response.ClientDataJSON, registration.Response.AttestationObject)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VerifyRegistration error = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(a *webauthntest.Authenticator, challenge []byte) []byte
+		corrupt func(response *webauthn.AuthenticationResponse)
+		wantErr error
+	}{
+		{
+			name:    "wrong challenge",
+			tamper:  func(a *webauthntest.Authenticator, challenge []byte) []byte { return flip(challenge) },
+			wantErr: webauthn.ErrChallengeMismatch,
+		},
+		{
+			name: "wrong origin",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Origin = "https://evil.example"
+				return challenge
+			},
+			wantErr: webauthn.ErrOriginMismatch,
+		},
+		{
+			name: "wrong rp id hash",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.RPID = "evil.example"
+				return challenge
+			},
+			wantErr: webauthn.ErrRPIDMismatch,
+		},
+		{
+			name: "user not present",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserPresent
+				return challenge
+			},
+			wantErr: webauthn.ErrUserNotPresent,
+		},
+		{
+			name: "user not verified",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserVerified
+				return challenge
+			},
+			wantErr: webauthn.ErrUserNotVerified,
+		},
+		{
+			name: "bad signature",
+			corrupt: func(response *webauthn.AuthenticationResponse) {
+				response.Response.Signature = flip(response.Response.Signature)
+			},
+			wantErr: webauthn.ErrBadSignature,
+		},
+		{
+			name: "signature over other authenticator data",
+			corrupt: func(response *webauthn.AuthenticationResponse) {
+				response.Response.AuthenticatorData[36]++
+			},
+			wantErr: webauthn.ErrBadSignature,
+		},
+		{
+			name: "sign count regression",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				// The stored count is 5 after the first assertion; replay from a clone that is behind
+				a.SignCount = 3
+				return challenge
+			},
+			wantErr: webauthn.ErrSignCountRegression,
+		},
+	}
+
+	for name, algorithm := range algorithms {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				rp := newRelyingParty()
+				authenticator := newAuthenticator(t, algorithm)
+				publicKey := authenticator.PublicKey()
+				authenticator.SignCount = 4
+
+				// A valid assertion first, so that a stored counter is in place
+				challenge := newChallenge(t)
+				response, err := authenticator.Assert(challenge, nil)
+				if err != nil {
+					t.Fatalf("Assert: %v", err)
+				}
+				assertion, err := rp.VerifyAssertion(challenge, response.Response.ClientDataJSON, response.Response.AuthenticatorData,
+					response.Response.Signature, publicKey, 0)
+				if err != nil {
+					t.Fatalf("VerifyAssertion: %v", err)
+				}
+
+				challenge = newChallenge(t)
+				signedChallenge := challenge
+				if tt.tamper != nil {
+					signedChallenge = tt.tamper(authenticator, challenge)
+				}
+				response, err = authenticator.Assert(signedChallenge, nil)
+				if err != nil {
+					t.Fatalf("Assert: %v", err)
+				}
+				if tt.corrupt != nil {
+					tt.corrupt(response)
+				}
+
+				_, err = rp.VerifyAssertion(challenge, response.Response.ClientDataJSON, response.Response.AuthenticatorData,
+					response.Response.Signature, publicKey, assertion.SignCount)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VerifyAssertion error = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyAssertionRejectsOtherCredentialKey(t *testing.T) {
+	rp := newRelyingParty()
+	authenticator := newAuthenticator(t, webauthn.AlgES256)
+	other := newAuthenticator(t, webauthn.AlgES256)
+
+	challenge := newChallenge(t)
+	response, err := authenticator.Assert(challenge, nil)
+	if err != nil {
+		t.Fatalf("Assert: %v", err)
+	}
+	_, err = rp.VerifyAssertion(challenge, response.Response.ClientDataJSON, response.Response.AuthenticatorData,
+		response.Response.Signature, other.PublicKey(), 0)
+	if !errors.Is(err, webauthn.ErrBadSignature) {
+		t.Errorf("VerifyAssertion error = %v, want %v", err, webauthn.ErrBadSignature)
+	}
+}
+
+// flip returns a copy of b with its last byte changed
+func flip(b []byte) []byte {
+	out := append([]byte(nil), b...)
+	out[len(out)-1] ^= 0xff
+	return out
+}
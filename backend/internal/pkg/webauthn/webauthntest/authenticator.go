@@ -0,0 +1,229 @@
+// Package webauthntest provides a software authenticator that produces registration and
+// assertion responses the way a browser and a platform authenticator would, so that the
+// relying party ceremonies can be exercised end to end in tests.
+package webauthntest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"bezbase/internal/pkg/webauthn"
+)
+
+// Authenticator holds a single credential. Its fields may be changed between ceremonies to
+// produce responses that a relying party must reject.
+type Authenticator struct {
+	RPID         string // Hashed into the authenticator data
+	Origin       string // Reported in clientDataJSON
+	Flags        byte   // Authenticator data flags; defaults to user present and user verified
+	SignCount    uint32 // Incremented before every assertion
+	CredentialID []byte
+	AAGUID       []byte
+
+	algorithm int64
+	signer    crypto.Signer
+}
+
+// New creates an authenticator with a fresh ES256 (webauthn.AlgES256) or Ed25519
+// (webauthn.AlgEdDSA) credential
+func New(rpID, origin string, algorithm int64) (*Authenticator, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case webauthn.AlgES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case webauthn.AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("webauthntest: unsupported algorithm %d", algorithm)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        webauthn.FlagUserPresent | webauthn.FlagUserVerified,
+		CredentialID: credentialID,
+		AAGUID:       make([]byte, 16),
+		algorithm:    algorithm,
+		signer:       signer,
+	}, nil
+}
+
+// PublicKey returns the credential public key as a COSE_Key
+func (a *Authenticator) PublicKey() []byte {
+	switch pub := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		return encodeCBOR(cborMap{
+			{int64(1), int64(2)},
+			{int64(3), webauthn.AlgES256},
+			{int64(-1), int64(1)},
+			{int64(-2), x},
+			{int64(-3), y},
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(cborMap{
+			{int64(1), int64(1)},
+			{int64(3), webauthn.AlgEdDSA},
+			{int64(-1), int64(6)},
+			{int64(-2), []byte(pub)},
+		})
+	}
+	return nil
+}
+
+// Register answers navigator.credentials.create() for the given challenge with a "none" attestation
+func (a *Authenticator) Register(challenge []byte) (*webauthn.RegistrationResponse, error) {
+	clientDataJSON, err := a.clientData(webauthn.ClientDataTypeCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData := a.authenticatorData(a.Flags | webauthn.FlagAttestedCredentialData)
+	authData = append(authData, a.AAGUID...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, a.PublicKey()...)
+
+	attestationObject := encodeCBOR(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", authData},
+	})
+
+	return &webauthn.RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAttestationJSON{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Assert answers navigator.credentials.get() for the given challenge, returning userHandle
+// as a discoverable credential does
+func (a *Authenticator) Assert(challenge, userHandle []byte) (*webauthn.AuthenticationResponse, error) {
+	clientDataJSON, err := a.clientData(webauthn.ClientDataTypeGet, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SignCount++
+	authData := a.authenticatorData(a.Flags)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signature, err := a.sign(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthn.AuthenticationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertionJSON{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        userHandle,
+		},
+	}, nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(webauthn.CollectedClientData{
+		Type:      ceremony,
+		Challenge: webauthn.EncodeChallenge(challenge),
+		Origin:    a.Origin,
+	})
+}
+
+// authenticatorData returns rpIdHash | flags | signCount
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+func (a *Authenticator) sign(data []byte) ([]byte, error) {
+	switch key := a.signer.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.SignASN1(rand.Reader, key, digest[:])
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, data), nil
+	}
+	return nil, errors.New("webauthntest: unsupported key")
+}
+
+// cborMap is an ordered CBOR map, so that encodings are deterministic
+type cborMap []cborEntry
+
+type cborEntry struct {
+	key, value interface{}
+}
+
+// encodeCBOR encodes the subset of CBOR used by authenticators: integers, byte and text
+// strings, and maps
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case cborMap:
+		out := cborHeader(5, uint64(len(v)))
+		for _, entry := range v {
+			out = append(out, encodeCBOR(entry.key)...)
+			out = append(out, encodeCBOR(entry.value)...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("webauthntest: cannot encode %T", value))
+}
+
+func cborHeader(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
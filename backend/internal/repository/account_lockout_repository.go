@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type accountLockoutRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountLockoutRepository(db *gorm.DB) AccountLockoutRepository {
+	return &accountLockoutRepository{db: db}
+}
+
+func (r *accountLockoutRepository) GetByUserID(ctx contextx.Contextx, userID uint) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).First(&lockout).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("account lockout not found")
+		}
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+func (r *accountLockoutRepository) GetByUnlockTokenHash(ctx contextx.Contextx, hash string) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	if err := ctx.GetTxn(r.db).Where("unlock_token_hash = ?", hash).First(&lockout).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("account lockout not found")
+		}
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// RecordFailure atomically increments the failure counter, starting over when the previous
+// failure is older than resetBefore, and returns the updated row
+func (r *accountLockoutRepository) RecordFailure(ctx contextx.Contextx, userID uint, ipAddress string, resetBefore time.Time) (*models.AccountLockout, error) {
+	now := time.Now()
+	err := ctx.GetTxn(r.db).Exec(`
+		INSERT INTO account_lockouts (user_id, failed_attempts, last_failed_at, last_failed_ip, created_at, updated_at)
+		VALUES (?, 1, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			failed_attempts = CASE WHEN account_lockouts.last_failed_at IS NULL OR account_lockouts.last_failed_at < ?
+				THEN 1 ELSE account_lockouts.failed_attempts + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			last_failed_ip = EXCLUDED.last_failed_ip,
+			updated_at = EXCLUDED.updated_at`,
+		userID, now, ipAddress, now, now, resetBefore,
+	).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByUserID(ctx, userID)
+}
+
+func (r *accountLockoutRepository) Update(ctx contextx.Contextx, lockout *models.AccountLockout) error {
+	return ctx.GetTxn(r.db).Omit("User").Save(lockout).Error
+}
+
+func (r *accountLockoutRepository) DeleteByUserID(ctx contextx.Contextx, userID uint) error {
+	return ctx.GetTxn(r.db).Where("user_id = ?", userID).Delete(&models.AccountLockout{}).Error
+}
+
+func (r *accountLockoutRepository) ListLocked(ctx contextx.Contextx) ([]models.AccountLockout, error) {
+	var lockouts []models.AccountLockout
+	if err := ctx.GetTxn(r.db).Preload("User.UserInfo").
+		Where("locked_at IS NOT NULL AND blocked_until > ?", time.Now()).
+		Order("locked_at DESC").Find(&lockouts).Error; err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
@@ -79,6 +79,15 @@ func (r *authProviderRepository) Delete(ctx contextx.Contextx, userID uint) erro
 	return nil
 }
 
+// DeleteByUserIDAndProvider permanently unlinks one provider so it can be linked again later
+// (soft-deleted rows would still hold the user_id + provider unique index)
+func (r *authProviderRepository) DeleteByUserIDAndProvider(ctx contextx.Contextx, userID uint, provider models.AuthProviderType) error {
+	if err := ctx.GetTxn(r.db).Unscoped().Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.AuthProvider{}).Error; err != nil {
+		return errors.New("failed to delete auth provider")
+	}
+	return nil
+}
+
 func (r *authProviderRepository) UpdateEmail(ctx contextx.Contextx, userID uint, provider models.AuthProviderType, newEmail string) error {
 	if err := ctx.GetTxn(r.db).Model(&models.AuthProvider{}).
 		Where("user_id = ? AND provider = ?", userID, provider).
@@ -76,8 +76,20 @@ func (r *contextualPermissionRepository) GetEffectivePermissions(ctx contextx.Co
 	return permissions, err
 }
 
+// Create stores a contextual permission. is_granted defaults to true, so GORM would replace a false
+// value on insert; denies are written explicitly afterwards.
 func (r *contextualPermissionRepository) Create(ctx contextx.Contextx, permission *models.ContextualPermission) error {
-	return ctx.GetTxn(r.db).Create(permission).Error
+	isGranted := permission.IsGranted
+	return ctx.GetTxn(r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(permission).Error; err != nil {
+			return err
+		}
+		if isGranted {
+			return nil
+		}
+		permission.IsGranted = false
+		return tx.Model(permission).Update("is_granted", false).Error
+	})
 }
 
 func (r *contextualPermissionRepository) Update(ctx contextx.Contextx, permission *models.ContextualPermission) error {
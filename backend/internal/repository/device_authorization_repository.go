@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type deviceAuthorizationRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceAuthorizationRepository(db *gorm.DB) DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{db: db}
+}
+
+func (r *deviceAuthorizationRepository) Create(ctx contextx.Contextx, authorization *models.DeviceAuthorization) error {
+	if err := ctx.GetTxn(r.db).Omit("User", "Client").Create(authorization).Error; err != nil {
+		return errors.New("failed to create device authorization")
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) GetByDeviceCodeHash(ctx contextx.Contextx, hash string) (*models.DeviceAuthorization, error) {
+	var authorization models.DeviceAuthorization
+	if err := ctx.GetTxn(r.db).Where("device_code_hash = ?", hash).First(&authorization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device authorization not found")
+		}
+		return nil, err
+	}
+	return &authorization, nil
+}
+
+// GetPendingByUserCodeHash finds the unexpired pending request for a user code. User codes are
+// short, so only pending requests are unique.
+func (r *deviceAuthorizationRepository) GetPendingByUserCodeHash(ctx contextx.Contextx, hash string) (*models.DeviceAuthorization, error) {
+	var authorization models.DeviceAuthorization
+	if err := ctx.GetTxn(r.db).Preload("Client").
+		Where("user_code_hash = ? AND status = ? AND expires_at > ?", hash, models.DeviceAuthorizationPending, time.Now()).
+		First(&authorization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device authorization not found")
+		}
+		return nil, err
+	}
+	return &authorization, nil
+}
+
+// List returns device authorizations newest first, optionally filtered by stored status
+func (r *deviceAuthorizationRepository) List(ctx contextx.Contextx, page, pageSize int, status models.DeviceAuthorizationStatus) ([]models.DeviceAuthorization, int64, error) {
+	query := ctx.GetTxn(r.db).Model(&models.DeviceAuthorization{})
+	switch status {
+	case "":
+	case models.DeviceAuthorizationExpired:
+		query = query.Where("status IN ? AND expires_at <= ?", []models.DeviceAuthorizationStatus{models.DeviceAuthorizationPending, models.DeviceAuthorizationApproved}, time.Now())
+	case models.DeviceAuthorizationPending, models.DeviceAuthorizationApproved:
+		query = query.Where("status = ? AND expires_at > ?", status, time.Now())
+	default:
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count device authorizations")
+	}
+
+	var authorizations []models.DeviceAuthorization
+	if err := query.Preload("Client").Preload("User.UserInfo").
+		Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&authorizations).Error; err != nil {
+		return nil, 0, errors.New("failed to get device authorizations")
+	}
+	return authorizations, total, nil
+}
+
+// Decide records the user's answer. It reports false when the request is no longer pending, so
+// that a code cannot be approved and denied concurrently.
+func (r *deviceAuthorizationRepository) Decide(ctx contextx.Contextx, id, userID uint, status models.DeviceAuthorizationStatus) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.DeviceAuthorization{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, models.DeviceAuthorizationPending, time.Now()).
+		Updates(map[string]interface{}{"status": status, "user_id": userID, "decided_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordPoll stores the time of a poll and the interval the device must keep from now on
+func (r *deviceAuthorizationRepository) RecordPoll(ctx contextx.Contextx, id uint, interval int) error {
+	return ctx.GetTxn(r.db).Model(&models.DeviceAuthorization{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_polled_at": time.Now(), "interval": interval}).Error
+}
+
+// Complete consumes an approved request. It reports false when tokens were already issued for
+// it, so that concurrent polls cannot both receive tokens.
+func (r *deviceAuthorizationRepository) Complete(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.DeviceAuthorization{}).
+		Where("id = ? AND status = ?", id, models.DeviceAuthorizationApproved).
+		Updates(map[string]interface{}{"status": models.DeviceAuthorizationCompleted, "completed_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) Create(ctx contextx.Contextx, request *models.EmailChangeRequest) error {
+	if err := ctx.GetTxn(r.db).Omit("User").Create(request).Error; err != nil {
+		return errors.New("failed to create email change request")
+	}
+	return nil
+}
+
+func (r *emailChangeRepository) GetByConfirmTokenHash(ctx contextx.Contextx, hash string) (*models.EmailChangeRequest, error) {
+	return r.getBy(ctx, "confirm_token_hash = ?", hash)
+}
+
+func (r *emailChangeRepository) GetByRevertTokenHash(ctx contextx.Contextx, hash string) (*models.EmailChangeRequest, error) {
+	return r.getBy(ctx, "revert_token_hash = ?", hash)
+}
+
+// GetPendingByUserID returns the user's unconfirmed, unexpired change
+func (r *emailChangeRepository) GetPendingByUserID(ctx contextx.Contextx, userID uint) (*models.EmailChangeRequest, error) {
+	return r.getBy(ctx, "user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL AND expires_at > ?", userID, time.Now())
+}
+
+// DeleteUnconfirmedByUserID removes changes that were never confirmed, so only the newest request is pending
+func (r *emailChangeRepository) DeleteUnconfirmedByUserID(ctx contextx.Contextx, userID uint) error {
+	return ctx.GetTxn(r.db).Where("user_id = ? AND confirmed_at IS NULL", userID).Delete(&models.EmailChangeRequest{}).Error
+}
+
+// MarkConfirmed reports false when the change was already confirmed or reverted, so that
+// concurrent confirmations cannot both apply it
+func (r *emailChangeRepository) MarkConfirmed(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.EmailChangeRequest{}).
+		Where("id = ? AND confirmed_at IS NULL AND reverted_at IS NULL", id).
+		Update("confirmed_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkReverted reports false when the change was already reverted
+func (r *emailChangeRepository) MarkReverted(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.EmailChangeRequest{}).
+		Where("id = ? AND reverted_at IS NULL", id).
+		Update("reverted_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *emailChangeRepository) getBy(ctx contextx.Contextx, query string, args ...interface{}) (*models.EmailChangeRequest, error) {
+	var request models.EmailChangeRequest
+	if err := ctx.GetTxn(r.db).Where(query, args...).Order("created_at DESC").First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("email change request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"bezbase/internal/models"
 	"bezbase/internal/pkg/contextx"
 )
@@ -24,6 +26,7 @@ type UserInfoRepository interface {
 	GetByUserID(ctx contextx.Contextx, userID uint) (*models.UserInfo, error)
 	GetByEmail(ctx contextx.Contextx, email string) (*models.UserInfo, error)
 	GetByUsername(ctx contextx.Contextx, username string) (*models.UserInfo, error)
+	GetByUsernameOrEmail(ctx contextx.Contextx, login string) (*models.UserInfo, error)
 	Create(ctx contextx.Contextx, userInfo *models.UserInfo) error
 	Update(ctx contextx.Contextx, userInfo *models.UserInfo) error
 	Delete(ctx contextx.Contextx, userID uint) error
@@ -40,9 +43,183 @@ type AuthProviderRepository interface {
 	Create(ctx contextx.Contextx, authProvider *models.AuthProvider) error
 	Update(ctx contextx.Contextx, authProvider *models.AuthProvider) error
 	Delete(ctx contextx.Contextx, userID uint) error
+	DeleteByUserIDAndProvider(ctx contextx.Contextx, userID uint, provider models.AuthProviderType) error
 	UpdateEmail(ctx contextx.Contextx, userID uint, provider models.AuthProviderType, newEmail string) error
 }
 
+// SessionRepository defines the interface for login session data access
+type SessionRepository interface {
+	Create(ctx contextx.Contextx, session *models.Session) error
+	GetByID(ctx contextx.Contextx, id uint) (*models.Session, error)
+	GetByRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.Session, error)
+	GetByPreviousRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.Session, error)
+	GetActiveByUserID(ctx contextx.Contextx, userID uint) ([]models.Session, error)
+	Rotate(ctx contextx.Contextx, session *models.Session, previousHash string) (bool, error)
+	MarkAuthenticated(ctx contextx.Contextx, id uint, at time.Time) (bool, error)
+	SetOrganization(ctx contextx.Contextx, id uint, orgID *uint) (bool, error)
+	Revoke(ctx contextx.Contextx, id uint) error
+	RevokeAllForUser(ctx contextx.Contextx, userID uint, exceptSessionID uint) error
+	DeleteExpired(ctx contextx.Contextx) error
+}
+
+// PersonalAccessTokenRepository defines the interface for personal access token data access
+type PersonalAccessTokenRepository interface {
+	Create(ctx contextx.Contextx, token *models.PersonalAccessToken) error
+	GetByID(ctx contextx.Contextx, id uint) (*models.PersonalAccessToken, error)
+	GetByTokenHash(ctx contextx.Contextx, hash string) (*models.PersonalAccessToken, error)
+	ListByUserID(ctx contextx.Contextx, userID uint) ([]models.PersonalAccessToken, error)
+	UpdateLastUsed(ctx contextx.Contextx, id uint, usedAt time.Time, ipAddress string) error
+	Revoke(ctx contextx.Contextx, id uint) error
+}
+
+// AccountLockoutRepository defines the interface for failed login tracking data access
+type AccountLockoutRepository interface {
+	GetByUserID(ctx contextx.Contextx, userID uint) (*models.AccountLockout, error)
+	GetByUnlockTokenHash(ctx contextx.Contextx, hash string) (*models.AccountLockout, error)
+	RecordFailure(ctx contextx.Contextx, userID uint, ipAddress string, resetBefore time.Time) (*models.AccountLockout, error)
+	Update(ctx contextx.Contextx, lockout *models.AccountLockout) error
+	DeleteByUserID(ctx contextx.Contextx, userID uint) error
+	ListLocked(ctx contextx.Contextx) ([]models.AccountLockout, error)
+}
+
+// PasswordHistoryRepository defines the interface for password history data access
+type PasswordHistoryRepository interface {
+	Create(ctx contextx.Contextx, entry *models.PasswordHistory) error
+	ListRecent(ctx contextx.Contextx, userID uint, limit int) ([]models.PasswordHistory, error)
+	Prune(ctx contextx.Contextx, userID uint, keep int) error
+}
+
+// MagicLinkRepository defines the interface for passwordless login link data access
+type MagicLinkRepository interface {
+	Create(ctx contextx.Contextx, token *models.MagicLinkToken) error
+	GetByTokenHash(ctx contextx.Contextx, hash string) (*models.MagicLinkToken, error)
+	GetLatestByUserID(ctx contextx.Contextx, userID uint) (*models.MagicLinkToken, error)
+	MarkUsed(ctx contextx.Contextx, id uint) (bool, error)
+	DeleteByUserID(ctx contextx.Contextx, userID uint) error
+}
+
+// EmailChangeRepository defines the interface for pending email change data access
+type EmailChangeRepository interface {
+	Create(ctx contextx.Contextx, request *models.EmailChangeRequest) error
+	GetByConfirmTokenHash(ctx contextx.Contextx, hash string) (*models.EmailChangeRequest, error)
+	GetByRevertTokenHash(ctx contextx.Contextx, hash string) (*models.EmailChangeRequest, error)
+	GetPendingByUserID(ctx contextx.Contextx, userID uint) (*models.EmailChangeRequest, error)
+	DeleteUnconfirmedByUserID(ctx contextx.Contextx, userID uint) error
+	MarkConfirmed(ctx contextx.Contextx, id uint) (bool, error)
+	MarkReverted(ctx contextx.Contextx, id uint) (bool, error)
+}
+
+// LoginEventRepository defines the interface for login history data access
+type LoginEventRepository interface {
+	Create(ctx contextx.Contextx, event *models.LoginEvent) error
+	GetLatestByUserID(ctx contextx.Contextx, userID uint) (*models.LoginEvent, error)
+	GetByReportTokenHash(ctx contextx.Contextx, hash string) (*models.LoginEvent, error)
+	HasFingerprint(ctx contextx.Contextx, userID uint, fingerprint string) (bool, error)
+	ListByUserID(ctx contextx.Contextx, userID uint, page, pageSize int) ([]models.LoginEvent, int64, error)
+	MarkReported(ctx contextx.Contextx, id uint) (bool, error)
+}
+
+// OIDCClientRepository defines the interface for OpenID Connect client data access
+type OIDCClientRepository interface {
+	Create(ctx contextx.Contextx, client *models.OIDCClient) error
+	GetByID(ctx contextx.Contextx, id uint) (*models.OIDCClient, error)
+	GetByClientID(ctx contextx.Contextx, clientID string) (*models.OIDCClient, error)
+	List(ctx contextx.Contextx) ([]models.OIDCClient, error)
+	Update(ctx contextx.Contextx, client *models.OIDCClient) error
+	Delete(ctx contextx.Contextx, id uint) error
+}
+
+// OIDCGrantRepository defines the interface for OpenID Connect consent data access
+type OIDCGrantRepository interface {
+	Get(ctx contextx.Contextx, userID, clientID uint) (*models.OIDCGrant, error)
+	Save(ctx contextx.Contextx, grant *models.OIDCGrant) error
+	ListByUserID(ctx contextx.Contextx, userID uint) ([]models.OIDCGrant, error)
+	ListByClientID(ctx contextx.Contextx, clientID uint) ([]models.OIDCGrant, error)
+	Delete(ctx contextx.Contextx, userID, clientID uint) error
+	DeleteByUserID(ctx contextx.Contextx, userID uint) error
+}
+
+// OIDCAuthorizationCodeRepository defines the interface for OpenID Connect authorization code data access
+type OIDCAuthorizationCodeRepository interface {
+	Create(ctx contextx.Contextx, code *models.OIDCAuthorizationCode) error
+	GetByCodeHash(ctx contextx.Contextx, hash string) (*models.OIDCAuthorizationCode, error)
+	MarkUsed(ctx contextx.Contextx, id uint) (bool, error)
+}
+
+// OIDCTokenRepository defines the interface for OpenID Connect token data access
+type OIDCTokenRepository interface {
+	Create(ctx contextx.Contextx, token *models.OIDCToken) error
+	GetByAccessTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error)
+	GetByRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error)
+	GetByPreviousRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error)
+	Update(ctx contextx.Contextx, token *models.OIDCToken) error
+	Rotate(ctx contextx.Contextx, token *models.OIDCToken, previousHash string) (bool, error)
+	Revoke(ctx contextx.Contextx, id uint) error
+	RevokeByAuthorizationCodeID(ctx contextx.Contextx, codeID uint) error
+	RevokeByUserAndClient(ctx contextx.Contextx, userID, clientID uint) error
+	RevokeAllForUser(ctx contextx.Contextx, userID uint) error
+}
+
+// DeviceAuthorizationRepository defines the interface for OAuth device authorization data access
+type DeviceAuthorizationRepository interface {
+	Create(ctx contextx.Contextx, authorization *models.DeviceAuthorization) error
+	GetByDeviceCodeHash(ctx contextx.Contextx, hash string) (*models.DeviceAuthorization, error)
+	GetPendingByUserCodeHash(ctx contextx.Contextx, hash string) (*models.DeviceAuthorization, error)
+	List(ctx contextx.Contextx, page, pageSize int, status models.DeviceAuthorizationStatus) ([]models.DeviceAuthorization, int64, error)
+	Decide(ctx contextx.Contextx, id, userID uint, status models.DeviceAuthorizationStatus) (bool, error)
+	RecordPoll(ctx contextx.Contextx, id uint, interval int) error
+	Complete(ctx contextx.Contextx, id uint) (bool, error)
+}
+
+// SAMLProviderRepository defines the interface for SAML identity provider data access
+type SAMLProviderRepository interface {
+	Create(ctx contextx.Contextx, provider *models.SAMLIdentityProvider) error
+	GetByID(ctx contextx.Contextx, id uint) (*models.SAMLIdentityProvider, error)
+	GetBySlug(ctx contextx.Contextx, slug string) (*models.SAMLIdentityProvider, error)
+	List(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error)
+	ListEnabled(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error)
+	Update(ctx contextx.Contextx, provider *models.SAMLIdentityProvider) error
+	Delete(ctx contextx.Contextx, id uint) error
+}
+
+// SigningKeyRepository defines the interface for JWT signing key data access
+type SigningKeyRepository interface {
+	List(ctx contextx.Contextx) ([]models.SigningKey, error)
+	Create(ctx contextx.Contextx, key *models.SigningKey) error
+	ExpireOthers(ctx contextx.Contextx, exceptID uint, expiresAt time.Time) error
+	DeleteExpired(ctx contextx.Contextx) error
+}
+
+// MFARepository defines the interface for TOTP enrollment and recovery code data access
+type MFARepository interface {
+	GetByUserID(ctx contextx.Contextx, userID uint) (*models.UserMFA, error)
+	Save(ctx contextx.Contextx, mfa *models.UserMFA) error
+	UpdateLastUsedStep(ctx contextx.Contextx, userID uint, step int64) (bool, error)
+	DeleteByUserID(ctx contextx.Contextx, userID uint) error
+	ReplaceRecoveryCodes(ctx contextx.Contextx, userID uint, codeHashes []string) error
+	UseRecoveryCode(ctx contextx.Contextx, userID uint, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx contextx.Contextx, userID uint) (int64, error)
+	CreateChallenge(ctx contextx.Contextx, challenge *models.MFAChallenge) error
+	GetChallengeByTokenHash(ctx contextx.Contextx, hash string) (*models.MFAChallenge, error)
+	ClaimChallengeAttempt(ctx contextx.Contextx, id uint, maxAttempts int, now time.Time) (bool, error)
+	ConsumeChallenge(ctx contextx.Contextx, id uint) (bool, error)
+}
+
+// WebAuthnRepository defines the interface for passkey credential and ceremony challenge data access
+type WebAuthnRepository interface {
+	CreateChallenge(ctx contextx.Contextx, challenge *models.WebAuthnChallenge) error
+	ConsumeChallenge(ctx contextx.Contextx, challenge string, ceremony string) (*models.WebAuthnChallenge, error)
+	DeleteExpiredChallenges(ctx contextx.Contextx) error
+	CreateCredential(ctx contextx.Contextx, credential *models.WebAuthnCredential) error
+	GetCredentialByID(ctx contextx.Contextx, id uint) (*models.WebAuthnCredential, error)
+	GetCredentialByCredentialID(ctx contextx.Contextx, credentialID string) (*models.WebAuthnCredential, error)
+	GetCredentialsByUserID(ctx contextx.Contextx, userID uint) ([]models.WebAuthnCredential, error)
+	UpdateCredential(ctx contextx.Contextx, credential *models.WebAuthnCredential) error
+	DeleteCredential(ctx contextx.Contextx, id uint) error
+	DeleteCredentialsCreatedSince(ctx contextx.Contextx, userID uint, since time.Time) error
+	CountCredentialsByUserID(ctx contextx.Contextx, userID uint) (int64, error)
+}
+
 // RoleRepository defines the interface for role data access
 type RoleRepository interface {
 	GetByID(ctx contextx.Contextx, id uint) (*models.Role, error)
@@ -93,3 +270,32 @@ type RoleInheritanceRepository interface {
 	GetParentRoles(ctx contextx.Contextx, roleID uint) ([]models.Role, error)
 	GetChildRoles(ctx contextx.Contextx, roleID uint) ([]models.Role, error)
 }
+
+// OrganizationRepository defines the interface for organization and membership data access
+type OrganizationRepository interface {
+	Create(ctx contextx.Contextx, organization *models.Organization) error
+	GetByID(ctx contextx.Contextx, id uint) (*models.Organization, error)
+	IsSlugTaken(ctx contextx.Contextx, slug string, excludeID uint) (bool, error)
+	Update(ctx contextx.Contextx, organization *models.Organization) error
+	Delete(ctx contextx.Contextx, id uint) error
+	ListByUserID(ctx contextx.Contextx, userID uint) ([]models.Organization, error)
+	AddMember(ctx contextx.Contextx, member *models.OrganizationMember) error
+	GetMember(ctx contextx.Contextx, organizationID, userID uint) (*models.OrganizationMember, error)
+	ListMembers(ctx contextx.Contextx, organizationID uint) ([]models.OrganizationMember, error)
+	RemoveMember(ctx contextx.Contextx, organizationID, userID uint) error
+}
+
+// RoleAssignmentRepository defines the interface for the validity windows of role assignments
+type RoleAssignmentRepository interface {
+	Save(ctx contextx.Contextx, assignment *models.RoleAssignment) error
+	Get(ctx contextx.Contextx, userID uint, role, domain string) (*models.RoleAssignment, error)
+	Delete(ctx contextx.Contextx, userID uint, role, domain string) error
+	DeleteByUserAndDomain(ctx contextx.Contextx, userID uint, domain string) error
+	DeleteByDomain(ctx contextx.Contextx, domain string) error
+	DeleteByRole(ctx contextx.Contextx, role string) error
+	ListByUser(ctx contextx.Contextx, userID uint, domain string) ([]models.RoleAssignment, error)
+	ListByRole(ctx contextx.Contextx, role, domain string) ([]models.RoleAssignment, error)
+	ListExpired(ctx contextx.Contextx, now time.Time) ([]models.RoleAssignment, error)
+	ListExpiring(ctx contextx.Contextx, now, before time.Time) ([]models.RoleAssignment, error)
+	MarkExpiryNotified(ctx contextx.Contextx, id uint, notifiedAt time.Time) (bool, error)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{db: db}
+}
+
+func (r *loginEventRepository) Create(ctx contextx.Contextx, event *models.LoginEvent) error {
+	if err := ctx.GetTxn(r.db).Omit("User").Create(event).Error; err != nil {
+		return errors.New("failed to create login event")
+	}
+	return nil
+}
+
+func (r *loginEventRepository) GetLatestByUserID(ctx contextx.Contextx, userID uint) (*models.LoginEvent, error) {
+	var event models.LoginEvent
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).Order("created_at DESC").First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login event not found")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *loginEventRepository) GetByReportTokenHash(ctx contextx.Contextx, hash string) (*models.LoginEvent, error) {
+	var event models.LoginEvent
+	if err := ctx.GetTxn(r.db).Where("report_token_hash = ?", hash).First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login event not found")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// HasFingerprint reports whether the user logged in from the device before
+func (r *loginEventRepository) HasFingerprint(ctx contextx.Contextx, userID uint, fingerprint string) (bool, error) {
+	var count int64
+	if err := ctx.GetTxn(r.db).Model(&models.LoginEvent{}).
+		Where("user_id = ? AND device_fingerprint = ?", userID, fingerprint).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListByUserID returns a page of the user's logins, newest first
+func (r *loginEventRepository) ListByUserID(ctx contextx.Contextx, userID uint, page, pageSize int) ([]models.LoginEvent, int64, error) {
+	query := ctx.GetTxn(r.db).Model(&models.LoginEvent{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count login events")
+	}
+
+	var events []models.LoginEvent
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, errors.New("failed to get login events")
+	}
+	return events, total, nil
+}
+
+// MarkReported reports false when the alert was already answered, so the link works only once
+func (r *loginEventRepository) MarkReported(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.LoginEvent{}).
+		Where("id = ? AND reported_at IS NULL", id).
+		Update("reported_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx contextx.Contextx, token *models.MagicLinkToken) error {
+	return ctx.GetTxn(r.db).Omit("User").Create(token).Error
+}
+
+func (r *magicLinkRepository) GetByTokenHash(ctx contextx.Contextx, hash string) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+	if err := ctx.GetTxn(r.db).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("magic link not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *magicLinkRepository) GetLatestByUserID(ctx contextx.Contextx, userID uint) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).Order("created_at DESC").First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("magic link not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes the token. It reports false when the token was already used, so that
+// concurrent redemptions of the same link cannot both succeed.
+func (r *magicLinkRepository) MarkUsed(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *magicLinkRepository) DeleteByUserID(ctx contextx.Contextx, userID uint) error {
+	return ctx.GetTxn(r.db).Where("user_id = ?", userID).Delete(&models.MagicLinkToken{}).Error
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetByUserID(ctx contextx.Contextx, userID uint) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa not found")
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) Save(ctx contextx.Contextx, mfa *models.UserMFA) error {
+	if err := ctx.GetTxn(r.db).Save(mfa).Error; err != nil {
+		return errors.New("failed to save mfa")
+	}
+	return nil
+}
+
+// UpdateLastUsedStep records the accepted time step, returning false if it was already consumed
+func (r *mfaRepository) UpdateLastUsedStep(ctx contextx.Contextx, userID uint, step int64) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, errors.New("failed to update mfa")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *mfaRepository) DeleteByUserID(ctx contextx.Contextx, userID uint) error {
+	db := ctx.GetTxn(r.db)
+	if err := db.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return errors.New("failed to delete recovery codes")
+	}
+	if err := db.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error; err != nil {
+		return errors.New("failed to delete mfa")
+	}
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx contextx.Contextx, userID uint, codeHashes []string) error {
+	db := ctx.GetTxn(r.db)
+	if err := db.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return errors.New("failed to delete recovery codes")
+	}
+
+	codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	if err := db.Create(&codes).Error; err != nil {
+		return errors.New("failed to create recovery codes")
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a matching unused code as used, returning false if none matched
+func (r *mfaRepository) UseRecoveryCode(ctx contextx.Contextx, userID uint, codeHash string) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, errors.New("failed to use recovery code")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *mfaRepository) CountUnusedRecoveryCodes(ctx contextx.Contextx, userID uint) (int64, error) {
+	var count int64
+	if err := ctx.GetTxn(r.db).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, errors.New("failed to count recovery codes")
+	}
+	return count, nil
+}
+
+func (r *mfaRepository) CreateChallenge(ctx contextx.Contextx, challenge *models.MFAChallenge) error {
+	if err := ctx.GetTxn(r.db).Create(challenge).Error; err != nil {
+		return errors.New("failed to create mfa challenge")
+	}
+	return nil
+}
+
+func (r *mfaRepository) GetChallengeByTokenHash(ctx contextx.Contextx, hash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	if err := ctx.GetTxn(r.db).Where("token_hash = ?", hash).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa challenge not found")
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ClaimChallengeAttempt counts an attempt against a pending challenge before its code is checked,
+// returning false once the challenge is completed, expired or out of attempts
+func (r *mfaRepository) ClaimChallengeAttempt(ctx contextx.Contextx, id uint, maxAttempts int, now time.Time) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.MFAChallenge{}).
+		Where("id = ? AND consumed_at IS NULL AND expires_at > ? AND attempts < ?", id, now, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return false, errors.New("failed to update mfa challenge")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ConsumeChallenge marks a challenge as completed, returning false if it already was
+func (r *mfaRepository) ConsumeChallenge(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.MFAChallenge{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return false, errors.New("failed to update mfa challenge")
+	}
+	return result.RowsAffected > 0, nil
+}
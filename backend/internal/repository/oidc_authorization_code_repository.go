@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type oidcAuthorizationCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCAuthorizationCodeRepository(db *gorm.DB) OIDCAuthorizationCodeRepository {
+	return &oidcAuthorizationCodeRepository{db: db}
+}
+
+func (r *oidcAuthorizationCodeRepository) Create(ctx contextx.Contextx, code *models.OIDCAuthorizationCode) error {
+	if err := ctx.GetTxn(r.db).Omit("User", "Client").Create(code).Error; err != nil {
+		return errors.New("failed to create authorization code")
+	}
+	return nil
+}
+
+func (r *oidcAuthorizationCodeRepository) GetByCodeHash(ctx contextx.Contextx, hash string) (*models.OIDCAuthorizationCode, error) {
+	var code models.OIDCAuthorizationCode
+	if err := ctx.GetTxn(r.db).Where("code_hash = ?", hash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("authorization code not found")
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkUsed consumes the code. It reports false when the code was already used, so that
+// concurrent exchanges of the same code cannot both succeed.
+func (r *oidcAuthorizationCodeRepository) MarkUsed(ctx contextx.Contextx, id uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.OIDCAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
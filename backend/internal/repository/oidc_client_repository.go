@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type oidcClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCClientRepository(db *gorm.DB) OIDCClientRepository {
+	return &oidcClientRepository{db: db}
+}
+
+func (r *oidcClientRepository) Create(ctx contextx.Contextx, client *models.OIDCClient) error {
+	if err := ctx.GetTxn(r.db).Create(client).Error; err != nil {
+		return errors.New("failed to create oidc client")
+	}
+	return nil
+}
+
+func (r *oidcClientRepository) GetByID(ctx contextx.Contextx, id uint) (*models.OIDCClient, error) {
+	var client models.OIDCClient
+	if err := ctx.GetTxn(r.db).First(&client, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc client not found")
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oidcClientRepository) GetByClientID(ctx contextx.Contextx, clientID string) (*models.OIDCClient, error) {
+	var client models.OIDCClient
+	if err := ctx.GetTxn(r.db).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc client not found")
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oidcClientRepository) List(ctx contextx.Contextx) ([]models.OIDCClient, error) {
+	var clients []models.OIDCClient
+	if err := ctx.GetTxn(r.db).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (r *oidcClientRepository) Update(ctx contextx.Contextx, client *models.OIDCClient) error {
+	return ctx.GetTxn(r.db).Save(client).Error
+}
+
+// Delete removes the client; its grants, codes and tokens are removed by the foreign keys
+func (r *oidcClientRepository) Delete(ctx contextx.Contextx, id uint) error {
+	return ctx.GetTxn(r.db).Delete(&models.OIDCClient{}, id).Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type oidcGrantRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCGrantRepository(db *gorm.DB) OIDCGrantRepository {
+	return &oidcGrantRepository{db: db}
+}
+
+func (r *oidcGrantRepository) Get(ctx contextx.Contextx, userID, clientID uint) (*models.OIDCGrant, error) {
+	var grant models.OIDCGrant
+	if err := ctx.GetTxn(r.db).Where("user_id = ? AND client_id = ?", userID, clientID).First(&grant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc grant not found")
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (r *oidcGrantRepository) Save(ctx contextx.Contextx, grant *models.OIDCGrant) error {
+	return ctx.GetTxn(r.db).Omit("User", "Client").Save(grant).Error
+}
+
+func (r *oidcGrantRepository) ListByUserID(ctx contextx.Contextx, userID uint) ([]models.OIDCGrant, error) {
+	var grants []models.OIDCGrant
+	if err := ctx.GetTxn(r.db).Preload("Client").Where("user_id = ?", userID).
+		Order("updated_at DESC").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *oidcGrantRepository) ListByClientID(ctx contextx.Contextx, clientID uint) ([]models.OIDCGrant, error) {
+	var grants []models.OIDCGrant
+	if err := ctx.GetTxn(r.db).Preload("User.UserInfo").Preload("Client").Where("client_id = ?", clientID).
+		Order("updated_at DESC").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *oidcGrantRepository) Delete(ctx contextx.Contextx, userID, clientID uint) error {
+	return ctx.GetTxn(r.db).Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OIDCGrant{}).Error
+}
+
+func (r *oidcGrantRepository) DeleteByUserID(ctx contextx.Contextx, userID uint) error {
+	return ctx.GetTxn(r.db).Where("user_id = ?", userID).Delete(&models.OIDCGrant{}).Error
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type oidcTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCTokenRepository(db *gorm.DB) OIDCTokenRepository {
+	return &oidcTokenRepository{db: db}
+}
+
+func (r *oidcTokenRepository) Create(ctx contextx.Contextx, token *models.OIDCToken) error {
+	if err := ctx.GetTxn(r.db).Omit("User", "Client").Create(token).Error; err != nil {
+		return errors.New("failed to create oidc token")
+	}
+	return nil
+}
+
+func (r *oidcTokenRepository) GetByAccessTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error) {
+	var token models.OIDCToken
+	if err := ctx.GetTxn(r.db).Preload("Client").Where("access_token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *oidcTokenRepository) GetByRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error) {
+	var token models.OIDCToken
+	if err := ctx.GetTxn(r.db).Preload("Client").Where("refresh_token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *oidcTokenRepository) GetByPreviousRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.OIDCToken, error) {
+	var token models.OIDCToken
+	if err := ctx.GetTxn(r.db).Where("previous_refresh_token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oidc token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *oidcTokenRepository) Update(ctx contextx.Contextx, token *models.OIDCToken) error {
+	return ctx.GetTxn(r.db).Omit("User", "Client").Save(token).Error
+}
+
+// Rotate stores the new token pair only if previousHash is still the current refresh token. It
+// reports false when another refresh rotated the token first.
+func (r *oidcTokenRepository) Rotate(ctx contextx.Contextx, token *models.OIDCToken, previousHash string) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.OIDCToken{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", token.ID, previousHash).
+		Updates(map[string]interface{}{
+			"access_token_hash":           token.AccessTokenHash,
+			"access_token_expires_at":     token.AccessTokenExpiresAt,
+			"refresh_token_hash":          token.RefreshTokenHash,
+			"refresh_token_expires_at":    token.RefreshTokenExpiresAt,
+			"previous_refresh_token_hash": token.PreviousRefreshTokenHash,
+		})
+	if result.Error != nil {
+		return false, errors.New("failed to update oidc token")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *oidcTokenRepository) Revoke(ctx contextx.Contextx, id uint) error {
+	return ctx.GetTxn(r.db).Model(&models.OIDCToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *oidcTokenRepository) RevokeByAuthorizationCodeID(ctx contextx.Contextx, codeID uint) error {
+	return ctx.GetTxn(r.db).Model(&models.OIDCToken{}).
+		Where("authorization_code_id = ? AND revoked_at IS NULL", codeID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *oidcTokenRepository) RevokeByUserAndClient(ctx contextx.Contextx, userID, clientID uint) error {
+	return ctx.GetTxn(r.db).Model(&models.OIDCToken{}).
+		Where("user_id = ? AND client_id = ? AND revoked_at IS NULL", userID, clientID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *oidcTokenRepository) RevokeAllForUser(ctx contextx.Contextx, userID uint) error {
+	return ctx.GetTxn(r.db).Model(&models.OIDCToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"errors"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) Create(ctx contextx.Contextx, organization *models.Organization) error {
+	if err := ctx.GetTxn(r.db).Create(organization).Error; err != nil {
+		return errors.New("failed to create organization")
+	}
+	return nil
+}
+
+func (r *organizationRepository) GetByID(ctx contextx.Contextx, id uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := ctx.GetTxn(r.db).First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *organizationRepository) IsSlugTaken(ctx contextx.Contextx, slug string, excludeID uint) (bool, error) {
+	var count int64
+	if err := ctx.GetTxn(r.db).Model(&models.Organization{}).
+		Where("slug = ? AND id <> ?", slug, excludeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *organizationRepository) Update(ctx contextx.Contextx, organization *models.Organization) error {
+	return ctx.GetTxn(r.db).Save(organization).Error
+}
+
+func (r *organizationRepository) Delete(ctx contextx.Contextx, id uint) error {
+	return ctx.GetTxn(r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Organization{}, id).Error
+	})
+}
+
+// ListByUserID returns the active organizations the user is a member of
+func (r *organizationRepository) ListByUserID(ctx contextx.Contextx, userID uint) ([]models.Organization, error) {
+	var organizations []models.Organization
+	if err := ctx.GetTxn(r.db).
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ? AND organizations.is_active = ?", userID, true).
+		Order("organizations.name ASC").
+		Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+func (r *organizationRepository) AddMember(ctx contextx.Contextx, member *models.OrganizationMember) error {
+	if err := ctx.GetTxn(r.db).Omit("Organization", "User").Create(member).Error; err != nil {
+		return errors.New("failed to add organization member")
+	}
+	return nil
+}
+
+func (r *organizationRepository) GetMember(ctx contextx.Contextx, organizationID, userID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := ctx.GetTxn(r.db).Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization member not found")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx contextx.Contextx, organizationID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	if err := ctx.GetTxn(r.db).Preload("User.UserInfo").
+		Where("organization_id = ?", organizationID).
+		Order("created_at ASC").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *organizationRepository) RemoveMember(ctx contextx.Contextx, organizationID, userID uint) error {
+	result := ctx.GetTxn(r.db).Where("organization_id = ? AND user_id = ?", organizationID, userID).Delete(&models.OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("organization member not found")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+func (r *passwordHistoryRepository) Create(ctx contextx.Contextx, entry *models.PasswordHistory) error {
+	return ctx.GetTxn(r.db).Omit("User").Create(entry).Error
+}
+
+func (r *passwordHistoryRepository) ListRecent(ctx contextx.Contextx, userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Prune deletes all but the newest keep entries of a user
+func (r *passwordHistoryRepository) Prune(ctx contextx.Contextx, userID uint, keep int) error {
+	db := ctx.GetTxn(r.db)
+	keepIDs := db.Model(&models.PasswordHistory{}).Select("id").
+		Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(keep)
+	return db.Where("user_id = ? AND id NOT IN (?)", userID, keepIDs).Delete(&models.PasswordHistory{}).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx contextx.Contextx, token *models.PersonalAccessToken) error {
+	if err := ctx.GetTxn(r.db).Create(token).Error; err != nil {
+		return errors.New("failed to create personal access token")
+	}
+	return nil
+}
+
+func (r *personalAccessTokenRepository) GetByID(ctx contextx.Contextx, id uint) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := ctx.GetTxn(r.db).First(&token, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("personal access token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *personalAccessTokenRepository) GetByTokenHash(ctx contextx.Contextx, hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := ctx.GetTxn(r.db).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("personal access token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *personalAccessTokenRepository) ListByUserID(ctx contextx.Contextx, userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) UpdateLastUsed(ctx contextx.Contextx, id uint, usedAt time.Time, ipAddress string) error {
+	return ctx.GetTxn(r.db).Model(&models.PersonalAccessToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ipAddress}).Error
+}
+
+func (r *personalAccessTokenRepository) Revoke(ctx contextx.Contextx, id uint) error {
+	return ctx.GetTxn(r.db).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type roleAssignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleAssignmentRepository(db *gorm.DB) RoleAssignmentRepository {
+	return &roleAssignmentRepository{db: db}
+}
+
+// Save creates the assignment or replaces the window of the existing one for the same user, role and domain
+func (r *roleAssignmentRepository) Save(ctx contextx.Contextx, assignment *models.RoleAssignment) error {
+	existing, err := r.Get(ctx, assignment.UserID, assignment.Role, assignment.Domain)
+	if err == nil {
+		assignment.ID = existing.ID
+		assignment.CreatedAt = existing.CreatedAt
+	}
+	if err := ctx.GetTxn(r.db).Save(assignment).Error; err != nil {
+		return errors.New("failed to save role assignment")
+	}
+	return nil
+}
+
+func (r *roleAssignmentRepository) Get(ctx contextx.Contextx, userID uint, role, domain string) (*models.RoleAssignment, error) {
+	var assignment models.RoleAssignment
+	if err := ctx.GetTxn(r.db).
+		Where("user_id = ? AND role = ? AND domain = ?", userID, role, domain).
+		First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role assignment not found")
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *roleAssignmentRepository) Delete(ctx contextx.Contextx, userID uint, role, domain string) error {
+	return ctx.GetTxn(r.db).
+		Where("user_id = ? AND role = ? AND domain = ?", userID, role, domain).
+		Delete(&models.RoleAssignment{}).Error
+}
+
+func (r *roleAssignmentRepository) DeleteByUserAndDomain(ctx contextx.Contextx, userID uint, domain string) error {
+	return ctx.GetTxn(r.db).
+		Where("user_id = ? AND domain = ?", userID, domain).
+		Delete(&models.RoleAssignment{}).Error
+}
+
+func (r *roleAssignmentRepository) DeleteByDomain(ctx contextx.Contextx, domain string) error {
+	return ctx.GetTxn(r.db).Where("domain = ?", domain).Delete(&models.RoleAssignment{}).Error
+}
+
+func (r *roleAssignmentRepository) DeleteByRole(ctx contextx.Contextx, role string) error {
+	return ctx.GetTxn(r.db).Where("role = ?", role).Delete(&models.RoleAssignment{}).Error
+}
+
+func (r *roleAssignmentRepository) ListByUser(ctx contextx.Contextx, userID uint, domain string) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	if err := ctx.GetTxn(r.db).
+		Where("user_id = ? AND domain = ?", userID, domain).
+		Order("role ASC").
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *roleAssignmentRepository) ListByRole(ctx contextx.Contextx, role, domain string) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	if err := ctx.GetTxn(r.db).
+		Where("role = ? AND domain = ?", role, domain).
+		Order("user_id ASC").
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// ListExpired returns the assignments whose window has closed
+func (r *roleAssignmentRepository) ListExpired(ctx contextx.Contextx, now time.Time) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	if err := ctx.GetTxn(r.db).
+		Where("expires_at <= ?", now).
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// ListExpiring returns the started assignments expiring before the given time whose user has not
+// been notified yet
+func (r *roleAssignmentRepository) ListExpiring(ctx contextx.Contextx, now, before time.Time) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	if err := ctx.GetTxn(r.db).
+		Where("(starts_at IS NULL OR starts_at <= ?) AND expires_at <= ? AND expiry_notified_at IS NULL", now, before).
+		Order("expires_at ASC").
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// MarkExpiryNotified records the expiry notice of an assignment. It reports false when the notice
+// was already recorded, so that concurrent sweepers notify a user only once.
+func (r *roleAssignmentRepository) MarkExpiryNotified(ctx contextx.Contextx, id uint, notifiedAt time.Time) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.RoleAssignment{}).
+		Where("id = ? AND expiry_notified_at IS NULL", id).
+		Update("expiry_notified_at", notifiedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -21,15 +21,15 @@ func (r *ruleRepository) GetPermissions(ctx contextx.Contextx, page, pageSize in
 	// Query casbin_rule table directly for permissions (ptype = 'p')
 	query := ctx.GetTxn(r.db).Model(&models.Rule{}).Where("ptype = 'p'")
 
-	// Apply filters
+	// Apply filters (v0 role, v1 domain, v2 resource, v3 action, v4 condition, v5 effect)
 	if roleFilter != "" {
 		query = query.Where("v0 LIKE ?", "%"+roleFilter+"%")
 	}
 	if resourceFilter != "" {
-		query = query.Where("v1 LIKE ?", "%"+resourceFilter+"%")
+		query = query.Where("v2 LIKE ?", "%"+resourceFilter+"%")
 	}
 	if actionFilter != "" {
-		query = query.Where("v2 LIKE ?", "%"+actionFilter+"%")
+		query = query.Where("v3 LIKE ?", "%"+actionFilter+"%")
 	}
 
 	// Count total records
@@ -44,10 +44,14 @@ func (r *ruleRepository) GetPermissions(ctx contextx.Contextx, page, pageSize in
 		switch sortField {
 		case "role":
 			orderClause = fmt.Sprintf("v0 %s", sortOrder)
-		case "resource":
+		case "domain":
 			orderClause = fmt.Sprintf("v1 %s", sortOrder)
-		case "action":
+		case "resource":
 			orderClause = fmt.Sprintf("v2 %s", sortOrder)
+		case "action":
+			orderClause = fmt.Sprintf("v3 %s", sortOrder)
+		case "effect":
+			orderClause = fmt.Sprintf("v5 %s", sortOrder)
 		default:
 			orderClause = fmt.Sprintf("id %s", sortOrder)
 		}
@@ -56,7 +60,7 @@ func (r *ruleRepository) GetPermissions(ctx contextx.Contextx, page, pageSize in
 	rules := make([]models.Rule, 0)
 	offset := (page - 1) * pageSize
 	if err := query.Order(orderClause).Offset(offset).Limit(pageSize).
-		Select("id, v0, v1, v2").Find(&rules).Error; err != nil {
+		Select("id, v0, v1, v2, v3, v4, v5").Find(&rules).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to get permissions: %w", err)
 	}
 
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type samlProviderRepository struct {
+	db *gorm.DB
+}
+
+func NewSAMLProviderRepository(db *gorm.DB) SAMLProviderRepository {
+	return &samlProviderRepository{db: db}
+}
+
+func (r *samlProviderRepository) Create(ctx contextx.Contextx, provider *models.SAMLIdentityProvider) error {
+	// GORM stores the column default in place of a false Enabled, so it is written separately
+	enabled := provider.Enabled
+	err := ctx.GetTxn(r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(provider).Error; err != nil {
+			return err
+		}
+		if enabled {
+			return nil
+		}
+		provider.Enabled = false
+		return tx.Model(provider).Update("enabled", false).Error
+	})
+	if err != nil {
+		return errors.New("failed to create saml provider")
+	}
+	return nil
+}
+
+func (r *samlProviderRepository) GetByID(ctx contextx.Contextx, id uint) (*models.SAMLIdentityProvider, error) {
+	var provider models.SAMLIdentityProvider
+	if err := ctx.GetTxn(r.db).First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("saml provider not found")
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (r *samlProviderRepository) GetBySlug(ctx contextx.Contextx, slug string) (*models.SAMLIdentityProvider, error) {
+	var provider models.SAMLIdentityProvider
+	if err := ctx.GetTxn(r.db).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("saml provider not found")
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (r *samlProviderRepository) List(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error) {
+	var providers []models.SAMLIdentityProvider
+	if err := ctx.GetTxn(r.db).Order("name ASC").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+func (r *samlProviderRepository) ListEnabled(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error) {
+	var providers []models.SAMLIdentityProvider
+	if err := ctx.GetTxn(r.db).Where("enabled = ?", true).Order("name ASC").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+func (r *samlProviderRepository) Update(ctx contextx.Contextx, provider *models.SAMLIdentityProvider) error {
+	return ctx.GetTxn(r.db).Save(provider).Error
+}
+
+func (r *samlProviderRepository) Delete(ctx contextx.Contextx, id uint) error {
+	return ctx.GetTxn(r.db).Delete(&models.SAMLIdentityProvider{}, id).Error
+}
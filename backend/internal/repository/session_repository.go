@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx contextx.Contextx, session *models.Session) error {
+	if err := ctx.GetTxn(r.db).Create(session).Error; err != nil {
+		return errors.New("failed to create session")
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetByID(ctx contextx.Contextx, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := ctx.GetTxn(r.db).First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetByRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.Session, error) {
+	var session models.Session
+	if err := ctx.GetTxn(r.db).Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetByPreviousRefreshTokenHash(ctx contextx.Contextx, hash string) (*models.Session, error) {
+	var session models.Session
+	if err := ctx.GetTxn(r.db).Where("previous_refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetActiveByUserID(ctx contextx.Contextx, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := ctx.GetTxn(r.db).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, errors.New("failed to get sessions")
+	}
+	return sessions, nil
+}
+
+// Rotate stores the new refresh token of a session only if previousHash is still its current one.
+// It reports false when another refresh rotated the token first.
+func (r *sessionRepository) Rotate(ctx contextx.Contextx, session *models.Session, previousHash string) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":          session.RefreshTokenHash,
+			"previous_refresh_token_hash": session.PreviousRefreshTokenHash,
+			"expires_at":                  session.ExpiresAt,
+			"last_used_at":                session.LastUsedAt,
+			"user_agent":                  session.UserAgent,
+			"ip_address":                  session.IPAddress,
+		})
+	if result.Error != nil {
+		return false, errors.New("failed to update session")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkAuthenticated sets the time the user last proved their identity on an unrevoked session. It
+// reports false when the session was revoked.
+func (r *sessionRepository) MarkAuthenticated(ctx contextx.Contextx, id uint, at time.Time) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("authenticated_at", at)
+	if result.Error != nil {
+		return false, errors.New("failed to update session")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SetOrganization sets the active organization of an unrevoked session, nil clearing it. It reports
+// false when the session was revoked.
+func (r *sessionRepository) SetOrganization(ctx contextx.Contextx, id uint, orgID *uint) (bool, error) {
+	result := ctx.GetTxn(r.db).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("organization_id", orgID)
+	if result.Error != nil {
+		return false, errors.New("failed to update session")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *sessionRepository) Revoke(ctx contextx.Contextx, id uint) error {
+	if err := ctx.GetTxn(r.db).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("failed to revoke session")
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx contextx.Contextx, userID uint, exceptSessionID uint) error {
+	query := ctx.GetTxn(r.db).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptSessionID > 0 {
+		query = query.Where("id != ?", exceptSessionID)
+	}
+	if err := query.Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	return nil
+}
+
+func (r *sessionRepository) DeleteExpired(ctx contextx.Contextx) error {
+	return ctx.GetTxn(r.db).Where("expires_at < ?", time.Now()).
+		Delete(&models.Session{}).Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r *signingKeyRepository) List(ctx contextx.Contextx) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	if err := ctx.GetTxn(r.db).Order("activates_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Create(ctx contextx.Contextx, key *models.SigningKey) error {
+	if err := ctx.GetTxn(r.db).Create(key).Error; err != nil {
+		return errors.New("failed to create signing key")
+	}
+	return nil
+}
+
+func (r *signingKeyRepository) ExpireOthers(ctx contextx.Contextx, exceptID uint, expiresAt time.Time) error {
+	return ctx.GetTxn(r.db).Model(&models.SigningKey{}).
+		Where("id <> ? AND expires_at IS NULL", exceptID).
+		Update("expires_at", expiresAt).Error
+}
+
+func (r *signingKeyRepository) DeleteExpired(ctx contextx.Contextx) error {
+	return ctx.GetTxn(r.db).Where("expires_at IS NOT NULL AND expires_at < ?", time.Now()).Delete(&models.SigningKey{}).Error
+}
@@ -5,6 +5,7 @@ import (
 
 	"bezbase/internal/models"
 	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/identifier"
 
 	"gorm.io/gorm"
 )
@@ -28,26 +29,22 @@ func (r *userInfoRepository) GetByUserID(ctx contextx.Contextx, userID uint) (*m
 	return &userInfo, nil
 }
 
+// GetByEmail matches the email case-insensitively after Unicode normalization
 func (r *userInfoRepository) GetByEmail(ctx contextx.Contextx, email string) (*models.UserInfo, error) {
-	var userInfo models.UserInfo
-	if err := ctx.GetTxn(r.db).Where("email = ?", email).First(&userInfo).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user info not found")
-		}
-		return nil, err
-	}
-	return &userInfo, nil
+	return r.getByIdentifier(ctx, "email", email)
 }
 
+// GetByUsername matches the username case-insensitively after Unicode normalization
 func (r *userInfoRepository) GetByUsername(ctx contextx.Contextx, username string) (*models.UserInfo, error) {
-	var userInfo models.UserInfo
-	if err := ctx.GetTxn(r.db).Where("username = ?", username).First(&userInfo).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user info not found")
-		}
-		return nil, err
+	return r.getByIdentifier(ctx, "username", username)
+}
+
+// GetByUsernameOrEmail resolves a login identifier, trying usernames before emails
+func (r *userInfoRepository) GetByUsernameOrEmail(ctx contextx.Contextx, login string) (*models.UserInfo, error) {
+	if userInfo, err := r.getByIdentifier(ctx, "username", login); err == nil {
+		return userInfo, nil
 	}
-	return &userInfo, nil
+	return r.getByIdentifier(ctx, "email", login)
 }
 
 func (r *userInfoRepository) Create(ctx contextx.Contextx, userInfo *models.UserInfo) error {
@@ -73,7 +70,7 @@ func (r *userInfoRepository) Delete(ctx contextx.Contextx, userID uint) error {
 
 func (r *userInfoRepository) IsEmailTaken(ctx contextx.Contextx, email string, excludeUserID uint) (bool, error) {
 	var count int64
-	query := ctx.GetTxn(r.db).Model(&models.UserInfo{}).Where("email = ?", email)
+	query := ctx.GetTxn(r.db).Model(&models.UserInfo{}).Where("(email_normalized = ? OR email = ?)", identifier.Normalize(email), email)
 
 	if excludeUserID > 0 {
 		query = query.Where("user_id != ?", excludeUserID)
@@ -88,7 +85,7 @@ func (r *userInfoRepository) IsEmailTaken(ctx contextx.Contextx, email string, e
 
 func (r *userInfoRepository) IsUsernameTaken(ctx contextx.Contextx, username string, excludeUserID uint) (bool, error) {
 	var count int64
-	query := ctx.GetTxn(r.db).Model(&models.UserInfo{}).Where("username = ?", username)
+	query := ctx.GetTxn(r.db).Model(&models.UserInfo{}).Where("(username_normalized = ? OR username = ?)", identifier.Normalize(username), username)
 
 	if excludeUserID > 0 {
 		query = query.Where("user_id != ?", excludeUserID)
@@ -100,3 +97,20 @@ func (r *userInfoRepository) IsUsernameTaken(ctx contextx.Contextx, username str
 
 	return count > 0, nil
 }
+
+// getByIdentifier prefers an exact match, which still reaches accounts left without a normalized
+// value after a collision, and otherwise compares the normalized column
+func (r *userInfoRepository) getByIdentifier(ctx contextx.Contextx, column, value string) (*models.UserInfo, error) {
+	var userInfo models.UserInfo
+	err := ctx.GetTxn(r.db).Where(column+" = ?", value).First(&userInfo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ctx.GetTxn(r.db).Where(column+"_normalized = ?", identifier.Normalize(value)).First(&userInfo).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user info not found")
+		}
+		return nil, err
+	}
+	return &userInfo, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+
+	"gorm.io/gorm"
+)
+
+type webAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) WebAuthnRepository {
+	return &webAuthnRepository{db: db}
+}
+
+func (r *webAuthnRepository) CreateChallenge(ctx contextx.Contextx, challenge *models.WebAuthnChallenge) error {
+	if err := ctx.GetTxn(r.db).Create(challenge).Error; err != nil {
+		return errors.New("failed to create challenge")
+	}
+	return nil
+}
+
+// ConsumeChallenge deletes and returns a pending challenge so that it can only be used once
+func (r *webAuthnRepository) ConsumeChallenge(ctx contextx.Contextx, challenge string, ceremony string) (*models.WebAuthnChallenge, error) {
+	db := ctx.GetTxn(r.db)
+
+	var pending models.WebAuthnChallenge
+	if err := db.Where("challenge = ? AND ceremony = ?", challenge, ceremony).First(&pending).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("challenge not found")
+		}
+		return nil, err
+	}
+
+	result := db.Delete(&models.WebAuthnChallenge{}, pending.ID)
+	if result.Error != nil {
+		return nil, errors.New("failed to consume challenge")
+	}
+	if result.RowsAffected == 0 {
+		// Consumed concurrently by another request
+		return nil, errors.New("challenge not found")
+	}
+	return &pending, nil
+}
+
+func (r *webAuthnRepository) DeleteExpiredChallenges(ctx contextx.Contextx) error {
+	return ctx.GetTxn(r.db).Where("expires_at < ?", time.Now()).
+		Delete(&models.WebAuthnChallenge{}).Error
+}
+
+func (r *webAuthnRepository) CreateCredential(ctx contextx.Contextx, credential *models.WebAuthnCredential) error {
+	if err := ctx.GetTxn(r.db).Create(credential).Error; err != nil {
+		return errors.New("failed to create credential")
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) GetCredentialByID(ctx contextx.Contextx, id uint) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	if err := ctx.GetTxn(r.db).First(&credential, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("credential not found")
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *webAuthnRepository) GetCredentialByCredentialID(ctx contextx.Contextx, credentialID string) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	if err := ctx.GetTxn(r.db).Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("credential not found")
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *webAuthnRepository) GetCredentialsByUserID(ctx contextx.Contextx, userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	if err := ctx.GetTxn(r.db).Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&credentials).Error; err != nil {
+		return nil, errors.New("failed to get credentials")
+	}
+	return credentials, nil
+}
+
+func (r *webAuthnRepository) UpdateCredential(ctx contextx.Contextx, credential *models.WebAuthnCredential) error {
+	if err := ctx.GetTxn(r.db).Save(credential).Error; err != nil {
+		return errors.New("failed to update credential")
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) DeleteCredential(ctx contextx.Contextx, id uint) error {
+	if err := ctx.GetTxn(r.db).Delete(&models.WebAuthnCredential{}, id).Error; err != nil {
+		return errors.New("failed to delete credential")
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) DeleteCredentialsCreatedSince(ctx contextx.Contextx, userID uint, since time.Time) error {
+	if err := ctx.GetTxn(r.db).Where("user_id = ? AND created_at >= ?", userID, since).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+		return errors.New("failed to delete credentials")
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) CountCredentialsByUserID(ctx contextx.Contextx, userID uint) (int64, error) {
+	var count int64
+	if err := ctx.GetTxn(r.db).Model(&models.WebAuthnCredential{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		return 0, errors.New("failed to count credentials")
+	}
+	return count, nil
+}
@@ -2,12 +2,17 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"bezbase/internal/config"
 	"bezbase/internal/dto"
 	"bezbase/internal/models"
 	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/identifier"
 	"bezbase/internal/repository"
 
 	"gorm.io/gorm"
@@ -17,6 +22,12 @@ type AuthService struct {
 	userRepo         repository.UserRepository
 	userInfoRepo     repository.UserInfoRepository
 	authProviderRepo repository.AuthProviderRepository
+	sessionService   *SessionService
+	mfaService       *MFAService
+	lockoutService   *LockoutService
+	passwordPolicy   *PasswordPolicyService
+	ldapService      *LDAPService
+	emailService     *EmailService
 	authConfig       *config.AuthConfig
 	db               *gorm.DB
 }
@@ -25,6 +36,12 @@ func NewAuthService(
 	userRepo repository.UserRepository,
 	userInfoRepo repository.UserInfoRepository,
 	authProviderRepo repository.AuthProviderRepository,
+	sessionService *SessionService,
+	mfaService *MFAService,
+	lockoutService *LockoutService,
+	passwordPolicy *PasswordPolicyService,
+	ldapService *LDAPService,
+	emailService *EmailService,
 	authConfig *config.AuthConfig,
 	db *gorm.DB,
 ) *AuthService {
@@ -32,13 +49,30 @@ func NewAuthService(
 		userRepo:         userRepo,
 		userInfoRepo:     userInfoRepo,
 		authProviderRepo: authProviderRepo,
+		sessionService:   sessionService,
+		mfaService:       mfaService,
+		lockoutService:   lockoutService,
+		passwordPolicy:   passwordPolicy,
+		ldapService:      ldapService,
+		emailService:     emailService,
 		authConfig:       authConfig,
 		db:               db,
 	}
 }
 
 // Register creates a new user with email/password authentication
-func (s *AuthService) Register(req dto.RegisterRequest) (*dto.AuthResponse, error) {
+func (s *AuthService) Register(ctx contextx.Contextx, req dto.RegisterRequest) (*dto.AuthResponse, error) {
+	username, err := identifier.Username(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	req.Username = username
+	req.Email = strings.TrimSpace(req.Email)
+
+	if err := s.passwordPolicy.Validate(ctx, 0, req.Password, req.Username, req.Email, req.FirstName, req.LastName); err != nil {
+		return nil, err
+	}
+
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -46,15 +80,15 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*dto.AuthResponse, erro
 		}
 	}()
 
-	// Check if username is already taken
+	// Check if username is already taken, ignoring case and Unicode compatibility forms
 	var existingUserInfo models.UserInfo
-	if err := tx.Where("username = ?", req.Username).First(&existingUserInfo).Error; err == nil {
+	if err := tx.Where("username_normalized = ?", identifier.Normalize(req.Username)).First(&existingUserInfo).Error; err == nil {
 		tx.Rollback()
 		return nil, errors.New("username already taken")
 	}
 
 	// Check if email is already registered
-	if err := tx.Where("email = ?", req.Email).First(&existingUserInfo).Error; err == nil {
+	if err := tx.Where("email_normalized = ?", identifier.Normalize(req.Email)).First(&existingUserInfo).Error; err == nil {
 		tx.Rollback()
 		return nil, errors.New("email already registered")
 	}
@@ -117,59 +151,272 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*dto.AuthResponse, erro
 		return nil, errors.New("failed to save user data")
 	}
 
+	if err := s.passwordPolicy.Record(ctx, user.ID, hashedPassword); err != nil {
+		log.Printf("Failed to record password history for user %d: %v", user.ID, err)
+	}
+
 	// Load relationships for response
 	user.UserInfo = &userInfo
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.UserInfo.Username, s.authConfig.JWTSecret)
+	// Start a session and issue the token pair
+	return s.sessionService.IssueTokens(ctx, &user)
+}
+
+// LoginWithUsername authenticates user with username or email and password
+func (s *AuthService) LoginWithUsername(ctx contextx.Contextx, req dto.LoginRequest) (*dto.AuthResponse, error) {
+	// Usernames without a local password are checked against the directory when LDAP is enabled
+	if s.ldapService.Enabled() {
+		if _, err := s.localProvider(ctx, req.Username); err != nil {
+			return s.loginWithLDAP(ctx, req.Username, req.Password)
+		}
+	}
+
+	authProvider, err := s.verifyPassword(ctx, req.Username, req.Password)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
+	}
+
+	// Expired passwords must be replaced through ChangeExpiredPassword before a session is started
+	if s.passwordPolicy.IsExpired(ctx, authProvider.UserID) {
+		return nil, errors.New("password expired")
 	}
 
-	return &dto.AuthResponse{
-		Token: token,
-		User:  dto.ToUserResponse(&user),
-	}, nil
+	return s.completeLogin(ctx, authProvider.UserID)
 }
 
-// LoginWithUsername authenticates user with username and password
-func (s *AuthService) LoginWithUsername(req dto.LoginRequest) (*dto.AuthResponse, error) {
-	// Find auth provider by username
-	var authProvider models.AuthProvider
-	if err := s.db.Where("user_name = ? AND provider = ?", req.Username, models.ProviderEmail).First(&authProvider).Error; err != nil {
+// ChangeExpiredPassword replaces an expired password using the current credentials and then logs in
+func (s *AuthService) ChangeExpiredPassword(ctx contextx.Contextx, req dto.ChangeExpiredPasswordRequest) (*dto.AuthResponse, error) {
+	authProvider, err := s.verifyPassword(ctx, req.Username, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.passwordPolicy.IsExpired(ctx, authProvider.UserID) {
+		return nil, errors.New("password not expired")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, authProvider.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := s.passwordPolicy.Validate(ctx, user.ID, req.NewPassword, authProvider.UserName, user.GetPrimaryEmail()); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	authProvider.Password = hashedPassword
+	if err := s.authProviderRepo.Update(ctx, authProvider); err != nil {
+		return nil, err
+	}
+	if err := s.passwordPolicy.Record(ctx, user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+	if err := s.sessionService.RevokeAllSessions(ctx, user.ID, 0); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user.ID)
+}
+
+// localProvider finds the password login of the account identified by a username or email
+func (s *AuthService) localProvider(ctx contextx.Contextx, login string) (*models.AuthProvider, error) {
+	userInfo, err := s.userInfoRepo.GetByUsernameOrEmail(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return s.authProviderRepo.GetByUserIDAndProvider(ctx, userInfo.UserID, models.ProviderEmail)
+}
+
+// verifyPassword checks username or email and password credentials, applying the account lockout
+func (s *AuthService) verifyPassword(ctx contextx.Contextx, login, password string) (*models.AuthProvider, error) {
+	authProvider, err := s.localProvider(ctx, login)
+	if err != nil {
 		return nil, errors.New("invalid credentials")
 	}
 
+	// Refuse attempts while the account is backing off or locked, before the password is checked
+	if err := s.lockoutService.Check(ctx, authProvider.UserID); err != nil {
+		return nil, err
+	}
+
 	// Check password
-	if !auth.CheckPasswordHash(req.Password, authProvider.Password) {
+	if !auth.CheckPasswordHash(password, authProvider.Password) {
+		s.lockoutService.RecordFailure(ctx, authProvider.UserID)
 		return nil, errors.New("invalid credentials")
 	}
+	s.recordPasswordSuccess(ctx, authProvider.UserID)
+
+	return authProvider, nil
+}
+
+// recordPasswordSuccess clears the failed login attempts after a correct password. With MFA the
+// login only succeeds with the second factor, whose wrong codes count towards the same lockout,
+// so the failures are kept until the MFA challenge is completed.
+func (s *AuthService) recordPasswordSuccess(ctx contextx.Contextx, userID uint) {
+	if s.mfaService.IsEnabled(ctx, userID) {
+		return
+	}
+	s.lockoutService.RecordSuccess(ctx, userID)
+}
+
+// Reauthenticate checks the password or a second factor of a signed-in user and refreshes the
+// authentication time of the current session, so that sensitive operations are allowed again
+func (s *AuthService) Reauthenticate(ctx contextx.Contextx, userID, sessionID uint, req dto.ReauthenticateRequest) (*dto.AuthResponse, error) {
+	switch {
+	case req.Password != "":
+		if err := s.checkPassword(ctx, userID, req.Password); err != nil {
+			return nil, err
+		}
+	case req.Code != "" || req.RecoveryCode != "":
+		if err := s.mfaService.VerifyCode(ctx, userID, req.Code, req.RecoveryCode); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("invalid credentials")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return s.sessionService.Reauthenticate(ctx, user, sessionID)
+}
+
+// checkPassword verifies the password of a known user against the local login, or against the
+// directory for LDAP accounts, applying the account lockout
+func (s *AuthService) checkPassword(ctx contextx.Contextx, userID uint, password string) error {
+	if err := s.lockoutService.Check(ctx, userID); err != nil {
+		return err
+	}
+
+	valid := false
+	if provider, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, userID, models.ProviderEmail); err == nil {
+		valid = auth.CheckPasswordHash(password, provider.Password)
+	} else if provider, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, userID, models.ProviderLDAP); err == nil && s.ldapService.Enabled() {
+		entry, err := s.ldapService.Authenticate(provider.UserName, password)
+		if err != nil && err.Error() != "invalid credentials" {
+			return err
+		}
+		valid = err == nil && entry.ID == provider.ProviderID
+	}
 
+	if !valid {
+		s.lockoutService.RecordFailure(ctx, userID)
+		return errors.New("invalid credentials")
+	}
+	s.recordPasswordSuccess(ctx, userID)
+	return nil
+}
+
+// loginWithLDAP authenticates with a directory bind, provisions the account on first login and
+// re-syncs the roles mapped from directory groups before the session starts
+func (s *AuthService) loginWithLDAP(ctx contextx.Contextx, username, password string) (*dto.AuthResponse, error) {
+	// Known directory users are subject to the account lockout like local ones
+	var knownProvider models.AuthProvider
+	known := s.db.Where("user_name = ? AND provider = ?", username, models.ProviderLDAP).First(&knownProvider).Error == nil
+	if known {
+		if err := s.lockoutService.Check(ctx, knownProvider.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := s.ldapService.Authenticate(username, password)
+	if err != nil {
+		if known && err.Error() == "invalid credentials" {
+			s.lockoutService.RecordFailure(ctx, knownProvider.UserID)
+		}
+		return nil, err
+	}
+
+	// Accounts are linked by the directory ID, which survives renames of the login name
+	var userID uint
+	var authProvider models.AuthProvider
+	if err := s.db.Where("provider_id = ? AND provider = ?", entry.ID, models.ProviderLDAP).First(&authProvider).Error; err == nil {
+		userID = authProvider.UserID
+		if authProvider.UserName != username {
+			authProvider.UserName = username
+			s.db.Save(&authProvider)
+		}
+	} else {
+		profile := externalProfile(s.ldapService.Attributes(entry))
+		if profile.Email == "" {
+			return nil, errors.New("ldap email missing")
+		}
+		user, err := s.createExternalAccount(models.ProviderLDAP, entry.ID, username, profile)
+		if err != nil {
+			return nil, err
+		}
+		userID = user.ID
+	}
+	s.recordPasswordSuccess(ctx, userID)
+
+	if err := s.ldapService.SyncRoles(userID, entry.Groups); err != nil {
+		log.Printf("Failed to sync LDAP group roles for user %d: %v", userID, err)
+		return nil, errors.New("ldap unavailable")
+	}
+
+	return s.completeLogin(ctx, userID)
+}
+
+// completeLogin starts a session for a user whose password was verified
+func (s *AuthService) completeLogin(ctx contextx.Contextx, userID uint) (*dto.AuthResponse, error) {
 	// Get user with info
 	var user models.User
-	if err := s.db.Preload("UserInfo").First(&user, authProvider.UserID).Error; err != nil {
+	if err := s.db.Preload("UserInfo").First(&user, userID).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 
+	// Users with MFA enabled must complete a second factor before a session is started
+	if s.mfaService.IsEnabled(ctx, user.ID) {
+		return s.mfaService.CreateChallenge(ctx, &user)
+	}
+
 	// Update last login time
 	now := time.Now()
 	user.LastLoginAt = &now
 	s.db.Save(&user)
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.UserInfo.Username, s.authConfig.JWTSecret)
+	// Start a session and issue the token pair
+	return s.sessionService.IssueTokens(ctx, &user)
+}
+
+// RegisterWithSocialProvider creates a user from social login, or logs in when the social account is already linked
+func (s *AuthService) RegisterWithSocialProvider(ctx contextx.Contextx, provider models.AuthProviderType, providerID, email, firstName, lastName string) (*dto.AuthResponse, error) {
+	return s.RegisterWithExternalProfile(ctx, provider, providerID, models.UserInfo{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+	})
+}
+
+// RegisterWithExternalProfile creates a user from an external identity (social login or SAML), copying the
+// profile fields the identity provider supplied, or logs in when the external account is already linked
+func (s *AuthService) RegisterWithExternalProfile(ctx contextx.Contextx, provider models.AuthProviderType, providerID string, profile models.UserInfo) (*dto.AuthResponse, error) {
+	// Check if this external account is already linked
+	var existingProvider models.AuthProvider
+	if err := s.db.Where("provider_id = ? AND provider = ?", providerID, provider).First(&existingProvider).Error; err == nil {
+		// User already exists, just login
+		return s.loginWithSocialProvider(ctx, provider, providerID)
+	}
+
+	// For social providers, use email as username
+	user, err := s.createExternalAccount(provider, providerID, profile.Email, profile)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
 	}
 
-	return &dto.AuthResponse{
-		Token: token,
-		User:  dto.ToUserResponse(&user),
-	}, nil
+	// Start a session and issue the token pair
+	return s.sessionService.IssueTokens(ctx, user)
 }
 
-// RegisterWithSocialProvider creates a user from social login (future implementation)
-func (s *AuthService) RegisterWithSocialProvider(provider models.AuthProviderType, providerID, email, firstName, lastName string) (*dto.AuthResponse, error) {
+// createExternalAccount provisions a user, its profile and the external auth provider link in one transaction.
+// userName is the auth provider login name.
+func (s *AuthService) createExternalAccount(provider models.AuthProviderType, providerID, userName string, profile models.UserInfo) (*models.User, error) {
+	email := profile.Email
+
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -177,17 +424,22 @@ func (s *AuthService) RegisterWithSocialProvider(provider models.AuthProviderTyp
 		}
 	}()
 
-	// Check if this social account is already linked
-	var existingProvider models.AuthProvider
-	if err := tx.Where("provider_id = ? AND provider = ?", providerID, provider).First(&existingProvider).Error; err == nil {
-		// User already exists, just login
+	// Never create a second account for an existing email; the owner has to confirm a link instead
+	var existingUserInfo models.UserInfo
+	if err := tx.Where("email_normalized = ?", identifier.Normalize(email)).First(&existingUserInfo).Error; err == nil {
+		tx.Rollback()
+		return nil, errors.New("email already registered")
+	}
+
+	username, err := generateUsername(tx, email)
+	if err != nil {
 		tx.Rollback()
-		return s.loginWithSocialProvider(provider, providerID)
+		return nil, errors.New("failed to create user info")
 	}
 
 	// Create new user
 	user := models.User{
-		Status:        models.UserStatusActive, // Social logins are typically pre-verified
+		Status:        models.UserStatusActive, // External identities are pre-verified by their provider
 		EmailVerified: true,
 	}
 	if err := tx.Create(&user).Error; err != nil {
@@ -196,13 +448,14 @@ func (s *AuthService) RegisterWithSocialProvider(provider models.AuthProviderTyp
 	}
 
 	// Create user info
-	userInfo := models.UserInfo{
-		UserID:    user.ID,
-		FirstName: firstName,
-		LastName:  lastName,
-		Email:     email,
-		Language:  "en",
-		Timezone:  "UTC",
+	userInfo := profile
+	userInfo.UserID = user.ID
+	userInfo.Username = username
+	if userInfo.Language == "" {
+		userInfo.Language = "en"
+	}
+	if userInfo.Timezone == "" {
+		userInfo.Timezone = "UTC"
 	}
 	if err := tx.Create(&userInfo).Error; err != nil {
 		tx.Rollback()
@@ -214,8 +467,8 @@ func (s *AuthService) RegisterWithSocialProvider(provider models.AuthProviderTyp
 		UserID:     user.ID,
 		Provider:   provider,
 		ProviderID: providerID,
-		UserName:   email, // For social providers, use email as username
-		Verified:   true,  // Social logins are typically pre-verified
+		UserName:   userName,
+		Verified:   true,
 	}
 	if err := tx.Create(&authProvider).Error; err != nil {
 		tx.Rollback()
@@ -229,21 +482,27 @@ func (s *AuthService) RegisterWithSocialProvider(provider models.AuthProviderTyp
 
 	// Load relationships for response
 	user.UserInfo = &userInfo
+	return &user, nil
+}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.UserInfo.Username, s.authConfig.JWTSecret)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
+// externalProfile copies attributes supplied by an identity provider or directory, keyed by
+// UserInfo field name, onto the profile of a new account
+func externalProfile(attributes map[string]string) models.UserInfo {
+	return models.UserInfo{
+		Email:     attributes["email"],
+		FirstName: attributes["first_name"],
+		LastName:  attributes["last_name"],
+		Phone:     attributes["phone"],
+		Location:  attributes["location"],
+		Website:   attributes["website"],
+		AvatarURL: attributes["avatar_url"],
+		Timezone:  attributes["timezone"],
+		Language:  attributes["language"],
 	}
-
-	return &dto.AuthResponse{
-		Token: token,
-		User:  dto.ToUserResponse(&user),
-	}, nil
 }
 
 // loginWithSocialProvider handles login for existing social accounts
-func (s *AuthService) loginWithSocialProvider(provider models.AuthProviderType, providerID string) (*dto.AuthResponse, error) {
+func (s *AuthService) loginWithSocialProvider(ctx contextx.Contextx, provider models.AuthProviderType, providerID string) (*dto.AuthResponse, error) {
 	// Find auth provider
 	var authProvider models.AuthProvider
 	if err := s.db.Where("provider_id = ? AND provider = ?", providerID, provider).First(&authProvider).Error; err != nil {
@@ -256,21 +515,18 @@ func (s *AuthService) loginWithSocialProvider(provider models.AuthProviderType,
 		return nil, errors.New("user not found")
 	}
 
+	// Users with MFA enabled must complete a second factor before a session is started
+	if s.mfaService.IsEnabled(ctx, user.ID) {
+		return s.mfaService.CreateChallenge(ctx, &user)
+	}
+
 	// Update last login time
 	now := time.Now()
 	user.LastLoginAt = &now
 	s.db.Save(&user)
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.UserInfo.Email, s.authConfig.JWTSecret)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
-	}
-
-	return &dto.AuthResponse{
-		Token: token,
-		User:  dto.ToUserResponse(&user),
-	}, nil
+	// Start a session and issue the token pair
+	return s.sessionService.IssueTokens(ctx, &user)
 }
 
 // LinkSocialProvider links a social account to existing user
@@ -278,9 +534,17 @@ func (s *AuthService) LinkSocialProvider(userID uint, provider models.AuthProvid
 	// Check if this social account is already linked to another user
 	var existingProvider models.AuthProvider
 	if err := s.db.Where("provider_id = ? AND provider = ?", providerID, provider).First(&existingProvider).Error; err == nil {
+		if existingProvider.UserID == userID {
+			return errors.New("social account already linked")
+		}
 		return errors.New("social account already linked to another user")
 	}
 
+	// Only one account per provider type can be linked to a user
+	if err := s.db.Where("user_id = ? AND provider = ?", userID, provider).First(&existingProvider).Error; err == nil {
+		return errors.New("provider already linked")
+	}
+
 	// Create new auth provider link
 	authProvider := models.AuthProvider{
 		UserID:     userID,
@@ -294,5 +558,37 @@ func (s *AuthService) LinkSocialProvider(userID uint, provider models.AuthProvid
 		return errors.New("failed to link social account")
 	}
 
+	notifyLinkedAccountChange(s.userRepo, s.emailService, userID, provider, true)
 	return nil
 }
+
+// generateUsername derives a free username from the local part of an email address
+func generateUsername(tx *gorm.DB, email string) (string, error) {
+	base := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	base = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return -1
+	}, base)
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		var count int64
+		if err := tx.Model(&models.UserInfo{}).Where("username_normalized = ?", identifier.Normalize(candidate)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix, err := auth.GenerateOpaqueToken()
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%s", base, strings.ToLower(suffix[:6]))
+	}
+	return "", errors.New("failed to generate username")
+}
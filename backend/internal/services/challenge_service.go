@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/pow"
+)
+
+// ChallengeVerifier protects public endpoints against automated abuse. The built-in implementation
+// is a proof-of-work puzzle; a hosted CAPTCHA can be used instead by implementing the same interface.
+type ChallengeVerifier interface {
+	// Issue returns what the client needs to produce a solution
+	Issue(ctx contextx.Contextx, clientIP string) (*dto.ChallengeResponse, error)
+	// Verify checks the solution sent with a protected request
+	Verify(ctx contextx.Contextx, solution, clientIP string) error
+	// ReportFailure records a rejected solution or a failed protected request from the client
+	ReportFailure(clientIP string)
+}
+
+// ProofOfWorkVerifier issues signed puzzles whose difficulty grows with the recent failures of the
+// requesting IP address. Nothing is stored per challenge except the seeds of redeemed ones, which
+// are kept in memory until they expire so that a solution cannot be replayed on this instance.
+type ProofOfWorkVerifier struct {
+	challengeConfig *config.ChallengeConfig
+	secret          []byte
+	mutex           sync.Mutex
+	failures        map[string]*challengeFailures
+	redeemed        map[string]time.Time
+}
+
+type challengeFailures struct {
+	count     int
+	expiresAt time.Time
+}
+
+func NewProofOfWorkVerifier(challengeConfig *config.ChallengeConfig, authConfig *config.AuthConfig) *ProofOfWorkVerifier {
+	return &ProofOfWorkVerifier{
+		challengeConfig: challengeConfig,
+		secret:          []byte(authConfig.JWTSecret),
+		failures:        make(map[string]*challengeFailures),
+		redeemed:        make(map[string]time.Time),
+	}
+}
+
+// Start periodically forgets expired failure counts and redeemed seeds
+func (v *ProofOfWorkVerifier) Start() {
+	if v.challengeConfig.TTL <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(v.challengeConfig.TTL)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			v.mutex.Lock()
+			for ip, failures := range v.failures {
+				if now.After(failures.expiresAt) {
+					delete(v.failures, ip)
+				}
+			}
+			for seed, expiresAt := range v.redeemed {
+				if now.After(expiresAt) {
+					delete(v.redeemed, seed)
+				}
+			}
+			v.mutex.Unlock()
+		}
+	}()
+}
+
+// Issue signs a new puzzle for the client at its current difficulty
+func (v *ProofOfWorkVerifier) Issue(ctx contextx.Contextx, clientIP string) (*dto.ChallengeResponse, error) {
+	challenge, err := pow.New(v.difficulty(clientIP), clientIP, v.challengeConfig.TTL)
+	if err != nil {
+		return nil, errors.New("failed to generate challenge")
+	}
+	signed, err := challenge.Sign(v.secret)
+	if err != nil {
+		return nil, errors.New("failed to generate challenge")
+	}
+
+	expiresAt := time.Unix(challenge.ExpiresAt, 0)
+	return &dto.ChallengeResponse{
+		Type:       "pow",
+		Challenge:  signed,
+		Algorithm:  pow.Algorithm,
+		Difficulty: challenge.Difficulty,
+		ExpiresAt:  &expiresAt,
+	}, nil
+}
+
+// Verify accepts "<challenge>:<nonce>" for an unexpired challenge issued to the same IP address.
+// Each challenge can be redeemed once.
+func (v *ProofOfWorkVerifier) Verify(ctx contextx.Contextx, solution, clientIP string) error {
+	if solution == "" {
+		return errors.New("challenge required")
+	}
+
+	signed, nonce, ok := cutLast(solution, ":")
+	if !ok {
+		return errors.New("invalid challenge solution")
+	}
+	challenge, err := pow.Parse(signed, v.secret)
+	if err != nil || challenge.Client != clientIP || !pow.Check(signed, nonce, challenge.Difficulty) {
+		return errors.New("invalid challenge solution")
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if _, used := v.redeemed[challenge.Seed]; used {
+		return errors.New("invalid challenge solution")
+	}
+	v.redeemed[challenge.Seed] = time.Unix(challenge.ExpiresAt, 0)
+	return nil
+}
+
+// ReportFailure counts a failure towards the client's difficulty for the failure window
+func (v *ProofOfWorkVerifier) ReportFailure(clientIP string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	now := time.Now()
+	failures, ok := v.failures[clientIP]
+	if !ok || now.After(failures.expiresAt) {
+		if !ok && v.challengeConfig.MaxTrackedIPs > 0 && len(v.failures) >= v.challengeConfig.MaxTrackedIPs {
+			v.forgetStalestFailures(now)
+		}
+		failures = &challengeFailures{}
+		v.failures[clientIP] = failures
+	}
+	failures.count++
+	failures.expiresAt = now.Add(v.challengeConfig.FailureWindow)
+}
+
+// forgetStalestFailures makes room for another IP address by dropping expired failure counts, or
+// the one closest to expiring when none has. The caller holds the mutex.
+func (v *ProofOfWorkVerifier) forgetStalestFailures(now time.Time) {
+	var stalest string
+	var stalestExpiry time.Time
+	for ip, failures := range v.failures {
+		if now.After(failures.expiresAt) {
+			delete(v.failures, ip)
+			continue
+		}
+		if stalest == "" || failures.expiresAt.Before(stalestExpiry) {
+			stalest, stalestExpiry = ip, failures.expiresAt
+		}
+	}
+	if len(v.failures) >= v.challengeConfig.MaxTrackedIPs {
+		delete(v.failures, stalest)
+	}
+}
+
+// difficulty adds one bit to the base difficulty for every FailuresPerLevel recent failures
+func (v *ProofOfWorkVerifier) difficulty(clientIP string) int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	difficulty := v.challengeConfig.BaseDifficulty
+	if failures, ok := v.failures[clientIP]; ok && time.Now().Before(failures.expiresAt) && v.challengeConfig.FailuresPerLevel > 0 {
+		difficulty += failures.count / v.challengeConfig.FailuresPerLevel
+	}
+	if difficulty > v.challengeConfig.MaxDifficulty {
+		difficulty = v.challengeConfig.MaxDifficulty
+	}
+	return difficulty
+}
+
+// cutLast splits s around the last separator; the signed challenge itself never contains ":"
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bezbase/internal/config"
+)
+
+func TestProofOfWorkVerifierCapsTrackedIPs(t *testing.T) {
+	challengeConfig := &config.ChallengeConfig{
+		BaseDifficulty:   8,
+		MaxDifficulty:    16,
+		FailuresPerLevel: 1,
+		FailureWindow:    time.Minute,
+		TTL:              time.Minute,
+		MaxTrackedIPs:    10,
+	}
+	verifier := NewProofOfWorkVerifier(challengeConfig, &config.AuthConfig{JWTSecret: "secret"})
+
+	// Failures from many addresses, then more from one of them
+	for i := 0; i < 100; i++ {
+		verifier.ReportFailure(fmt.Sprintf("198.51.100.%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		verifier.ReportFailure("203.0.113.7")
+	}
+
+	if tracked := len(verifier.failures); tracked > challengeConfig.MaxTrackedIPs {
+		t.Errorf("%d IP addresses tracked, want at most %d", tracked, challengeConfig.MaxTrackedIPs)
+	}
+	if difficulty := verifier.difficulty("203.0.113.7"); difficulty != 11 {
+		t.Errorf("difficulty of the most recent offender = %d, want 11", difficulty)
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+const (
+	// DeviceCodeGrantType is the token endpoint grant type for polling devices
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// User codes use consonants only, which avoids look-alike characters and accidental words
+	// (RFC 8628 section 6.1). Eight of them give about 34 bits of entropy.
+	deviceUserCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	deviceUserCodeLength   = 8
+
+	// deviceSlowDownStep is added to the polling interval whenever a device polls too fast
+	deviceSlowDownStep = 5
+)
+
+// DeviceAuthorizationService implements the OAuth device authorization grant (RFC 8628) for
+// registered OIDC clients such as CLIs and TVs. Approved devices receive a normal bezbase session,
+// the same as a password login, rather than provider tokens.
+type DeviceAuthorizationService struct {
+	deviceRepo     repository.DeviceAuthorizationRepository
+	clientRepo     repository.OIDCClientRepository
+	userRepo       repository.UserRepository
+	sessionService *SessionService
+	providerConfig *config.IdentityProviderConfig
+}
+
+func NewDeviceAuthorizationService(
+	deviceRepo repository.DeviceAuthorizationRepository,
+	clientRepo repository.OIDCClientRepository,
+	userRepo repository.UserRepository,
+	sessionService *SessionService,
+	providerConfig *config.IdentityProviderConfig,
+) *DeviceAuthorizationService {
+	return &DeviceAuthorizationService{
+		deviceRepo:     deviceRepo,
+		clientRepo:     clientRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		providerConfig: providerConfig,
+	}
+}
+
+// Authorize starts a device authorization and returns the codes for the device
+func (s *DeviceAuthorizationService) Authorize(ctx contextx.Contextx, req dto.DeviceAuthorizationRequest) (*dto.DeviceAuthorizationResponse, error) {
+	client, err := authenticateOIDCClient(ctx, s.clientRepo, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := generateSecureToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	// A user code must not match another pending request
+	var userCode string
+	for attempt := 0; attempt < 3 && userCode == ""; attempt++ {
+		candidate, err := generateUserCode()
+		if err != nil {
+			return nil, errors.New("failed to generate token")
+		}
+		if _, err := s.deviceRepo.GetPendingByUserCodeHash(ctx, auth.HashToken(candidate)); err != nil {
+			userCode = candidate
+		}
+	}
+	if userCode == "" {
+		return nil, errors.New("failed to generate token")
+	}
+
+	userAgent, ipAddress := requestClientInfo(ctx)
+	interval := int(s.providerConfig.DevicePollInterval.Seconds())
+	authorization := models.DeviceAuthorization{
+		ClientID:       client.ID,
+		DeviceCodeHash: auth.HashToken(deviceCode),
+		UserCodeHash:   auth.HashToken(userCode),
+		Status:         models.DeviceAuthorizationPending,
+		Interval:       interval,
+		RequestIP:      ipAddress,
+		UserAgent:      userAgent,
+		ExpiresAt:      time.Now().Add(s.providerConfig.DeviceCodeTTL),
+	}
+	if err := s.deviceRepo.Create(ctx, &authorization); err != nil {
+		return nil, err
+	}
+
+	displayCode := userCode[:deviceUserCodeLength/2] + "-" + userCode[deviceUserCodeLength/2:]
+	return &dto.DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                displayCode,
+		VerificationURI:         s.providerConfig.DeviceVerificationURL,
+		VerificationURIComplete: s.providerConfig.DeviceVerificationURL + "?user_code=" + url.QueryEscape(displayCode),
+		ExpiresIn:               int(s.providerConfig.DeviceCodeTTL.Seconds()),
+		Interval:                interval,
+	}, nil
+}
+
+// Verify returns what the approval screen shows for a pending user code
+func (s *DeviceAuthorizationService) Verify(ctx contextx.Contextx, userCode string) (*dto.DeviceVerificationResponse, error) {
+	authorization, err := s.findPending(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.DeviceVerificationResponse{
+		ClientID:   authorization.Client.ClientID,
+		ClientName: authorization.Client.Name,
+		RequestIP:  authorization.RequestIP,
+		UserAgent:  authorization.UserAgent,
+		ExpiresAt:  authorization.ExpiresAt,
+	}, nil
+}
+
+// Decide approves or denies a pending user code on behalf of the signed-in user
+func (s *DeviceAuthorizationService) Decide(ctx contextx.Contextx, userID uint, userCode string, approve bool) error {
+	authorization, err := s.findPending(ctx, userCode)
+	if err != nil {
+		return err
+	}
+
+	status := models.DeviceAuthorizationDenied
+	if approve {
+		status = models.DeviceAuthorizationApproved
+	}
+	decided, err := s.deviceRepo.Decide(ctx, authorization.ID, userID, status)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return errors.New("invalid user code")
+	}
+	return nil
+}
+
+// Token answers a polling device: authorization_pending until the user decides, slow_down when it
+// polls faster than its interval, and the session tokens exactly once after approval
+func (s *DeviceAuthorizationService) Token(ctx contextx.Contextx, clientID, clientSecret, deviceCode string) (*dto.DeviceTokenResponse, error) {
+	client, err := authenticateOIDCClient(ctx, s.clientRepo, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if deviceCode == "" {
+		return nil, newOAuth2Error("invalid_request", "device_code is required")
+	}
+
+	authorization, err := s.deviceRepo.GetByDeviceCodeHash(ctx, auth.HashToken(deviceCode))
+	if err != nil || authorization.ClientID != client.ID {
+		return nil, newOAuth2Error("invalid_grant", "")
+	}
+
+	switch authorization.CurrentStatus() {
+	case models.DeviceAuthorizationExpired:
+		return nil, newOAuth2Error("expired_token", "")
+	case models.DeviceAuthorizationDenied:
+		return nil, newOAuth2Error("access_denied", "")
+	case models.DeviceAuthorizationCompleted:
+		return nil, newOAuth2Error("invalid_grant", "device code already used")
+	case models.DeviceAuthorizationPending:
+		interval := authorization.Interval
+		tooFast := authorization.LastPolledAt != nil && time.Since(*authorization.LastPolledAt) < time.Duration(interval)*time.Second
+		if tooFast {
+			interval += deviceSlowDownStep
+		}
+		if err := s.deviceRepo.RecordPoll(ctx, authorization.ID, interval); err != nil {
+			return nil, err
+		}
+		if tooFast {
+			return nil, newOAuth2Error("slow_down", "")
+		}
+		return nil, newOAuth2Error("authorization_pending", "")
+	}
+
+	completed, err := s.deviceRepo.Complete(ctx, authorization.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed || authorization.UserID == nil {
+		return nil, newOAuth2Error("invalid_grant", "device code already used")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, *authorization.UserID, "UserInfo")
+	if err != nil {
+		return nil, newOAuth2Error("invalid_grant", "")
+	}
+	now := time.Now()
+	user.LastLoginAt = &now
+	_ = s.userRepo.Update(ctx, user)
+
+	response, err := s.sessionService.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	expiresIn := 0
+	if response.ExpiresAt != nil {
+		expiresIn = int(time.Until(*response.ExpiresAt).Seconds())
+	}
+	return &dto.DeviceTokenResponse{
+		AccessToken:  response.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    expiresIn,
+		RefreshToken: response.RefreshToken,
+	}, nil
+}
+
+// ListAuthorizations returns device authorization requests for admins
+func (s *DeviceAuthorizationService) ListAuthorizations(ctx contextx.Contextx, page, pageSize int, status models.DeviceAuthorizationStatus) ([]models.DeviceAuthorization, int64, error) {
+	return s.deviceRepo.List(ctx, page, pageSize, status)
+}
+
+func (s *DeviceAuthorizationService) findPending(ctx contextx.Contextx, userCode string) (*models.DeviceAuthorization, error) {
+	normalized := normalizeUserCode(userCode)
+	if len(normalized) != deviceUserCodeLength {
+		return nil, errors.New("invalid user code")
+	}
+	authorization, err := s.deviceRepo.GetPendingByUserCodeHash(ctx, auth.HashToken(normalized))
+	if err != nil {
+		return nil, errors.New("invalid user code")
+	}
+	return authorization, nil
+}
+
+func generateUserCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(deviceUserCodeAlphabet)))
+	code := make([]byte, deviceUserCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code[i] = deviceUserCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode accepts user codes typed in any case and with or without separators
+func normalizeUserCode(userCode string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(deviceUserCodeAlphabet, r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
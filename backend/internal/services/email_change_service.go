@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// emailChangeRequestInterval limits how often a user can start an email change
+const emailChangeRequestInterval = time.Minute
+
+// EmailChangeService holds email changes as pending until the new address is confirmed. The old
+// address is notified with a link that cancels the change, or undoes it after confirmation.
+type EmailChangeService struct {
+	emailChangeRepo  repository.EmailChangeRepository
+	userRepo         repository.UserRepository
+	userInfoRepo     repository.UserInfoRepository
+	authProviderRepo repository.AuthProviderRepository
+	emailService     *EmailService
+	sessionService   *SessionService
+	changeConfig     *config.EmailChangeConfig
+	db               *gorm.DB
+}
+
+func NewEmailChangeService(
+	emailChangeRepo repository.EmailChangeRepository,
+	userRepo repository.UserRepository,
+	userInfoRepo repository.UserInfoRepository,
+	authProviderRepo repository.AuthProviderRepository,
+	emailService *EmailService,
+	sessionService *SessionService,
+	changeConfig *config.EmailChangeConfig,
+	db *gorm.DB,
+) *EmailChangeService {
+	return &EmailChangeService{
+		emailChangeRepo:  emailChangeRepo,
+		userRepo:         userRepo,
+		userInfoRepo:     userInfoRepo,
+		authProviderRepo: authProviderRepo,
+		emailService:     emailService,
+		sessionService:   sessionService,
+		changeConfig:     changeConfig,
+		db:               db,
+	}
+}
+
+// ValidateChange returns the error RequestChange would return for newEmail, without starting the
+// change, so that callers can reject it before saving anything else
+func (s *EmailChangeService) ValidateChange(ctx contextx.Contextx, userID uint, newEmail string) error {
+	_, _, err := s.checkChange(ctx, userID, newEmail)
+	return err
+}
+
+// RequestChange starts a change to newEmail, replacing any earlier unconfirmed request. The new
+// address gets a confirmation link and the current one a notice with a revert link.
+func (s *EmailChangeService) RequestChange(ctx contextx.Contextx, userID uint, newEmail string) error {
+	user, newEmail, err := s.checkChange(ctx, userID, newEmail)
+	if err != nil || newEmail == "" {
+		return err
+	}
+	oldEmail := user.GetPrimaryEmail()
+
+	confirmToken, err := generateSecureToken()
+	if err != nil {
+		return errors.New("failed to generate token")
+	}
+	revertToken, err := generateSecureToken()
+	if err != nil {
+		return errors.New("failed to generate token")
+	}
+
+	if err := s.emailChangeRepo.DeleteUnconfirmedByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	_, ipAddress := requestClientInfo(ctx)
+	now := time.Now()
+	request := models.EmailChangeRequest{
+		UserID:           userID,
+		OldEmail:         oldEmail,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: auth.HashToken(confirmToken),
+		RevertTokenHash:  auth.HashToken(revertToken),
+		RequestIP:        ipAddress,
+		ExpiresAt:        now.Add(s.changeConfig.ConfirmTTL),
+		RevertExpiresAt:  now.Add(s.changeConfig.RevertTTL),
+	}
+	if err := s.emailChangeRepo.Create(ctx, &request); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.emailService.SendEmailChangeConfirmationEmail(contextx.Background(), user, newEmail, confirmToken, s.changeConfig.ConfirmTTL); err != nil {
+			log.Printf("Failed to send email change confirmation to user %d: %v", userID, err)
+		}
+		if oldEmail == "" {
+			return
+		}
+		if err := s.emailService.SendEmailChangeNoticeEmail(contextx.Background(), user, oldEmail, newEmail, revertToken, s.changeConfig.RevertTTL); err != nil {
+			log.Printf("Failed to send email change notice to user %d: %v", userID, err)
+		}
+	}()
+
+	return nil
+}
+
+// checkChange checks the format, availability and request rate of newEmail. It returns the user and
+// the trimmed address, which is empty when it is the current one.
+func (s *EmailChangeService) checkChange(ctx contextx.Contextx, userID uint, newEmail string) (*models.User, string, error) {
+	newEmail = strings.TrimSpace(newEmail)
+	if address, err := mail.ParseAddress(newEmail); err != nil || address.Address != newEmail {
+		return nil, "", errors.New("invalid email")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.EqualFold(user.GetPrimaryEmail(), newEmail) {
+		return user, "", nil
+	}
+
+	taken, err := s.userInfoRepo.IsEmailTaken(ctx, newEmail, userID)
+	if err != nil {
+		return nil, "", errors.New("failed to check email availability")
+	}
+	if taken {
+		return nil, "", errors.New("email already taken")
+	}
+
+	if pending, err := s.emailChangeRepo.GetPendingByUserID(ctx, userID); err == nil && time.Since(pending.CreatedAt) < emailChangeRequestInterval {
+		return nil, "", errors.New("email change requested recently")
+	}
+	return user, newEmail, nil
+}
+
+// PendingEmail returns the address the user is changing to, or an empty string
+func (s *EmailChangeService) PendingEmail(ctx contextx.Contextx, userID uint) string {
+	if pending, err := s.emailChangeRepo.GetPendingByUserID(ctx, userID); err == nil {
+		return pending.NewEmail
+	}
+	return ""
+}
+
+// ConfirmChange applies a pending change from the link sent to the new address. The profile, the
+// email login and the verification state are updated in one transaction.
+func (s *EmailChangeService) ConfirmChange(ctx contextx.Contextx, token string) error {
+	if token == "" {
+		return errors.New("invalid email change token")
+	}
+	request, err := s.emailChangeRepo.GetByConfirmTokenHash(ctx, auth.HashToken(token))
+	if err != nil || !request.IsPending() {
+		return errors.New("invalid email change token")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txCtx := contextx.WithTransaction(ctx, tx)
+
+		confirmed, err := s.emailChangeRepo.MarkConfirmed(txCtx, request.ID)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("invalid email change token")
+		}
+		return s.switchEmail(txCtx, request.UserID, request.OldEmail, request.NewEmail)
+	})
+}
+
+// RevertChange handles the link sent to the old address. A pending change is cancelled; a
+// confirmed one is undone and all sessions are revoked, since the account may be in the wrong hands.
+func (s *EmailChangeService) RevertChange(ctx contextx.Contextx, token string) error {
+	if token == "" {
+		return errors.New("invalid email change token")
+	}
+	request, err := s.emailChangeRepo.GetByRevertTokenHash(ctx, auth.HashToken(token))
+	if err != nil || !request.CanRevert() {
+		return errors.New("invalid email change token")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txCtx := contextx.WithTransaction(ctx, tx)
+
+		reverted, err := s.emailChangeRepo.MarkReverted(txCtx, request.ID)
+		if err != nil {
+			return err
+		}
+		if !reverted {
+			return errors.New("invalid email change token")
+		}
+		if request.ConfirmedAt == nil {
+			return nil
+		}
+		return s.switchEmail(txCtx, request.UserID, request.NewEmail, request.OldEmail)
+	})
+	if err != nil {
+		return err
+	}
+
+	if request.ConfirmedAt != nil {
+		if err := s.sessionService.RevokeAllSessions(ctx, request.UserID, 0); err != nil {
+			log.Printf("Failed to revoke sessions of user %d after reverting an email change: %v", request.UserID, err)
+		}
+	}
+	return nil
+}
+
+// switchEmail moves the account from one address to another. The account must still use the
+// address being replaced, so a stale link cannot overwrite a later change.
+func (s *EmailChangeService) switchEmail(ctx contextx.Contextx, userID uint, fromEmail, toEmail string) error {
+	userInfo, err := s.userInfoRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return errors.New("invalid email change token")
+	}
+	if !strings.EqualFold(userInfo.Email, fromEmail) {
+		return errors.New("invalid email change token")
+	}
+
+	taken, err := s.userInfoRepo.IsEmailTaken(ctx, toEmail, userID)
+	if err != nil {
+		return errors.New("failed to check email availability")
+	}
+	if taken {
+		return errors.New("email already taken")
+	}
+
+	userInfo.Email = toEmail
+	if err := s.userInfoRepo.Update(ctx, userInfo); err != nil {
+		return err
+	}
+	if err := s.authProviderRepo.UpdateEmail(ctx, userID, models.ProviderEmail, toEmail); err != nil {
+		return err
+	}
+	// Following the link proved control of the address the account now uses
+	return s.userRepo.VerifyEmail(ctx, userID)
+}
@@ -105,6 +105,98 @@ func (s *EmailService) SendPasswordResetEmail(ctx contextx.Contextx, user *model
 	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
 }
 
+func (s *EmailService) SendMagicLinkEmail(ctx contextx.Contextx, user *models.User, token string, ttl time.Duration) error {
+	subject := "Your sign-in link"
+	loginURL := fmt.Sprintf("%s/magic-link?token=%s", s.baseURL, token)
+
+	body, err := s.generateMagicLinkHTML(user.GetFullName(), loginURL, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
+}
+
+func (s *EmailService) SendAccountUnlockEmail(ctx contextx.Contextx, user *models.User, token string, lockedUntil time.Time) error {
+	subject := "Your account has been locked"
+	unlockURL := fmt.Sprintf("%s/unlock-account?token=%s", s.baseURL, token)
+
+	body, err := s.generateAccountUnlockHTML(user.GetFullName(), unlockURL, lockedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
+}
+
+// SendEmailChangeConfirmationEmail asks the owner of the new address to confirm an email change
+func (s *EmailService) SendEmailChangeConfirmationEmail(ctx contextx.Contextx, user *models.User, newEmail, token string, ttl time.Duration) error {
+	subject := "Confirm your new email address"
+	confirmURL := fmt.Sprintf("%s/confirm-email-change?token=%s", s.baseURL, token)
+
+	body, err := s.generateEmailChangeConfirmationHTML(user.GetFullName(), newEmail, confirmURL, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), newEmail, subject, body)
+}
+
+// SendEmailChangeNoticeEmail tells the old address about an email change and how to revert it
+func (s *EmailService) SendEmailChangeNoticeEmail(ctx contextx.Contextx, user *models.User, oldEmail, newEmail, revertToken string, revertTTL time.Duration) error {
+	subject := "Your email address is being changed"
+	revertURL := fmt.Sprintf("%s/revert-email-change?token=%s", s.baseURL, revertToken)
+
+	body, err := s.generateEmailChangeNoticeHTML(user.GetFullName(), newEmail, revertURL, revertTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), oldEmail, subject, body)
+}
+
+// SendLoginAlertEmail tells the user about a login from a new device or an unexpected network, with
+// a link that revokes access if the login wasn't theirs
+func (s *EmailService) SendLoginAlertEmail(ctx contextx.Contextx, user *models.User, event *models.LoginEvent, reportToken string, reportTTL time.Duration) error {
+	subject := "New sign-in to your account"
+	reportURL := fmt.Sprintf("%s/report-login?token=%s", s.baseURL, reportToken)
+
+	body, err := s.generateLoginAlertHTML(user.GetFullName(), event, reportURL, reportTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
+}
+
+
+// SendLinkedAccountEmail tells the user that a sign-in method was added to or removed from their account
+func (s *EmailService) SendLinkedAccountEmail(ctx contextx.Contextx, user *models.User, provider models.AuthProviderType, linked bool) error {
+	subject := "A sign-in method was removed from your account"
+	if linked {
+		subject = "A sign-in method was added to your account"
+	}
+
+	body, err := s.generateLinkedAccountHTML(user.GetFullName(), linkedAccountLabel(provider), linked)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
+}
+
+// SendRoleExpiryEmail warns the user that a time-bound role is about to expire. organization names
+// the organization the role is held in, and is empty for global roles.
+func (s *EmailService) SendRoleExpiryEmail(ctx contextx.Contextx, user *models.User, role, organization string, expiresAt time.Time) error {
+	subject := "Your role is about to expire"
+
+	body, err := s.generateRoleExpiryHTML(user.GetFullName(), role, organization, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return s.emailProvider.SendEmail(context.Background(), user.GetPrimaryEmail(), subject, body)
+}
 
 func (s *EmailService) generateEmailVerificationHTML(name, verificationURL string) (string, error) {
 	tmpl := `
@@ -224,6 +316,329 @@ func (s *EmailService) generatePasswordResetHTML(name, resetURL string) (string,
 	return buf.String(), nil
 }
 
+func (s *EmailService) generateMagicLinkHTML(name, loginURL string, ttl time.Duration) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Sign In to BezBase</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #007bff; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Sign In{{if .Name}}, {{.Name}}{{end}}</h2>
+            <p>We received a request to sign in to your BezBase account. Click the button below to sign in:</p>
+            <a href="{{.LoginURL}}" class="button">Sign In</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.LoginURL}}</p>
+            <p>This link can be used once, expires in {{.ExpiresIn}} and only works in the browser where you requested it.</p>
+            <p>If you didn't request this link, please ignore this email.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name      string
+		LoginURL  string
+		ExpiresIn string
+	}{
+		Name:      name,
+		LoginURL:  loginURL,
+		ExpiresIn: fmt.Sprintf("%d minutes", int(ttl.Minutes())),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (s *EmailService) generateAccountUnlockHTML(name, unlockURL string, lockedUntil time.Time) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Account Locked</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #007bff; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Account Locked{{if .Name}} for {{.Name}}{{end}}</h2>
+            <p>Your BezBase account has been temporarily locked after too many failed sign-in attempts. It will unlock automatically at {{.LockedUntil}}.</p>
+            <p>If these attempts were yours, you can unlock your account right away by clicking the button below:</p>
+            <a href="{{.UnlockURL}}" class="button">Unlock Account</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.UnlockURL}}</p>
+            <p>If you didn't try to sign in, someone may be guessing your password. We recommend changing it once your account is unlocked.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name        string
+		UnlockURL   string
+		LockedUntil string
+	}{
+		Name:        name,
+		UnlockURL:   unlockURL,
+		LockedUntil: lockedUntil.UTC().Format("2006-01-02 15:04 UTC"),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (s *EmailService) generateEmailChangeConfirmationHTML(name, newEmail, confirmURL string, ttl time.Duration) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Confirm Your New Email</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #007bff; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Hello{{if .Name}}, {{.Name}}{{end}}!</h2>
+            <p>We received a request to change the email address of your BezBase account to {{.NewEmail}}. The change takes effect once you confirm it by clicking the button below:</p>
+            <a href="{{.ConfirmURL}}" class="button">Confirm Email Address</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.ConfirmURL}}</p>
+            <p>This link will expire in {{.ExpiresIn}}.</p>
+            <p>If you didn't request this change, please ignore this email. Your account will keep its current address.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name       string
+		NewEmail   string
+		ConfirmURL string
+		ExpiresIn  string
+	}{
+		Name:       name,
+		NewEmail:   newEmail,
+		ConfirmURL: confirmURL,
+		ExpiresIn:  fmt.Sprintf("%d hours", int(ttl.Hours())),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (s *EmailService) generateEmailChangeNoticeHTML(name, newEmail, revertURL string, revertTTL time.Duration) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Email Change Requested</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #dc3545; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Email Change Requested{{if .Name}} for {{.Name}}{{end}}</h2>
+            <p>Someone asked to change the email address of your BezBase account to {{.NewEmail}}. The new address has to be confirmed before the change takes effect.</p>
+            <p>If this wasn't you, click the button below. It cancels the change, or restores this address and signs out every session if the change was already confirmed:</p>
+            <a href="{{.RevertURL}}" class="button">This Wasn't Me</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.RevertURL}}</p>
+            <p>This link will expire in {{.ExpiresIn}}. If you made this change, no action is needed.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name      string
+		NewEmail  string
+		RevertURL string
+		ExpiresIn string
+	}{
+		Name:      name,
+		NewEmail:  newEmail,
+		RevertURL: revertURL,
+		ExpiresIn: fmt.Sprintf("%d days", int(revertTTL.Hours()/24)),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (s *EmailService) generateLoginAlertHTML(name string, event *models.LoginEvent, reportURL string, reportTTL time.Duration) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>New Sign-in</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .details { background-color: #fff; border: 1px solid #ddd; padding: 15px; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #dc3545; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>New Sign-in{{if .Name}} for {{.Name}}{{end}}</h2>
+            <p>{{if .Suspicious}}Your BezBase account was signed in from a different network shortly after your previous sign-in.{{else}}Your BezBase account was signed in from a device we haven't seen before.{{end}}</p>
+            <div class="details">
+                <p><strong>Device:</strong> {{.Device}}</p>
+                <p><strong>IP address:</strong> {{.IPAddress}}</p>
+                <p><strong>Time:</strong> {{.Time}}</p>
+            </div>
+            <p>If this wasn't you, click the button below. It signs out every session, revokes your access tokens and disables your password until you reset it:</p>
+            <a href="{{.ReportURL}}" class="button">This Wasn't Me</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.ReportURL}}</p>
+            <p>This link will expire in {{.ExpiresIn}}. If this was you, no action is needed.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name       string
+		Suspicious bool
+		Device     string
+		IPAddress  string
+		Time       string
+		ReportURL  string
+		ExpiresIn  string
+	}{
+		Name:       name,
+		Suspicious: event.Suspicious,
+		Device:     fmt.Sprintf("%s on %s (%s)", event.Browser, event.OS, event.DeviceType),
+		IPAddress:  event.IPAddress,
+		Time:       event.CreatedAt.UTC().Format("January 2, 2006 at 15:04 MST"),
+		ReportURL:  reportURL,
+		ExpiresIn:  fmt.Sprintf("%d days", int(reportTTL.Hours()/24)),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
 func generateSecureToken() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -232,3 +647,139 @@ func generateSecureToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+func (s *EmailService) generateLinkedAccountHTML(name, method string, linked bool) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Sign-in Methods Changed</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 30px; background-color: #dc3545; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Sign-in Methods Changed{{if .Name}} for {{.Name}}{{end}}</h2>
+            {{if .Linked}}<p>{{.Method}} was added to your BezBase account and can now be used to sign in.</p>{{else}}<p>{{.Method}} was removed from your BezBase account and can no longer be used to sign in.</p>{{end}}
+            <p>If you didn't make this change, review the sign-in methods of your account and change your password right away:</p>
+            <a href="{{.ProfileURL}}" class="button">Review Sign-in Methods</a>
+            <p>If the button doesn't work, you can also copy and paste the following link into your browser:</p>
+            <p style="word-break: break-all; color: #007bff;">{{.ProfileURL}}</p>
+            <p>If you made this change, no action is needed.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name       string
+		Method     string
+		Linked     bool
+		ProfileURL string
+	}{
+		Name:       name,
+		Method:     method,
+		Linked:     linked,
+		ProfileURL: fmt.Sprintf("%s/profile", s.baseURL),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// linkedAccountLabel names a sign-in method for notification emails
+func linkedAccountLabel(provider models.AuthProviderType) string {
+	switch provider {
+	case models.ProviderEmail:
+		return "A password"
+	case models.ProviderPasskey:
+		return "Passkey sign-in"
+	case models.ProviderSAML:
+		return "Single sign-on"
+	case models.ProviderLDAP:
+		return "Directory (LDAP) sign-in"
+	default:
+		return "Your " + string(provider) + " account"
+	}
+}
+
+func (s *EmailService) generateRoleExpiryHTML(name, role, organization string, expiresAt time.Time) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Role Expiring</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #007bff; color: white; text-align: center; padding: 20px; }
+        .content { padding: 30px; background-color: #f9f9f9; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>BezBase</h1>
+        </div>
+        <div class="content">
+            <h2>Role Expiring{{if .Name}} for {{.Name}}{{end}}</h2>
+            <p>Your <strong>{{.Role}}</strong> role{{if .Organization}} in {{.Organization}}{{end}} expires at {{.ExpiresAt}}. After that, you will lose the access it grants.</p>
+            <p>If you still need this access, ask an administrator to extend the assignment before it expires.</p>
+        </div>
+        <div class="footer">
+            <p>© 2024 BezBase. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Name         string
+		Role         string
+		Organization string
+		ExpiresAt    string
+	}{
+		Name:         name,
+		Role:         role,
+		Organization: organization,
+		ExpiresAt:    expiresAt.UTC().Format("2006-01-02 15:04 UTC"),
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"bezbase/internal/config"
+	"bezbase/internal/database"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "Correct-Horse-9-Battery"
+
+// testEnv wires the services the way cmd/main.go does, on a throwaway SQLite database
+type testEnv struct {
+	cfg  *config.Config
+	db   *gorm.DB
+	mail *recordingEmailProvider
+
+	userRepo         repository.UserRepository
+	userInfoRepo     repository.UserInfoRepository
+	authProviderRepo repository.AuthProviderRepository
+	sessionRepo      repository.SessionRepository
+	webAuthnRepo     repository.WebAuthnRepository
+
+	rbacService         *RBACService
+	loginHistoryService *LoginHistoryService
+	sessionService      *SessionService
+	lockoutService      *LockoutService
+	mfaService          *MFAService
+	ldapService         *LDAPService
+	authService         *AuthService
+	passkeyService      *PasskeyService
+	oauthService        *OAuthService
+	oidcProviderService *OIDCProviderService
+	samlService         *SAMLService
+	emailChangeService  *EmailChangeService
+	userService         *UserService
+}
+
+// newTestEnv builds the services on a fresh database. configure may adjust the configuration
+// before anything is constructed from it.
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.Load()
+	cfg.Auth.JWTKeys.Source = "database"
+	cfg.WebAuthn.RPID = "example.com"
+	cfg.WebAuthn.Origins = []string{"https://example.com"}
+	cfg.Auth.LDAP.Enabled = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	// A file rather than ":memory:", which would give every pooled connection its own empty database
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "bezbase.db")+"?_busy_timeout=5000&_journal_mode=WAL"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	// The initial schema leaves the RBAC tables to the Postgres-only migrations
+	if err := db.AutoMigrate(&models.Role{}, &models.RoleInheritance{}, &models.ContextualPermission{}); err != nil {
+		t.Fatalf("AutoMigrate RBAC tables: %v", err)
+	}
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	jwtKeys := auth.NewKeySet(signingKey)
+	mail := &recordingEmailProvider{}
+
+	env := &testEnv{
+		cfg:              cfg,
+		db:               db,
+		mail:             mail,
+		userRepo:         repository.NewUserRepository(db),
+		userInfoRepo:     repository.NewUserInfoRepository(db),
+		authProviderRepo: repository.NewAuthProviderRepository(db),
+		sessionRepo:      repository.NewSessionRepository(db),
+		webAuthnRepo:     repository.NewWebAuthnRepository(db),
+	}
+
+	env.rbacService, err = NewRBACService(repository.NewRoleRepository(db), repository.NewRuleRepository(db), repository.NewRoleAssignmentRepository(db), db)
+	if err != nil {
+		t.Fatalf("NewRBACService: %v", err)
+	}
+	passwordPolicy, err := NewPasswordPolicyService(repository.NewPasswordHistoryRepository(db), &cfg.Auth.PasswordPolicy)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicyService: %v", err)
+	}
+	env.ldapService, err = NewLDAPService(env.rbacService, &cfg.Auth.LDAP)
+	if err != nil {
+		t.Fatalf("NewLDAPService: %v", err)
+	}
+
+	emailService := &EmailService{
+		verificationRepo: repository.NewEmailVerificationRepository(db),
+		emailProvider:    mail,
+		baseURL:          cfg.Server.BaseURL,
+	}
+	env.loginHistoryService = NewLoginHistoryService(repository.NewLoginEventRepository(db), env.userRepo, env.sessionRepo, env.authProviderRepo,
+		repository.NewPersonalAccessTokenRepository(db), repository.NewPasswordResetRepository(db), repository.NewOIDCGrantRepository(db),
+		repository.NewOIDCTokenRepository(db), env.webAuthnRepo, emailService, &cfg.Auth.LoginAlerts, db)
+	env.sessionService = NewSessionService(env.sessionRepo, env.userRepo, env.loginHistoryService, jwtKeys, &cfg.Auth)
+	env.lockoutService = NewLockoutService(repository.NewAccountLockoutRepository(db), env.userRepo, emailService, &cfg.Auth.Lockout)
+	env.mfaService = NewMFAService(repository.NewMFARepository(db), env.userRepo, env.sessionService, env.lockoutService, jwtKeys, &cfg.Auth)
+	env.passkeyService = NewPasskeyService(env.webAuthnRepo, env.authProviderRepo, env.userRepo, env.sessionService, &cfg.WebAuthn)
+	env.authService = NewAuthService(env.userRepo, env.userInfoRepo, env.authProviderRepo, env.sessionService, env.mfaService,
+		env.lockoutService, passwordPolicy, env.ldapService, emailService, &cfg.Auth, db)
+	env.oauthService = NewOAuthService(env.authService, env.userInfoRepo, env.authProviderRepo, &cfg.OAuth, &cfg.Auth)
+	env.oidcProviderService = NewOIDCProviderService(repository.NewOIDCClientRepository(db), repository.NewOIDCGrantRepository(db),
+		repository.NewOIDCAuthorizationCodeRepository(db), repository.NewOIDCTokenRepository(db), env.userRepo, env.sessionRepo, jwtKeys, &cfg.IdentityProvider)
+	env.samlService, err = NewSAMLService(env.authService, env.authProviderRepo, repository.NewSAMLProviderRepository(db), &cfg.SAML, &cfg.Auth)
+	if err != nil {
+		t.Fatalf("NewSAMLService: %v", err)
+	}
+
+	env.emailChangeService = NewEmailChangeService(repository.NewEmailChangeRepository(db), env.userRepo, env.userInfoRepo, env.authProviderRepo,
+		emailService, env.sessionService, &cfg.Auth.EmailChange, db)
+	env.userService = NewUserService(env.userRepo, env.userInfoRepo, env.authProviderRepo, env.rbacService, env.sessionService, passwordPolicy,
+		env.emailChangeService, db)
+
+	return env
+}
+
+// registerUser signs up a local user with testPassword
+func (env *testEnv) registerUser(t *testing.T, username, email string) *models.User {
+	t.Helper()
+
+	ctx := contextx.Background()
+	resp, err := env.authService.Register(ctx, dto.RegisterRequest{
+		Username:  username,
+		Email:     email,
+		Password:  testPassword,
+		FirstName: "Test",
+		LastName:  "User",
+	})
+	if err != nil {
+		t.Fatalf("Register %s: %v", username, err)
+	}
+
+	user, err := env.userRepo.GetByIDWithPreload(ctx, resp.User.ID, "UserInfo")
+	if err != nil {
+		t.Fatalf("GetByIDWithPreload: %v", err)
+	}
+	return user
+}
+
+// recordingEmailProvider keeps sent emails instead of delivering them
+type recordingEmailProvider struct {
+	mu   sync.Mutex
+	sent []sentEmail
+}
+
+type sentEmail struct {
+	To, Subject, Body string
+}
+
+func (p *recordingEmailProvider) SendEmail(ctx context.Context, to, subject, body string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, sentEmail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+func (p *recordingEmailProvider) IsConfigured() bool {
+	return true
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// ImpersonationService lets admins act as another user for support. Impersonation runs in a
+// dedicated, non-refreshable session of the target user that remembers the admin and the admin
+// session to return to.
+type ImpersonationService struct {
+	sessionRepo    repository.SessionRepository
+	userRepo       repository.UserRepository
+	sessionService *SessionService
+	rbacService    *RBACService
+	keys           *auth.KeySet
+	authConfig     *config.AuthConfig
+}
+
+func NewImpersonationService(
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+	sessionService *SessionService,
+	rbacService *RBACService,
+	keys *auth.KeySet,
+	authConfig *config.AuthConfig,
+) *ImpersonationService {
+	return &ImpersonationService{
+		sessionRepo:    sessionRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		rbacService:    rbacService,
+		keys:           keys,
+		authConfig:     authConfig,
+	}
+}
+
+// Start opens an impersonation session for the target user on behalf of the admin identified by
+// claims. The returned token has no refresh token and expires with the session.
+func (s *ImpersonationService) Start(ctx contextx.Contextx, claims *auth.Claims, targetUserID uint) (*dto.AuthResponse, error) {
+	if claims.IsImpersonated() {
+		return nil, errors.New("already impersonating")
+	}
+	if claims.UserID == targetUserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	target, err := s.userRepo.GetByIDWithPreload(ctx, targetUserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Admins who may impersonate are out of reach, so impersonation cannot be chained into them
+	canImpersonate, err := s.rbacService.CheckPermission(target.ID, models.PermissionImpersonateUsers.Resource.String(), models.PermissionImpersonateUsers.Action.String())
+	if err != nil {
+		return nil, err
+	}
+	if canImpersonate {
+		return nil, errors.New("cannot impersonate admin")
+	}
+
+	// The refresh token is never handed out; it only satisfies the unique column
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.authConfig.ImpersonationTTL)
+	userAgent, ipAddress := requestClientInfo(ctx)
+	impersonatorID := claims.UserID
+	impersonatorSessionID := claims.SessionID
+	session := models.Session{
+		UserID:                target.ID,
+		RefreshTokenHash:      auth.HashToken(refreshToken),
+		UserAgent:             userAgent,
+		IPAddress:             ipAddress,
+		ExpiresAt:             expiresAt,
+		LastUsedAt:            now,
+		ImpersonatorID:        &impersonatorID,
+		ImpersonatorSessionID: &impersonatorSessionID,
+	}
+	if err := s.sessionRepo.Create(ctx, &session); err != nil {
+		return nil, err
+	}
+
+	token, err := auth.GenerateImpersonationToken(target.ID, target.GetPrimaryEmail(), session.ID, impersonatorID, s.keys, s.authConfig.ImpersonationTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	log.Printf("Impersonation started: user %d as user %d (session %d, ip %s)", impersonatorID, target.ID, session.ID, ipAddress)
+
+	userResponse := dto.ToUserResponse(target)
+	return &dto.AuthResponse{
+		Token:          token,
+		ExpiresAt:      &expiresAt,
+		ImpersonatorID: impersonatorID,
+		User:           &userResponse,
+	}, nil
+}
+
+// Stop ends the impersonation session and returns a new access token for the admin's original session
+func (s *ImpersonationService) Stop(ctx contextx.Contextx, claims *auth.Claims) (*dto.AuthResponse, error) {
+	if !claims.IsImpersonated() {
+		return nil, errors.New("not impersonating")
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+	if err != nil || !session.IsImpersonation() {
+		return nil, errors.New("not impersonating")
+	}
+	if err := s.sessionRepo.Revoke(ctx, session.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Impersonation stopped: user %d as user %d (session %d)", *session.ImpersonatorID, session.UserID, session.ID)
+
+	if session.ImpersonatorSessionID == nil {
+		return nil, errors.New("session revoked")
+	}
+	return s.sessionService.ResumeSession(ctx, *session.ImpersonatorID, *session.ImpersonatorSessionID)
+}
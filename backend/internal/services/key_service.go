@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+const (
+	KeySourceDatabase = "database"
+	KeySourceFile     = "file"
+)
+
+// KeyService loads JWT signing keys into a KeySet and rotates them
+type KeyService struct {
+	signingKeyRepo repository.SigningKeyRepository
+	keys           *auth.KeySet
+	keysConfig     *config.JWTKeysConfig
+}
+
+func NewKeyService(
+	signingKeyRepo repository.SigningKeyRepository,
+	keys *auth.KeySet,
+	keysConfig *config.JWTKeysConfig,
+) *KeyService {
+	return &KeyService{
+		signingKeyRepo: signingKeyRepo,
+		keys:           keys,
+		keysConfig:     keysConfig,
+	}
+}
+
+// Load rotates keys if due and loads them into the key set. It fails when no key can sign.
+func (s *KeyService) Load(ctx contextx.Contextx) error {
+	switch s.keysConfig.Source {
+	case KeySourceFile:
+		if err := s.loadFromDir(); err != nil {
+			return err
+		}
+	case KeySourceDatabase:
+		if err := s.Rotate(ctx); err != nil {
+			return err
+		}
+		if err := s.loadFromDatabase(ctx); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown JWT key source %q", s.keysConfig.Source)
+	}
+
+	if _, err := s.keys.SigningKey(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start reloads keys and performs scheduled rotation in the background
+func (s *KeyService) Start() {
+	if s.keysConfig.RefreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.keysConfig.RefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Load(contextx.Background()); err != nil {
+				log.Printf("Failed to refresh JWT signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Rotate generates a new signing key when none exists or the current one is older than the
+// rotation interval. The new key is published one refresh interval before it starts signing so
+// that every instance has loaded it by then; older keys stay valid for the grace period after
+// it takes over.
+func (s *KeyService) Rotate(ctx contextx.Contextx) error {
+	if s.keysConfig.Source != KeySourceDatabase {
+		return errors.New("key rotation requires the database key source")
+	}
+
+	if err := s.signingKeyRepo.DeleteExpired(ctx); err != nil {
+		return err
+	}
+
+	stored, err := s.signingKeyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	activatesAt := now
+	if len(stored) > 0 {
+		newest := stored[0]
+		if s.keysConfig.RotationInterval <= 0 || now.Sub(newest.ActivatesAt) < s.keysConfig.RotationInterval {
+			return nil
+		}
+		activatesAt = now.Add(s.keysConfig.RefreshInterval)
+	}
+
+	key, err := auth.GenerateSigningKey(s.keysConfig.Algorithm)
+	if err != nil {
+		return err
+	}
+	privatePEM, publicPEM, err := auth.EncodeKeyPEM(key)
+	if err != nil {
+		return err
+	}
+
+	record := models.SigningKey{
+		KID:         key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  privatePEM,
+		PublicKey:   publicPEM,
+		ActivatesAt: activatesAt,
+	}
+	if err := s.signingKeyRepo.Create(ctx, &record); err != nil {
+		return err
+	}
+
+	return s.signingKeyRepo.ExpireOthers(ctx, record.ID, activatesAt.Add(s.keysConfig.GracePeriod))
+}
+
+// JWKS returns the public keys currently accepted for verification
+func (s *KeyService) JWKS() auth.JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *KeyService) loadFromDatabase(ctx contextx.Contextx) error {
+	stored, err := s.signingKeyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*auth.SigningKey, 0, len(stored))
+	for _, record := range stored {
+		key, err := auth.ParseKeyPEM(record.KID, []byte(record.PrivateKey))
+		if err != nil {
+			log.Printf("Skipping unreadable JWT signing key %s: %v", record.KID, err)
+			continue
+		}
+		key.ActivatesAt = record.ActivatesAt
+		key.ExpiresAt = record.ExpiresAt
+		keys = append(keys, key)
+	}
+
+	s.keys.Replace(keys)
+	return nil
+}
+
+// loadFromDir reads <kid>.pem files. Private keys can sign; public keys only verify, which is how
+// a replaced key is kept during its grace period. Keys are accepted until their file is removed.
+func (s *KeyService) loadFromDir() error {
+	paths, err := filepath.Glob(filepath.Join(s.keysConfig.Dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	keys := make([]*auth.SigningKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := auth.ParseKeyPEM(kid, data)
+		if err != nil {
+			return fmt.Errorf("failed to load JWT key %s: %w", path, err)
+		}
+
+		// The configured signing key goes first; otherwise the last private key by name signs
+		if kid == s.keysConfig.SigningKID {
+			keys = append([]*auth.SigningKey{key}, keys...)
+		} else {
+			if s.keysConfig.SigningKID != "" {
+				key.PrivateKey = nil
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	s.keys.Replace(keys)
+	return nil
+}
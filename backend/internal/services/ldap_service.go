@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sort"
+
+	"bezbase/internal/config"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/ldap"
+)
+
+// LDAPService authenticates users against an LDAP or Active Directory server and keeps the roles
+// mapped from directory groups in sync
+type LDAPService struct {
+	directory   *ldap.Directory // nil when LDAP is disabled
+	rbacService *RBACService
+	ldapConfig  *config.LDAPConfig
+}
+
+func NewLDAPService(rbacService *RBACService, ldapConfig *config.LDAPConfig) (*LDAPService, error) {
+	service := &LDAPService{
+		rbacService: rbacService,
+		ldapConfig:  ldapConfig,
+	}
+	if !ldapConfig.Enabled {
+		return service, nil
+	}
+
+	attributes := make([]string, 0, len(ldapConfig.AttributeMapping))
+	for _, attribute := range ldapConfig.AttributeMapping {
+		attributes = append(attributes, attribute)
+	}
+	directory, err := ldap.NewDirectory(ldap.Config{
+		URL:                ldapConfig.URL,
+		StartTLS:           ldapConfig.StartTLS,
+		InsecureSkipVerify: ldapConfig.InsecureSkipVerify,
+		CACertFile:         ldapConfig.CACertFile,
+		BindDN:             ldapConfig.BindDN,
+		BindPassword:       ldapConfig.BindPassword,
+		BaseDN:             ldapConfig.BaseDN,
+		UserFilter:         ldapConfig.UserFilter,
+		GroupBaseDN:        ldapConfig.GroupBaseDN,
+		GroupFilter:        ldapConfig.GroupFilter,
+		GroupAttribute:     ldapConfig.GroupAttribute,
+		IDAttribute:        ldapConfig.IDAttribute,
+		Attributes:         attributes,
+		Timeout:            ldapConfig.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	service.directory = directory
+	return service, nil
+}
+
+// Enabled reports whether logins may be checked against the directory
+func (s *LDAPService) Enabled() bool {
+	return s.directory != nil
+}
+
+// Authenticate verifies the credentials with a directory bind and returns the directory entry
+func (s *LDAPService) Authenticate(username, password string) (*ldap.Entry, error) {
+	if s.directory == nil {
+		return nil, errors.New("invalid credentials")
+	}
+	entry, err := s.directory.Authenticate(username, password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, errors.New("invalid credentials")
+		}
+		log.Printf("LDAP authentication for %q failed: %v", username, err)
+		return nil, errors.New("ldap unavailable")
+	}
+	return entry, nil
+}
+
+// Attributes returns the entry's mapped attributes keyed by UserInfo field name
+func (s *LDAPService) Attributes(entry *ldap.Entry) map[string]string {
+	attributes := make(map[string]string, len(s.ldapConfig.AttributeMapping))
+	for field, attribute := range s.ldapConfig.AttributeMapping {
+		if value, ok := entry.Attributes[attribute]; ok {
+			attributes[field] = value
+		}
+	}
+	return attributes
+}
+
+// SyncRoles grants the roles mapped from the user's groups and removes mapped roles the user no
+// longer qualifies for. Roles that do not appear in the group mapping are left alone, so roles
+// assigned by hand survive. A role that cannot be removed fails the sync so that a login never
+// proceeds with privileges the directory revoked.
+func (s *LDAPService) SyncRoles(userID uint, groups []string) error {
+	if len(s.ldapConfig.GroupRoles) == 0 {
+		return nil
+	}
+
+	desired := make(map[string]bool)
+	var managed []string
+	for groupDN, role := range s.ldapConfig.GroupRoles {
+		if !slices.Contains(managed, role) {
+			managed = append(managed, role)
+		}
+		for _, group := range groups {
+			if ldap.SameDN(group, groupDN) {
+				desired[role] = true
+			}
+		}
+	}
+	sort.Strings(managed)
+
+	// Directory users get the default role like everyone else before mapped roles are added
+	if err := s.rbacService.AssignDefaultRoleToUser(contextx.Background(), userID); err != nil {
+		return err
+	}
+	current, err := s.rbacService.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	for _, role := range managed {
+		has := slices.Contains(current, role)
+		switch {
+		case desired[role] && !has:
+			if err := s.rbacService.AssignRoleToUser(userID, role, 0); err != nil {
+				log.Printf("Failed to assign LDAP group role %q to user %d: %v", role, userID, err)
+			}
+		case !desired[role] && has:
+			if err := s.rbacService.RemoveRoleFromUser(userID, role, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/ldap/ldaptest"
+)
+
+const (
+	ldapServiceDN = "cn=bezbase,ou=services,dc=example,dc=com"
+	ldapUserDN    = "uid=jane,ou=people,dc=example,dc=com"
+	engineersDN   = "cn=engineers,ou=groups,dc=example,dc=com"
+	opsDN         = "cn=ops,ou=groups,dc=example,dc=com"
+)
+
+// ldapUser is jane's directory entry with the given group DNs
+func ldapUser(groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       ldapUserDN,
+		Password: "jane-secret",
+		Attributes: map[string][]string{
+			"uid":       {"jane"},
+			"mail":      {"jane@example.com"},
+			"givenName": {"Jane"},
+			"sn":        {"Doe"},
+			"entryUUID": {"6f1c2b9e-5d0a-4c43-9b8e-7d1a3e2f4c5b"},
+			"memberOf":  groups,
+		},
+	}
+}
+
+// newLDAPTestEnv enables LDAP logins against an in-process directory holding entries
+func newLDAPTestEnv(t *testing.T, entries ...ldaptest.Entry) (*testEnv, *ldaptest.Server) {
+	t.Helper()
+	server, err := ldaptest.Start(append([]ldaptest.Entry{{DN: ldapServiceDN, Password: "service-secret"}}, entries...)...)
+	if err != nil {
+		t.Fatalf("ldaptest.Start: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.LDAP = config.LDAPConfig{
+			Enabled:        true,
+			URL:            server.URL,
+			BindDN:         ldapServiceDN,
+			BindPassword:   "service-secret",
+			BaseDN:         "ou=people,dc=example,dc=com",
+			UserFilter:     "(uid=%s)",
+			GroupAttribute: "memberOf",
+			IDAttribute:    "entryUUID",
+			AttributeMapping: map[string]string{
+				"email":      "mail",
+				"first_name": "givenName",
+				"last_name":  "sn",
+			},
+			GroupRoles: map[string]string{
+				engineersDN: "engineer",
+				opsDN:       "operator",
+			},
+			Timeout: 5 * time.Second,
+		}
+	})
+	return env, server
+}
+
+func TestLDAPLoginSyncsGroupRoles(t *testing.T) {
+	env, server := newLDAPTestEnv(t, ldapUser(engineersDN))
+	ctx := contextx.Background()
+	env.createRole(t, "engineer", "deployments", "read")
+	env.createRole(t, "operator", "deployments", "write")
+	env.createRole(t, "auditor", "reports", "read")
+
+	login := func() uint {
+		t.Helper()
+		resp, err := env.authService.LoginWithUsername(ctx, dto.LoginRequest{Username: "jane", Password: "jane-secret"})
+		if err != nil {
+			t.Fatalf("LoginWithUsername: %v", err)
+		}
+		return resp.User.ID
+	}
+	assertRoles := func(userID uint, want ...string) {
+		t.Helper()
+		roles, err := env.rbacService.GetUserRoles(userID)
+		if err != nil {
+			t.Fatalf("GetUserRoles: %v", err)
+		}
+		slices.Sort(roles)
+		slices.Sort(want)
+		if !slices.Equal(roles, want) {
+			t.Errorf("roles = %v, want %v", roles, want)
+		}
+	}
+
+	// First login provisions the account from the directory entry
+	userID := login()
+	user, err := env.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		t.Fatalf("GetByIDWithPreload: %v", err)
+	}
+	if user.UserInfo.Email != "jane@example.com" || user.UserInfo.FirstName != "Jane" || user.UserInfo.LastName != "Doe" {
+		t.Errorf("profile = %s %s <%s>, want Jane Doe <jane@example.com>", user.UserInfo.FirstName, user.UserInfo.LastName, user.UserInfo.Email)
+	}
+	if link, err := env.authProviderRepo.GetByUserIDAndProvider(ctx, userID, models.ProviderLDAP); err != nil || link.ProviderID != "6f1c2b9e-5d0a-4c43-9b8e-7d1a3e2f4c5b" {
+		t.Errorf("ldap link = %v, %v; want linked by entryUUID", link, err)
+	}
+	assertRoles(userID, "user", "engineer")
+
+	// A role assigned by hand is not managed by the group mapping
+	if err := env.rbacService.AssignRoleToUser(userID, "auditor", 0); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	// Moved from engineers to ops in the directory
+	server.Put(ldapUser(opsDN))
+	if login() != userID {
+		t.Fatal("returning directory user signed in to another account")
+	}
+	assertRoles(userID, "user", "auditor", "operator")
+
+	// Group DNs are compared regardless of case and spacing
+	server.Put(ldapUser("CN=Engineers, OU=Groups, DC=example, DC=com", opsDN))
+	login()
+	assertRoles(userID, "user", "auditor", "engineer", "operator")
+
+	// Leaving every mapped group removes the mapped roles only
+	server.Put(ldapUser())
+	login()
+	assertRoles(userID, "user", "auditor")
+}
+
+func TestLDAPLoginRejectsBadCredentials(t *testing.T) {
+	env, _ := newLDAPTestEnv(t, ldapUser(engineersDN))
+	ctx := contextx.Background()
+
+	for name, req := range map[string]dto.LoginRequest{
+		"wrong password":     {Username: "jane", Password: "wrong"},
+		"empty password":     {Username: "jane", Password: ""},
+		"filter wildcard":    {Username: "*", Password: "jane-secret"},
+		"filter injection":   {Username: "*)(uid=*", Password: "jane-secret"},
+		"unknown login name": {Username: "john", Password: "jane-secret"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := env.authService.LoginWithUsername(ctx, req); err == nil || err.Error() != "invalid credentials" {
+				t.Errorf("LoginWithUsername error = %v, want invalid credentials", err)
+			}
+		})
+	}
+	var links int64
+	env.db.Model(&models.AuthProvider{}).Where("provider = ?", models.ProviderLDAP).Count(&links)
+	if links != 0 {
+		t.Errorf("%d directory accounts provisioned by rejected logins, want 0", links)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LinkedAccountService lets users manage the sign-in methods attached to their account: the
+// password, passkeys and linked social or enterprise accounts
+type LinkedAccountService struct {
+	authProviderRepo repository.AuthProviderRepository
+	userRepo         repository.UserRepository
+	passwordPolicy   *PasswordPolicyService
+	emailService     *EmailService
+}
+
+func NewLinkedAccountService(
+	authProviderRepo repository.AuthProviderRepository,
+	userRepo repository.UserRepository,
+	passwordPolicy *PasswordPolicyService,
+	emailService *EmailService,
+) *LinkedAccountService {
+	return &LinkedAccountService{
+		authProviderRepo: authProviderRepo,
+		userRepo:         userRepo,
+		passwordPolicy:   passwordPolicy,
+		emailService:     emailService,
+	}
+}
+
+// ListLinkedAccounts returns the sign-in methods of the user
+func (s *LinkedAccountService) ListLinkedAccounts(ctx contextx.Contextx, userID uint) ([]models.AuthProvider, error) {
+	return s.authProviderRepo.GetByUserID(ctx, userID)
+}
+
+// Unlink removes a sign-in method. Unlinking the passkey provider deletes every passkey and
+// unlinking the email provider removes the password. The last usable method cannot be removed.
+func (s *LinkedAccountService) Unlink(ctx contextx.Contextx, userID uint, provider models.AuthProviderType) error {
+	if _, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, userID, provider); err != nil {
+		return errors.New("linked account not found")
+	}
+
+	hasOther, err := hasOtherCredential(ctx, s.authProviderRepo, userID, provider)
+	if err != nil {
+		return err
+	}
+	if !hasOther {
+		return errors.New("last sign-in method")
+	}
+
+	if err := s.authProviderRepo.DeleteByUserIDAndProvider(ctx, userID, provider); err != nil {
+		return err
+	}
+	notifyLinkedAccountChange(s.userRepo, s.emailService, userID, provider, false)
+	return nil
+}
+
+// SetPassword adds a password to an account that has none, e.g. one created through social login.
+// Accounts with a password change it with the current one instead.
+func (s *LinkedAccountService) SetPassword(ctx contextx.Contextx, userID uint, password string) error {
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	provider, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, userID, models.ProviderEmail)
+	if err == nil && provider.Password != "" {
+		return errors.New("password already set")
+	}
+
+	userInputs := []string{user.GetPrimaryEmail()}
+	if user.UserInfo != nil {
+		userInputs = append(userInputs, user.UserInfo.Username, user.UserInfo.FirstName, user.UserInfo.LastName)
+	}
+	if err := s.passwordPolicy.Validate(ctx, userID, password, userInputs...); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	// A password disabled after a reported login keeps its row, only the hash is restored
+	if provider != nil {
+		provider.Password = string(hashedPassword)
+		if err := s.authProviderRepo.Update(ctx, provider); err != nil {
+			return err
+		}
+	} else {
+		userName := user.GetPrimaryEmail()
+		if user.UserInfo != nil && user.UserInfo.Username != "" {
+			userName = user.UserInfo.Username
+		}
+		if err := s.authProviderRepo.Create(ctx, &models.AuthProvider{
+			UserID:     userID,
+			Provider:   models.ProviderEmail,
+			ProviderID: user.GetPrimaryEmail(),
+			UserName:   userName,
+			Password:   string(hashedPassword),
+			Verified:   user.EmailVerified,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.passwordPolicy.Record(ctx, userID, string(hashedPassword)); err != nil {
+		log.Printf("Failed to record password history for user %d: %v", userID, err)
+	}
+	notifyLinkedAccountChange(s.userRepo, s.emailService, userID, models.ProviderEmail, true)
+	return nil
+}
+
+// hasOtherCredential reports whether the user can still sign in without the given provider.
+// An email provider only counts while it has a password.
+func hasOtherCredential(ctx contextx.Contextx, authProviderRepo repository.AuthProviderRepository, userID uint, except models.AuthProviderType) (bool, error) {
+	providers, err := authProviderRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range providers {
+		if p.Provider == except {
+			continue
+		}
+		if p.Provider == models.ProviderEmail && p.Password == "" {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// notifyLinkedAccountChange emails the user about an added or removed sign-in method in the background
+func notifyLinkedAccountChange(userRepo repository.UserRepository, emailService *EmailService, userID uint, provider models.AuthProviderType, linked bool) {
+	go func() {
+		user, err := userRepo.GetByIDWithPreload(contextx.Background(), userID, "UserInfo")
+		if err != nil {
+			log.Printf("Failed to load user %d for linked account notice: %v", userID, err)
+			return
+		}
+		if err := emailService.SendLinkedAccountEmail(contextx.Background(), user, provider, linked); err != nil {
+			log.Printf("Failed to send linked account notice to user %d: %v", userID, err)
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// LockoutError is returned while an account refuses login attempts
+type LockoutError struct {
+	Locked     bool // The failure threshold was reached; otherwise this is a backoff delay
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	if e.Locked {
+		return "account locked"
+	}
+	return "too many login attempts"
+}
+
+// LockoutService slows down and locks password guessing against a single account,
+// independently of the client IP
+type LockoutService struct {
+	lockoutRepo   repository.AccountLockoutRepository
+	userRepo      repository.UserRepository
+	emailService  *EmailService
+	lockoutConfig *config.LockoutConfig
+}
+
+func NewLockoutService(
+	lockoutRepo repository.AccountLockoutRepository,
+	userRepo repository.UserRepository,
+	emailService *EmailService,
+	lockoutConfig *config.LockoutConfig,
+) *LockoutService {
+	return &LockoutService{
+		lockoutRepo:   lockoutRepo,
+		userRepo:      userRepo,
+		emailService:  emailService,
+		lockoutConfig: lockoutConfig,
+	}
+}
+
+// Check returns a LockoutError when the account currently refuses login attempts
+func (s *LockoutService) Check(ctx contextx.Contextx, userID uint) error {
+	lockout, err := s.lockoutRepo.GetByUserID(ctx, userID)
+	if err != nil || !lockout.IsBlocked() {
+		return nil
+	}
+	return &LockoutError{
+		Locked:     lockout.LockedAt != nil,
+		RetryAfter: time.Until(*lockout.BlockedUntil).Round(time.Second),
+	}
+}
+
+// RecordFailure counts a failed password attempt and applies the backoff delay or the lock
+func (s *LockoutService) RecordFailure(ctx contextx.Contextx, userID uint) {
+	now := time.Now()
+	_, ipAddress := requestClientInfo(ctx)
+	lockout, err := s.lockoutRepo.RecordFailure(ctx, userID, ipAddress, now.Add(-s.lockoutConfig.Duration))
+	if err != nil {
+		log.Printf("Failed to record failed login for user %d: %v", userID, err)
+		return
+	}
+
+	switch {
+	case s.lockoutConfig.Threshold > 0 && lockout.FailedAttempts >= s.lockoutConfig.Threshold:
+		s.lock(ctx, lockout, now)
+		return
+	case lockout.FailedAttempts > s.lockoutConfig.FreeAttempts:
+		blockedUntil := now.Add(s.backoff(lockout.FailedAttempts))
+		lockout.BlockedUntil = &blockedUntil
+		lockout.LockedAt = nil
+	default:
+		lockout.BlockedUntil = nil
+		lockout.LockedAt = nil
+	}
+
+	if err := s.lockoutRepo.Update(ctx, lockout); err != nil {
+		log.Printf("Failed to update lockout for user %d: %v", userID, err)
+	}
+}
+
+// RecordSuccess clears the failure counter after a successful password login
+func (s *LockoutService) RecordSuccess(ctx contextx.Contextx, userID uint) {
+	if err := s.lockoutRepo.DeleteByUserID(ctx, userID); err != nil {
+		log.Printf("Failed to reset lockout for user %d: %v", userID, err)
+	}
+}
+
+// Unlock lifts a lock using the token from the unlock email
+func (s *LockoutService) Unlock(ctx contextx.Contextx, token string) error {
+	if token == "" {
+		return errors.New("invalid unlock token")
+	}
+	lockout, err := s.lockoutRepo.GetByUnlockTokenHash(ctx, auth.HashToken(token))
+	if err != nil || lockout.UnlockTokenExpiresAt == nil || time.Now().After(*lockout.UnlockTokenExpiresAt) {
+		return errors.New("invalid unlock token")
+	}
+	return s.lockoutRepo.DeleteByUserID(ctx, lockout.UserID)
+}
+
+// UnlockUser lifts any lock or backoff on an account (admin)
+func (s *LockoutService) UnlockUser(ctx contextx.Contextx, userID uint) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return errors.New("user not found")
+	}
+	return s.lockoutRepo.DeleteByUserID(ctx, userID)
+}
+
+// ListLocked returns accounts that are currently locked
+func (s *LockoutService) ListLocked(ctx contextx.Contextx) ([]models.AccountLockout, error) {
+	return s.lockoutRepo.ListLocked(ctx)
+}
+
+// backoff doubles the delay for every failure beyond the free attempts, up to the maximum
+func (s *LockoutService) backoff(failedAttempts int) time.Duration {
+	delay := s.lockoutConfig.BackoffBase
+	for i := s.lockoutConfig.FreeAttempts + 1; i < failedAttempts && delay < s.lockoutConfig.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.lockoutConfig.BackoffMax {
+		delay = s.lockoutConfig.BackoffMax
+	}
+	return delay
+}
+
+// lock blocks the account for the lock duration and emails the owner an unlock link
+func (s *LockoutService) lock(ctx contextx.Contextx, lockout *models.AccountLockout, now time.Time) {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Failed to generate unlock token for user %d: %v", lockout.UserID, err)
+		return
+	}
+
+	blockedUntil := now.Add(s.lockoutConfig.Duration)
+	lockout.BlockedUntil = &blockedUntil
+	lockout.LockedAt = &now
+	lockout.UnlockTokenHash = auth.HashToken(token)
+	lockout.UnlockTokenExpiresAt = &blockedUntil
+	if err := s.lockoutRepo.Update(ctx, lockout); err != nil {
+		log.Printf("Failed to lock user %d: %v", lockout.UserID, err)
+		return
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, lockout.UserID, "UserInfo")
+	if err != nil {
+		return
+	}
+	if err := s.emailService.SendAccountUnlockEmail(ctx, user, token, blockedUntil); err != nil {
+		log.Printf("Failed to send unlock email to user %d: %v", lockout.UserID, err)
+	}
+}
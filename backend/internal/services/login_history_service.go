@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/useragent"
+	"bezbase/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// loginReportResetTTL is how long the password reset link sent after a "this wasn't me" report is valid
+const loginReportResetTTL = time.Hour
+
+// LoginHistoryService records successful logins and alerts users about logins from unseen devices
+// or from another network sooner than they could plausibly have moved
+type LoginHistoryService struct {
+	loginEventRepo    repository.LoginEventRepository
+	userRepo          repository.UserRepository
+	sessionRepo       repository.SessionRepository
+	authProviderRepo  repository.AuthProviderRepository
+	tokenRepo         repository.PersonalAccessTokenRepository
+	passwordResetRepo repository.PasswordResetRepository
+	oidcGrantRepo     repository.OIDCGrantRepository
+	oidcTokenRepo     repository.OIDCTokenRepository
+	webAuthnRepo      repository.WebAuthnRepository
+	emailService      *EmailService
+	alertConfig       *config.LoginAlertConfig
+	db                *gorm.DB
+}
+
+func NewLoginHistoryService(
+	loginEventRepo repository.LoginEventRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	authProviderRepo repository.AuthProviderRepository,
+	tokenRepo repository.PersonalAccessTokenRepository,
+	passwordResetRepo repository.PasswordResetRepository,
+	oidcGrantRepo repository.OIDCGrantRepository,
+	oidcTokenRepo repository.OIDCTokenRepository,
+	webAuthnRepo repository.WebAuthnRepository,
+	emailService *EmailService,
+	alertConfig *config.LoginAlertConfig,
+	db *gorm.DB,
+) *LoginHistoryService {
+	return &LoginHistoryService{
+		loginEventRepo:    loginEventRepo,
+		userRepo:          userRepo,
+		sessionRepo:       sessionRepo,
+		authProviderRepo:  authProviderRepo,
+		tokenRepo:         tokenRepo,
+		passwordResetRepo: passwordResetRepo,
+		oidcGrantRepo:     oidcGrantRepo,
+		oidcTokenRepo:     oidcTokenRepo,
+		webAuthnRepo:      webAuthnRepo,
+		emailService:      emailService,
+		alertConfig:       alertConfig,
+		db:                db,
+	}
+}
+
+// Record stores a login that started the given session. Failures are only logged, a login is
+// never refused because its history could not be written.
+func (s *LoginHistoryService) Record(ctx contextx.Contextx, userID, sessionID uint) {
+	userAgent, ipAddress := requestClientInfo(ctx)
+	info := useragent.Parse(userAgent)
+	now := time.Now()
+	event := models.LoginEvent{
+		UserID:            userID,
+		SessionID:         sessionID,
+		IPAddress:         ipAddress,
+		UserAgent:         userAgent,
+		Browser:           info.Browser,
+		OS:                info.OS,
+		DeviceType:        info.DeviceType,
+		DeviceFingerprint: deviceFingerprint(ctx, info),
+		CreatedAt:         now,
+	}
+
+	// The first recorded login only establishes what the user's devices look like
+	if previous, err := s.loginEventRepo.GetLatestByUserID(ctx, userID); err == nil {
+		known, err := s.loginEventRepo.HasFingerprint(ctx, userID, event.DeviceFingerprint)
+		event.NewDevice = err == nil && !known
+		event.Suspicious = now.Sub(previous.CreatedAt) < s.alertConfig.TravelWindow &&
+			networkChanged(previous.IPAddress, ipAddress)
+	}
+
+	var reportToken string
+	if s.alertConfig.Enabled && (event.NewDevice || event.Suspicious) {
+		token, err := generateSecureToken()
+		if err != nil {
+			log.Printf("Failed to generate login report token for user %d: %v", userID, err)
+		} else {
+			hash := auth.HashToken(token)
+			expiresAt := now.Add(s.alertConfig.ReportTTL)
+			event.ReportTokenHash = &hash
+			event.ReportExpiresAt = &expiresAt
+			reportToken = token
+		}
+	}
+
+	if err := s.loginEventRepo.Create(ctx, &event); err != nil {
+		log.Printf("Failed to record login of user %d: %v", userID, err)
+		return
+	}
+	if reportToken == "" {
+		return
+	}
+
+	go func() {
+		user, err := s.userRepo.GetByIDWithPreload(contextx.Background(), userID, "UserInfo")
+		if err != nil {
+			log.Printf("Failed to load user %d for login alert: %v", userID, err)
+			return
+		}
+		if err := s.emailService.SendLoginAlertEmail(contextx.Background(), user, &event, reportToken, s.alertConfig.ReportTTL); err != nil {
+			log.Printf("Failed to send login alert to user %d: %v", userID, err)
+		}
+	}()
+}
+
+// ListHistory returns a page of a user's logins, newest first
+func (s *LoginHistoryService) ListHistory(ctx contextx.Contextx, userID uint, page, pageSize int) ([]models.LoginEvent, int64, error) {
+	return s.loginEventRepo.ListByUserID(ctx, userID, page, pageSize)
+}
+
+// ReportLogin handles the "this wasn't me" link of a login alert. Everything the intruder could
+// still use is revoked in one transaction: sessions, personal access tokens, tokens and consents
+// of the identity provider, and passkeys registered since the reported login. A password is
+// disabled and replaced through an emailed reset link.
+func (s *LoginHistoryService) ReportLogin(ctx contextx.Contextx, token string) error {
+	if token == "" {
+		return errors.New("invalid login report token")
+	}
+	event, err := s.loginEventRepo.GetByReportTokenHash(ctx, auth.HashToken(token))
+	if err != nil || !event.CanReport() {
+		return errors.New("invalid login report token")
+	}
+
+	hadPassword := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txCtx := contextx.WithTransaction(ctx, tx)
+
+		reported, err := s.loginEventRepo.MarkReported(txCtx, event.ID)
+		if err != nil {
+			return err
+		}
+		if !reported {
+			return errors.New("invalid login report token")
+		}
+		if err := s.revokeAccess(txCtx, event); err != nil {
+			return err
+		}
+
+		// Accounts without a password (social or passkey only) are secured by the revocation alone
+		provider, err := s.authProviderRepo.GetByUserIDAndProvider(txCtx, event.UserID, models.ProviderEmail)
+		if err != nil {
+			return nil
+		}
+		provider.Password = ""
+		hadPassword = true
+		return s.authProviderRepo.Update(txCtx, provider)
+	})
+	if err != nil {
+		return err
+	}
+
+	if hadPassword {
+		if err := s.sendPasswordReset(ctx, event.UserID); err != nil {
+			// The account is already secured; the user can still ask for a reset themselves
+			log.Printf("Failed to send password reset after login report to user %d: %v", event.UserID, err)
+		}
+	}
+	return nil
+}
+
+// revokeAccess revokes every credential of the user that the reported login could have used or
+// created
+func (s *LoginHistoryService) revokeAccess(ctx contextx.Contextx, event *models.LoginEvent) error {
+	if err := s.sessionRepo.RevokeAllForUser(ctx, event.UserID, 0); err != nil {
+		return err
+	}
+	tokens, err := s.tokenRepo.ListByUserID(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	for _, pat := range tokens {
+		if !pat.IsActive() {
+			continue
+		}
+		if err := s.tokenRepo.Revoke(ctx, pat.ID); err != nil {
+			return err
+		}
+	}
+
+	// Clients authorized through the identity provider must ask for consent again
+	if err := s.oidcTokenRepo.RevokeAllForUser(ctx, event.UserID); err != nil {
+		return err
+	}
+	if err := s.oidcGrantRepo.DeleteByUserID(ctx, event.UserID); err != nil {
+		return err
+	}
+
+	// A passkey registered from the intruder's session would survive a password reset
+	if err := s.webAuthnRepo.DeleteCredentialsCreatedSince(ctx, event.UserID, event.CreatedAt); err != nil {
+		return err
+	}
+	remaining, err := s.webAuthnRepo.CountCredentialsByUserID(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return s.authProviderRepo.DeleteByUserIDAndProvider(ctx, event.UserID, models.ProviderPasskey)
+	}
+	return nil
+}
+
+// sendPasswordReset emails a reset link to an account whose password was disabled
+func (s *LoginHistoryService) sendPasswordReset(ctx contextx.Contextx, userID uint) error {
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return err
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return errors.New("failed to generate token")
+	}
+	if err := s.passwordResetRepo.DeleteByUserID(userID); err != nil {
+		return err
+	}
+	if err := s.passwordResetRepo.Create(&models.PasswordResetToken{
+		UserID:    userID,
+		Token:     token,
+		Email:     user.GetPrimaryEmail(),
+		ExpiresAt: time.Now().Add(loginReportResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.emailService.SendPasswordResetEmail(contextx.Background(), user, token); err != nil {
+			log.Printf("Failed to send password reset after login report to user %d: %v", userID, err)
+		}
+	}()
+	return nil
+}
+
+// deviceFingerprint identifies a device by its browser, OS, device type and preferred language.
+// Versions and IP addresses are left out so that updates and roaming keep the same fingerprint.
+func deviceFingerprint(ctx contextx.Contextx, info useragent.Info) string {
+	language := ""
+	if reqCtx := ctx.ReqContext(); reqCtx != nil {
+		language = strings.TrimSpace(strings.SplitN((*reqCtx).Request().Header.Get("Accept-Language"), ",", 2)[0])
+	}
+	return auth.HashToken(strings.Join([]string{info.Browser, info.OS, info.DeviceType, strings.ToLower(language)}, "|"))
+}
+
+// networkChanged compares the /16 (IPv4) or /48 (IPv6) networks of two addresses
+func networkChanged(previousIP, currentIP string) bool {
+	if previousIP == "" || currentIP == "" {
+		return false
+	}
+	return networkOf(previousIP) != networkOf(currentIP)
+}
+
+func networkOf(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
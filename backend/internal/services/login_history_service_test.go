@@ -0,0 +1,199 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// reportableLogin stores a login of the user that can be reported with the returned token
+func (env *testEnv) reportableLogin(t *testing.T, userID uint, at time.Time) string {
+	t.Helper()
+
+	token := fmt.Sprintf("report-%d-%d", userID, at.UnixNano())
+	hash := auth.HashToken(token)
+	expiresAt := time.Now().Add(time.Hour)
+	event := models.LoginEvent{
+		UserID:            userID,
+		DeviceFingerprint: "intruder",
+		NewDevice:         true,
+		ReportTokenHash:   &hash,
+		ReportExpiresAt:   &expiresAt,
+		CreatedAt:         at,
+	}
+	if err := env.db.Create(&event).Error; err != nil {
+		t.Fatalf("create login event: %v", err)
+	}
+	return token
+}
+
+// addPasskey stores a passkey registered at the given time
+func (env *testEnv) addPasskey(t *testing.T, userID uint, name string, createdAt time.Time) {
+	t.Helper()
+
+	provider, err := env.authProviderRepo.GetByUserIDAndProvider(contextx.Background(), userID, models.ProviderPasskey)
+	if err != nil {
+		provider = &models.AuthProvider{UserID: userID, Provider: models.ProviderPasskey, ProviderID: fmt.Sprintf("handle-%d", userID), Verified: true}
+		if err := env.authProviderRepo.Create(contextx.Background(), provider); err != nil {
+			t.Fatalf("create passkey provider: %v", err)
+		}
+	}
+	credential := models.WebAuthnCredential{
+		UserID:         userID,
+		AuthProviderID: provider.ID,
+		CredentialID:   name,
+		PublicKey:      []byte{0},
+		Name:           name,
+		CreatedAt:      createdAt,
+	}
+	if err := env.db.Create(&credential).Error; err != nil {
+		t.Fatalf("create passkey %s: %v", name, err)
+	}
+}
+
+func TestReportLoginRevokesAccess(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	other := env.registerUser(t, "bob", "bob@example.com")
+
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := env.authService.LoginWithUsername(ctx, dto.LoginRequest{Username: username, Password: testPassword}); err != nil {
+			t.Fatalf("LoginWithUsername %s: %v", username, err)
+		}
+	}
+	reportedAt := time.Now().Add(-time.Hour)
+	token := env.reportableLogin(t, user.ID, reportedAt)
+
+	env.addPasskey(t, user.ID, "own", reportedAt.Add(-24*time.Hour))
+	env.addPasskey(t, user.ID, "intruder", reportedAt.Add(time.Minute))
+	pat := models.PersonalAccessToken{UserID: user.ID, Name: "cli", TokenHash: "pat-hash", Prefix: "bzb_pat_", Scopes: "profile:read", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := env.db.Create(&pat).Error; err != nil {
+		t.Fatalf("create personal access token: %v", err)
+	}
+	client := models.OIDCClient{ClientID: "app", Name: "App", RedirectURIs: "https://app.example/cb", Scopes: "openid"}
+	if err := env.db.Create(&client).Error; err != nil {
+		t.Fatalf("create oidc client: %v", err)
+	}
+	for _, userID := range []uint{user.ID, other.ID} {
+		if err := env.db.Create(&models.OIDCGrant{UserID: userID, ClientID: client.ID, Scopes: "openid"}).Error; err != nil {
+			t.Fatalf("create oidc grant: %v", err)
+		}
+		refreshHash := fmt.Sprintf("rt-%d", userID)
+		if err := env.db.Create(&models.OIDCToken{
+			ClientID:             client.ID,
+			UserID:               userID,
+			Scopes:               "openid",
+			AccessTokenHash:      fmt.Sprintf("at-%d", userID),
+			AccessTokenExpiresAt: time.Now().Add(time.Hour),
+			RefreshTokenHash:     &refreshHash,
+			AuthTime:             time.Now(),
+		}).Error; err != nil {
+			t.Fatalf("create oidc token: %v", err)
+		}
+	}
+
+	count := func(model interface{}, query string, args ...interface{}) int64 {
+		t.Helper()
+		var n int64
+		if err := env.db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return n
+	}
+	otherSessions := count(&models.Session{}, "user_id = ? AND revoked_at IS NULL", other.ID)
+
+	if err := env.loginHistoryService.ReportLogin(ctx, token); err != nil {
+		t.Fatalf("ReportLogin: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want int64
+	}{
+		{"active sessions", count(&models.Session{}, "user_id = ? AND revoked_at IS NULL", user.ID), 0},
+		{"active personal access tokens", count(&models.PersonalAccessToken{}, "user_id = ? AND revoked_at IS NULL", user.ID), 0},
+		{"active oidc tokens", count(&models.OIDCToken{}, "user_id = ? AND revoked_at IS NULL", user.ID), 0},
+		{"oidc grants", count(&models.OIDCGrant{}, "user_id = ?", user.ID), 0},
+		{"passkeys registered before the login", count(&models.WebAuthnCredential{}, "user_id = ? AND name = ?", user.ID, "own"), 1},
+		{"passkeys registered since the login", count(&models.WebAuthnCredential{}, "user_id = ? AND name = ?", user.ID, "intruder"), 0},
+		{"other user's active sessions", count(&models.Session{}, "user_id = ? AND revoked_at IS NULL", other.ID), otherSessions},
+		{"other user's active oidc tokens", count(&models.OIDCToken{}, "user_id = ? AND revoked_at IS NULL", other.ID), 1},
+		{"other user's oidc grants", count(&models.OIDCGrant{}, "user_id = ?", other.ID), 1},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %d, want %d", check.name, check.got, check.want)
+		}
+	}
+
+	provider, err := env.authProviderRepo.GetByUserIDAndProvider(ctx, user.ID, models.ProviderEmail)
+	if err != nil {
+		t.Fatalf("GetByUserIDAndProvider: %v", err)
+	}
+	if provider.Password != "" {
+		t.Error("password still set after the report")
+	}
+	if _, err := env.authProviderRepo.GetByUserIDAndProvider(ctx, user.ID, models.ProviderPasskey); err != nil {
+		t.Errorf("passkey provider removed although a passkey is left: %v", err)
+	}
+
+	if err := env.loginHistoryService.ReportLogin(ctx, token); err == nil || err.Error() != "invalid login report token" {
+		t.Errorf("second ReportLogin error = %v, want invalid login report token", err)
+	}
+}
+
+func TestReportLoginUnlinksPasskeysRegisteredByIntruder(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	reportedAt := time.Now().Add(-time.Hour)
+	token := env.reportableLogin(t, user.ID, reportedAt)
+	env.addPasskey(t, user.ID, "intruder", reportedAt.Add(time.Minute))
+
+	if err := env.loginHistoryService.ReportLogin(ctx, token); err != nil {
+		t.Fatalf("ReportLogin: %v", err)
+	}
+	if count, err := env.webAuthnRepo.CountCredentialsByUserID(ctx, user.ID); err != nil || count != 0 {
+		t.Errorf("CountCredentialsByUserID = %d, %v; want 0", count, err)
+	}
+	if _, err := env.authProviderRepo.GetByUserIDAndProvider(ctx, user.ID, models.ProviderPasskey); err == nil {
+		t.Error("passkey provider left without passkeys")
+	}
+}
+
+func TestReportLoginRollsBackOnFailure(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	if _, err := env.authService.LoginWithUsername(ctx, dto.LoginRequest{Username: "alice", Password: testPassword}); err != nil {
+		t.Fatalf("LoginWithUsername: %v", err)
+	}
+	token := env.reportableLogin(t, user.ID, time.Now().Add(-time.Hour))
+	var sessions int64
+	env.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&sessions)
+
+	// Revoking the identity provider tokens fails after the sessions were revoked
+	if err := env.db.Migrator().DropTable(&models.OIDCToken{}); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+	if err := env.loginHistoryService.ReportLogin(ctx, token); err == nil {
+		t.Fatal("ReportLogin succeeded without the oidc_tokens table")
+	}
+
+	var remaining int64
+	env.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&remaining)
+	if remaining != sessions {
+		t.Errorf("%d active sessions after the failed report, want %d", remaining, sessions)
+	}
+	event, err := repository.NewLoginEventRepository(env.db).GetByReportTokenHash(ctx, auth.HashToken(token))
+	if err != nil || !event.CanReport() {
+		t.Errorf("report token unusable after the failed report: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// magicLinkRequestInterval limits how often a link is emailed to the same account
+const magicLinkRequestInterval = time.Minute
+
+// MagicLinkService signs users in with single-use links sent by email. Each link is bound to the
+// device that requested it through a secret the handler keeps in a cookie.
+type MagicLinkService struct {
+	magicLinkRepo  repository.MagicLinkRepository
+	userRepo       repository.UserRepository
+	userInfoRepo   repository.UserInfoRepository
+	emailService   *EmailService
+	sessionService *SessionService
+	mfaService     *MFAService
+	linkConfig     *config.MagicLinkConfig
+}
+
+func NewMagicLinkService(
+	magicLinkRepo repository.MagicLinkRepository,
+	userRepo repository.UserRepository,
+	userInfoRepo repository.UserInfoRepository,
+	emailService *EmailService,
+	sessionService *SessionService,
+	mfaService *MFAService,
+	linkConfig *config.MagicLinkConfig,
+) *MagicLinkService {
+	return &MagicLinkService{
+		magicLinkRepo:  magicLinkRepo,
+		userRepo:       userRepo,
+		userInfoRepo:   userInfoRepo,
+		emailService:   emailService,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		linkConfig:     linkConfig,
+	}
+}
+
+// RequestLink emails a login link when the address belongs to an account. It returns the device
+// secret the link is bound to; the result is the same whether or not the account exists.
+// deviceSecret is reused when the device already holds one so that repeated requests keep
+// earlier links redeemable.
+func (s *MagicLinkService) RequestLink(ctx contextx.Contextx, email, deviceSecret string) (string, error) {
+	if !s.linkConfig.Enabled {
+		return "", errors.New("magic link login disabled")
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", errors.New("invalid email")
+	}
+
+	if !isDeviceSecret(deviceSecret) {
+		var err error
+		if deviceSecret, err = generateSecureToken(); err != nil {
+			return "", errors.New("failed to generate token")
+		}
+	}
+
+	userInfo, err := s.userInfoRepo.GetByEmail(ctx, email)
+	if err != nil {
+		// Don't reveal if email exists or not
+		return deviceSecret, nil
+	}
+
+	// Throttled requests are silently dropped for the same reason
+	if latest, err := s.magicLinkRepo.GetLatestByUserID(ctx, userInfo.UserID); err == nil && time.Since(latest.CreatedAt) < magicLinkRequestInterval {
+		return deviceSecret, nil
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userInfo.UserID, "UserInfo")
+	if err != nil {
+		return deviceSecret, nil
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+
+	// Only the newest link of a user can be redeemed
+	if err := s.magicLinkRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return "", err
+	}
+
+	_, ipAddress := requestClientInfo(ctx)
+	link := models.MagicLinkToken{
+		UserID:     user.ID,
+		TokenHash:  auth.HashToken(token),
+		DeviceHash: auth.HashToken(deviceSecret),
+		Email:      userInfo.Email,
+		RequestIP:  ipAddress,
+		ExpiresAt:  time.Now().Add(s.linkConfig.TTL),
+	}
+	if err := s.magicLinkRepo.Create(ctx, &link); err != nil {
+		return "", err
+	}
+
+	// Sending in the background keeps the response time independent of whether the account exists
+	go func() {
+		if err := s.emailService.SendMagicLinkEmail(contextx.Background(), user, token, s.linkConfig.TTL); err != nil {
+			log.Printf("Failed to send magic link email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return deviceSecret, nil
+}
+
+// Login redeems a link from the device that requested it and returns a normal auth response,
+// or an MFA challenge when the user has a second factor enabled
+func (s *MagicLinkService) Login(ctx contextx.Contextx, token, deviceSecret string) (*dto.AuthResponse, error) {
+	if !s.linkConfig.Enabled {
+		return nil, errors.New("magic link login disabled")
+	}
+	if token == "" {
+		return nil, errors.New("invalid magic link")
+	}
+
+	link, err := s.magicLinkRepo.GetByTokenHash(ctx, auth.HashToken(token))
+	if err != nil || !link.IsValid() {
+		return nil, errors.New("invalid magic link")
+	}
+
+	// A link opened on another device is rejected without consuming it
+	if subtle.ConstantTimeCompare([]byte(link.DeviceHash), []byte(auth.HashToken(deviceSecret))) != 1 {
+		return nil, errors.New("magic link device mismatch")
+	}
+
+	used, err := s.magicLinkRepo.MarkUsed(ctx, link.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("invalid magic link")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, link.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("invalid magic link")
+	}
+	// The link proves control of the address it was sent to, which must still be the user's email
+	if !strings.EqualFold(user.GetPrimaryEmail(), link.Email) {
+		return nil, errors.New("invalid magic link")
+	}
+
+	// Email possession is a single factor, so MFA still applies
+	if s.mfaService.IsEnabled(ctx, user.ID) {
+		return s.mfaService.CreateChallenge(ctx, user)
+	}
+
+	now := time.Now()
+	user.LastLoginAt = &now
+	_ = s.userRepo.Update(ctx, user)
+
+	return s.sessionService.IssueTokens(ctx, user)
+}
+
+// isDeviceSecret checks that a value presented by the client has the shape of a generated secret
+func isDeviceSecret(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
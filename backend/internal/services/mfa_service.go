@@ -0,0 +1,334 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/totp"
+	"bezbase/internal/repository"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+	// mfaChallengeMaxAttempts is how many codes a single login challenge accepts
+	mfaChallengeMaxAttempts = 5
+)
+
+type MFAService struct {
+	mfaRepo        repository.MFARepository
+	userRepo       repository.UserRepository
+	sessionService *SessionService
+	lockoutService *LockoutService
+	keys           *auth.KeySet
+	authConfig     *config.AuthConfig
+}
+
+func NewMFAService(
+	mfaRepo repository.MFARepository,
+	userRepo repository.UserRepository,
+	sessionService *SessionService,
+	lockoutService *LockoutService,
+	keys *auth.KeySet,
+	authConfig *config.AuthConfig,
+) *MFAService {
+	return &MFAService{
+		mfaRepo:        mfaRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		lockoutService: lockoutService,
+		keys:           keys,
+		authConfig:     authConfig,
+	}
+}
+
+// GetStatus returns the MFA enrollment status of a user
+func (s *MFAService) GetStatus(ctx contextx.Contextx, userID uint) (*dto.MFAStatusResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return &dto.MFAStatusResponse{Enabled: false}, nil
+	}
+
+	remaining, err := s.mfaRepo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAStatusResponse{
+		Enabled:                true,
+		ConfirmedAt:            mfa.ConfirmedAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// IsEnabled reports whether the user has a confirmed TOTP enrollment
+func (s *MFAService) IsEnabled(ctx contextx.Contextx, userID uint) bool {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	return err == nil && mfa.Enabled
+}
+
+// EnrollTOTP starts (or restarts) a pending enrollment and returns the secret to scan
+func (s *MFAService) EnrollTOTP(ctx contextx.Contextx, userID uint) (*dto.TOTPEnrollResponse, error) {
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err == nil && mfa.Enabled {
+		return nil, errors.New("mfa already enabled")
+	}
+	if err != nil {
+		mfa = &models.UserMFA{UserID: userID}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	mfa.Secret = secret
+	mfa.Enabled = false
+	mfa.ConfirmedAt = nil
+	mfa.LastUsedStep = 0
+	if err := s.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	accountName := user.GetPrimaryEmail()
+	if accountName == "" && user.UserInfo != nil {
+		accountName = user.UserInfo.Username
+	}
+
+	return &dto.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    totp.URI(s.authConfig.MFAIssuer, accountName, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables MFA once the user proves the authenticator is set up, and returns recovery codes
+func (s *MFAService) ConfirmTOTP(ctx contextx.Contextx, userID uint, code string) (*dto.MFARecoveryCodesResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("mfa not enrolled")
+	}
+	if mfa.Enabled {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	if !s.verifyTOTP(ctx, mfa, code) {
+		return nil, errors.New("invalid mfa code")
+	}
+
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.ConfirmedAt = &now
+	if err := s.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// DisableTOTP removes the user's enrollment after checking a TOTP or recovery code
+func (s *MFAService) DisableTOTP(ctx contextx.Contextx, userID uint, req dto.MFACodeRequest) error {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return errors.New("mfa not enabled")
+	}
+
+	if !s.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode) {
+		return errors.New("invalid mfa code")
+	}
+
+	return s.mfaRepo.DeleteByUserID(ctx, userID)
+}
+
+// RegenerateRecoveryCodes invalidates existing recovery codes and returns a new set
+func (s *MFAService) RegenerateRecoveryCodes(ctx contextx.Contextx, userID uint, code string) (*dto.MFARecoveryCodesResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return nil, errors.New("mfa not enabled")
+	}
+
+	if !s.verifyTOTP(ctx, mfa, code) {
+		return nil, errors.New("invalid mfa code")
+	}
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// ResetMFA removes a user's MFA enrollment and recovery codes (admin action)
+func (s *MFAService) ResetMFA(ctx contextx.Contextx, userID uint) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return errors.New("user not found")
+	}
+	if _, err := s.mfaRepo.GetByUserID(ctx, userID); err != nil {
+		return errors.New("mfa not enabled")
+	}
+	return s.mfaRepo.DeleteByUserID(ctx, userID)
+}
+
+// CreateChallenge returns the response for a login that still needs a second factor. The challenge
+// token is recorded so that it can only be completed once and only with a few attempts.
+func (s *MFAService) CreateChallenge(ctx contextx.Contextx, user *models.User) (*dto.AuthResponse, error) {
+	token, err := auth.GeneratePurposeToken(user.ID, user.GetPrimaryEmail(), auth.PurposeMFAChallenge, s.keys, s.authConfig.MFAChallengeTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	expiresAt := time.Now().Add(s.authConfig.MFAChallengeTTL)
+	challenge := models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.mfaRepo.CreateChallenge(ctx, &challenge); err != nil {
+		return nil, err
+	}
+
+	return &dto.AuthResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresAt:      &expiresAt,
+	}, nil
+}
+
+// VerifyChallenge completes an MFA login and starts a session. Wrong codes count towards the
+// account lockout like wrong passwords, so that codes cannot be guessed across many challenges.
+func (s *MFAService) VerifyChallenge(ctx contextx.Contextx, req dto.MFAVerifyRequest) (*dto.AuthResponse, error) {
+	claims, err := auth.ValidatePurposeToken(req.ChallengeToken, auth.PurposeMFAChallenge, s.keys)
+	if err != nil {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	challenge, err := s.mfaRepo.GetChallengeByTokenHash(ctx, auth.HashToken(req.ChallengeToken))
+	if err != nil || challenge.UserID != claims.UserID {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	if err := s.lockoutService.Check(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.mfaRepo.ClaimChallengeAttempt(ctx, challenge.ID, mfaChallengeMaxAttempts, time.Now())
+	if err != nil || !claimed {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	mfa, err := s.mfaRepo.GetByUserID(ctx, claims.UserID)
+	if err != nil || !mfa.Enabled {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	if !s.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode) {
+		s.lockoutService.RecordFailure(ctx, claims.UserID)
+		return nil, errors.New("invalid mfa code")
+	}
+
+	consumed, err := s.mfaRepo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil || !consumed {
+		return nil, errors.New("invalid challenge token")
+	}
+	s.lockoutService.RecordSuccess(ctx, claims.UserID)
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, claims.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Update last login time
+	now := time.Now()
+	user.LastLoginAt = &now
+	_ = s.userRepo.Update(ctx, user)
+
+	return s.sessionService.IssueTokens(ctx, user)
+}
+
+// VerifyCode checks a TOTP or recovery code of an enrolled user, for example to re-authenticate
+func (s *MFAService) VerifyCode(ctx contextx.Contextx, userID uint, code, recoveryCode string) error {
+	mfa, err := s.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return errors.New("mfa not enabled")
+	}
+	if !s.verifySecondFactor(ctx, mfa, code, recoveryCode) {
+		return errors.New("invalid mfa code")
+	}
+	return nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code
+func (s *MFAService) verifySecondFactor(ctx contextx.Contextx, mfa *models.UserMFA, code, recoveryCode string) bool {
+	if code != "" {
+		return s.verifyTOTP(ctx, mfa, code)
+	}
+	if recoveryCode != "" {
+		used, err := s.mfaRepo.UseRecoveryCode(ctx, mfa.UserID, auth.HashToken(normalizeRecoveryCode(recoveryCode)))
+		return err == nil && used
+	}
+	return false
+}
+
+// verifyTOTP validates a code and consumes its time step so it cannot be replayed
+func (s *MFAService) verifyTOTP(ctx contextx.Contextx, mfa *models.UserMFA, code string) bool {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return false
+	}
+	accepted, err := s.mfaRepo.UpdateLastUsedStep(ctx, mfa.UserID, step)
+	if err != nil || !accepted {
+		return false
+	}
+	mfa.LastUsedStep = step
+	return true
+}
+
+func (s *MFAService) issueRecoveryCodes(ctx contextx.Contextx, userID uint) (*dto.MFARecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery codes")
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, auth.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &dto.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i, b := range bytes {
+		if i == recoveryCodeLength/2 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
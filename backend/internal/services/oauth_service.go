@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/oauth"
+	"bezbase/internal/repository"
+)
+
+// linkTicketTTL bounds how long a user has to confirm linking a social account to an existing one
+const linkTicketTTL = 10 * time.Minute
+
+type OAuthService struct {
+	authService      *AuthService
+	userInfoRepo     repository.UserInfoRepository
+	authProviderRepo repository.AuthProviderRepository
+	providers        map[string]*oauth.Provider
+	oauthConfig      *config.OAuthConfig
+	secret           []byte
+}
+
+func NewOAuthService(
+	authService *AuthService,
+	userInfoRepo repository.UserInfoRepository,
+	authProviderRepo repository.AuthProviderRepository,
+	oauthConfig *config.OAuthConfig,
+	authConfig *config.AuthConfig,
+) *OAuthService {
+	callback := strings.TrimRight(oauthConfig.CallbackBaseURL, "/")
+	redirectURL := func(name string) string {
+		return callback + "/" + name + "/callback"
+	}
+
+	providers := make(map[string]*oauth.Provider)
+	if oauthConfig.Google.Enabled() {
+		name := oauthConfig.Google.Name
+		providers[name] = oauth.NewGoogleProvider(oauthConfig.Google.ClientID, oauthConfig.Google.ClientSecret, redirectURL(name))
+	}
+	if oauthConfig.GitHub.Enabled() {
+		name := oauthConfig.GitHub.Name
+		providers[name] = oauth.NewGitHubProvider(oauthConfig.GitHub.ClientID, oauthConfig.GitHub.ClientSecret, redirectURL(name))
+	}
+	if oauthConfig.OIDC.Enabled() && oauthConfig.OIDC.Issuer != "" {
+		name := oauthConfig.OIDC.Name
+		providers[name] = oauth.NewOIDCProvider(name, oauthConfig.OIDC.Issuer, oauthConfig.OIDC.ClientID, oauthConfig.OIDC.ClientSecret, redirectURL(name), oauthConfig.OIDC.Scopes)
+	}
+
+	return &OAuthService{
+		authService:      authService,
+		userInfoRepo:     userInfoRepo,
+		authProviderRepo: authProviderRepo,
+		providers:        providers,
+		oauthConfig:      oauthConfig,
+		secret:           []byte(authConfig.JWTSecret),
+	}
+}
+
+// RegisterProvider adds or replaces a provider, e.g. one pointing at a local identity provider
+func (s *OAuthService) RegisterProvider(provider *oauth.Provider) {
+	s.providers[provider.Name] = provider
+}
+
+// Providers returns the names of the configured providers
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginAuthorization returns the provider authorization URL and the signed state to keep in a cookie.
+// linkUserID is set when a signed-in user links an additional provider.
+func (s *OAuthService) BeginAuthorization(ctx contextx.Contextx, name string, linkUserID uint) (string, string, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return "", "", errors.New("oauth provider not found")
+	}
+
+	state, err := oauth.NewState(name, s.oauthConfig.StateTTL)
+	if err != nil {
+		return "", "", err
+	}
+	state.LinkUserID = linkUserID
+
+	authURL, err := provider.AuthCodeURL(ctx, state)
+	if err != nil {
+		return "", "", errors.New("oauth login failed")
+	}
+	cookie, err := state.Sign(s.secret)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, cookie, nil
+}
+
+// HandleCallback completes the authorization code flow. The state parameter must match the
+// signed state cookie set by BeginAuthorization, which also carries the PKCE verifier and nonce.
+func (s *OAuthService) HandleCallback(ctx contextx.Contextx, name, stateCookie, stateParam, code string) (*dto.OAuthCallbackResult, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, errors.New("oauth provider not found")
+	}
+
+	state, err := oauth.ParseState(stateCookie, s.secret)
+	if err != nil || state.Provider != name || stateParam == "" || state.State != stateParam {
+		return nil, errors.New("invalid oauth state")
+	}
+	if code == "" {
+		return nil, errors.New("oauth login failed")
+	}
+
+	token, err := provider.Exchange(ctx, code, state.Verifier)
+	if err != nil {
+		return nil, errors.New("oauth login failed")
+	}
+	identity, err := provider.Identity(ctx, token, state.Nonce)
+	if err != nil {
+		return nil, errors.New("oauth login failed")
+	}
+
+	providerType := models.AuthProviderType(name)
+
+	// A signed-in user linking another provider has already proven account ownership
+	if state.LinkUserID != 0 {
+		if err := s.authService.LinkSocialProvider(state.LinkUserID, providerType, identity.Subject, identity.Email); err != nil {
+			return nil, err
+		}
+		return &dto.OAuthCallbackResult{Linked: true}, nil
+	}
+
+	// Returning users sign in with the linked account
+	if _, err := s.authProviderRepo.GetByProviderIDAndType(ctx, identity.Subject, providerType); err == nil {
+		response, err := s.authService.loginWithSocialProvider(ctx, providerType, identity.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.OAuthCallbackResult{Auth: response}, nil
+	}
+
+	// Unverified provider emails cannot be trusted to identify an account
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("social email not verified")
+	}
+
+	// The email belongs to an existing account; its owner must confirm the link
+	if userInfo, err := s.userInfoRepo.GetByEmail(ctx, identity.Email); err == nil {
+		ticket := oauth.LinkTicket{
+			Provider:  name,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			UserID:    userInfo.UserID,
+			ExpiresAt: time.Now().Add(linkTicketTTL).Unix(),
+		}
+		linkToken, err := ticket.Sign(s.secret)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.OAuthCallbackResult{LinkRequired: true, LinkToken: linkToken, Email: identity.Email}, nil
+	}
+
+	response, err := s.authService.RegisterWithSocialProvider(ctx, providerType, identity.Subject, identity.Email, identity.FirstName, identity.LastName)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OAuthCallbackResult{Auth: response}, nil
+}
+
+// ConfirmLink links the social account in a link token to the existing account it matched.
+// The caller proves ownership either by being signed in as that user (currentUserID) or with
+// the account password.
+func (s *OAuthService) ConfirmLink(ctx contextx.Contextx, req dto.OAuthLinkConfirmRequest, currentUserID uint) (*dto.AuthResponse, error) {
+	ticket, err := oauth.ParseLinkTicket(req.LinkToken, s.secret)
+	if err != nil {
+		return nil, errors.New("invalid link token")
+	}
+	if _, ok := s.providers[ticket.Provider]; !ok {
+		return nil, errors.New("oauth provider not found")
+	}
+
+	if currentUserID != 0 {
+		if currentUserID != ticket.UserID {
+			return nil, errors.New("invalid link token")
+		}
+	} else {
+		// The password is checked like a login, so guesses count towards the account lockout
+		if req.Password == "" {
+			return nil, errors.New("invalid credentials")
+		}
+		if err := s.authService.checkPassword(ctx, ticket.UserID, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	providerType := models.AuthProviderType(ticket.Provider)
+	if err := s.authService.LinkSocialProvider(ticket.UserID, providerType, ticket.Subject, ticket.Email); err != nil {
+		return nil, err
+	}
+
+	if currentUserID != 0 {
+		return nil, nil
+	}
+	return s.authService.loginWithSocialProvider(ctx, providerType, ticket.Subject)
+}
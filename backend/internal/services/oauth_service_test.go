@@ -0,0 +1,450 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/oauth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockIdPName     = "mockidp"
+	mockIdPClientID = "bezbase-client"
+	mockIdPSecret   = "bezbase-secret"
+	mockRedirectURL = "https://app.example.com/api/v1/auth/oauth/mockidp/callback"
+)
+
+// mockIdP is an OpenID provider that issues codes for the authorization requests it is shown
+// and enforces PKCE at its token endpoint
+type mockIdP struct {
+	*httptest.Server
+	t   *testing.T
+	key *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	idp := &mockIdP{t: t, key: key, codes: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": "mock-key",
+			"use": "sig",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// provider returns a client for the mock IdP that can be registered with the OAuth service
+func (idp *mockIdP) provider() *oauth.Provider {
+	provider := oauth.NewOIDCProvider(mockIdPName, idp.URL, mockIdPClientID, mockIdPSecret, mockRedirectURL, nil)
+	provider.HTTPClient = idp.Client()
+	return provider
+}
+
+// authorize plays the user approving the authorization request at authURL. The returned code is
+// redeemed for an ID token with the default claims for subject, overridden by claims.
+func (idp *mockIdP) authorize(authURL, subject string, claims jwt.MapClaims) (string, string) {
+	idp.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		idp.t.Fatalf("unexpected authorization URL %q", authURL)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != mockIdPClientID || query.Get("redirect_uri") != mockRedirectURL || query.Get("response_type") != "code" {
+		idp.t.Fatalf("unexpected authorization request %v", query)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("nonce") == "" || query.Get("state") == "" {
+		idp.t.Fatalf("authorization request without PKCE, nonce or state: %v", query)
+	}
+
+	now := time.Now()
+	grant := mockGrant{
+		challenge: query.Get("code_challenge"),
+		claims: jwt.MapClaims{
+			"iss":            idp.URL,
+			"sub":            subject,
+			"aud":            mockIdPClientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+			"nonce":          query.Get("nonce"),
+			"email":          subject + "@example.com",
+			"email_verified": true,
+			"given_name":     "Social",
+			"family_name":    "User",
+		},
+	}
+	for name, value := range claims {
+		grant.claims[name] = value
+	}
+
+	code, err := oauth.RandomString(16)
+	if err != nil {
+		idp.t.Fatalf("RandomString: %v", err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = grant
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != mockRedirectURL:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+	case r.PostForm.Get("client_id") != mockIdPClientID || r.PostForm.Get("client_secret") != mockIdPSecret:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+	case !ok || oauth.CodeChallengeS256(r.PostForm.Get("code_verifier")) != grant.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+	default:
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, grant.claims)
+		token.Header["kid"] = "mock-key"
+		idToken, err := token.SignedString(idp.key)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idToken,
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newOAuthTestEnv(t *testing.T, configure ...func(cfg *config.Config)) (*testEnv, *mockIdP) {
+	t.Helper()
+	env := newTestEnv(t, configure...)
+	idp := newMockIdP(t)
+	env.oauthService.RegisterProvider(idp.provider())
+	return env, idp
+}
+
+// socialLogin runs the authorize redirect and the callback for subject
+func socialLogin(t *testing.T, env *testEnv, idp *mockIdP, subject string, claims jwt.MapClaims) (*dto.OAuthCallbackResult, error) {
+	t.Helper()
+
+	ctx := contextx.Background()
+	authURL, cookie, err := env.oauthService.BeginAuthorization(ctx, mockIdPName, 0)
+	if err != nil {
+		t.Fatalf("BeginAuthorization: %v", err)
+	}
+	code, state := idp.authorize(authURL, subject, claims)
+	return env.oauthService.HandleCallback(ctx, mockIdPName, cookie, state, code)
+}
+
+func TestOAuthLoginRegistersThenSignsIn(t *testing.T) {
+	env, idp := newOAuthTestEnv(t)
+	ctx := contextx.Background()
+
+	result, err := socialLogin(t, env, idp, "carol", nil)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if result.Auth == nil || result.Auth.Token == "" || result.Auth.User == nil {
+		t.Fatalf("HandleCallback = %+v, want a session", result)
+	}
+	userID := result.Auth.User.ID
+
+	provider, err := env.authProviderRepo.GetByProviderIDAndType(ctx, "carol", models.AuthProviderType(mockIdPName))
+	if err != nil || provider.UserID != userID {
+		t.Fatalf("linked provider = %+v, %v; want one for user %d", provider, err, userID)
+	}
+
+	result, err = socialLogin(t, env, idp, "carol", nil)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if result.Auth == nil || result.Auth.User == nil || result.Auth.User.ID != userID {
+		t.Fatalf("returning login = %+v, want a session for user %d", result, userID)
+	}
+}
+
+func TestOAuthCallbackRejectsState(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		callback  func(cookie, state string) (string, string, string)
+	}{
+		{
+			name: "state parameter does not match the cookie",
+			callback: func(cookie, state string) (string, string, string) {
+				return mockIdPName, cookie, state + "x"
+			},
+		},
+		{
+			name: "missing state parameter",
+			callback: func(cookie, state string) (string, string, string) {
+				return mockIdPName, cookie, ""
+			},
+		},
+		{
+			name: "tampered cookie payload",
+			callback: func(cookie, state string) (string, string, string) {
+				payload, signature, _ := strings.Cut(cookie, ".")
+				decoded, _ := base64.RawURLEncoding.DecodeString(payload)
+				tampered := strings.Replace(string(decoded), `"p":"`+mockIdPName+`"`, `"p":"`+mockIdPName+`","l":1`, 1)
+				return mockIdPName, base64.RawURLEncoding.EncodeToString([]byte(tampered)) + "." + signature, state
+			},
+		},
+		{
+			name: "tampered cookie signature",
+			callback: func(cookie, state string) (string, string, string) {
+				return mockIdPName, cookie[:len(cookie)-2] + "AA", state
+			},
+		},
+		{
+			name:      "expired state",
+			configure: func(cfg *config.Config) { cfg.OAuth.StateTTL = -time.Second },
+			callback: func(cookie, state string) (string, string, string) {
+				return mockIdPName, cookie, state
+			},
+		},
+		{
+			name: "state of another provider",
+			callback: func(cookie, state string) (string, string, string) {
+				return "other", cookie, state
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configure []func(cfg *config.Config)
+			if tt.configure != nil {
+				configure = append(configure, tt.configure)
+			}
+			env, idp := newOAuthTestEnv(t, configure...)
+			other := idp.provider()
+			other.Name = "other"
+			env.oauthService.RegisterProvider(other)
+			ctx := contextx.Background()
+
+			authURL, cookie, err := env.oauthService.BeginAuthorization(ctx, mockIdPName, 0)
+			if err != nil {
+				t.Fatalf("BeginAuthorization: %v", err)
+			}
+			code, state := idp.authorize(authURL, "carol", nil)
+
+			name, cookie, state := tt.callback(cookie, state)
+			_, err = env.oauthService.HandleCallback(ctx, name, cookie, state, code)
+			if err == nil || err.Error() != "invalid oauth state" {
+				t.Fatalf("HandleCallback error = %v, want %q", err, "invalid oauth state")
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackEnforcesPKCE(t *testing.T) {
+	env, idp := newOAuthTestEnv(t)
+	ctx := contextx.Background()
+
+	// The attacker's own login attempt provides a valid state cookie and state pair
+	_, attackerCookie, err := env.oauthService.BeginAuthorization(ctx, mockIdPName, 0)
+	if err != nil {
+		t.Fatalf("BeginAuthorization: %v", err)
+	}
+	attackerState, err := oauth.ParseState(attackerCookie, []byte(env.cfg.Auth.JWTSecret))
+	if err != nil {
+		t.Fatalf("ParseState: %v", err)
+	}
+
+	// A code intercepted from the victim is bound to the victim's code challenge
+	victimURL, _, err := env.oauthService.BeginAuthorization(ctx, mockIdPName, 0)
+	if err != nil {
+		t.Fatalf("BeginAuthorization: %v", err)
+	}
+	code, _ := idp.authorize(victimURL, "victim", nil)
+
+	_, err = env.oauthService.HandleCallback(ctx, mockIdPName, attackerCookie, attackerState.State, code)
+	if err == nil || err.Error() != "oauth login failed" {
+		t.Fatalf("HandleCallback error = %v, want %q", err, "oauth login failed")
+	}
+	if _, err := env.authProviderRepo.GetByProviderIDAndType(ctx, "victim", models.AuthProviderType(mockIdPName)); err == nil {
+		t.Error("an account was created from the intercepted code")
+	}
+}
+
+func TestOAuthCallbackRejectsIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(idp *mockIdP) jwt.MapClaims
+	}{
+		{
+			name:   "wrong nonce",
+			claims: func(idp *mockIdP) jwt.MapClaims { return jwt.MapClaims{"nonce": "replayed-nonce"} },
+		},
+		{
+			name:   "missing nonce",
+			claims: func(idp *mockIdP) jwt.MapClaims { return jwt.MapClaims{"nonce": ""} },
+		},
+		{
+			name:   "wrong audience",
+			claims: func(idp *mockIdP) jwt.MapClaims { return jwt.MapClaims{"aud": "another-client"} },
+		},
+		{
+			name:   "wrong issuer",
+			claims: func(idp *mockIdP) jwt.MapClaims { return jwt.MapClaims{"iss": "https://evil.example"} },
+		},
+		{
+			name: "expired",
+			claims: func(idp *mockIdP) jwt.MapClaims {
+				return jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, idp := newOAuthTestEnv(t)
+
+			_, err := socialLogin(t, env, idp, "carol", tt.claims(idp))
+			if err == nil || err.Error() != "oauth login failed" {
+				t.Fatalf("HandleCallback error = %v, want %q", err, "oauth login failed")
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedEmail(t *testing.T) {
+	env, idp := newOAuthTestEnv(t)
+
+	_, err := socialLogin(t, env, idp, "carol", jwt.MapClaims{"email_verified": false})
+	if err == nil || err.Error() != "social email not verified" {
+		t.Fatalf("HandleCallback error = %v, want %q", err, "social email not verified")
+	}
+}
+
+func TestOAuthLinkConfirm(t *testing.T) {
+	env, idp := newOAuthTestEnv(t)
+	ctx := contextx.Background()
+	alice := env.registerUser(t, "alice", "alice@example.com")
+
+	result, err := socialLogin(t, env, idp, "alice-social", jwt.MapClaims{"email": "alice@example.com"})
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if !result.LinkRequired || result.LinkToken == "" || result.Auth != nil {
+		t.Fatalf("HandleCallback = %+v, want a link token", result)
+	}
+
+	if _, err := env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken + "x", Password: testPassword}, 0); err == nil || err.Error() != "invalid link token" {
+		t.Fatalf("ConfirmLink with a tampered token error = %v, want %q", err, "invalid link token")
+	}
+	if _, err := env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken}, 0); err == nil || err.Error() != "invalid credentials" {
+		t.Fatalf("ConfirmLink without a password error = %v, want %q", err, "invalid credentials")
+	}
+	if _, err := env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken, Password: "wrong"}, 0); err == nil || err.Error() != "invalid credentials" {
+		t.Fatalf("ConfirmLink with a wrong password error = %v, want %q", err, "invalid credentials")
+	}
+	if _, err := env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken}, alice.ID+1); err == nil || err.Error() != "invalid link token" {
+		t.Fatalf("ConfirmLink as another user error = %v, want %q", err, "invalid link token")
+	}
+
+	response, err := env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken, Password: testPassword}, 0)
+	if err != nil {
+		t.Fatalf("ConfirmLink: %v", err)
+	}
+	if response == nil || response.Token == "" || response.User == nil || response.User.ID != alice.ID {
+		t.Fatalf("ConfirmLink = %+v, want a session for user %d", response, alice.ID)
+	}
+
+	// The linked account now signs in directly
+	result, err = socialLogin(t, env, idp, "alice-social", jwt.MapClaims{"email": "alice@example.com"})
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if result.Auth == nil || result.Auth.User == nil || result.Auth.User.ID != alice.ID {
+		t.Fatalf("HandleCallback = %+v, want a session for user %d", result, alice.ID)
+	}
+}
+
+func TestOAuthLinkConfirmAppliesLockout(t *testing.T) {
+	env, idp := newOAuthTestEnv(t)
+	ctx := contextx.Background()
+	env.registerUser(t, "alice", "alice@example.com")
+
+	result, err := socialLogin(t, env, idp, "alice-social", jwt.MapClaims{"email": "alice@example.com"})
+	if err != nil || !result.LinkRequired {
+		t.Fatalf("HandleCallback = %+v, %v; want a link token", result, err)
+	}
+
+	// The failure beyond the free attempts starts the backoff
+	for i := 0; i <= env.cfg.Auth.Lockout.FreeAttempts; i++ {
+		_, err = env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken, Password: "wrong"}, 0)
+		if err == nil || err.Error() != "invalid credentials" {
+			t.Fatalf("ConfirmLink with a wrong password error = %v, want %q", err, "invalid credentials")
+		}
+	}
+
+	// Even the right password is refused while the account backs off
+	_, err = env.oauthService.ConfirmLink(ctx, dto.OAuthLinkConfirmRequest{LinkToken: result.LinkToken, Password: testPassword}, 0)
+	var lockoutErr *LockoutError
+	if !errors.As(err, &lockoutErr) || lockoutErr.Locked {
+		t.Fatalf("ConfirmLink error during backoff = %v, want a backoff delay", err)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// oidcClientSecretPrefix makes client secrets recognizable to secret scanners
+const oidcClientSecretPrefix = "bzb_cs_"
+
+// OIDCClientService manages the applications allowed to sign users in through bezbase and
+// the consent users gave them
+type OIDCClientService struct {
+	clientRepo repository.OIDCClientRepository
+	grantRepo  repository.OIDCGrantRepository
+	tokenRepo  repository.OIDCTokenRepository
+}
+
+func NewOIDCClientService(
+	clientRepo repository.OIDCClientRepository,
+	grantRepo repository.OIDCGrantRepository,
+	tokenRepo repository.OIDCTokenRepository,
+) *OIDCClientService {
+	return &OIDCClientService{
+		clientRepo: clientRepo,
+		grantRepo:  grantRepo,
+		tokenRepo:  tokenRepo,
+	}
+}
+
+// CreateClient registers a client. Confidential clients get a secret that is only returned here.
+func (s *OIDCClientService) CreateClient(ctx contextx.Contextx, req dto.CreateOIDCClientRequest) (*dto.OIDCClientCreatedResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("invalid client name")
+	}
+	redirectURIs, err := validateRedirectURIs(req.RedirectURIs)
+	if err != nil {
+		return nil, err
+	}
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidcScopeOpenID, oidcScopeProfile, oidcScopeEmail}
+	}
+	if scopes, err = validateClientScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	clientID, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	client := models.OIDCClient{
+		ClientID:     clientID[:32],
+		Name:         name,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		SkipConsent:  req.SkipConsent,
+	}
+
+	var secret string
+	if !req.Public {
+		if secret, err = generateClientSecret(); err != nil {
+			return nil, err
+		}
+		client.ClientSecretHash = auth.HashToken(secret)
+	}
+
+	if err := s.clientRepo.Create(ctx, &client); err != nil {
+		return nil, err
+	}
+
+	return &dto.OIDCClientCreatedResponse{
+		OIDCClientResponse: dto.ToOIDCClientResponse(&client),
+		ClientSecret:       secret,
+	}, nil
+}
+
+// ListClients returns all registered clients
+func (s *OIDCClientService) ListClients(ctx contextx.Contextx) ([]models.OIDCClient, error) {
+	return s.clientRepo.List(ctx)
+}
+
+// GetClient returns a client by its database ID
+func (s *OIDCClientService) GetClient(ctx contextx.Contextx, id uint) (*models.OIDCClient, error) {
+	return s.clientRepo.GetByID(ctx, id)
+}
+
+// UpdateClient changes a client's name, redirect URIs, allowed scopes or consent setting
+func (s *OIDCClientService) UpdateClient(ctx contextx.Contextx, id uint, req dto.UpdateOIDCClientRequest) (*models.OIDCClient, error) {
+	client, err := s.clientRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > 100 {
+			return nil, errors.New("invalid client name")
+		}
+		client.Name = name
+	}
+	if req.RedirectURIs != nil {
+		redirectURIs, err := validateRedirectURIs(req.RedirectURIs)
+		if err != nil {
+			return nil, err
+		}
+		client.RedirectURIs = strings.Join(redirectURIs, " ")
+	}
+	if req.Scopes != nil {
+		scopes, err := validateClientScopes(req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		client.Scopes = strings.Join(scopes, " ")
+	}
+	if req.SkipConsent != nil {
+		client.SkipConsent = *req.SkipConsent
+	}
+
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// RotateClientSecret replaces the secret of a confidential client. The old secret stops working immediately.
+func (s *OIDCClientService) RotateClientSecret(ctx contextx.Contextx, id uint) (*dto.OIDCClientCreatedResponse, error) {
+	client, err := s.clientRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return nil, errors.New("public client has no secret")
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	client.ClientSecretHash = auth.HashToken(secret)
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return &dto.OIDCClientCreatedResponse{
+		OIDCClientResponse: dto.ToOIDCClientResponse(client),
+		ClientSecret:       secret,
+	}, nil
+}
+
+// DeleteClient removes a client together with its grants and tokens
+func (s *OIDCClientService) DeleteClient(ctx contextx.Contextx, id uint) error {
+	if _, err := s.clientRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.clientRepo.Delete(ctx, id)
+}
+
+// ListClientGrants returns the users who consented to a client (admin)
+func (s *OIDCClientService) ListClientGrants(ctx contextx.Contextx, id uint) ([]models.OIDCGrant, error) {
+	if _, err := s.clientRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.grantRepo.ListByClientID(ctx, id)
+}
+
+// RevokeClientGrant withdraws a user's consent for a client and revokes its tokens (admin)
+func (s *OIDCClientService) RevokeClientGrant(ctx contextx.Contextx, id, userID uint) error {
+	if _, err := s.grantRepo.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.revokeGrant(ctx, userID, id)
+}
+
+// ListUserGrants returns the applications the user has authorized
+func (s *OIDCClientService) ListUserGrants(ctx contextx.Contextx, userID uint) ([]models.OIDCGrant, error) {
+	return s.grantRepo.ListByUserID(ctx, userID)
+}
+
+// RevokeUserGrant withdraws the user's own consent for a client, identified by its public client ID
+func (s *OIDCClientService) RevokeUserGrant(ctx contextx.Contextx, userID uint, clientID string) error {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return errors.New("oidc grant not found")
+	}
+	if _, err := s.grantRepo.Get(ctx, userID, client.ID); err != nil {
+		return err
+	}
+	return s.revokeGrant(ctx, userID, client.ID)
+}
+
+func (s *OIDCClientService) revokeGrant(ctx contextx.Contextx, userID, clientID uint) error {
+	if err := s.tokenRepo.RevokeByUserAndClient(ctx, userID, clientID); err != nil {
+		return err
+	}
+	return s.grantRepo.Delete(ctx, userID, clientID)
+}
+
+func generateClientSecret() (string, error) {
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+	return oidcClientSecretPrefix + secret, nil
+}
+
+// validateRedirectURIs requires absolute URIs without fragments. Plain http is only accepted for
+// loopback addresses; custom schemes are allowed for native apps.
+func validateRedirectURIs(uris []string) ([]string, error) {
+	seen := make(map[string]bool)
+	valid := make([]string, 0, len(uris))
+	for _, raw := range uris {
+		raw = strings.TrimSpace(raw)
+		if seen[raw] {
+			continue
+		}
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Scheme == "" || parsed.Fragment != "" || strings.ContainsAny(raw, " \t\n") {
+			return nil, errors.New("invalid redirect uri")
+		}
+		if parsed.Scheme == "http" && !isLoopbackHost(parsed.Hostname()) {
+			return nil, errors.New("invalid redirect uri")
+		}
+		if (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host == "" {
+			return nil, errors.New("invalid redirect uri")
+		}
+		seen[raw] = true
+		valid = append(valid, raw)
+	}
+	if len(valid) == 0 {
+		return nil, errors.New("invalid redirect uri")
+	}
+	return valid, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateClientScopes checks scopes against the ones this provider supports
+func validateClientScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if seen[scope] {
+			continue
+		}
+		if !isSupportedOIDCScope(scope) {
+			return nil, errors.New("invalid scope")
+		}
+		seen[scope] = true
+		valid = append(valid, scope)
+	}
+	if len(valid) == 0 {
+		return nil, errors.New("invalid scope")
+	}
+	return valid, nil
+}
@@ -0,0 +1,693 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcScopeOpenID        = "openid"
+	oidcScopeProfile       = "profile"
+	oidcScopeEmail         = "email"
+	oidcScopeOfflineAccess = "offline_access" // Required for a refresh token
+
+	// Token prefixes keep provider tokens apart from API credentials and visible to secret scanners
+	oidcAccessTokenPrefix  = "bzb_at_"
+	oidcRefreshTokenPrefix = "bzb_rt_"
+
+	oidcTokenTypeAccess  = "access_token"
+	oidcTokenTypeRefresh = "refresh_token"
+)
+
+var oidcSupportedScopes = []string{oidcScopeOpenID, oidcScopeProfile, oidcScopeEmail, oidcScopeOfflineAccess}
+
+var oidcSupportedClaims = []string{
+	"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+	"name", "given_name", "family_name", "preferred_username", "picture", "website",
+	"gender", "birthdate", "zoneinfo", "locale", "updated_at", "email", "email_verified",
+}
+
+func isSupportedOIDCScope(scope string) bool {
+	for _, supported := range oidcSupportedScopes {
+		if scope == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuth2Error is a protocol error reported to clients in the RFC 6749 format
+type OAuth2Error struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	return e.Code
+}
+
+func newOAuth2Error(code, description string) *OAuth2Error {
+	return &OAuth2Error{Code: code, Description: description}
+}
+
+// ErrOAuth2InvalidRequest is returned for requests that cannot be parsed
+var ErrOAuth2InvalidRequest = newOAuth2Error("invalid_request", "malformed request")
+
+// OIDCProviderService implements the OpenID Connect provider: the authorization code flow with
+// PKCE, ID tokens, userinfo, token introspection and revocation. Access and refresh tokens are
+// opaque and stored as hashes; ID tokens are signed with the same keys as API tokens.
+type OIDCProviderService struct {
+	clientRepo     repository.OIDCClientRepository
+	grantRepo      repository.OIDCGrantRepository
+	codeRepo       repository.OIDCAuthorizationCodeRepository
+	tokenRepo      repository.OIDCTokenRepository
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	keys           *auth.KeySet
+	providerConfig *config.IdentityProviderConfig
+}
+
+func NewOIDCProviderService(
+	clientRepo repository.OIDCClientRepository,
+	grantRepo repository.OIDCGrantRepository,
+	codeRepo repository.OIDCAuthorizationCodeRepository,
+	tokenRepo repository.OIDCTokenRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	keys *auth.KeySet,
+	providerConfig *config.IdentityProviderConfig,
+) *OIDCProviderService {
+	return &OIDCProviderService{
+		clientRepo:     clientRepo,
+		grantRepo:      grantRepo,
+		codeRepo:       codeRepo,
+		tokenRepo:      tokenRepo,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		keys:           keys,
+		providerConfig: providerConfig,
+	}
+}
+
+// Discovery returns the OpenID Provider metadata. The endpoint paths match the routes in main.
+func (s *OIDCProviderService) Discovery() dto.OIDCDiscoveryDocument {
+	algorithms := []string{auth.AlgorithmRS256, auth.AlgorithmEdDSA}
+	if key, err := s.keys.SigningKey(); err == nil {
+		algorithms = []string{key.Algorithm}
+	}
+
+	issuer := s.providerConfig.Issuer
+	return dto.OIDCDiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             s.providerConfig.AuthorizeURL,
+		TokenEndpoint:                     issuer + "/api/oauth2/token",
+		UserinfoEndpoint:                  issuer + "/api/oauth2/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/api/oauth2/introspect",
+		RevocationEndpoint:                issuer + "/api/oauth2/revoke",
+		DeviceAuthorizationEndpoint:       issuer + "/api/oauth2/device_authorization",
+		ScopesSupported:                   oidcSupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", DeviceCodeGrantType},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   oidcSupportedClaims,
+	}
+}
+
+// Authorize checks an authorization request for the signed-in user and tells the consent page
+// whether consent is needed. Errors that can be reported to the client come back as RedirectTo.
+func (s *OIDCProviderService) Authorize(ctx contextx.Contextx, userID uint, req dto.OIDCAuthorizeRequest) (*dto.OIDCAuthorizeResponse, error) {
+	client, scopes, err := s.resolveAuthorizeRequest(ctx, req)
+	if err != nil {
+		return s.authorizeErrorResponse(req, err)
+	}
+
+	response := &dto.OIDCAuthorizeResponse{
+		Client:          dto.OIDCConsentClient{ClientID: client.ClientID, Name: client.Name},
+		Scopes:          scopes,
+		ConsentRequired: s.consentRequired(ctx, userID, client, scopes, req.Prompt),
+	}
+	if response.ConsentRequired && hasPrompt(req.Prompt, "none") {
+		response.RedirectTo = s.errorRedirect(req, newOAuth2Error("consent_required", "user consent is required"))
+	}
+	return response, nil
+}
+
+// Decide records the user's answer on the consent screen. Approval stores the grant and
+// redirects to the client with a single-use authorization code.
+func (s *OIDCProviderService) Decide(ctx contextx.Contextx, userID, sessionID uint, req dto.OIDCAuthorizeDecisionRequest) (*dto.OIDCAuthorizeResponse, error) {
+	client, scopes, err := s.resolveAuthorizeRequest(ctx, req.OIDCAuthorizeRequest)
+	if err != nil {
+		return s.authorizeErrorResponse(req.OIDCAuthorizeRequest, err)
+	}
+
+	response := &dto.OIDCAuthorizeResponse{
+		Client: dto.OIDCConsentClient{ClientID: client.ClientID, Name: client.Name},
+		Scopes: scopes,
+	}
+	if !req.Approve {
+		response.RedirectTo = s.errorRedirect(req.OIDCAuthorizeRequest, newOAuth2Error("access_denied", "the user denied the request"))
+		return response, nil
+	}
+
+	if err := s.saveGrant(ctx, userID, client.ID, scopes); err != nil {
+		return nil, err
+	}
+
+	code, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	authorizationCode := models.OIDCAuthorizationCode{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        strings.Join(scopes, " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      s.authTime(ctx, sessionID),
+		ExpiresAt:     time.Now().Add(s.providerConfig.CodeTTL),
+	}
+	if err := s.codeRepo.Create(ctx, &authorizationCode); err != nil {
+		return nil, err
+	}
+
+	response.RedirectTo = s.redirect(req.RedirectURI, map[string]string{"code": code, "state": req.State})
+	return response, nil
+}
+
+// Token handles the token endpoint. The client credentials come from the form or from Basic
+// authentication, filled in by the handler.
+func (s *OIDCProviderService) Token(ctx contextx.Contextx, req dto.OIDCTokenRequest) (*dto.OIDCTokenResponse, error) {
+	client, err := authenticateOIDCClient(ctx, s.clientRepo, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return s.refresh(ctx, client, req.RefreshToken)
+	case "":
+		return nil, newOAuth2Error("invalid_request", "grant_type is required")
+	default:
+		return nil, newOAuth2Error("unsupported_grant_type", "")
+	}
+}
+
+// UserInfo returns the claims of the user an access token was issued for
+func (s *OIDCProviderService) UserInfo(ctx contextx.Contextx, accessToken string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(accessToken, oidcAccessTokenPrefix) {
+		return nil, errors.New("invalid token")
+	}
+	token, err := s.tokenRepo.GetByAccessTokenHash(ctx, auth.HashToken(accessToken))
+	if err != nil || !token.IsAccessTokenActive() {
+		return nil, errors.New("invalid token")
+	}
+	if !token.HasScope(oidcScopeOpenID) {
+		return nil, errors.New("insufficient scope")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, token.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	return userClaims(user, token.ScopeList()), nil
+}
+
+// Introspect describes a token to a confidential client (RFC 7662). Unknown, expired and revoked
+// tokens are all reported as inactive.
+func (s *OIDCProviderService) Introspect(ctx contextx.Contextx, req dto.OIDCTokenActionRequest) (*dto.OIDCIntrospectionResponse, error) {
+	client, err := authenticateOIDCClient(ctx, s.clientRepo, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return nil, newOAuth2Error("invalid_client", "public clients cannot introspect tokens")
+	}
+
+	token, tokenType := s.findToken(ctx, req.Token, req.TokenTypeHint)
+	if token == nil {
+		return &dto.OIDCIntrospectionResponse{Active: false}, nil
+	}
+
+	response := &dto.OIDCIntrospectionResponse{
+		Active:    true,
+		Scope:     token.Scopes,
+		ClientID:  token.Client.ClientID,
+		TokenType: "Bearer",
+		Iat:       token.UpdatedAt.Unix(),
+		Sub:       strconv.FormatUint(uint64(token.UserID), 10),
+		Aud:       token.Client.ClientID,
+		Iss:       s.providerConfig.Issuer,
+	}
+	if tokenType == oidcTokenTypeRefresh {
+		response.TokenType = oidcTokenTypeRefresh
+		response.Exp = token.RefreshTokenExpiresAt.Unix()
+	} else {
+		response.Exp = token.AccessTokenExpiresAt.Unix()
+	}
+	if user, err := s.userRepo.GetByIDWithPreload(ctx, token.UserID, "UserInfo"); err == nil && user.UserInfo != nil {
+		response.Username = user.UserInfo.Username
+	}
+	return response, nil
+}
+
+// Revoke revokes an access or refresh token together with its counterpart (RFC 7009). Tokens that
+// are unknown or belong to another client are ignored, as the RFC requires.
+func (s *OIDCProviderService) Revoke(ctx contextx.Contextx, req dto.OIDCTokenActionRequest) error {
+	client, err := authenticateOIDCClient(ctx, s.clientRepo, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	token, _ := s.findToken(ctx, req.Token, req.TokenTypeHint)
+	if token == nil || token.ClientID != client.ID {
+		return nil
+	}
+	return s.tokenRepo.Revoke(ctx, token.ID)
+}
+
+// resolveAuthorizeRequest validates the client and redirect URI first; once those are trusted,
+// other problems are returned as *OAuth2Error so they can be sent back to the client
+func (s *OIDCProviderService) resolveAuthorizeRequest(ctx contextx.Contextx, req dto.OIDCAuthorizeRequest) (*models.OIDCClient, []string, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, nil, errors.New("invalid client")
+	}
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, nil, errors.New("invalid redirect uri")
+	}
+
+	if req.ResponseType != "code" {
+		return nil, nil, newOAuth2Error("unsupported_response_type", "only the code response type is supported")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return nil, nil, newOAuth2Error("invalid_scope", "scope is required")
+	}
+	allowed := make(map[string]bool)
+	for _, scope := range client.ScopeList() {
+		allowed[scope] = true
+	}
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return nil, nil, newOAuth2Error("invalid_scope", "scope not allowed for this client: "+scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+
+	// PKCE is required for every client, confidential ones included
+	if req.CodeChallenge == "" {
+		return nil, nil, newOAuth2Error("invalid_request", "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return nil, nil, newOAuth2Error("invalid_request", "code_challenge_method must be S256")
+	}
+	if len(req.CodeChallenge) < 43 || len(req.CodeChallenge) > 128 {
+		return nil, nil, newOAuth2Error("invalid_request", "invalid code_challenge")
+	}
+	if len(req.Nonce) > 255 {
+		return nil, nil, newOAuth2Error("invalid_request", "nonce is too long")
+	}
+	if hasPrompt(req.Prompt, "none") && len(strings.Fields(req.Prompt)) > 1 {
+		return nil, nil, newOAuth2Error("invalid_request", "prompt=none cannot be combined with other values")
+	}
+
+	return client, unique, nil
+}
+
+func (s *OIDCProviderService) authorizeErrorResponse(req dto.OIDCAuthorizeRequest, err error) (*dto.OIDCAuthorizeResponse, error) {
+	var oauthErr *OAuth2Error
+	if errors.As(err, &oauthErr) {
+		return &dto.OIDCAuthorizeResponse{RedirectTo: s.errorRedirect(req, oauthErr)}, nil
+	}
+	return nil, err
+}
+
+// consentRequired reports whether the user must be asked before the client gets the scopes
+func (s *OIDCProviderService) consentRequired(ctx contextx.Contextx, userID uint, client *models.OIDCClient, scopes []string, prompt string) bool {
+	if hasPrompt(prompt, "consent") {
+		return true
+	}
+	if client.SkipConsent {
+		return false
+	}
+	grant, err := s.grantRepo.Get(ctx, userID, client.ID)
+	if err != nil {
+		return true
+	}
+	granted := make(map[string]bool)
+	for _, scope := range grant.ScopeList() {
+		granted[scope] = true
+	}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// saveGrant adds the scopes to the user's consent for the client
+func (s *OIDCProviderService) saveGrant(ctx contextx.Contextx, userID, clientID uint, scopes []string) error {
+	grant, err := s.grantRepo.Get(ctx, userID, clientID)
+	if err != nil {
+		grant = &models.OIDCGrant{UserID: userID, ClientID: clientID}
+	}
+
+	merged := grant.ScopeList()
+	for _, scope := range scopes {
+		if !slices.Contains(merged, scope) {
+			merged = append(merged, scope)
+		}
+	}
+	grant.Scopes = strings.Join(merged, " ")
+	return s.grantRepo.Save(ctx, grant)
+}
+
+// authTime returns when the user last signed in or re-authenticated on the session that approved
+// the request
+func (s *OIDCProviderService) authTime(ctx contextx.Contextx, sessionID uint) time.Time {
+	if session, err := s.sessionRepo.GetByID(ctx, sessionID); err == nil {
+		return session.AuthenticatedAt
+	}
+	return time.Now()
+}
+
+// authenticateOIDCClient checks the credentials a client presents at a protocol endpoint. Public
+// clients only identify themselves.
+func authenticateOIDCClient(ctx contextx.Contextx, clientRepo repository.OIDCClientRepository, clientID, clientSecret string) (*models.OIDCClient, error) {
+	if clientID == "" {
+		return nil, newOAuth2Error("invalid_client", "client authentication is required")
+	}
+	client, err := clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, newOAuth2Error("invalid_client", "")
+	}
+	if client.IsPublic() {
+		if clientSecret != "" {
+			return nil, newOAuth2Error("invalid_client", "")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(clientSecret)), []byte(client.ClientSecretHash)) != 1 {
+		return nil, newOAuth2Error("invalid_client", "")
+	}
+	return client, nil
+}
+
+func (s *OIDCProviderService) exchangeCode(ctx contextx.Contextx, client *models.OIDCClient, req dto.OIDCTokenRequest) (*dto.OIDCTokenResponse, error) {
+	code, err := s.codeRepo.GetByCodeHash(ctx, auth.HashToken(req.Code))
+	if err != nil || code.ClientID != client.ID {
+		return nil, newOAuth2Error("invalid_grant", "invalid authorization code")
+	}
+
+	// A replayed code means it leaked, so everything issued from it is revoked
+	if code.UsedAt != nil {
+		_ = s.tokenRepo.RevokeByAuthorizationCodeID(ctx, code.ID)
+		return nil, newOAuth2Error("invalid_grant", "invalid authorization code")
+	}
+	if !code.IsValid() || code.RedirectURI != req.RedirectURI {
+		return nil, newOAuth2Error("invalid_grant", "invalid authorization code")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, newOAuth2Error("invalid_grant", "invalid code_verifier")
+	}
+
+	used, err := s.codeRepo.MarkUsed(ctx, code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		_ = s.tokenRepo.RevokeByAuthorizationCodeID(ctx, code.ID)
+		return nil, newOAuth2Error("invalid_grant", "invalid authorization code")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, code.UserID, "UserInfo")
+	if err != nil {
+		return nil, newOAuth2Error("invalid_grant", "user not found")
+	}
+
+	scopes := strings.Fields(code.Scopes)
+	accessToken, refreshToken, err := s.generateTokenPair(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	token := models.OIDCToken{
+		ClientID:            client.ID,
+		UserID:              user.ID,
+		AuthorizationCodeID: &code.ID,
+		Scopes:              code.Scopes,
+		AuthTime:            code.AuthTime,
+	}
+	s.applyTokenPair(&token, accessToken, refreshToken)
+	if err := s.tokenRepo.Create(ctx, &token); err != nil {
+		return nil, err
+	}
+
+	return s.buildTokenResponse(client, user, &token, accessToken, refreshToken, code.Nonce)
+}
+
+// refresh rotates a refresh token. The old access and refresh tokens stop working. Presenting an
+// already rotated-out refresh token revokes the whole grant, since it indicates the token was
+// copied by someone else. So does losing a race against a concurrent refresh with the same token.
+func (s *OIDCProviderService) refresh(ctx contextx.Contextx, client *models.OIDCClient, refreshToken string) (*dto.OIDCTokenResponse, error) {
+	if !strings.HasPrefix(refreshToken, oidcRefreshTokenPrefix) {
+		return nil, newOAuth2Error("invalid_grant", "invalid refresh token")
+	}
+	hash := auth.HashToken(refreshToken)
+	token, err := s.tokenRepo.GetByRefreshTokenHash(ctx, hash)
+	if err != nil {
+		if reused, err := s.tokenRepo.GetByPreviousRefreshTokenHash(ctx, hash); err == nil {
+			s.revokeGrant(ctx, reused.UserID, reused.ClientID)
+		}
+		return nil, newOAuth2Error("invalid_grant", "invalid refresh token")
+	}
+	if token.ClientID != client.ID || !token.IsRefreshTokenActive() {
+		return nil, newOAuth2Error("invalid_grant", "invalid refresh token")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, token.UserID, "UserInfo")
+	if err != nil {
+		return nil, newOAuth2Error("invalid_grant", "user not found")
+	}
+
+	newAccessToken, newRefreshToken, err := s.generateTokenPair(token.ScopeList())
+	if err != nil {
+		return nil, err
+	}
+	token.PreviousRefreshTokenHash = &hash
+	s.applyTokenPair(token, newAccessToken, newRefreshToken)
+	rotated, err := s.tokenRepo.Rotate(ctx, token, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		s.revokeGrant(ctx, token.UserID, token.ClientID)
+		return nil, newOAuth2Error("invalid_grant", "invalid refresh token")
+	}
+
+	return s.buildTokenResponse(client, user, token, newAccessToken, newRefreshToken, "")
+}
+
+// revokeGrant revokes every token of the user for the client and withdraws the consent, so that
+// neither the thief nor the client can continue without the user
+func (s *OIDCProviderService) revokeGrant(ctx contextx.Contextx, userID, clientID uint) {
+	_ = s.tokenRepo.RevokeByUserAndClient(ctx, userID, clientID)
+	_ = s.grantRepo.Delete(ctx, userID, clientID)
+}
+
+// generateTokenPair creates a new access token, plus a refresh token when offline access was granted
+func (s *OIDCProviderService) generateTokenPair(scopes []string) (string, string, error) {
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", errors.New("failed to generate token")
+	}
+	accessToken := oidcAccessTokenPrefix + secret
+
+	if !slices.Contains(scopes, oidcScopeOfflineAccess) {
+		return accessToken, "", nil
+	}
+	if secret, err = auth.GenerateOpaqueToken(); err != nil {
+		return "", "", errors.New("failed to generate token")
+	}
+	return accessToken, oidcRefreshTokenPrefix + secret, nil
+}
+
+func (s *OIDCProviderService) applyTokenPair(token *models.OIDCToken, accessToken, refreshToken string) {
+	now := time.Now()
+	token.AccessTokenHash = auth.HashToken(accessToken)
+	token.AccessTokenExpiresAt = now.Add(s.providerConfig.AccessTokenTTL)
+	if refreshToken != "" {
+		refreshHash := auth.HashToken(refreshToken)
+		refreshExpiresAt := now.Add(s.providerConfig.RefreshTokenTTL)
+		token.RefreshTokenHash = &refreshHash
+		token.RefreshTokenExpiresAt = &refreshExpiresAt
+	}
+}
+
+func (s *OIDCProviderService) buildTokenResponse(client *models.OIDCClient, user *models.User, token *models.OIDCToken, accessToken, refreshToken, nonce string) (*dto.OIDCTokenResponse, error) {
+	response := &dto.OIDCTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.providerConfig.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        token.Scopes,
+	}
+
+	if token.HasScope(oidcScopeOpenID) {
+		idToken, err := s.signIDToken(client, user, token, nonce)
+		if err != nil {
+			return nil, errors.New("failed to generate token")
+		}
+		response.IDToken = idToken
+	}
+	return response, nil
+}
+
+// signIDToken issues the ID token. It has no user_id or sid claim, so JWTMiddleware never accepts
+// it as an API access token.
+func (s *OIDCProviderService) signIDToken(client *models.OIDCClient, user *models.User, token *models.OIDCToken, nonce string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       s.providerConfig.Issuer,
+		"aud":       client.ClientID,
+		"azp":       client.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.providerConfig.IDTokenTTL).Unix(),
+		"auth_time": token.AuthTime.Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for name, value := range userClaims(user, token.ScopeList()) {
+		claims[name] = value
+	}
+	return s.keys.Sign(claims)
+}
+
+// findToken looks a presented token up as the hinted type first, then as the other type.
+// It returns nil when the token is unknown or no longer active.
+func (s *OIDCProviderService) findToken(ctx contextx.Contextx, plain, hint string) (*models.OIDCToken, string) {
+	hash := auth.HashToken(plain)
+	lookups := []string{oidcTokenTypeAccess, oidcTokenTypeRefresh}
+	if hint == oidcTokenTypeRefresh {
+		lookups = []string{oidcTokenTypeRefresh, oidcTokenTypeAccess}
+	}
+
+	for _, tokenType := range lookups {
+		if tokenType == oidcTokenTypeAccess {
+			if token, err := s.tokenRepo.GetByAccessTokenHash(ctx, hash); err == nil && token.IsAccessTokenActive() {
+				return token, tokenType
+			}
+		} else {
+			if token, err := s.tokenRepo.GetByRefreshTokenHash(ctx, hash); err == nil && token.IsRefreshTokenActive() {
+				return token, tokenType
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (s *OIDCProviderService) errorRedirect(req dto.OIDCAuthorizeRequest, oauthErr *OAuth2Error) string {
+	return s.redirect(req.RedirectURI, map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"state":             req.State,
+	})
+}
+
+// redirect appends response parameters and the issuer (RFC 9207) to the client's redirect URI
+func (s *OIDCProviderService) redirect(redirectURI string, params map[string]string) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := target.Query()
+	for name, value := range params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	query.Set("iss", s.providerConfig.Issuer)
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// userClaims builds the standard claims the scopes give access to
+func userClaims(user *models.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+
+	info := user.UserInfo
+	if info != nil && slices.Contains(scopes, oidcScopeProfile) {
+		claims["name"] = strings.TrimSpace(info.FirstName + " " + info.LastName)
+		claims["given_name"] = info.FirstName
+		claims["family_name"] = info.LastName
+		claims["preferred_username"] = info.Username
+		claims["zoneinfo"] = info.Timezone
+		claims["locale"] = info.Language
+		claims["updated_at"] = info.UpdatedAt.Unix()
+		if info.AvatarURL != "" {
+			claims["picture"] = info.AvatarURL
+		}
+		if info.Website != "" {
+			claims["website"] = info.Website
+		}
+		if info.Gender != "" {
+			claims["gender"] = info.Gender
+		}
+		if info.DateOfBirth != nil {
+			claims["birthdate"] = info.DateOfBirth.Format("2006-01-02")
+		}
+	}
+	if slices.Contains(scopes, oidcScopeEmail) {
+		claims["email"] = user.GetPrimaryEmail()
+		claims["email_verified"] = user.EmailVerified
+	}
+	return claims
+}
+
+// verifyCodeChallenge checks a PKCE verifier against its S256 challenge
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func hasPrompt(prompt, value string) bool {
+	return slices.Contains(strings.Fields(prompt), value)
+}
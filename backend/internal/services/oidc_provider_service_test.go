@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+)
+
+// issueOIDCTokens stores a consent and a token pair with offline access for the user, as the code
+// exchange would, and returns the refresh token
+func (env *testEnv) issueOIDCTokens(t *testing.T, userID uint, client *models.OIDCClient) string {
+	t.Helper()
+
+	scopes := "openid offline_access"
+	if err := env.db.Create(&models.OIDCGrant{UserID: userID, ClientID: client.ID, Scopes: scopes}).Error; err != nil {
+		t.Fatalf("create oidc grant: %v", err)
+	}
+	accessToken, refreshToken, err := env.oidcProviderService.generateTokenPair([]string{"openid", "offline_access"})
+	if err != nil {
+		t.Fatalf("generateTokenPair: %v", err)
+	}
+	token := models.OIDCToken{ClientID: client.ID, UserID: userID, Scopes: scopes, AuthTime: time.Now()}
+	env.oidcProviderService.applyTokenPair(&token, accessToken, refreshToken)
+	if err := env.db.Create(&token).Error; err != nil {
+		t.Fatalf("create oidc token: %v", err)
+	}
+	return refreshToken
+}
+
+func newOIDCTestClient(t *testing.T, env *testEnv, clientID string) *models.OIDCClient {
+	t.Helper()
+	client := models.OIDCClient{ClientID: clientID, Name: clientID, RedirectURIs: "https://app.example/cb", Scopes: "openid offline_access"}
+	if err := env.db.Create(&client).Error; err != nil {
+		t.Fatalf("create oidc client: %v", err)
+	}
+	return &client
+}
+
+func TestOIDCRefreshRotates(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	client := newOIDCTestClient(t, env, "app")
+	refreshToken := env.issueOIDCTokens(t, user.ID, client)
+
+	for i := 0; i < 3; i++ {
+		response, err := env.oidcProviderService.Token(ctx, dto.OIDCTokenRequest{GrantType: "refresh_token", RefreshToken: refreshToken, ClientID: client.ClientID})
+		if err != nil {
+			t.Fatalf("refresh #%d: %v", i+1, err)
+		}
+		if response.RefreshToken == "" || response.RefreshToken == refreshToken {
+			t.Fatalf("refresh #%d did not rotate the refresh token", i+1)
+		}
+		refreshToken = response.RefreshToken
+	}
+}
+
+func TestOIDCRefreshReuseRevokesGrant(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	client := newOIDCTestClient(t, env, "app")
+	other := newOIDCTestClient(t, env, "other")
+	stolen := env.issueOIDCTokens(t, user.ID, client)
+	otherRefreshToken := env.issueOIDCTokens(t, user.ID, other)
+
+	refresh := func(client *models.OIDCClient, refreshToken string) (*dto.OIDCTokenResponse, error) {
+		return env.oidcProviderService.Token(ctx, dto.OIDCTokenRequest{GrantType: "refresh_token", RefreshToken: refreshToken, ClientID: client.ClientID})
+	}
+
+	// The client rotates the token; the copy is replayed afterwards
+	rotated, err := refresh(client, stolen)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	var oauthErr *OAuth2Error
+	if _, err := refresh(client, stolen); !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Fatalf("replayed refresh error = %v, want invalid_grant", err)
+	}
+
+	// The legitimate client is cut off as well and must ask for consent again
+	if _, err := refresh(client, rotated.RefreshToken); err == nil {
+		t.Error("current refresh token still works after the replay")
+	}
+	token, err := env.oidcProviderService.tokenRepo.GetByAccessTokenHash(ctx, auth.HashToken(rotated.AccessToken))
+	if err != nil {
+		t.Fatalf("GetByAccessTokenHash: %v", err)
+	}
+	if token.IsAccessTokenActive() {
+		t.Error("current access token still active after the replay")
+	}
+	if _, err := env.oidcProviderService.grantRepo.Get(ctx, user.ID, client.ID); err == nil {
+		t.Error("consent kept after the replay")
+	}
+
+	// Other clients of the user are not affected
+	if _, err := refresh(other, otherRefreshToken); err != nil {
+		t.Errorf("refresh for another client: %v", err)
+	}
+}
+
+func TestOIDCTokenRotateRequiresCurrentRefreshToken(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	client := newOIDCTestClient(t, env, "app")
+	refreshToken := env.issueOIDCTokens(t, user.ID, client)
+
+	// A concurrent refresh rotates the token between this lookup and the update below
+	token, err := env.oidcProviderService.tokenRepo.GetByRefreshTokenHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		t.Fatalf("GetByRefreshTokenHash: %v", err)
+	}
+	if _, err := env.oidcProviderService.refresh(ctx, client, refreshToken); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	rotated, err := env.oidcProviderService.tokenRepo.Rotate(ctx, token, auth.HashToken(refreshToken))
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotated {
+		t.Fatal("Rotate succeeded with a rotated-out refresh token")
+	}
+}
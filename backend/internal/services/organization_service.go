@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// defaultOrganizationRole is granted to members added without explicit roles
+const defaultOrganizationRole = "user"
+
+// OrganizationService manages organizations (tenants) and their members. Roles inside an
+// organization are Casbin assignments in the organization's domain, kept by the RBAC service.
+type OrganizationService struct {
+	organizationRepo repository.OrganizationRepository
+	userRepo         repository.UserRepository
+	rbacService      *RBACService
+}
+
+func NewOrganizationService(
+	organizationRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	rbacService *RBACService,
+) *OrganizationService {
+	return &OrganizationService{
+		organizationRepo: organizationRepo,
+		userRepo:         userRepo,
+		rbacService:      rbacService,
+	}
+}
+
+// CreateOrganization creates an organization with the creator as its first member and admin
+func (s *OrganizationService) CreateOrganization(ctx contextx.Contextx, userID uint, req dto.CreateOrganizationRequest) (*models.Organization, error) {
+	organization := models.Organization{
+		Name:        strings.TrimSpace(req.Name),
+		Slug:        strings.ToLower(strings.TrimSpace(req.Slug)),
+		Description: req.Description,
+		IsActive:    true,
+		CreatedByID: userID,
+	}
+	if err := organization.Validate(); err != nil {
+		return nil, err
+	}
+
+	taken, err := s.organizationRepo.IsSlugTaken(ctx, organization.Slug, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, errors.New("organization slug taken")
+	}
+
+	if err := s.organizationRepo.Create(ctx, &organization); err != nil {
+		return nil, err
+	}
+	if err := s.organizationRepo.AddMember(ctx, &models.OrganizationMember{OrganizationID: organization.ID, UserID: userID}); err != nil {
+		return nil, err
+	}
+	if err := s.rbacService.AssignRoleToUser(userID, "admin", organization.ID); err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// ListUserOrganizations returns the active organizations the user is a member of
+func (s *OrganizationService) ListUserOrganizations(ctx contextx.Contextx, userID uint) ([]models.Organization, error) {
+	return s.organizationRepo.ListByUserID(ctx, userID)
+}
+
+// GetOrganization returns an organization by ID
+func (s *OrganizationService) GetOrganization(ctx contextx.Contextx, orgID uint) (*models.Organization, error) {
+	return s.organizationRepo.GetByID(ctx, orgID)
+}
+
+// UpdateOrganization changes the name, description or active flag of an organization
+func (s *OrganizationService) UpdateOrganization(ctx contextx.Contextx, orgID uint, req dto.UpdateOrganizationRequest) (*models.Organization, error) {
+	organization, err := s.organizationRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		organization.Name = strings.TrimSpace(req.Name)
+	}
+	if req.Description != "" {
+		organization.Description = req.Description
+	}
+	if req.IsActive != nil {
+		organization.IsActive = *req.IsActive
+	}
+	if err := organization.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.organizationRepo.Update(ctx, organization); err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+// DeleteOrganization deletes an organization, its memberships and every role and policy in its domain
+func (s *OrganizationService) DeleteOrganization(ctx contextx.Contextx, orgID uint) error {
+	if _, err := s.organizationRepo.GetByID(ctx, orgID); err != nil {
+		return err
+	}
+	if err := s.organizationRepo.Delete(ctx, orgID); err != nil {
+		return err
+	}
+	return s.rbacService.DeleteOrganizationPolicies(orgID)
+}
+
+// ListMembers returns the members of an organization with the roles they hold in it
+func (s *OrganizationService) ListMembers(ctx contextx.Contextx, orgID uint) ([]dto.OrganizationMemberResponse, error) {
+	members, err := s.organizationRepo.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.OrganizationMemberResponse, len(members))
+	for i := range members {
+		roles, err := s.rbacService.GetUserRolesInOrganization(members[i].UserID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = dto.ToOrganizationMemberResponse(&members[i], roles)
+	}
+	return responses, nil
+}
+
+// AddMember adds a user to an organization with the given roles, or the default role
+func (s *OrganizationService) AddMember(ctx contextx.Contextx, orgID uint, req dto.AddOrganizationMemberRequest) error {
+	if _, err := s.organizationRepo.GetByID(ctx, orgID); err != nil {
+		return err
+	}
+	if _, err := s.userRepo.GetByID(ctx, req.UserID); err != nil {
+		return errors.New("user not found")
+	}
+	if _, err := s.organizationRepo.GetMember(ctx, orgID, req.UserID); err == nil {
+		return errors.New("already an organization member")
+	}
+
+	roles := req.Roles
+	if len(roles) == 0 {
+		roles = []string{defaultOrganizationRole}
+	}
+	if err := s.validateRoles(ctx, roles); err != nil {
+		return err
+	}
+
+	if err := s.organizationRepo.AddMember(ctx, &models.OrganizationMember{OrganizationID: orgID, UserID: req.UserID}); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if err := s.rbacService.AssignRoleToUser(req.UserID, role, orgID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateMemberRoles replaces the roles a member holds inside an organization
+func (s *OrganizationService) UpdateMemberRoles(ctx contextx.Contextx, orgID, userID uint, req dto.UpdateOrganizationMemberRequest) error {
+	if _, err := s.organizationRepo.GetMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	if err := s.validateRoles(ctx, req.Roles); err != nil {
+		return err
+	}
+
+	if err := s.rbacService.RemoveUserFromOrganization(userID, orgID); err != nil {
+		return err
+	}
+	for _, role := range req.Roles {
+		if err := s.rbacService.AssignRoleToUser(userID, role, orgID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveMember removes a user from an organization together with their roles in it
+func (s *OrganizationService) RemoveMember(ctx contextx.Contextx, orgID, userID uint) error {
+	if err := s.organizationRepo.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return s.rbacService.RemoveUserFromOrganization(userID, orgID)
+}
+
+// CheckAccess verifies that the user may act in an organization. Members may act in active
+// organizations; users allowed to read organizations globally (platform administrators) may act
+// in any organization, including deactivated ones.
+func (s *OrganizationService) CheckAccess(ctx contextx.Contextx, userID, orgID uint) error {
+	organization, err := s.organizationRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.organizationRepo.GetMember(ctx, orgID, userID); err == nil && organization.IsActive {
+		return nil
+	}
+
+	allowed, err := s.rbacService.CheckPermission(userID, models.PermissionViewOrganizations.Resource.String(), models.PermissionViewOrganizations.Action.String())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		if !organization.IsActive {
+			return errors.New("organization not found")
+		}
+		return errors.New("not an organization member")
+	}
+	return nil
+}
+
+// validateRoles checks that every role exists and is active before any assignment is changed
+func (s *OrganizationService) validateRoles(ctx contextx.Contextx, roles []string) error {
+	for _, name := range roles {
+		role, err := s.rbacService.GetRoleByName(ctx, name)
+		if err != nil || !role.IsActive {
+			return errors.New("invalid role")
+		}
+	}
+	return nil
+}
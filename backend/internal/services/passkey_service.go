@@ -0,0 +1,352 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/webauthn"
+	"bezbase/internal/repository"
+)
+
+type PasskeyService struct {
+	webAuthnRepo     repository.WebAuthnRepository
+	authProviderRepo repository.AuthProviderRepository
+	userRepo         repository.UserRepository
+	sessionService   *SessionService
+	relyingParty     *webauthn.Config
+}
+
+func NewPasskeyService(
+	webAuthnRepo repository.WebAuthnRepository,
+	authProviderRepo repository.AuthProviderRepository,
+	userRepo repository.UserRepository,
+	sessionService *SessionService,
+	webAuthnConfig *config.WebAuthnConfig,
+) *PasskeyService {
+	return &PasskeyService{
+		webAuthnRepo:     webAuthnRepo,
+		authProviderRepo: authProviderRepo,
+		userRepo:         userRepo,
+		sessionService:   sessionService,
+		relyingParty: &webauthn.Config{
+			RPID:    webAuthnConfig.RPID,
+			RPName:  webAuthnConfig.RPName,
+			Origins: webAuthnConfig.Origins,
+			Timeout: webAuthnConfig.Timeout,
+		},
+	}
+}
+
+// BeginRegistration starts a registration ceremony for the current user
+func (s *PasskeyService) BeginRegistration(ctx contextx.Contextx, userID uint) (*webauthn.CreationOptions, error) {
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	existing, err := s.webAuthnRepo.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(existing))
+	for _, credential := range existing {
+		descriptor, err := credentialDescriptor(&credential)
+		if err != nil {
+			continue
+		}
+		exclude = append(exclude, descriptor)
+	}
+
+	challenge, err := s.createChallenge(ctx, models.WebAuthnCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := user.GetPrimaryEmail()
+	if user.UserInfo != nil && user.UserInfo.Username != "" {
+		name = user.UserInfo.Username
+	}
+	displayName := strings.TrimSpace(user.GetFullName())
+	if displayName == "" {
+		displayName = name
+	}
+
+	return s.relyingParty.NewCreationOptions(challenge, webauthn.UserEntity{
+		ID:          userHandle(userID),
+		Name:        name,
+		DisplayName: displayName,
+	}, exclude), nil
+}
+
+// FinishRegistration verifies the authenticator response and stores the new passkey
+func (s *PasskeyService) FinishRegistration(ctx contextx.Contextx, userID uint, req dto.PasskeyRegistrationRequest) (*dto.PasskeyResponse, error) {
+	challenge, err := s.consumeChallenge(ctx, req.Credential.Response.ClientDataJSON, models.WebAuthnCeremonyRegistration)
+	if err != nil || challenge.UserID != userID {
+		return nil, errors.New("invalid passkey challenge")
+	}
+	challengeBytes, _ := base64.RawURLEncoding.DecodeString(challenge.Challenge)
+
+	credential, err := s.relyingParty.VerifyRegistration(challengeBytes, req.Credential.Response.ClientDataJSON, req.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.New("passkey verification failed")
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	if _, err := s.webAuthnRepo.GetCredentialByCredentialID(ctx, credentialID); err == nil {
+		return nil, errors.New("passkey already registered")
+	}
+
+	provider, err := s.ensurePasskeyProvider(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		count, _ := s.webAuthnRepo.CountCredentialsByUserID(ctx, userID)
+		name = fmt.Sprintf("Passkey %d", count+1)
+	}
+
+	stored := models.WebAuthnCredential{
+		UserID:         userID,
+		AuthProviderID: provider.ID,
+		CredentialID:   credentialID,
+		PublicKey:      credential.PublicKey,
+		AAGUID:         formatAAGUID(credential.AAGUID),
+		SignCount:      credential.SignCount,
+		Transports:     strings.Join(req.Credential.Response.Transports, ","),
+		Name:           name,
+		BackupEligible: credential.BackupEligible,
+		BackupState:    credential.BackupState,
+	}
+	if err := s.webAuthnRepo.CreateCredential(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	response := dto.ToPasskeyResponse(&stored)
+	return &response, nil
+}
+
+// BeginLogin starts a passwordless login ceremony for discoverable credentials
+func (s *PasskeyService) BeginLogin(ctx contextx.Contextx) (*webauthn.RequestOptions, error) {
+	challenge, err := s.createChallenge(ctx, models.WebAuthnCeremonyLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.relyingParty.NewRequestOptions(challenge, nil), nil
+}
+
+// FinishLogin verifies a passkey assertion and starts a session. A user-verified
+// passkey already combines possession and knowledge/biometrics, so no MFA challenge follows.
+func (s *PasskeyService) FinishLogin(ctx contextx.Contextx, req dto.PasskeyLoginRequest) (*dto.AuthResponse, error) {
+	challenge, err := s.consumeChallenge(ctx, req.Credential.Response.ClientDataJSON, models.WebAuthnCeremonyLogin)
+	if err != nil {
+		return nil, errors.New("invalid passkey challenge")
+	}
+	challengeBytes, _ := base64.RawURLEncoding.DecodeString(challenge.Challenge)
+
+	credentialID := base64.RawURLEncoding.EncodeToString(req.Credential.RawID)
+	if len(req.Credential.RawID) == 0 {
+		credentialID = req.Credential.ID
+	}
+	credential, err := s.webAuthnRepo.GetCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, errors.New("passkey verification failed")
+	}
+
+	if handle := req.Credential.Response.UserHandle; len(handle) > 0 && string(handle) != string(userHandle(credential.UserID)) {
+		return nil, errors.New("passkey verification failed")
+	}
+
+	assertion, err := s.relyingParty.VerifyAssertion(
+		challengeBytes,
+		req.Credential.Response.ClientDataJSON,
+		req.Credential.Response.AuthenticatorData,
+		req.Credential.Response.Signature,
+		credential.PublicKey,
+		credential.SignCount,
+	)
+	if err != nil {
+		return nil, errors.New("passkey verification failed")
+	}
+
+	now := time.Now()
+	credential.SignCount = assertion.SignCount
+	credential.BackupState = assertion.BackupState
+	credential.LastUsedAt = &now
+	if err := s.webAuthnRepo.UpdateCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, credential.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Update last login time
+	user.LastLoginAt = &now
+	_ = s.userRepo.Update(ctx, user)
+
+	return s.sessionService.IssueTokens(ctx, user)
+}
+
+// ListPasskeys returns the passkeys registered by a user
+func (s *PasskeyService) ListPasskeys(ctx contextx.Contextx, userID uint) ([]dto.PasskeyResponse, error) {
+	credentials, err := s.webAuthnRepo.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToPasskeyResponses(credentials), nil
+}
+
+// RenamePasskey changes the display name of one of the user's passkeys
+func (s *PasskeyService) RenamePasskey(ctx contextx.Contextx, userID, passkeyID uint, name string) (*dto.PasskeyResponse, error) {
+	credential, err := s.webAuthnRepo.GetCredentialByID(ctx, passkeyID)
+	if err != nil || credential.UserID != userID {
+		return nil, errors.New("passkey not found")
+	}
+
+	credential.Name = strings.TrimSpace(name)
+	if err := s.webAuthnRepo.UpdateCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	response := dto.ToPasskeyResponse(credential)
+	return &response, nil
+}
+
+// DeletePasskey removes one of the user's passkeys, unlinking the passkey provider with the last one
+func (s *PasskeyService) DeletePasskey(ctx contextx.Contextx, userID, passkeyID uint) error {
+	credential, err := s.webAuthnRepo.GetCredentialByID(ctx, passkeyID)
+	if err != nil || credential.UserID != userID {
+		return errors.New("passkey not found")
+	}
+
+	// The last passkey may only go when the user can still sign in some other way
+	count, err := s.webAuthnRepo.CountCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		hasOther, err := hasOtherCredential(ctx, s.authProviderRepo, userID, models.ProviderPasskey)
+		if err != nil {
+			return err
+		}
+		if !hasOther {
+			return errors.New("last sign-in method")
+		}
+	}
+
+	if err := s.webAuthnRepo.DeleteCredential(ctx, credential.ID); err != nil {
+		return err
+	}
+
+	remaining, err := s.webAuthnRepo.CountCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return s.authProviderRepo.DeleteByUserIDAndProvider(ctx, userID, models.ProviderPasskey)
+	}
+	return nil
+}
+
+func (s *PasskeyService) createChallenge(ctx contextx.Contextx, ceremony string, userID uint) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, errors.New("failed to generate challenge")
+	}
+
+	pending := models.WebAuthnChallenge{
+		Challenge: webauthn.EncodeChallenge(challenge),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.relyingParty.Timeout),
+	}
+	if err := s.webAuthnRepo.CreateChallenge(ctx, &pending); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeChallenge looks up the challenge echoed in clientDataJSON and invalidates it
+func (s *PasskeyService) consumeChallenge(ctx contextx.Contextx, clientDataJSON []byte, ceremony string) (*models.WebAuthnChallenge, error) {
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.webAuthnRepo.ConsumeChallenge(ctx, strings.TrimRight(clientData.Challenge, "="), ceremony)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.IsExpired() {
+		return nil, errors.New("challenge expired")
+	}
+	return challenge, nil
+}
+
+// ensurePasskeyProvider returns the user's passkey AuthProvider, creating it with the first passkey
+func (s *PasskeyService) ensurePasskeyProvider(ctx contextx.Contextx, userID uint) (*models.AuthProvider, error) {
+	if provider, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, userID, models.ProviderPasskey); err == nil {
+		return provider, nil
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	userName := user.GetPrimaryEmail()
+	if user.UserInfo != nil && user.UserInfo.Username != "" {
+		userName = user.UserInfo.Username
+	}
+
+	provider := models.AuthProvider{
+		UserID:     userID,
+		Provider:   models.ProviderPasskey,
+		ProviderID: base64.RawURLEncoding.EncodeToString(userHandle(userID)),
+		UserName:   userName,
+		Verified:   true,
+	}
+	if err := s.authProviderRepo.Create(ctx, &provider); err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// userHandle is the opaque WebAuthn user.id for a user
+func userHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+func credentialDescriptor(credential *models.WebAuthnCredential) (webauthn.CredentialDescriptor, error) {
+	id, err := base64.RawURLEncoding.DecodeString(credential.CredentialID)
+	if err != nil {
+		return webauthn.CredentialDescriptor{}, err
+	}
+	descriptor := webauthn.CredentialDescriptor{Type: "public-key", ID: id}
+	if credential.Transports != "" {
+		descriptor.Transports = strings.Split(credential.Transports, ",")
+	}
+	return descriptor, nil
+}
+
+// formatAAGUID renders the authenticator model identifier as a UUID string
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"testing"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/webauthn"
+	"bezbase/internal/pkg/webauthn/webauthntest"
+)
+
+var passkeyAlgorithms = map[string]int64{
+	"ES256":   webauthn.AlgES256,
+	"Ed25519": webauthn.AlgEdDSA,
+}
+
+func newTestAuthenticator(t *testing.T, env *testEnv, algorithm int64) *webauthntest.Authenticator {
+	t.Helper()
+	authenticator, err := webauthntest.New(env.cfg.WebAuthn.RPID, env.cfg.WebAuthn.Origins[0], algorithm)
+	if err != nil {
+		t.Fatalf("webauthntest.New: %v", err)
+	}
+	return authenticator
+}
+
+// registerPasskey runs a registration ceremony for the user with the authenticator
+func registerPasskey(t *testing.T, env *testEnv, user *models.User, authenticator *webauthntest.Authenticator) *dto.PasskeyResponse {
+	t.Helper()
+
+	ctx := contextx.Background()
+	options, err := env.passkeyService.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	credential, err := authenticator.Register(options.Challenge)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	passkey, err := env.passkeyService.FinishRegistration(ctx, user.ID, dto.PasskeyRegistrationRequest{Name: "Laptop", Credential: *credential})
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return passkey
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	for name, algorithm := range passkeyAlgorithms {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := contextx.Background()
+			user := env.registerUser(t, "alice", "alice@example.com")
+			authenticator := newTestAuthenticator(t, env, algorithm)
+
+			passkey := registerPasskey(t, env, user, authenticator)
+			if passkey.Name != "Laptop" {
+				t.Errorf("passkey name = %q, want %q", passkey.Name, "Laptop")
+			}
+			if _, err := env.authProviderRepo.GetByUserIDAndProvider(ctx, user.ID, models.ProviderPasskey); err != nil {
+				t.Errorf("passkey auth provider not created: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				options, err := env.passkeyService.BeginLogin(ctx)
+				if err != nil {
+					t.Fatalf("BeginLogin: %v", err)
+				}
+				assertion, err := authenticator.Assert(options.Challenge, userHandle(user.ID))
+				if err != nil {
+					t.Fatalf("Assert: %v", err)
+				}
+				resp, err := env.passkeyService.FinishLogin(ctx, dto.PasskeyLoginRequest{Credential: *assertion})
+				if err != nil {
+					t.Fatalf("FinishLogin #%d: %v", i+1, err)
+				}
+				if resp.Token == "" || resp.RefreshToken == "" || resp.User == nil || resp.User.ID != user.ID {
+					t.Fatalf("FinishLogin #%d returned %+v, want tokens for user %d", i+1, resp, user.ID)
+				}
+			}
+
+			stored, err := env.webAuthnRepo.GetCredentialsByUserID(ctx, user.ID)
+			if err != nil || len(stored) != 1 {
+				t.Fatalf("GetCredentialsByUserID = %v, %v", stored, err)
+			}
+			if stored[0].SignCount != authenticator.SignCount || stored[0].LastUsedAt == nil {
+				t.Errorf("stored sign count = %d, last used %v; want %d and a time", stored[0].SignCount, stored[0].LastUsedAt, authenticator.SignCount)
+			}
+		})
+	}
+}
+
+func TestPasskeyFinishRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(a *webauthntest.Authenticator, challenge []byte) []byte
+		wantErr string
+	}{
+		{
+			name:    "wrong challenge",
+			tamper:  func(a *webauthntest.Authenticator, challenge []byte) []byte { return flipLastByte(challenge) },
+			wantErr: "invalid passkey challenge",
+		},
+		{
+			name: "wrong origin",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Origin = "https://evil.example"
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "wrong rp id hash",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.RPID = "evil.example"
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "user not present",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserPresent
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "user not verified",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserVerified
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := contextx.Background()
+			user := env.registerUser(t, "alice", "alice@example.com")
+			authenticator := newTestAuthenticator(t, env, webauthn.AlgES256)
+
+			options, err := env.passkeyService.BeginRegistration(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("BeginRegistration: %v", err)
+			}
+			credential, err := authenticator.Register(tt.tamper(authenticator, options.Challenge))
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			_, err = env.passkeyService.FinishRegistration(ctx, user.ID, dto.PasskeyRegistrationRequest{Credential: *credential})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("FinishRegistration error = %v, want %q", err, tt.wantErr)
+			}
+
+			if count, _ := env.webAuthnRepo.CountCredentialsByUserID(ctx, user.ID); count != 0 {
+				t.Errorf("%d passkeys stored after a rejected registration", count)
+			}
+		})
+	}
+}
+
+func TestPasskeyFinishRegistrationRejectsOtherUsersChallenge(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	alice := env.registerUser(t, "alice", "alice@example.com")
+	mallory := env.registerUser(t, "mallory", "mallory@example.com")
+	authenticator := newTestAuthenticator(t, env, webauthn.AlgES256)
+
+	options, err := env.passkeyService.BeginRegistration(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	credential, err := authenticator.Register(options.Challenge)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	_, err = env.passkeyService.FinishRegistration(ctx, mallory.ID, dto.PasskeyRegistrationRequest{Credential: *credential})
+	if err == nil || err.Error() != "invalid passkey challenge" {
+		t.Fatalf("FinishRegistration error = %v, want %q", err, "invalid passkey challenge")
+	}
+
+	// The challenge was consumed by the failed attempt, so its owner cannot replay it either
+	_, err = env.passkeyService.FinishRegistration(ctx, alice.ID, dto.PasskeyRegistrationRequest{Credential: *credential})
+	if err == nil || err.Error() != "invalid passkey challenge" {
+		t.Fatalf("FinishRegistration replay error = %v, want %q", err, "invalid passkey challenge")
+	}
+}
+
+func TestPasskeyFinishLoginRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(a *webauthntest.Authenticator, challenge []byte) []byte
+		corrupt func(response *webauthn.AuthenticationResponse)
+		wantErr string
+	}{
+		{
+			name:    "wrong challenge",
+			tamper:  func(a *webauthntest.Authenticator, challenge []byte) []byte { return flipLastByte(challenge) },
+			wantErr: "invalid passkey challenge",
+		},
+		{
+			name: "wrong origin",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Origin = "https://evil.example"
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "wrong rp id hash",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.RPID = "evil.example"
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "user not present",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserPresent
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "user not verified",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.Flags &^= webauthn.FlagUserVerified
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "bad signature",
+			corrupt: func(response *webauthn.AuthenticationResponse) {
+				response.Response.Signature = flipLastByte(response.Response.Signature)
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "sign count regression",
+			tamper: func(a *webauthntest.Authenticator, challenge []byte) []byte {
+				a.SignCount = 0
+				return challenge
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "user handle of another user",
+			corrupt: func(response *webauthn.AuthenticationResponse) {
+				response.Response.UserHandle = userHandle(9999)
+			},
+			wantErr: "passkey verification failed",
+		},
+		{
+			name: "unknown credential",
+			corrupt: func(response *webauthn.AuthenticationResponse) {
+				response.RawID = flipLastByte(response.RawID)
+			},
+			wantErr: "passkey verification failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := contextx.Background()
+			user := env.registerUser(t, "alice", "alice@example.com")
+			authenticator := newTestAuthenticator(t, env, webauthn.AlgES256)
+			registerPasskey(t, env, user, authenticator)
+			authenticator.SignCount = 10
+
+			// One good login first, so that a sign count is stored
+			options, err := env.passkeyService.BeginLogin(ctx)
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+			assertion, err := authenticator.Assert(options.Challenge, userHandle(user.ID))
+			if err != nil {
+				t.Fatalf("Assert: %v", err)
+			}
+			if _, err := env.passkeyService.FinishLogin(ctx, dto.PasskeyLoginRequest{Credential: *assertion}); err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+
+			options, err = env.passkeyService.BeginLogin(ctx)
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+			challenge := []byte(options.Challenge)
+			if tt.tamper != nil {
+				challenge = tt.tamper(authenticator, challenge)
+			}
+			assertion, err = authenticator.Assert(challenge, userHandle(user.ID))
+			if err != nil {
+				t.Fatalf("Assert: %v", err)
+			}
+			if tt.corrupt != nil {
+				tt.corrupt(assertion)
+			}
+
+			_, err = env.passkeyService.FinishLogin(ctx, dto.PasskeyLoginRequest{Credential: *assertion})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("FinishLogin error = %v, want %q", err, tt.wantErr)
+			}
+
+			stored, err := env.webAuthnRepo.GetCredentialsByUserID(ctx, user.ID)
+			if err != nil || len(stored) != 1 {
+				t.Fatalf("GetCredentialsByUserID = %v, %v", stored, err)
+			}
+			if stored[0].SignCount != 11 {
+				t.Errorf("stored sign count = %d after a rejected login, want 11", stored[0].SignCount)
+			}
+		})
+	}
+}
+
+func TestPasskeyFinishLoginChallengeIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	authenticator := newTestAuthenticator(t, env, webauthn.AlgEdDSA)
+	registerPasskey(t, env, user, authenticator)
+
+	options, err := env.passkeyService.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	assertion, err := authenticator.Assert(options.Challenge, userHandle(user.ID))
+	if err != nil {
+		t.Fatalf("Assert: %v", err)
+	}
+	if _, err := env.passkeyService.FinishLogin(ctx, dto.PasskeyLoginRequest{Credential: *assertion}); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+
+	replay, err := authenticator.Assert(options.Challenge, userHandle(user.ID))
+	if err != nil {
+		t.Fatalf("Assert: %v", err)
+	}
+	_, err = env.passkeyService.FinishLogin(ctx, dto.PasskeyLoginRequest{Credential: *replay})
+	if err == nil || err.Error() != "invalid passkey challenge" {
+		t.Fatalf("FinishLogin replay error = %v, want %q", err, "invalid passkey challenge")
+	}
+}
+
+// flipLastByte returns a copy of b with its last byte changed
+func flipLastByte(b []byte) []byte {
+	out := append([]byte(nil), b...)
+	out[len(out)-1] ^= 0xff
+	return out
+}
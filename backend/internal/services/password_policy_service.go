@@ -0,0 +1,116 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/password"
+	"bezbase/internal/repository"
+)
+
+// PasswordPolicyService is the single place new passwords are validated and recorded
+type PasswordPolicyService struct {
+	historyRepo  repository.PasswordHistoryRepository
+	policy       password.Policy
+	breachList   *password.BreachList
+	policyConfig *config.PasswordPolicyConfig
+}
+
+func NewPasswordPolicyService(
+	historyRepo repository.PasswordHistoryRepository,
+	policyConfig *config.PasswordPolicyConfig,
+) (*PasswordPolicyService, error) {
+	service := &PasswordPolicyService{
+		historyRepo: historyRepo,
+		policy: password.Policy{
+			MinLength:     policyConfig.MinLength,
+			MaxLength:     policyConfig.MaxLength,
+			RequireUpper:  policyConfig.RequireUpper,
+			RequireLower:  policyConfig.RequireLower,
+			RequireDigit:  policyConfig.RequireDigit,
+			RequireSymbol: policyConfig.RequireSymbol,
+		},
+		policyConfig: policyConfig,
+	}
+
+	if policyConfig.BreachFile != "" {
+		breachList, err := password.OpenBreachList(policyConfig.BreachFile)
+		if err != nil {
+			return nil, err
+		}
+		service.breachList = breachList
+	}
+
+	return service, nil
+}
+
+// Validate checks a new password against every rule and returns a *password.PolicyError listing
+// all violations. userID 0 skips the reuse check for users that do not exist yet. userInputs are
+// values such as the username and email that must not appear in the password.
+func (s *PasswordPolicyService) Validate(ctx contextx.Contextx, userID uint, plain string, userInputs ...string) error {
+	violations := s.policy.Check(plain, userInputs...)
+
+	if s.breachList != nil {
+		breached, err := s.breachList.Contains(plain)
+		if err != nil {
+			// An unreadable corpus must not lock everyone out of changing passwords
+			log.Printf("Failed to check breached password list: %v", err)
+		} else if breached {
+			violations = append(violations, password.Violation{Code: password.CodeBreached})
+		}
+	}
+
+	if userID != 0 && s.policyConfig.HistorySize > 0 {
+		history, err := s.historyRepo.ListRecent(ctx, userID, s.policyConfig.HistorySize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range history {
+			if auth.CheckPasswordHash(plain, entry.PasswordHash) {
+				violations = append(violations, password.Violation{
+					Code:   password.CodeRecentlyUsed,
+					Params: map[string]interface{}{"Count": s.policyConfig.HistorySize},
+				})
+				break
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &password.PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Record adds a newly set password hash to the user's history and drops entries beyond the
+// history size. The newest entry is always kept since it dates the current password.
+func (s *PasswordPolicyService) Record(ctx contextx.Contextx, userID uint, passwordHash string) error {
+	if err := s.historyRepo.Create(ctx, &models.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	}); err != nil {
+		return err
+	}
+
+	keep := s.policyConfig.HistorySize
+	if keep < 1 {
+		keep = 1
+	}
+	return s.historyRepo.Prune(ctx, userID, keep)
+}
+
+// IsExpired reports whether the user's current password is older than the maximum age
+func (s *PasswordPolicyService) IsExpired(ctx contextx.Contextx, userID uint) bool {
+	if s.policyConfig.MaxAge <= 0 {
+		return false
+	}
+	latest, err := s.historyRepo.ListRecent(ctx, userID, 1)
+	if err != nil || len(latest) == 0 {
+		return false
+	}
+	return time.Since(latest[0].CreatedAt) > s.policyConfig.MaxAge
+}
@@ -18,6 +18,8 @@ type PasswordResetService struct {
 	authProviderRepo  repository.AuthProviderRepository
 	passwordResetRepo repository.PasswordResetRepository
 	emailService      *EmailService
+	sessionService    *SessionService
+	passwordPolicy    *PasswordPolicyService
 }
 
 func NewPasswordResetService(
@@ -26,6 +28,8 @@ func NewPasswordResetService(
 	authProviderRepo repository.AuthProviderRepository,
 	passwordResetRepo repository.PasswordResetRepository,
 	emailService *EmailService,
+	sessionService *SessionService,
+	passwordPolicy *PasswordPolicyService,
 ) *PasswordResetService {
 	return &PasswordResetService{
 		userRepo:          userRepo,
@@ -33,6 +37,8 @@ func NewPasswordResetService(
 		authProviderRepo:  authProviderRepo,
 		passwordResetRepo: passwordResetRepo,
 		emailService:      emailService,
+		sessionService:    sessionService,
+		passwordPolicy:    passwordPolicy,
 	}
 }
 
@@ -113,28 +119,41 @@ func (s *PasswordResetService) ResetPassword(ctx contextx.Contextx, token, newPa
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	// Hash the new password
-	hashedPassword, err := auth.HashPassword(newPassword)
-	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
-	}
-
 	// Update user's password in auth provider
 	authProvider, err := s.authProviderRepo.GetByUserIDAndProvider(ctx, user.ID, models.ProviderEmail)
 	if err != nil {
 		return fmt.Errorf("failed to get auth provider: %w", err)
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, user.ID, newPassword, authProvider.UserName, authProvider.ProviderID); err != nil {
+		return err
+	}
+
+	// Hash the new password
+	hashedPassword, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
 	authProvider.Password = hashedPassword
 	if err := s.authProviderRepo.Update(ctx, authProvider); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	if err := s.passwordPolicy.Record(ctx, user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+
 	// Mark token as used
 	if err := s.passwordResetRepo.MarkAsUsed(token); err != nil {
 		return fmt.Errorf("failed to mark token as used: %w", err)
 	}
 
+	// Revoke all sessions so anyone holding the old credentials is signed out
+	if err := s.sessionService.RevokeAllSessions(ctx, user.ID, 0); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
 	return nil
 }
 
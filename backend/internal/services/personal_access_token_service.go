@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+const (
+	// personalAccessTokenPrefix makes tokens recognizable to the middleware and to secret scanners
+	personalAccessTokenPrefix = "bzb_pat_"
+	// lastUsedUpdateInterval limits how often last-used time and IP are written for busy tokens
+	lastUsedUpdateInterval = time.Minute
+)
+
+type PersonalAccessTokenService struct {
+	tokenRepo   repository.PersonalAccessTokenRepository
+	userRepo    repository.UserRepository
+	rbacService *RBACService
+	authConfig  *config.AuthConfig
+}
+
+func NewPersonalAccessTokenService(
+	tokenRepo repository.PersonalAccessTokenRepository,
+	userRepo repository.UserRepository,
+	rbacService *RBACService,
+	authConfig *config.AuthConfig,
+) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		tokenRepo:   tokenRepo,
+		userRepo:    userRepo,
+		rbacService: rbacService,
+		authConfig:  authConfig,
+	}
+}
+
+// IsPersonalAccessToken reports whether a bearer credential is a personal access token rather than a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// Create issues a new token for the user. Every scope must be a known permission the user currently holds.
+func (s *PersonalAccessTokenService) Create(ctx contextx.Contextx, userID uint, req dto.CreatePersonalAccessTokenRequest) (*dto.PersonalAccessTokenCreatedResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("invalid token name")
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	if req.ExpiresInDays < 1 || ttl > s.authConfig.PATMaxTTL {
+		return nil, errors.New("invalid token expiry")
+	}
+
+	scopes, err := s.validateScopes(userID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	plain := personalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(plain),
+		Prefix:    plain[:len(personalAccessTokenPrefix)+6],
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, &token); err != nil {
+		return nil, err
+	}
+
+	return &dto.PersonalAccessTokenCreatedResponse{
+		PersonalAccessTokenResponse: dto.ToPersonalAccessTokenResponse(&token),
+		Token:                       plain,
+	}, nil
+}
+
+// List returns all tokens of a user, including expired and revoked ones
+func (s *PersonalAccessTokenService) List(ctx contextx.Contextx, userID uint) ([]models.PersonalAccessToken, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	return s.tokenRepo.ListByUserID(ctx, userID)
+}
+
+// Revoke revokes one of the user's tokens
+func (s *PersonalAccessTokenService) Revoke(ctx contextx.Contextx, userID, tokenID uint) error {
+	token, err := s.tokenRepo.GetByID(ctx, tokenID)
+	if err != nil || token.UserID != userID {
+		return errors.New("personal access token not found")
+	}
+	return s.tokenRepo.Revoke(ctx, token.ID)
+}
+
+// Authenticate resolves a presented token to claims carrying its scopes and records its use
+func (s *PersonalAccessTokenService) Authenticate(ctx contextx.Contextx, plain string) (*auth.Claims, error) {
+	token, err := s.tokenRepo.GetByTokenHash(ctx, auth.HashToken(plain))
+	if err != nil || !token.IsActive() {
+		return nil, errors.New("invalid token")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, token.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	_, ipAddress := requestClientInfo(ctx)
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedUpdateInterval || token.LastUsedIP != ipAddress {
+		// Usage tracking must not fail the request
+		_ = s.tokenRepo.UpdateLastUsed(ctx, token.ID, now, ipAddress)
+	}
+
+	return &auth.Claims{
+		UserID:        user.ID,
+		Email:         user.GetPrimaryEmail(),
+		AccessTokenID: token.ID,
+		Scopes:        token.ScopeList(),
+	}, nil
+}
+
+// validateScopes normalizes requested scopes and checks each against the hardcoded permissions
+// and the user's current permissions
+func (s *PersonalAccessTokenService) validateScopes(userID uint, requested []string) ([]string, error) {
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if seen[scope] {
+			continue
+		}
+		resource, action, ok := strings.Cut(scope, ":")
+		if !ok {
+			return nil, errors.New("invalid token scope")
+		}
+		if _, known := models.GetPermissionByResourceAction(models.ResourceType(resource), models.ActionType(action)); !known {
+			return nil, errors.New("invalid token scope")
+		}
+
+		allowed, err := s.rbacService.CheckPermission(userID, resource, action)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.New("token scope not permitted")
+		}
+
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("invalid token scope")
+	}
+
+	sort.Strings(scopes)
+	return scopes, nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"bezbase/internal/models"
+
+	"github.com/casbin/govaluate"
+	"gorm.io/gorm"
+)
+
+// maxConditionLength is the size of the Casbin rule column conditions are stored in
+const maxConditionLength = 100
+
+// subjectAttributes and environmentAttributes are the attributes conditions may use besides the
+// free-form resource.* attributes supplied by handlers
+var (
+	subjectAttributes = map[string]bool{
+		"id": true, "status": true, "email_verified": true, "username": true, "email": true,
+		"first_name": true, "last_name": true, "location": true, "timezone": true, "language": true,
+	}
+	environmentAttributes = map[string]bool{
+		"time": true, "date": true, "hour": true, "minute": true, "weekday": true, "ip": true,
+		"organization_id": true,
+	}
+)
+
+// conditionFunctions are the functions policy conditions may call
+var conditionFunctions = map[string]govaluate.ExpressionFunction{
+	// ipInRange(env.ip, "10.0.0.0/8") reports whether an IP address is inside a CIDR range
+	"ipInRange": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("ipInRange expects an IP address and a CIDR range")
+		}
+		ip, _ := args[0].(string)
+		cidr, _ := args[1].(string)
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("ipInRange: invalid CIDR range %q", cidr)
+		}
+		parsed := net.ParseIP(ip)
+		return parsed != nil && network.Contains(parsed), nil
+	},
+}
+
+// AccessRequest carries everything a permission check can depend on besides the user, resource
+// and action. The zero value checks outside of organizations, contexts and attributes.
+type AccessRequest struct {
+	OrganizationID     uint
+	Context            *PermissionContext
+	ResourceAttributes map[string]interface{} // resource.* in policy conditions
+	IP                 string                 // env.ip in policy conditions
+	Time               time.Time              // env.time and derived attributes, now when zero
+}
+
+// ValidateCondition checks that a policy condition parses and only refers to subject.*,
+// resource.* and env.* attributes. An empty condition is valid and always holds.
+func ValidateCondition(condition string) error {
+	if condition == "" {
+		return nil
+	}
+	if len(condition) > maxConditionLength {
+		return fmt.Errorf("invalid condition: longer than %d characters", maxConditionLength)
+	}
+
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(condition, conditionFunctions)
+	if err != nil {
+		return fmt.Errorf("invalid condition: %v", err)
+	}
+
+	for _, token := range expression.Tokens() {
+		switch token.Kind {
+		case govaluate.VARIABLE:
+			return fmt.Errorf("invalid condition: unknown attribute %q, use subject.*, resource.* or env.*", token.Value)
+		case govaluate.ACCESSOR:
+			path := token.Value.([]string)
+			name := strings.Join(path, ".")
+			switch path[0] {
+			case "subject":
+				if len(path) != 2 || !subjectAttributes[path[1]] {
+					return fmt.Errorf("invalid condition: unknown subject attribute %q", name)
+				}
+			case "env":
+				if len(path) != 2 || !environmentAttributes[path[1]] {
+					return fmt.Errorf("invalid condition: unknown environment attribute %q", name)
+				}
+			case "resource":
+			default:
+				return fmt.Errorf("invalid condition: unknown attribute %q, use subject.*, resource.* or env.*", name)
+			}
+		}
+	}
+	return nil
+}
+
+// compileCondition parses a condition once and caches it for the lifetime of the service
+func (r *RBACService) compileCondition(condition string) (*govaluate.EvaluableExpression, error) {
+	if cached, ok := r.conditions.Load(condition); ok {
+		return cached.(*govaluate.EvaluableExpression), nil
+	}
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(condition, conditionFunctions)
+	if err != nil {
+		return nil, err
+	}
+	r.conditions.Store(condition, expression)
+	return expression, nil
+}
+
+// matchCondition is the "condition" function of the Casbin matcher: condition(p.cond, p.eft, r.attrs).
+// A condition that cannot be evaluated, for instance because the handler supplied no resource
+// attributes, fails closed: allow rules do not match and deny rules do.
+func (r *RBACService) matchCondition(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 {
+		return nil, errors.New("condition expects a condition, an effect and the request attributes")
+	}
+	condition, _ := args[0].(string)
+	if condition == "" {
+		return true, nil
+	}
+	effect, _ := args[1].(string)
+	failed := effect == models.EffectDeny.String()
+
+	attributes, ok := args[2].(*accessAttributes)
+	if !ok {
+		return failed, nil
+	}
+	expression, err := r.compileCondition(condition)
+	if err != nil {
+		return failed, nil
+	}
+	result, err := expression.Eval(attributes)
+	if err != nil {
+		return failed, nil
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return failed, nil
+	}
+	return matched, nil
+}
+
+// accessAttributes are the parameters of policy conditions. Subject attributes are loaded on first
+// use, so checks that meet no conditional policy never query the user.
+type accessAttributes struct {
+	db          *gorm.DB
+	userID      uint
+	subject     map[string]interface{}
+	subjectErr  error
+	resource    map[string]interface{}
+	environment map[string]interface{}
+}
+
+func newAccessAttributes(db *gorm.DB, userID uint, request AccessRequest) *accessAttributes {
+	now := request.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	resource := make(map[string]interface{}, len(request.ResourceAttributes))
+	for key, value := range request.ResourceAttributes {
+		resource[key] = normalizeAttribute(value)
+	}
+
+	return &accessAttributes{
+		db:       db,
+		userID:   userID,
+		resource: resource,
+		environment: map[string]interface{}{
+			"time":            now.Format(time.RFC3339),
+			"date":            now.Format("2006-01-02"),
+			"hour":            float64(now.Hour()),
+			"minute":          float64(now.Minute()),
+			"weekday":         float64(now.Weekday()),
+			"ip":              request.IP,
+			"organization_id": float64(request.OrganizationID),
+		},
+	}
+}
+
+// Get implements govaluate.Parameters
+func (a *accessAttributes) Get(name string) (interface{}, error) {
+	switch name {
+	case "subject":
+		return a.loadSubject()
+	case "resource":
+		return a.resource, nil
+	case "env":
+		return a.environment, nil
+	}
+	return nil, fmt.Errorf("unknown attribute %q", name)
+}
+
+func (a *accessAttributes) loadSubject() (map[string]interface{}, error) {
+	if a.subject != nil || a.subjectErr != nil {
+		return a.subject, a.subjectErr
+	}
+
+	var user models.User
+	if err := a.db.Preload("UserInfo").First(&user, a.userID).Error; err != nil {
+		a.subjectErr = fmt.Errorf("failed to load subject attributes: %w", err)
+		return nil, a.subjectErr
+	}
+
+	a.subject = map[string]interface{}{
+		"id":             float64(user.ID),
+		"status":         string(user.Status),
+		"email_verified": user.EmailVerified,
+	}
+	if info := user.UserInfo; info != nil {
+		a.subject["username"] = info.Username
+		a.subject["email"] = info.Email
+		a.subject["first_name"] = info.FirstName
+		a.subject["last_name"] = info.LastName
+		a.subject["location"] = info.Location
+		a.subject["timezone"] = info.Timezone
+		a.subject["language"] = info.Language
+	}
+	return a.subject, nil
+}
+
+// normalizeAttribute turns numbers into float64, the only numeric type conditions compare
+func normalizeAttribute(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	}
+	return value
+}
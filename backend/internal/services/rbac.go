@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"bezbase/internal/dto"
 	"bezbase/internal/models"
@@ -19,21 +22,26 @@ import (
 )
 
 type RBACService struct {
-	enforcer *casbin.Enforcer
-	roleRepo repository.RoleRepository
-	ruleRepo repository.RuleRepository
-	db       *gorm.DB
+	enforcer           *casbin.Enforcer
+	roleRepo           repository.RoleRepository
+	ruleRepo           repository.RuleRepository
+	roleAssignmentRepo repository.RoleAssignmentRepository
+	db                 *gorm.DB
+	conditions         sync.Map // Compiled policy conditions by expression
 }
 
+// Positions of the condition and the effect in a policy (sub, dom, obj, act, cond, eft)
+const (
+	policyConditionIndex = 4
+	policyEffectIndex    = 5
+)
+
 // AssignDefaultRoleToUser assigns the default 'user' role to a user if they have no roles
 func (r *RBACService) AssignDefaultRoleToUser(ctx contextx.Contextx, userID uint) error {
 	subject := fmt.Sprintf("user:%d", userID)
-	roles, err := r.enforcer.GetRolesForUser(subject)
-	if err != nil {
-		return err
-	}
+	roles := r.enforcer.GetRolesForUserInDomain(subject, models.GlobalDomain)
 	if len(roles) == 0 {
-		_, err := r.enforcer.AddRoleForUser(subject, "user")
+		_, err := r.enforcer.AddRoleForUserInDomain(subject, "user", models.GlobalDomain)
 		if err != nil {
 			return err
 		}
@@ -42,24 +50,24 @@ func (r *RBACService) AssignDefaultRoleToUser(ctx contextx.Contextx, userID uint
 	return nil
 }
 
-// GetPermissionsForUser returns all permissions for a user (resource, action)
+// GetPermissionsForUser returns all global permissions for a user (resource, action)
 func (r *RBACService) GetPermissionsForUser(ctx contextx.Contextx, userID uint) ([]string, error) {
 	subject := fmt.Sprintf("user:%d", userID)
 	var result []string
 
 	// Get direct permissions for user
-	perms, err := r.enforcer.GetPermissionsForUser(subject)
+	perms, err := r.enforcer.GetFilteredPolicy(0, subject, models.GlobalDomain)
 	if err != nil {
 		return nil, err
 	}
 	for _, perm := range perms {
-		if len(perm) >= 3 {
-			result = append(result, fmt.Sprintf("%s:%s:%s", subject, perm[1], perm[2]))
+		if len(perm) >= 4 && !isDenyPolicy(perm) {
+			result = append(result, fmt.Sprintf("%s:%s:%s", subject, perm[2], perm[3]))
 		}
 	}
 
 	// Get roles assigned to user
-	roles, err := r.enforcer.GetRolesForUser(subject)
+	roles, err := r.rolesInEffect(userID, models.GlobalDomain)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +76,13 @@ func (r *RBACService) GetPermissionsForUser(ctx contextx.Contextx, userID uint)
 		roles = append(roles, "user")
 	}
 	for _, role := range roles {
-		rolePerms, err := r.enforcer.GetPermissionsForUser(role)
+		rolePerms, err := r.enforcer.GetFilteredPolicy(0, role, models.GlobalDomain)
 		if err != nil {
 			continue
 		}
 		for _, perm := range rolePerms {
-			if len(perm) >= 3 {
-				result = append(result, fmt.Sprintf("%s:%s:%s", role, perm[1], perm[2]))
+			if len(perm) >= 4 && !isDenyPolicy(perm) {
+				result = append(result, fmt.Sprintf("%s:%s:%s", role, perm[2], perm[3]))
 			}
 		}
 	}
@@ -82,9 +90,15 @@ func (r *RBACService) GetPermissionsForUser(ctx contextx.Contextx, userID uint)
 	return result, nil
 }
 
+// isDenyPolicy reports whether a policy (sub, dom, obj, act, cond, eft) is a deny rule
+func isDenyPolicy(policy []string) bool {
+	return len(policy) > policyEffectIndex && policy[policyEffectIndex] == models.EffectDeny.String()
+}
+
 func NewRBACService(
 	roleRepo repository.RoleRepository,
 	ruleRepo repository.RuleRepository,
+	roleAssignmentRepo repository.RoleAssignmentRepository,
 	db *gorm.DB,
 ) (*RBACService, error) {
 	adapter, err := gormadapter.NewAdapterByDBUseTableName(db, "", "rules")
@@ -110,11 +124,13 @@ func NewRBACService(
 	}
 
 	rbacService := &RBACService{
-		enforcer: enforcer,
-		roleRepo: roleRepo,
-		ruleRepo: ruleRepo,
-		db:       db,
+		enforcer:           enforcer,
+		roleRepo:           roleRepo,
+		ruleRepo:           ruleRepo,
+		roleAssignmentRepo: roleAssignmentRepo,
+		db:                 db,
 	}
+	enforcer.AddFunction("condition", rbacService.matchCondition)
 
 	if err := rbacService.initializeDefaultRoles(); err != nil {
 		log.Printf("Warning: failed to initialize default roles: %v", err)
@@ -123,22 +139,27 @@ func NewRBACService(
 	return rbacService, nil
 }
 
+// getRBACModel returns the RBAC-with-domains model. A domain is models.GlobalDomain or an
+// organization's "org:<id>". Policies in "org:*" apply in every organization but never globally,
+// so a role held inside an organization cannot reach global policies. Every policy has an allow or
+// deny effect, and a request is granted when an allow matches and no deny does. A policy with a
+// condition only matches when the condition holds for the request attributes.
 func getRBACModel() string {
 	return `
 [request_definition]
-r = sub, obj, act
+r = sub, dom, obj, act, attrs
 
 [policy_definition]
-p = sub, obj, act
+p = sub, dom, obj, act, cond, eft
 
 [role_definition]
-g = _, _
+g = _, _, _
 
 [policy_effect]
-e = some(where (p.eft == allow))
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
 
 [matchers]
-m = g(r.sub, p.sub) && (r.obj == p.obj || p.obj == "*") && (r.act == p.act || p.act == "*")
+m = g(r.sub, p.sub, r.dom) && (r.dom == p.dom || (p.dom == "org:*" && r.dom != "*")) && (r.obj == p.obj || p.obj == "*") && (r.act == p.act || p.act == "*") && condition(p.cond, p.eft, r.attrs)
 `
 }
 
@@ -178,30 +199,34 @@ func (r *RBACService) addDefaultPermissionsForRole(roleName string) error {
 	switch roleName {
 	case "admin":
 		permissions = [][]string{
-			{"admin", models.ResourceTypeAll.String(), models.ActionTypeAll.String()},
+			{"admin", models.GlobalDomain, models.ResourceTypeAll.String(), models.ActionTypeAll.String(), "", models.EffectAllow.String()},
+			{"admin", models.AnyOrganizationDomain, models.ResourceTypeOrganization.String(), models.ActionTypeRead.String(), "", models.EffectAllow.String()},
+			{"admin", models.AnyOrganizationDomain, models.ResourceTypeOrganization.String(), models.ActionTypeUpdate.String(), "", models.EffectAllow.String()},
+			{"admin", models.AnyOrganizationDomain, models.ResourceTypeOrganization.String(), models.ActionTypeDelete.String(), "", models.EffectAllow.String()},
 		}
 	case "moderator":
 		permissions = [][]string{
-			{"moderator", models.ResourceTypeUser.String(), models.ActionTypeRead.String()},
-			{"moderator", models.ResourceTypeUser.String(), models.ActionTypeUpdate.String()},
-			{"moderator", models.ResourceTypePost.String(), models.ActionTypeAll.String()},
+			{"moderator", models.GlobalDomain, models.ResourceTypeUser.String(), models.ActionTypeRead.String(), "", models.EffectAllow.String()},
+			{"moderator", models.GlobalDomain, models.ResourceTypeUser.String(), models.ActionTypeUpdate.String(), "", models.EffectAllow.String()},
+			{"moderator", models.GlobalDomain, models.ResourceTypePost.String(), models.ActionTypeAll.String(), "", models.EffectAllow.String()},
 		}
 	case "user":
 		permissions = [][]string{
-			{"user", models.ResourceTypeProfile.String(), models.ActionTypeRead.String()},
-			{"user", models.ResourceTypeProfile.String(), models.ActionTypeUpdate.String()},
-			{"user", models.ResourceTypePost.String(), models.ActionTypeCreate.String()},
-			{"user", models.ResourceTypePost.String(), models.ActionTypeRead.String()},
+			{"user", models.GlobalDomain, models.ResourceTypeProfile.String(), models.ActionTypeRead.String(), "", models.EffectAllow.String()},
+			{"user", models.GlobalDomain, models.ResourceTypeProfile.String(), models.ActionTypeUpdate.String(), "", models.EffectAllow.String()},
+			{"user", models.GlobalDomain, models.ResourceTypePost.String(), models.ActionTypeCreate.String(), "", models.EffectAllow.String()},
+			{"user", models.GlobalDomain, models.ResourceTypePost.String(), models.ActionTypeRead.String(), "", models.EffectAllow.String()},
+			{"user", models.AnyOrganizationDomain, models.ResourceTypeOrganization.String(), models.ActionTypeRead.String(), "", models.EffectAllow.String()},
 		}
 	}
 
 	for _, policy := range permissions {
-		exists, err := r.enforcer.HasPolicy(policy[0], policy[1], policy[2])
+		exists, err := r.enforcer.HasPolicy(policy[0], policy[1], policy[2], policy[3], policy[4], policy[5])
 		if err != nil {
 			return err
 		}
 		if !exists {
-			if err := r.AddPermission(policy[0], policy[1], policy[2]); err != nil {
+			if err := r.addPolicy(policy[0], policy[1], policy[2], policy[3], policy[4], models.EffectType(policy[5])); err != nil {
 				return err
 			}
 		}
@@ -210,50 +235,212 @@ func (r *RBACService) addDefaultPermissionsForRole(roleName string) error {
 	return nil
 }
 
+// PermissionContext narrows a permission check to one object, such as ("project", "42").
+// Contextual permissions scoped to that type apply when their value is empty or matches.
+type PermissionContext struct {
+	Type  string
+	Value string
+}
+
+// CheckPermission checks a permission outside of any organization, using global roles only
 func (r *RBACService) CheckPermission(userID uint, resource, action string) (bool, error) {
-	user := fmt.Sprintf("user:%d", userID)
+	return r.Authorize(userID, resource, action, AccessRequest{})
+}
+
+// CheckPermissionInOrganization checks a permission for a request made in an organization (0 for
+// none). Global roles grant their global policies everywhere; roles held in the organization add
+// the policies of its domain and of models.AnyOrganizationDomain.
+func (r *RBACService) CheckPermissionInOrganization(userID, orgID uint, resource, action string) (bool, error) {
+	return r.Authorize(userID, resource, action, AccessRequest{OrganizationID: orgID})
+}
+
+// CheckPermissionWithContext checks a permission on a specific object, in an organization (0 for
+// none). It adds the contextual permissions scoped to the object to the regular evaluation.
+func (r *RBACService) CheckPermissionWithContext(userID, orgID uint, permissionContext PermissionContext, resource, action string) (bool, error) {
+	return r.Authorize(userID, resource, action, AccessRequest{OrganizationID: orgID, Context: &permissionContext})
+}
+
+// Authorize is the single evaluation path of every permission check. It combines the Casbin
+// policies of the user's roles and of their parent roles, with their conditions evaluated against
+// the request attributes, and the contextual permissions of the global roles. A deny from any of
+// them overrides every allow.
+func (r *RBACService) Authorize(userID uint, resource, action string, request AccessRequest) (bool, error) {
 	// Check roles for user
-	roles, err := r.enforcer.GetRolesForUser(user)
+	roles, err := r.rolesInEffect(userID, models.GlobalDomain)
 	if err != nil {
 		return false, err
 	}
-	
+
 	// If user has no roles, assign default 'user' role automatically
 	if len(roles) == 0 {
-		if err := r.AssignDefaultRoleToUser(contextx.Background(), userID); err != nil {
-			// If assignment fails, fall back to checking with default role
-			roles = append(roles, "user")
-		} else {
+		if err := r.AssignDefaultRoleToUser(contextx.Background(), userID); err == nil {
 			// Re-fetch roles after assignment
-			roles, err = r.enforcer.GetRolesForUser(user)
-			if err != nil {
+			if roles, err = r.rolesInEffect(userID, models.GlobalDomain); err != nil {
 				return false, err
 			}
 		}
+		// If assignment fails, or the user only holds roles outside their window, fall back to checking with default role
+		if len(roles) == 0 {
+			roles = append(roles, "user")
+		}
 	}
-	
-	// Check as each role (including inherited permissions)
-	for _, roleName := range roles {
-		// Check direct permission
-		ok, err := r.enforcer.Enforce(roleName, resource, action)
+
+	attributes := newAccessAttributes(r.db, userID, request)
+	decision, err := r.checkRolesInDomain(roles, models.GlobalDomain, request.Context, attributes, resource, action)
+	if err != nil {
+		return false, err
+	}
+	if request.OrganizationID != 0 && !decision.denied {
+		// Contextual permissions belong to roles, not domains, so like global policies they are
+		// out of reach of roles held inside an organization
+		domain := models.OrganizationDomain(request.OrganizationID)
+		orgRoles, err := r.rolesInEffect(userID, domain)
 		if err != nil {
-			continue
+			return false, err
 		}
-		if ok {
-			return true, nil
+		orgDecision, err := r.checkRolesInDomain(orgRoles, domain, nil, attributes, resource, action)
+		if err != nil {
+			return false, err
 		}
-		
-		// Check inherited permissions from parent roles
-		// First get the role by name to get its ID
-		roleObj, err := r.roleRepo.GetByName(contextx.Background(), roleName)
-		if err == nil {
-			if hasInheritedPermission, err := r.checkInheritedPermissions(roleObj.ID, resource, action); err == nil && hasInheritedPermission {
-				return true, nil
+		decision.merge(orgDecision)
+	}
+
+	return decision.granted(), nil
+}
+
+// permissionDecision collects the effects of the policies matching a request
+type permissionDecision struct {
+	allowed bool
+	denied  bool
+}
+
+func (d *permissionDecision) merge(other permissionDecision) {
+	d.allowed = d.allowed || other.allowed
+	d.denied = d.denied || other.denied
+}
+
+// granted reports whether an allow matched and no deny did
+func (d permissionDecision) granted() bool {
+	return d.allowed && !d.denied
+}
+
+// checkRolesInDomain evaluates the roles a user holds in a domain together with their parent
+// roles, whose policies apply in the same domain. Contextual permissions are only evaluated for
+// global roles. Evaluation stops at the first deny since nothing can grant the request after it.
+func (r *RBACService) checkRolesInDomain(roles []string, domain string, permissionContext *PermissionContext, attributes *accessAttributes, resource, action string) (permissionDecision, error) {
+	var decision permissionDecision
+	if len(roles) == 0 {
+		return decision, nil
+	}
+
+	roleNames, roleIDs, err := r.expandRoleHierarchy(roles)
+	if err != nil {
+		return decision, err
+	}
+
+	for _, roleName := range roleNames {
+		roleDecision, err := r.enforceRole(roleName, domain, resource, action, attributes)
+		if err != nil {
+			return decision, err
+		}
+		decision.merge(roleDecision)
+		if decision.denied {
+			return decision, nil
+		}
+	}
+
+	if domain != models.GlobalDomain || len(roleIDs) == 0 {
+		return decision, nil
+	}
+	contextual, err := r.checkContextualPermissions(roleIDs, permissionContext, resource, action)
+	if err != nil {
+		return decision, err
+	}
+	decision.merge(contextual)
+	return decision, nil
+}
+
+// expandRoleHierarchy returns the roles with all their parent roles, by name for Casbin and by ID
+// for contextual permissions. Roles that only exist in Casbin have no parents and no ID.
+func (r *RBACService) expandRoleHierarchy(roles []string) ([]string, []uint, error) {
+	var names []string
+	var ids []uint
+	seen := make(map[string]bool)
+	add := func(name string, id uint) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, roleName := range roles {
+		role, err := r.roleRepo.GetByName(contextx.Background(), roleName)
+		if err != nil {
+			if err.Error() == "role not found" {
+				add(roleName, 0)
+				continue
 			}
+			return nil, nil, err
+		}
+		add(role.Name, role.ID)
+
+		// Get all parent roles in the hierarchy
+		parentRoles, err := models.GetAllParentRoles(r.db, role.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, parentRole := range parentRoles {
+			add(parentRole.Name, parentRole.ID)
 		}
 	}
-	
-	return false, nil
+
+	return names, ids, nil
+}
+
+// enforceRole evaluates the Casbin policies of a role. A denied request only counts as a deny when a
+// deny rule matched, not when no allow rule did.
+func (r *RBACService) enforceRole(role, domain, resource, action string, attributes *accessAttributes) (permissionDecision, error) {
+	ok, explain, err := r.enforcer.EnforceEx(role, domain, resource, action, attributes)
+	if err != nil {
+		return permissionDecision{}, err
+	}
+	if ok {
+		return permissionDecision{allowed: true}, nil
+	}
+	return permissionDecision{denied: isDenyPolicy(explain)}, nil
+}
+
+// checkContextualPermissions evaluates the contextual permissions of roles; IsGranted=false rows
+// are deny rules. Unscoped rows always apply, scoped rows only to checks made in their context.
+func (r *RBACService) checkContextualPermissions(roleIDs []uint, permissionContext *PermissionContext, resource, action string) (permissionDecision, error) {
+	query := r.db.Model(&models.ContextualPermission{}).
+		Where("role_id IN ? AND resource IN (?, ?) AND action IN (?, ?)",
+			roleIDs, resource, models.ResourceTypeAll.String(), action, models.ActionTypeAll.String())
+	if permissionContext == nil {
+		query = query.Where("COALESCE(context_type, '') = ''")
+	} else {
+		query = query.Where("(COALESCE(context_type, '') = '' OR (context_type = ? AND COALESCE(context_value, '') IN ('', ?)))",
+			permissionContext.Type, permissionContext.Value)
+	}
+
+	var grants []bool
+	if err := query.Distinct().Pluck("is_granted", &grants).Error; err != nil {
+		return permissionDecision{}, err
+	}
+
+	var decision permissionDecision
+	for _, granted := range grants {
+		if granted {
+			decision.allowed = true
+		} else {
+			decision.denied = true
+		}
+	}
+	return decision, nil
 }
 
 func (r *RBACService) AddRole(role string) error {
@@ -412,7 +599,16 @@ func (r *RBACService) GetActiveRoles(ctx contextx.Contextx) ([]models.Role, erro
 	return roles, nil
 }
 
-func (r *RBACService) AddPermission(role, resource, action string) error {
+// AddPermission adds a global allow or deny rule for a role, applying only when the condition
+// holds if one is given
+func (r *RBACService) AddPermission(role, resource, action string, effect models.EffectType, condition string) error {
+	if !effect.IsValid() {
+		return fmt.Errorf("invalid permission effect: %s", effect)
+	}
+	if err := ValidateCondition(condition); err != nil {
+		return err
+	}
+
 	// Validate role exists
 	roleModel, err := r.GetRoleByName(contextx.Background(), role)
 	if err != nil {
@@ -423,22 +619,39 @@ func (r *RBACService) AddPermission(role, resource, action string) error {
 		return fmt.Errorf("cannot add permission to inactive role: %s", role)
 	}
 
-	_, err = r.enforcer.AddPolicy(role, resource, action)
+	return r.addPolicy(role, models.GlobalDomain, resource, action, condition, effect)
+}
+
+func (r *RBACService) addPolicy(role, domain, resource, action, condition string, effect models.EffectType) error {
+	_, err := r.enforcer.AddPolicy(role, domain, resource, action, condition, effect.String())
 	if err != nil {
 		return fmt.Errorf("failed to add permission: %w", err)
 	}
 	return r.enforcer.SavePolicy()
 }
 
-func (r *RBACService) RemovePermission(role, resource, action string) error {
-	_, err := r.enforcer.RemovePolicy(role, resource, action)
+// RemovePermission removes a global allow or deny rule, with the given condition, from a role
+func (r *RBACService) RemovePermission(role, resource, action string, effect models.EffectType, condition string) error {
+	if !effect.IsValid() {
+		return fmt.Errorf("invalid permission effect: %s", effect)
+	}
+	_, err := r.enforcer.RemovePolicy(role, models.GlobalDomain, resource, action, condition, effect.String())
 	if err != nil {
 		return fmt.Errorf("failed to remove permission: %w", err)
 	}
 	return r.enforcer.SavePolicy()
 }
 
-func (r *RBACService) AssignRoleToUser(userID uint, role string) error {
+// AssignRoleToUser grants a role to a user inside an organization, or globally when orgID is 0
+func (r *RBACService) AssignRoleToUser(userID uint, role string, orgID uint) error {
+	return r.AssignRoleToUserWithWindow(userID, role, orgID, nil, nil)
+}
+
+// AssignRoleToUserWithWindow grants a role that is only held between startsAt and expiresAt; a nil
+// bound leaves that side of the window open. The window replaces any earlier assignment of the
+// role, so a role held indefinitely becomes time-bound and vice versa. The g rule is added right
+// away; permission checks ignore it outside the window.
+func (r *RBACService) AssignRoleToUserWithWindow(userID uint, role string, orgID uint, startsAt, expiresAt *time.Time) error {
 	// Validate role exists and is active
 	roleModel, err := r.GetRoleByName(contextx.Background(), role)
 	if err != nil {
@@ -449,26 +662,54 @@ func (r *RBACService) AssignRoleToUser(userID uint, role string) error {
 		return fmt.Errorf("cannot assign inactive role: %s", role)
 	}
 
+	now := time.Now()
+	if (expiresAt != nil && !expiresAt.After(now)) || (startsAt != nil && expiresAt != nil && !expiresAt.After(*startsAt)) {
+		return errors.New("invalid role assignment window")
+	}
+
+	ctx := contextx.Background()
 	user := fmt.Sprintf("user:%d", userID)
-	_, err = r.enforcer.AddRoleForUser(user, role)
-	if err != nil {
+	domain := models.OrganizationDomain(orgID)
+	if startsAt == nil && expiresAt == nil {
+		if err := r.roleAssignmentRepo.Delete(ctx, userID, role, domain); err != nil {
+			return fmt.Errorf("failed to assign role to user: %w", err)
+		}
+	} else {
+		assignment := &models.RoleAssignment{
+			UserID:    userID,
+			Role:      role,
+			Domain:    domain,
+			StartsAt:  startsAt,
+			ExpiresAt: expiresAt,
+		}
+		if err := r.roleAssignmentRepo.Save(ctx, assignment); err != nil {
+			return fmt.Errorf("failed to assign role to user: %w", err)
+		}
+	}
+
+	if _, err := r.enforcer.AddRoleForUserInDomain(user, role, domain); err != nil {
 		return fmt.Errorf("failed to assign role to user: %w", err)
 	}
 	return r.enforcer.SavePolicy()
 }
 
-func (r *RBACService) RemoveRoleFromUser(userID uint, role string) error {
+// RemoveRoleFromUser revokes a role held inside an organization, or globally when orgID is 0
+func (r *RBACService) RemoveRoleFromUser(userID uint, role string, orgID uint) error {
 	user := fmt.Sprintf("user:%d", userID)
-	_, err := r.enforcer.DeleteRoleForUser(user, role)
+	domain := models.OrganizationDomain(orgID)
+	if err := r.roleAssignmentRepo.Delete(contextx.Background(), userID, role, domain); err != nil {
+		return fmt.Errorf("failed to remove role from user: %w", err)
+	}
+	_, err := r.enforcer.DeleteRoleForUserInDomain(user, role, domain)
 	if err != nil {
 		return fmt.Errorf("failed to remove role from user: %w", err)
 	}
 	return r.enforcer.SavePolicy()
 }
 
+// GetUserRoles returns the global roles of a user
 func (r *RBACService) GetUserRoles(userID uint) ([]string, error) {
-	user := fmt.Sprintf("user:%d", userID)
-	roles, err := r.enforcer.GetRolesForUser(user)
+	roles, err := r.rolesInEffect(userID, models.GlobalDomain)
 	if err != nil {
 		return nil, err
 	}
@@ -478,10 +719,86 @@ func (r *RBACService) GetUserRoles(userID uint) ([]string, error) {
 	return roles, nil
 }
 
+// GetUserRolesInOrganization returns the roles a user holds inside an organization
+func (r *RBACService) GetUserRolesInOrganization(userID, orgID uint) ([]string, error) {
+	return r.rolesInEffect(userID, models.OrganizationDomain(orgID))
+}
+
+// rolesInEffect returns the roles a user holds in a domain, leaving out the time-bound ones whose
+// window is not open. The window is checked here rather than by adding and removing g rules on
+// time, so that grants start and end on time even when the sweeper is late or disabled.
+func (r *RBACService) rolesInEffect(userID uint, domain string) ([]string, error) {
+	roles := r.enforcer.GetRolesForUserInDomain(fmt.Sprintf("user:%d", userID), domain)
+	if len(roles) == 0 {
+		return roles, nil
+	}
+
+	assignments, err := r.roleAssignmentRepo.ListByUser(contextx.Background(), userID, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role assignments: %w", err)
+	}
+	now := time.Now()
+	inactive := make(map[string]bool, len(assignments))
+	for i := range assignments {
+		if !assignments[i].IsActive(now) {
+			inactive[assignments[i].Role] = true
+		}
+	}
+	if len(inactive) == 0 {
+		return roles, nil
+	}
+
+	active := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !inactive[role] {
+			active = append(active, role)
+		}
+	}
+	return active, nil
+}
+
+// RemoveUserFromOrganization revokes every role the user holds inside an organization
+func (r *RBACService) RemoveUserFromOrganization(userID, orgID uint) error {
+	user := fmt.Sprintf("user:%d", userID)
+	if err := r.roleAssignmentRepo.DeleteByUserAndDomain(contextx.Background(), userID, models.OrganizationDomain(orgID)); err != nil {
+		return fmt.Errorf("failed to remove organization roles: %w", err)
+	}
+	if _, err := r.enforcer.DeleteRolesForUserInDomain(user, models.OrganizationDomain(orgID)); err != nil {
+		return fmt.Errorf("failed to remove organization roles: %w", err)
+	}
+	return r.enforcer.SavePolicy()
+}
+
+// DeleteOrganizationPolicies removes the role assignments and policies of an organization's domain
+func (r *RBACService) DeleteOrganizationPolicies(orgID uint) error {
+	domain := models.OrganizationDomain(orgID)
+	if err := r.roleAssignmentRepo.DeleteByDomain(contextx.Background(), domain); err != nil {
+		return fmt.Errorf("failed to remove organization roles: %w", err)
+	}
+	if _, err := r.enforcer.RemoveFilteredGroupingPolicy(2, domain); err != nil {
+		return fmt.Errorf("failed to remove organization roles: %w", err)
+	}
+	if _, err := r.enforcer.RemoveFilteredPolicy(1, domain); err != nil {
+		return fmt.Errorf("failed to remove organization permissions: %w", err)
+	}
+	return r.enforcer.SavePolicy()
+}
+
+// GetUsersWithRole returns the users holding a global role, leaving out time-bound assignments
+// whose window is not open
 func (r *RBACService) GetUsersWithRole(role string) ([]uint, error) {
-	subjects, err := r.enforcer.GetUsersForRole(role)
+	subjects := r.enforcer.GetUsersForRoleInDomain(role, models.GlobalDomain)
+
+	assignments, err := r.roleAssignmentRepo.ListByRole(contextx.Background(), role, models.GlobalDomain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users for role: %w", err)
+		return nil, fmt.Errorf("failed to get role assignments: %w", err)
+	}
+	now := time.Now()
+	inactive := make(map[uint]bool, len(assignments))
+	for i := range assignments {
+		if !assignments[i].IsActive(now) {
+			inactive[assignments[i].UserID] = true
+		}
 	}
 
 	var userIDs []uint
@@ -489,7 +806,7 @@ func (r *RBACService) GetUsersWithRole(role string) ([]uint, error) {
 		if len(subject) > 5 && subject[:5] == "user:" {
 			userIDStr := subject[5:]
 			userID, err := strconv.ParseUint(userIDStr, 10, 32)
-			if err != nil {
+			if err != nil || inactive[uint(userID)] {
 				continue
 			}
 			userIDs = append(userIDs, uint(userID))
@@ -499,6 +816,116 @@ func (r *RBACService) GetUsersWithRole(role string) ([]uint, error) {
 	return userIDs, nil
 }
 
+// GetUserRoleAssignments returns the roles a user holds inside an organization, or globally when
+// orgID is 0, with their validity windows. Assignments that have not started yet are included as
+// inactive.
+func (r *RBACService) GetUserRoleAssignments(userID, orgID uint) ([]dto.RoleAssignmentResponse, error) {
+	var roles []string
+	var err error
+	if orgID != 0 {
+		roles, err = r.GetUserRolesInOrganization(userID, orgID)
+	} else {
+		roles, err = r.GetUserRoles(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := r.roleAssignmentRepo.ListByUser(contextx.Background(), userID, models.OrganizationDomain(orgID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role assignments: %w", err)
+	}
+
+	responses := make([]dto.RoleAssignmentResponse, 0, len(roles))
+	for _, role := range roles {
+		responses = append(responses, dto.RoleAssignmentResponse{UserID: userID, Role: role, Active: true})
+	}
+	return withAssignmentWindows(responses, assignments), nil
+}
+
+// GetRoleAssignments returns the users holding a global role with their validity windows.
+// Assignments that have not started yet are included as inactive.
+func (r *RBACService) GetRoleAssignments(role string) ([]dto.RoleAssignmentResponse, error) {
+	userIDs, err := r.GetUsersWithRole(role)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := r.roleAssignmentRepo.ListByRole(contextx.Background(), role, models.GlobalDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role assignments: %w", err)
+	}
+
+	responses := make([]dto.RoleAssignmentResponse, 0, len(userIDs))
+	for _, userID := range userIDs {
+		responses = append(responses, dto.RoleAssignmentResponse{UserID: userID, Role: role, Active: true})
+	}
+	return withAssignmentWindows(responses, assignments), nil
+}
+
+// withAssignmentWindows adds the validity windows of time-bound assignments to the roles held
+// according to the enforcer, and appends the assignments that are not held yet
+func withAssignmentWindows(responses []dto.RoleAssignmentResponse, assignments []models.RoleAssignment) []dto.RoleAssignmentResponse {
+	type grant struct {
+		userID uint
+		role   string
+	}
+	windows := make(map[grant]*models.RoleAssignment, len(assignments))
+	for i := range assignments {
+		windows[grant{assignments[i].UserID, assignments[i].Role}] = &assignments[i]
+	}
+
+	for i := range responses {
+		key := grant{responses[i].UserID, responses[i].Role}
+		if assignment, ok := windows[key]; ok {
+			responses[i].StartsAt = assignment.StartsAt
+			responses[i].ExpiresAt = assignment.ExpiresAt
+			delete(windows, key)
+		}
+	}
+	for i := range assignments {
+		if _, pending := windows[grant{assignments[i].UserID, assignments[i].Role}]; pending {
+			responses = append(responses, dto.RoleAssignmentResponse{
+				UserID:    assignments[i].UserID,
+				Role:      assignments[i].Role,
+				StartsAt:  assignments[i].StartsAt,
+				ExpiresAt: assignments[i].ExpiresAt,
+			})
+		}
+	}
+	return responses
+}
+
+// RemoveExpiredRoleAssignments deletes the time-bound assignments whose window has closed, with
+// their g rules. Permission checks already ignore them, so this only keeps the policy tidy. The
+// policy is reloaded first, so that this instance also picks up the changes other instances have
+// made since.
+func (r *RBACService) RemoveExpiredRoleAssignments(ctx contextx.Contextx, now time.Time) error {
+	if err := r.enforcer.LoadPolicy(); err != nil {
+		return fmt.Errorf("failed to reload policy: %w", err)
+	}
+
+	expired, err := r.roleAssignmentRepo.ListExpired(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to get expired role assignments: %w", err)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for i := range expired {
+		assignment := &expired[i]
+		user := fmt.Sprintf("user:%d", assignment.UserID)
+		if _, err := r.enforcer.DeleteRoleForUserInDomain(user, assignment.Role, assignment.Domain); err != nil {
+			return fmt.Errorf("failed to revoke expired role: %w", err)
+		}
+		if err := r.roleAssignmentRepo.Delete(ctx, assignment.UserID, assignment.Role, assignment.Domain); err != nil {
+			return fmt.Errorf("failed to delete expired role assignment: %w", err)
+		}
+	}
+	return r.enforcer.SavePolicy()
+}
+
 func (r *RBACService) GetAllRoles() ([]string, error) {
 	return r.enforcer.GetAllRoles()
 }
@@ -507,7 +934,7 @@ func (r *RBACService) GetPermissionsForRole(role string) ([][]string, error) {
 	return r.enforcer.GetPermissionsForUser(role)
 }
 
-func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resourceFilter, actionFilter, permissionFilter, sortField, sortOrder string) ([]dto.PermissionResponse, int, error) {
+func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resourceFilter, actionFilter, effectFilter, permissionFilter, sortField, sortOrder string) ([]dto.PermissionResponse, int, error) {
 	// Get hardcoded permissions to create a mapping
 	hardcodedPermissions := models.GetHardcodedPermissions()
 	permissionMap := make(map[string]models.Permission)
@@ -519,15 +946,18 @@ func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resource
 	// Query casbin_rule table directly for permissions (ptype = 'p')
 	query := r.db.Model(&models.Rule{}).Where("ptype = 'p'")
 
-	// Apply filters
+	// Apply filters (v0 role, v1 domain, v2 resource, v3 action, v4 condition, v5 effect)
 	if roleFilter != "" {
 		query = query.Where("v0 LIKE ?", "%"+roleFilter+"%")
 	}
 	if resourceFilter != "" {
-		query = query.Where("v1 LIKE ?", "%"+resourceFilter+"%")
+		query = query.Where("v2 LIKE ?", "%"+resourceFilter+"%")
 	}
 	if actionFilter != "" {
-		query = query.Where("v2 LIKE ?", "%"+actionFilter+"%")
+		query = query.Where("v3 LIKE ?", "%"+actionFilter+"%")
+	}
+	if effectFilter != "" {
+		query = query.Where("v5 = ?", effectFilter)
 	}
 	if permissionFilter != "" {
 		// Filter by the permission field from hardcoded permissions
@@ -544,13 +974,13 @@ func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resource
 			conditions := make([]string, len(matchingResourceActions))
 			args := make([]interface{}, len(matchingResourceActions))
 			for i, resourceAction := range matchingResourceActions {
-				conditions[i] = "CONCAT(v1, ':', v2) = ?"
+				conditions[i] = "CONCAT(v2, ':', v3) = ?"
 				args[i] = resourceAction
 			}
 			query = query.Where(fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), args...)
 		} else {
 			// If no hardcoded permissions match, also check resource:action format
-			query = query.Where("CONCAT(v1, ':', v2) LIKE ?", "%"+permissionFilter+"%")
+			query = query.Where("CONCAT(v2, ':', v3) LIKE ?", "%"+permissionFilter+"%")
 		}
 	}
 
@@ -566,14 +996,18 @@ func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resource
 		switch sortField {
 		case "role":
 			orderClause = fmt.Sprintf("v0 %s", sortOrder)
-		case "resource":
+		case "domain":
 			orderClause = fmt.Sprintf("v1 %s", sortOrder)
-		case "action":
+		case "resource":
 			orderClause = fmt.Sprintf("v2 %s", sortOrder)
+		case "action":
+			orderClause = fmt.Sprintf("v3 %s", sortOrder)
+		case "effect":
+			orderClause = fmt.Sprintf("v5 %s", sortOrder)
 		case "permission":
 			// For permission sorting, we'll need to sort by resource:action format
 			// since we can't easily sort by the hardcoded permission values in SQL
-			orderClause = fmt.Sprintf("CONCAT(v1, ':', v2) %s", sortOrder)
+			orderClause = fmt.Sprintf("CONCAT(v2, ':', v3) %s", sortOrder)
 		default:
 			orderClause = fmt.Sprintf("id %s", sortOrder)
 		}
@@ -582,14 +1016,14 @@ func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resource
 	rules := make([]models.Rule, 0)
 	offset := (page - 1) * pageSize
 	if err := query.Order(orderClause).Offset(offset).Limit(pageSize).
-		Select("id, v0, v1, v2").Find(&rules).Error; err != nil {
+		Select("id, v0, v1, v2, v3, v4, v5").Find(&rules).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to get permissions: %w", err)
 	}
 
 	// Convert to PermissionResponse format using hardcoded permissions
 	permissions := make([]dto.PermissionResponse, len(rules))
 	for i, rule := range rules {
-		key := fmt.Sprintf("%s:%s", rule.V1, rule.V2)
+		key := fmt.Sprintf("%s:%s", rule.V2, rule.V3)
 		permission := key // default to resource:action format
 
 		// Use the hardcoded permission if available
@@ -600,8 +1034,11 @@ func (r *RBACService) GetAllPermissions(page, pageSize int, roleFilter, resource
 		permissions[i] = dto.PermissionResponse{
 			ID:         rule.ID,
 			Role:       rule.V0,
-			Resource:   rule.V1,
-			Action:     rule.V2,
+			Domain:     rule.V1,
+			Resource:   rule.V2,
+			Action:     rule.V3,
+			Condition:  rule.V4,
+			Effect:     rule.V5,
 			Permission: permission,
 		}
 	}
@@ -620,7 +1057,10 @@ func (r *RBACService) DeleteRole(role string) error {
 		return fmt.Errorf("cannot delete system role")
 	}
 
-	// Delete from Casbin
+	// Delete from Casbin, with the validity windows of its assignments
+	if err := r.roleAssignmentRepo.DeleteByRole(contextx.Background(), role); err != nil {
+		return fmt.Errorf("failed to delete role assignments: %w", err)
+	}
 	_, err := r.enforcer.DeleteRole(role)
 	if err != nil {
 		return fmt.Errorf("failed to delete role from enforcer: %w", err)
@@ -647,56 +1087,6 @@ func (r *RBACService) ReloadPolicy() error {
 	return r.enforcer.LoadPolicy()
 }
 
-// Check permission with contextual information (simplified without organization context)
-func (r *RBACService) CheckPermissionWithContext(userID uint, resource, action string) (bool, error) {
-	// Use the standard permission check since we removed organization context
-	return r.CheckPermission(userID, resource, action)
-}
-
-
-// Check contextual permissions
-func (r *RBACService) checkContextualPermissions(roleID uint, resource, action string) (bool, error) {
-	var count int64
-	query := r.db.Model(&models.ContextualPermission{}).
-		Where("role_id = ? AND resource = ? AND action = ? AND is_granted = ?",
-			roleID, resource, action, true)
-
-
-	err := query.Count(&count).Error
-	return count > 0, err
-}
-
-// Check inherited permissions from parent roles
-func (r *RBACService) checkInheritedPermissions(roleID uint, resource, action string) (bool, error) {
-	// Get all parent roles in the hierarchy
-	parentRoles, err := models.GetAllParentRoles(r.db, roleID)
-	if err != nil {
-		return false, err
-	}
-
-	for _, parentRole := range parentRoles {
-		// Check Casbin permissions for parent role
-		ok, err := r.enforcer.Enforce(parentRole.Name, resource, action)
-		if err != nil {
-			continue
-		}
-		if ok {
-			return true, nil
-		}
-
-		// Check contextual permissions for parent role
-		hasContextualPermission, err := r.checkContextualPermissions(parentRole.ID, resource, action)
-		if err != nil {
-			continue
-		}
-		if hasContextualPermission {
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
-
 // Create role from template
 func (r *RBACService) CreateRoleFromTemplate(templateID uint, customName string) (*models.Role, error) {
 	var template models.RoleTemplate
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+)
+
+// createRole adds an active role granting resource:action
+func (env *testEnv) createRole(t *testing.T, name, resource, action string) {
+	t.Helper()
+	if err := env.db.Create(&models.Role{Name: name, DisplayName: name, IsActive: true}).Error; err != nil {
+		t.Fatalf("create role %s: %v", name, err)
+	}
+	if err := env.rbacService.AddPermission(name, resource, action, models.EffectAllow, ""); err != nil {
+		t.Fatalf("AddPermission: %v", err)
+	}
+}
+
+func TestAuthorizeAppliesRoleAssignmentWindowWithoutSweeper(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.registerUser(t, "alice", "alice@example.com")
+	env.createRole(t, "auditor", "reports", "read")
+
+	now := time.Now()
+	startsAt, expiresAt := now.Add(time.Hour), now.Add(2*time.Hour)
+	if err := env.rbacService.AssignRoleToUserWithWindow(user.ID, "auditor", 0, &startsAt, &expiresAt); err != nil {
+		t.Fatalf("AssignRoleToUserWithWindow: %v", err)
+	}
+
+	assertAuditor := func(want bool) {
+		t.Helper()
+		allowed, err := env.rbacService.CheckPermission(user.ID, "reports", "read")
+		if err != nil {
+			t.Fatalf("CheckPermission: %v", err)
+		}
+		roles, err := env.rbacService.GetUserRoles(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserRoles: %v", err)
+		}
+		users, err := env.rbacService.GetUsersWithRole("auditor")
+		if err != nil {
+			t.Fatalf("GetUsersWithRole: %v", err)
+		}
+		if allowed != want || slices.Contains(roles, "auditor") != want || slices.Contains(users, user.ID) != want {
+			t.Fatalf("auditor in effect: allowed=%v roles=%v users=%v, want %v", allowed, roles, users, want)
+		}
+	}
+	moveWindow := func(column string, value time.Time) {
+		t.Helper()
+		if err := env.db.Model(&models.RoleAssignment{}).Where("user_id = ? AND role = ?", user.ID, "auditor").Update(column, value).Error; err != nil {
+			t.Fatalf("update %s: %v", column, err)
+		}
+	}
+
+	// Not started yet
+	assertAuditor(false)
+
+	// Started; no sweep has run
+	moveWindow("starts_at", now.Add(-time.Hour))
+	assertAuditor(true)
+
+	// Expired; no sweep has run
+	moveWindow("expires_at", now.Add(-time.Minute))
+	assertAuditor(false)
+
+	// The sweep only cleans up
+	if err := env.rbacService.RemoveExpiredRoleAssignments(contextx.Background(), time.Now()); err != nil {
+		t.Fatalf("RemoveExpiredRoleAssignments: %v", err)
+	}
+	if roles := env.rbacService.enforcer.GetRolesForUserInDomain(fmt.Sprintf("user:%d", user.ID), models.GlobalDomain); slices.Contains(roles, "auditor") {
+		t.Errorf("expired g rule still in place: %v", roles)
+	}
+	var count int64
+	env.db.Model(&models.RoleAssignment{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d role assignments left after the sweep, want 0", count)
+	}
+	assertAuditor(false)
+}
+
+func TestAuthorizeOrganizationRoleWindow(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.registerUser(t, "alice", "alice@example.com")
+	env.createRole(t, "auditor", "reports", "read")
+	if err := env.rbacService.addPolicy("auditor", models.OrganizationDomain(7), "invoices", "read", "", models.EffectAllow); err != nil {
+		t.Fatalf("addPolicy: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := env.rbacService.AssignRoleToUserWithWindow(user.ID, "auditor", 7, nil, &expiresAt); err != nil {
+		t.Fatalf("AssignRoleToUserWithWindow: %v", err)
+	}
+	if allowed, err := env.rbacService.CheckPermissionInOrganization(user.ID, 7, "invoices", "read"); err != nil || !allowed {
+		t.Fatalf("CheckPermissionInOrganization = %v, %v; want allowed", allowed, err)
+	}
+
+	if err := env.db.Model(&models.RoleAssignment{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("update expires_at: %v", err)
+	}
+	if allowed, err := env.rbacService.CheckPermissionInOrganization(user.ID, 7, "invoices", "read"); err != nil || allowed {
+		t.Fatalf("CheckPermissionInOrganization after expiry = %v, %v; want denied", allowed, err)
+	}
+	if roles, err := env.rbacService.GetUserRolesInOrganization(user.ID, 7); err != nil || len(roles) != 0 {
+		t.Fatalf("GetUserRolesInOrganization after expiry = %v, %v; want none", roles, err)
+	}
+}
+
+func TestAuthorizeOwnerCondition(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.registerUser(t, "alice", "alice@example.com")
+	bob := env.registerUser(t, "bob", "bob@example.com")
+	if err := env.db.Create(&models.Role{Name: "member", DisplayName: "member", IsActive: true}).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if err := env.rbacService.AddPermission("member", "users", "update", models.EffectAllow, "subject.id == resource.owner_id"); err != nil {
+		t.Fatalf("AddPermission: %v", err)
+	}
+	if err := env.rbacService.AssignRoleToUserWithWindow(alice.ID, "member", 0, nil, nil); err != nil {
+		t.Fatalf("AssignRoleToUserWithWindow: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]interface{}
+		want       bool
+	}{
+		{"own account", map[string]interface{}{"id": alice.ID, "owner_id": alice.ID}, true},
+		{"other account", map[string]interface{}{"id": bob.ID, "owner_id": bob.ID}, false},
+		{"no attributes", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := env.rbacService.Authorize(alice.ID, "users", "update", AccessRequest{ResourceAttributes: tt.attributes})
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if allowed != tt.want {
+				t.Errorf("Authorize = %v, want %v", allowed, tt.want)
+			}
+		})
+	}
+}
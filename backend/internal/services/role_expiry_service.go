@@ -0,0 +1,111 @@
+package services
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// RoleExpiryService maintains time-bound role assignments. Permission checks apply the windows on
+// their own; the service periodically removes the expired assignments and warns users before a
+// role expires.
+type RoleExpiryService struct {
+	rbacService          *RBACService
+	roleAssignmentRepo   repository.RoleAssignmentRepository
+	userRepo             repository.UserRepository
+	organizationRepo     repository.OrganizationRepository
+	emailService         *EmailService
+	roleAssignmentConfig *config.RoleAssignmentConfig
+}
+
+func NewRoleExpiryService(
+	rbacService *RBACService,
+	roleAssignmentRepo repository.RoleAssignmentRepository,
+	userRepo repository.UserRepository,
+	organizationRepo repository.OrganizationRepository,
+	emailService *EmailService,
+	roleAssignmentConfig *config.RoleAssignmentConfig,
+) *RoleExpiryService {
+	return &RoleExpiryService{
+		rbacService:          rbacService,
+		roleAssignmentRepo:   roleAssignmentRepo,
+		userRepo:             userRepo,
+		organizationRepo:     organizationRepo,
+		emailService:         emailService,
+		roleAssignmentConfig: roleAssignmentConfig,
+	}
+}
+
+// Start sweeps role assignments every sweep interval until the process exits
+func (s *RoleExpiryService) Start() {
+	if s.roleAssignmentConfig.SweepInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.roleAssignmentConfig.SweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Sweep(contextx.Background()); err != nil {
+				log.Printf("Failed to sweep role assignments: %v", err)
+			}
+		}
+	}()
+}
+
+// Sweep removes the role assignments that expired since the last sweep, then notifies the users
+// whose roles expire within the notice period
+func (s *RoleExpiryService) Sweep(ctx contextx.Contextx) error {
+	now := time.Now()
+	if err := s.rbacService.RemoveExpiredRoleAssignments(ctx, now); err != nil {
+		return err
+	}
+	if s.roleAssignmentConfig.NotifyBefore <= 0 {
+		return nil
+	}
+
+	expiring, err := s.roleAssignmentRepo.ListExpiring(ctx, now, now.Add(s.roleAssignmentConfig.NotifyBefore))
+	if err != nil {
+		return err
+	}
+	for i := range expiring {
+		if err := s.notifyExpiry(ctx, &expiring[i], now); err != nil {
+			log.Printf("Failed to send role expiry notice to user %d: %v", expiring[i].UserID, err)
+		}
+	}
+	return nil
+}
+
+// notifyExpiry emails the user about an expiring role once, even when several instances sweep
+func (s *RoleExpiryService) notifyExpiry(ctx contextx.Contextx, assignment *models.RoleAssignment, now time.Time) error {
+	claimed, err := s.roleAssignmentRepo.MarkExpiryNotified(ctx, assignment.ID, now)
+	if err != nil || !claimed {
+		return err
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, assignment.UserID, "UserInfo")
+	if err != nil {
+		return err
+	}
+
+	organization := ""
+	if id, ok := strings.CutPrefix(assignment.Domain, "org:"); ok {
+		orgID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return err
+		}
+		org, err := s.organizationRepo.GetByID(ctx, uint(orgID))
+		if err != nil {
+			return err
+		}
+		organization = org.Name
+	}
+
+	return s.emailService.SendRoleExpiryEmail(ctx, user, assignment.Role, organization, *assignment.ExpiresAt)
+}
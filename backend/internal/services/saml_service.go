@@ -0,0 +1,258 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/saml"
+	"bezbase/internal/repository"
+)
+
+var samlSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// SAMLService signs users in through corporate SAML 2.0 identity providers, acting as the service provider
+type SAMLService struct {
+	authService      *AuthService
+	authProviderRepo repository.AuthProviderRepository
+	providerRepo     repository.SAMLProviderRepository
+	samlConfig       *config.SAMLConfig
+	keyPair          *saml.KeyPair
+	secret           []byte
+}
+
+func NewSAMLService(
+	authService *AuthService,
+	authProviderRepo repository.AuthProviderRepository,
+	providerRepo repository.SAMLProviderRepository,
+	samlConfig *config.SAMLConfig,
+	authConfig *config.AuthConfig,
+) (*SAMLService, error) {
+	var keyPair *saml.KeyPair
+	if samlConfig.KeyFile != "" || samlConfig.CertFile != "" {
+		var err error
+		if keyPair, err = saml.LoadKeyPair(samlConfig.KeyFile, samlConfig.CertFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SAMLService{
+		authService:      authService,
+		authProviderRepo: authProviderRepo,
+		providerRepo:     providerRepo,
+		samlConfig:       samlConfig,
+		keyPair:          keyPair,
+		secret:           []byte(authConfig.JWTSecret),
+	}, nil
+}
+
+// ListLoginProviders returns the identity providers users can currently sign in with
+func (s *SAMLService) ListLoginProviders(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error) {
+	return s.providerRepo.ListEnabled(ctx)
+}
+
+// BeginLogin returns the identity provider URL for a new authentication request and the signed
+// state to keep in a cookie until the response arrives
+func (s *SAMLService) BeginLogin(ctx contextx.Contextx, slug string) (string, string, error) {
+	provider, err := s.loadProvider(ctx, slug, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	redirectURL, requestID, err := provider.AuthnRequestURL()
+	if err != nil {
+		return "", "", errors.New("saml login failed")
+	}
+	state := saml.RequestState{
+		Provider:  slug,
+		RequestID: requestID,
+		ExpiresAt: time.Now().Add(s.samlConfig.StateTTL).Unix(),
+	}
+	cookie, err := state.Sign(s.secret)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL, cookie, nil
+}
+
+// HandleResponse validates the SAML response posted to the assertion consumer service and signs the
+// user in, provisioning an account on first sign-in. Only responses to the request recorded in the
+// signed state cookie are accepted.
+func (s *SAMLService) HandleResponse(ctx contextx.Contextx, slug, stateCookie string, r *http.Request) (*dto.AuthResponse, error) {
+	provider, err := s.loadProvider(ctx, slug, true)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := saml.ParseRequestState(stateCookie, s.secret)
+	if err != nil || state.Provider != slug {
+		return nil, errors.New("invalid saml state")
+	}
+
+	identity, err := provider.ParseResponse(r, state.RequestID)
+	if err != nil {
+		return nil, errors.New("invalid saml response")
+	}
+
+	providerID := slug + ":" + identity.NameID
+
+	// Returning users sign in with the linked account
+	if _, err := s.authProviderRepo.GetByProviderIDAndType(ctx, providerID, models.ProviderSAML); err == nil {
+		return s.authService.loginWithSocialProvider(ctx, models.ProviderSAML, providerID)
+	}
+
+	profile := externalProfile(identity.Attributes)
+	if profile.Email == "" {
+		return nil, errors.New("saml email missing")
+	}
+	return s.authService.RegisterWithExternalProfile(ctx, models.ProviderSAML, providerID, profile)
+}
+
+// Metadata returns the service provider metadata for an identity provider
+func (s *SAMLService) Metadata(ctx contextx.Contextx, slug string) ([]byte, error) {
+	provider, err := s.loadProvider(ctx, slug, false)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Metadata()
+}
+
+// CreateProvider registers an identity provider after checking its metadata and certificates
+func (s *SAMLService) CreateProvider(ctx contextx.Contextx, req dto.CreateSAMLProviderRequest) (*models.SAMLIdentityProvider, error) {
+	slug := strings.TrimSpace(req.Slug)
+	if !samlSlugPattern.MatchString(slug) {
+		return nil, errors.New("invalid saml slug")
+	}
+	if _, err := s.providerRepo.GetBySlug(ctx, slug); err == nil {
+		return nil, errors.New("saml provider already exists")
+	}
+
+	provider := models.SAMLIdentityProvider{
+		Slug:    slug,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.applyProviderSettings(&provider, req.Name, req.MetadataXML, req.Certificates, req.AttributeMapping); err != nil {
+		return nil, err
+	}
+
+	if err := s.providerRepo.Create(ctx, &provider); err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// ListProviders returns all identity providers
+func (s *SAMLService) ListProviders(ctx contextx.Contextx) ([]models.SAMLIdentityProvider, error) {
+	return s.providerRepo.List(ctx)
+}
+
+// GetProvider returns an identity provider by its database ID
+func (s *SAMLService) GetProvider(ctx contextx.Contextx, id uint) (*models.SAMLIdentityProvider, error) {
+	return s.providerRepo.GetByID(ctx, id)
+}
+
+// UpdateProvider changes an identity provider's settings. The slug is fixed because it is part of
+// the URLs registered with the identity provider and of the linked accounts.
+func (s *SAMLService) UpdateProvider(ctx contextx.Contextx, id uint, req dto.UpdateSAMLProviderRequest) (*models.SAMLIdentityProvider, error) {
+	provider, err := s.providerRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := provider.Name
+	if req.Name != "" {
+		name = req.Name
+	}
+	metadataXML := provider.MetadataXML
+	if req.MetadataXML != "" {
+		metadataXML = req.MetadataXML
+	}
+	certificates := provider.Certificates
+	if req.Certificates != nil {
+		certificates = *req.Certificates
+	}
+	mapping := provider.Mapping()
+	if req.AttributeMapping != nil {
+		mapping = req.AttributeMapping
+	}
+	if err := s.applyProviderSettings(provider, name, metadataXML, certificates, mapping); err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		provider.Enabled = *req.Enabled
+	}
+
+	if err := s.providerRepo.Update(ctx, provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// DeleteProvider removes an identity provider. Accounts provisioned through it keep their other sign-in methods.
+func (s *SAMLService) DeleteProvider(ctx contextx.Contextx, id uint) error {
+	if _, err := s.providerRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.providerRepo.Delete(ctx, id)
+}
+
+// BaseURL returns the public URL of the SAML routes
+func (s *SAMLService) BaseURL() string {
+	return s.samlConfig.BaseURL
+}
+
+// applyProviderSettings validates and sets the configurable fields of a provider
+func (s *SAMLService) applyProviderSettings(provider *models.SAMLIdentityProvider, name, metadataXML, certificates string, mapping map[string]string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return errors.New("invalid saml provider name")
+	}
+	if _, err := saml.ParseMetadata(metadataXML); err != nil {
+		return errors.New("invalid saml metadata")
+	}
+	pem := strings.TrimSpace(certificates)
+	if pem != "" {
+		if _, err := saml.ParseCertificates(pem); err != nil {
+			return errors.New("invalid saml certificates")
+		}
+	}
+	for field, attribute := range mapping {
+		if _, ok := saml.DefaultAttributes[field]; !ok || strings.TrimSpace(attribute) == "" {
+			return errors.New("invalid attribute mapping")
+		}
+	}
+	encodedMapping, err := json.Marshal(mapping)
+	if err != nil {
+		return errors.New("invalid attribute mapping")
+	}
+
+	provider.Name = name
+	provider.MetadataXML = metadataXML
+	provider.Certificates = pem
+	provider.AttributeMapping = string(encodedMapping)
+	return nil
+}
+
+// loadProvider builds the service provider for a stored identity provider
+func (s *SAMLService) loadProvider(ctx contextx.Contextx, slug string, requireEnabled bool) (*saml.Provider, error) {
+	stored, err := s.providerRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if requireEnabled && !stored.Enabled {
+		return nil, errors.New("saml provider not found")
+	}
+
+	provider, err := saml.NewProvider(stored.MetadataXML, stored.Certificates, stored.Mapping(), s.samlConfig.BaseURL+"/"+stored.Slug, s.keyPair)
+	if err != nil {
+		return nil, errors.New("saml login failed")
+	}
+	return provider, nil
+}
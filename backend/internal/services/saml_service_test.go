@@ -0,0 +1,245 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/saml/samltest"
+)
+
+// newSAMLTestEnv registers the identity provider idp under slug
+func newSAMLTestEnv(t *testing.T, idp *samltest.IdentityProvider, slug string, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+	env := newTestEnv(t, configure...)
+	if _, err := env.samlService.CreateProvider(contextx.Background(), dto.CreateSAMLProviderRequest{
+		Slug:        slug,
+		Name:        "Acme SSO",
+		MetadataXML: idp.Metadata(),
+	}); err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+	return env
+}
+
+// beginSAMLLogin starts a login at idp and returns the state cookie and the request ID the
+// response has to answer
+func (env *testEnv) beginSAMLLogin(t *testing.T, idp *samltest.IdentityProvider, slug string) (string, string) {
+	t.Helper()
+	redirectURL, cookie, err := env.samlService.BeginLogin(contextx.Background(), slug)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	requestID, err := idp.RequestID(redirectURL)
+	if err != nil {
+		t.Fatalf("RequestID: %v", err)
+	}
+	return cookie, requestID
+}
+
+// samlAssertion answers requestID for the provider registered under slug
+func (env *testEnv) samlAssertion(slug, requestID, nameID string) samltest.Assertion {
+	base := env.cfg.SAML.BaseURL + "/" + slug
+	return samltest.Assertion{
+		ACSURL:       base + "/acs",
+		Audience:     base + "/metadata",
+		InResponseTo: requestID,
+		NameID:       nameID,
+		Attributes: map[string]string{
+			"mail":      "jane@example.com",
+			"givenName": "Jane",
+			"sn":        "Doe",
+		},
+	}
+}
+
+func (env *testEnv) samlResponseRequest(t *testing.T, idp *samltest.IdentityProvider, slug string, assertion samltest.Assertion) *http.Request {
+	t.Helper()
+	response, err := idp.Response(assertion)
+	if err != nil {
+		t.Fatalf("Response: %v", err)
+	}
+	form := url.Values{"SAMLResponse": {response}}
+	r := httptest.NewRequest(http.MethodPost, env.cfg.SAML.BaseURL+"/"+slug+"/acs", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestSAMLLoginProvisionsThenSignsIn(t *testing.T) {
+	idp, err := samltest.New("https://idp.example")
+	if err != nil {
+		t.Fatalf("samltest.New: %v", err)
+	}
+	env := newSAMLTestEnv(t, idp, "acme")
+	ctx := contextx.Background()
+
+	cookie, requestID := env.beginSAMLLogin(t, idp, "acme")
+	first, err := env.samlService.HandleResponse(ctx, "acme", cookie, env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", requestID, "u-1001")))
+	if err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	if first.Token == "" || first.RefreshToken == "" {
+		t.Fatal("first sign-in issued no tokens")
+	}
+
+	// Provisioned just in time from the assertion
+	user, err := env.userRepo.GetByIDWithPreload(ctx, first.User.ID, "UserInfo")
+	if err != nil {
+		t.Fatalf("GetByIDWithPreload: %v", err)
+	}
+	if user.UserInfo.Email != "jane@example.com" || user.UserInfo.FirstName != "Jane" || user.UserInfo.LastName != "Doe" {
+		t.Errorf("profile = %s %s <%s>, want Jane Doe <jane@example.com>", user.UserInfo.FirstName, user.UserInfo.LastName, user.UserInfo.Email)
+	}
+	if !user.EmailVerified || user.Status != models.UserStatusActive {
+		t.Errorf("provisioned user status = %s, email verified = %v; want active and verified", user.Status, user.EmailVerified)
+	}
+	link, err := env.authProviderRepo.GetByProviderIDAndType(ctx, "acme:u-1001", models.ProviderSAML)
+	if err != nil || link.UserID != user.ID {
+		t.Fatalf("saml link = %v, %v; want acme:u-1001 linked to user %d", link, err, user.ID)
+	}
+
+	// A returning user gets the same account, even with changed attributes
+	cookie, requestID = env.beginSAMLLogin(t, idp, "acme")
+	assertion := env.samlAssertion("acme", requestID, "u-1001")
+	assertion.Attributes["mail"] = "jane.doe@example.com"
+	second, err := env.samlService.HandleResponse(ctx, "acme", cookie, env.samlResponseRequest(t, idp, "acme", assertion))
+	if err != nil {
+		t.Fatalf("HandleResponse for returning user: %v", err)
+	}
+	if second.User.ID != user.ID {
+		t.Errorf("returning user signed in as %d, want %d", second.User.ID, user.ID)
+	}
+}
+
+func TestSAMLLoginDoesNotTakeOverExistingEmail(t *testing.T) {
+	idp, err := samltest.New("https://idp.example")
+	if err != nil {
+		t.Fatalf("samltest.New: %v", err)
+	}
+	env := newSAMLTestEnv(t, idp, "acme")
+	env.registerUser(t, "jane", "jane@example.com")
+
+	cookie, requestID := env.beginSAMLLogin(t, idp, "acme")
+	_, err = env.samlService.HandleResponse(contextx.Background(), "acme", cookie, env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", requestID, "u-1001")))
+	if err == nil || err.Error() != "email already registered" {
+		t.Errorf("HandleResponse error = %v, want email already registered", err)
+	}
+}
+
+func TestSAMLHandleResponseRejects(t *testing.T) {
+	idp, err := samltest.New("https://idp.example")
+	if err != nil {
+		t.Fatalf("samltest.New: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		// run returns the state cookie and the request to post for the login started as requestID
+		run     func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request)
+		wantErr string
+	}{
+		{
+			name: "response to another request",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				_, otherRequestID := env.beginSAMLLogin(t, idp, "acme")
+				return cookie, env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", otherRequestID, "u-1001"))
+			},
+			wantErr: "invalid saml response",
+		},
+		{
+			name: "unsigned response",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				assertion := env.samlAssertion("acme", requestID, "u-1001")
+				assertion.Unsigned = true
+				return cookie, env.samlResponseRequest(t, idp, "acme", assertion)
+			},
+			wantErr: "invalid saml response",
+		},
+		{
+			name: "missing state",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				return "", env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", requestID, "u-1001"))
+			},
+			wantErr: "invalid saml state",
+		},
+		{
+			name: "tampered state",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				return cookie + "x", env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", requestID, "u-1001"))
+			},
+			wantErr: "invalid saml state",
+		},
+		{
+			name:      "expired state",
+			configure: func(cfg *config.Config) { cfg.SAML.StateTTL = -time.Second },
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				return cookie, env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", requestID, "u-1001"))
+			},
+			wantErr: "invalid saml state",
+		},
+		{
+			name: "state of another provider",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				if _, err := env.samlService.CreateProvider(contextx.Background(), dto.CreateSAMLProviderRequest{Slug: "other", Name: "Other", MetadataXML: idp.Metadata()}); err != nil {
+					t.Fatalf("CreateProvider: %v", err)
+				}
+				otherCookie, otherRequestID := env.beginSAMLLogin(t, idp, "other")
+				return otherCookie, env.samlResponseRequest(t, idp, "acme", env.samlAssertion("acme", otherRequestID, "u-1001"))
+			},
+			wantErr: "invalid saml state",
+		},
+		{
+			name: "no email",
+			run: func(t *testing.T, env *testEnv, cookie, requestID string) (string, *http.Request) {
+				assertion := env.samlAssertion("acme", requestID, "u-1001")
+				delete(assertion.Attributes, "mail")
+				return cookie, env.samlResponseRequest(t, idp, "acme", assertion)
+			},
+			wantErr: "saml email missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configure []func(cfg *config.Config)
+			if tt.configure != nil {
+				configure = append(configure, tt.configure)
+			}
+			env := newSAMLTestEnv(t, idp, "acme", configure...)
+			cookie, requestID := env.beginSAMLLogin(t, idp, "acme")
+
+			cookie, r := tt.run(t, env, cookie, requestID)
+			if _, err := env.samlService.HandleResponse(contextx.Background(), "acme", cookie, r); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("HandleResponse error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSAMLLoginRequiresEnabledProvider(t *testing.T) {
+	idp, err := samltest.New("https://idp.example")
+	if err != nil {
+		t.Fatalf("samltest.New: %v", err)
+	}
+	env := newTestEnv(t)
+	disabled := false
+	if _, err := env.samlService.CreateProvider(contextx.Background(), dto.CreateSAMLProviderRequest{
+		Slug:        "acme",
+		Name:        "Acme SSO",
+		MetadataXML: idp.Metadata(),
+		Enabled:     &disabled,
+	}); err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+	if _, _, err := env.samlService.BeginLogin(contextx.Background(), "acme"); err == nil {
+		t.Error("BeginLogin succeeded for a disabled provider")
+	}
+}
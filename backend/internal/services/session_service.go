@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"bezbase/internal/config"
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/auth"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+type SessionService struct {
+	sessionRepo  repository.SessionRepository
+	userRepo     repository.UserRepository
+	loginHistory *LoginHistoryService
+	keys         *auth.KeySet
+	authConfig   *config.AuthConfig
+}
+
+func NewSessionService(
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+	loginHistory *LoginHistoryService,
+	keys *auth.KeySet,
+	authConfig *config.AuthConfig,
+) *SessionService {
+	return &SessionService{
+		sessionRepo:  sessionRepo,
+		userRepo:     userRepo,
+		loginHistory: loginHistory,
+		keys:         keys,
+		authConfig:   authConfig,
+	}
+}
+
+// IssueTokens starts a new session for the user and returns an access/refresh token pair. Every
+// login method ends here, so this is where logins are recorded in the history.
+func (s *SessionService) IssueTokens(ctx contextx.Contextx, user *models.User) (*dto.AuthResponse, error) {
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	now := time.Now()
+	userAgent, ipAddress := requestClientInfo(ctx)
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		ExpiresAt:        now.Add(s.authConfig.RefreshTokenTTL),
+		LastUsedAt:       now,
+		AuthenticatedAt:  now,
+	}
+	if err := s.sessionRepo.Create(ctx, &session); err != nil {
+		return nil, err
+	}
+	s.loginHistory.Record(ctx, user.ID, session.ID)
+
+	return s.buildAuthResponse(user, &session, refreshToken)
+}
+
+// Refresh rotates a refresh token and issues a new access token for the same session.
+// Presenting an already rotated-out token revokes the session, since it indicates the
+// token was copied by someone else. So does losing a race against a concurrent refresh
+// with the same token.
+func (s *SessionService) Refresh(ctx contextx.Contextx, refreshToken string) (*dto.AuthResponse, error) {
+	hash := auth.HashToken(refreshToken)
+	session, err := s.sessionRepo.GetByRefreshTokenHash(ctx, hash)
+	if err != nil {
+		if reused, err := s.sessionRepo.GetByPreviousRefreshTokenHash(ctx, hash); err == nil {
+			_ = s.sessionRepo.Revoke(ctx, reused.ID)
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+	if !session.IsActive() {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, session.UserID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	newRefreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	now := time.Now()
+	userAgent, ipAddress := requestClientInfo(ctx)
+	session.PreviousRefreshTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = auth.HashToken(newRefreshToken)
+	session.ExpiresAt = now.Add(s.authConfig.RefreshTokenTTL)
+	session.LastUsedAt = now
+	if userAgent != "" {
+		session.UserAgent = userAgent
+	}
+	if ipAddress != "" {
+		session.IPAddress = ipAddress
+	}
+	rotated, err := s.sessionRepo.Rotate(ctx, session, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		_ = s.sessionRepo.Revoke(ctx, session.ID)
+		return nil, errors.New("invalid refresh token")
+	}
+
+	return s.buildAuthResponse(user, session, newRefreshToken)
+}
+
+// Logout revokes the session that owns the given refresh token
+func (s *SessionService) Logout(ctx contextx.Contextx, refreshToken string) error {
+	session, err := s.sessionRepo.GetByRefreshTokenHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return errors.New("invalid refresh token")
+	}
+	return s.sessionRepo.Revoke(ctx, session.ID)
+}
+
+// IsSessionActive reports whether access tokens bound to the session are still honoured
+func (s *SessionService) IsSessionActive(ctx contextx.Contextx, sessionID uint) bool {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return false
+	}
+	return session.IsActive()
+}
+
+// ListSessions returns the active sessions of a user
+func (s *SessionService) ListSessions(ctx contextx.Contextx, userID uint) ([]models.Session, error) {
+	return s.sessionRepo.GetActiveByUserID(ctx, userID)
+}
+
+// RevokeSession revokes one of the user's own sessions
+func (s *SessionService) RevokeSession(ctx contextx.Contextx, userID, sessionID uint) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return errors.New("session not found")
+	}
+	return s.sessionRepo.Revoke(ctx, session.ID)
+}
+
+// RevokeAllSessions revokes every session of the user, optionally keeping one alive
+func (s *SessionService) RevokeAllSessions(ctx contextx.Contextx, userID uint, exceptSessionID uint) error {
+	return s.sessionRepo.RevokeAllForUser(ctx, userID, exceptSessionID)
+}
+
+// ResumeSession issues a fresh access token for an existing session without rotating its refresh
+// token. It is used to hand an admin back their own session when impersonation ends.
+func (s *SessionService) ResumeSession(ctx contextx.Contextx, userID, sessionID uint) (*dto.AuthResponse, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return nil, errors.New("session revoked")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	return s.buildAuthResponse(user, session, "")
+}
+
+// Reauthenticate marks the session as freshly authenticated after the caller proved their identity
+// again, and returns an access token with the new auth_time. The refresh token is unchanged.
+func (s *SessionService) Reauthenticate(ctx contextx.Contextx, user *models.User, sessionID uint) (*dto.AuthResponse, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != user.ID || !session.IsActive() {
+		return nil, errors.New("session revoked")
+	}
+
+	// Only the one column is written, so a concurrent refresh or revocation is not overwritten
+	session.AuthenticatedAt = time.Now()
+	updated, err := s.sessionRepo.MarkAuthenticated(ctx, session.ID, session.AuthenticatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("session revoked")
+	}
+
+	return s.buildAuthResponse(user, session, "")
+}
+
+// SwitchOrganization makes orgID the active organization of the session (0 clears it) and returns an
+// access token carrying it. Callers check that the user may act in the organization.
+func (s *SessionService) SwitchOrganization(ctx contextx.Contextx, userID, sessionID, orgID uint) (*dto.AuthResponse, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return nil, errors.New("session revoked")
+	}
+
+	user, err := s.userRepo.GetByIDWithPreload(ctx, userID, "UserInfo")
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	session.OrganizationID = nil
+	if orgID != 0 {
+		session.OrganizationID = &orgID
+	}
+	updated, err := s.sessionRepo.SetOrganization(ctx, session.ID, session.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("session revoked")
+	}
+
+	return s.buildAuthResponse(user, session, "")
+}
+
+func (s *SessionService) buildAuthResponse(user *models.User, session *models.Session, refreshToken string) (*dto.AuthResponse, error) {
+	expiresAt := time.Now().Add(s.authConfig.AccessTokenTTL)
+	var orgID uint
+	if session.OrganizationID != nil {
+		orgID = *session.OrganizationID
+	}
+	token, err := auth.GenerateToken(user.ID, user.GetPrimaryEmail(), session.ID, orgID, session.AuthenticatedAt, s.keys, s.authConfig.AccessTokenTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	userResponse := dto.ToUserResponse(user)
+	return &dto.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    &expiresAt,
+		User:         &userResponse,
+	}, nil
+}
+
+// requestClientInfo extracts the user agent and client IP when the context wraps an HTTP request
+func requestClientInfo(ctx contextx.Contextx) (string, string) {
+	reqCtx := ctx.ReqContext()
+	if reqCtx == nil {
+		return "", ""
+	}
+	c := *reqCtx
+	userAgent := c.Request().UserAgent()
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	return userAgent, c.RealIP()
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/repository"
+)
+
+// interleavingSessionRepo runs between once, right after the next session is read by ID, to let a
+// concurrent request land between the read and the write
+type interleavingSessionRepo struct {
+	repository.SessionRepository
+	between func()
+}
+
+func (r *interleavingSessionRepo) GetByID(ctx contextx.Contextx, id uint) (*models.Session, error) {
+	session, err := r.SessionRepository.GetByID(ctx, id)
+	if r.between != nil {
+		between := r.between
+		r.between = nil
+		between()
+	}
+	return session, err
+}
+
+// interleaved returns a session service whose next session read by ID is followed by between
+func (env *testEnv) interleaved(between func()) *SessionService {
+	service := *env.sessionService
+	service.sessionRepo = &interleavingSessionRepo{SessionRepository: env.sessionRepo, between: between}
+	return &service
+}
+
+// signIn starts a session for the user and returns it with its refresh token
+func (env *testEnv) signIn(t *testing.T, user *models.User) (*models.Session, string) {
+	t.Helper()
+	ctx := contextx.Background()
+	resp, err := env.sessionService.IssueTokens(ctx, user)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	var session models.Session
+	if err := env.db.Where("user_id = ?", user.ID).Order("id DESC").First(&session).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	return &session, resp.RefreshToken
+}
+
+func (env *testEnv) reloadSession(t *testing.T, id uint) *models.Session {
+	t.Helper()
+	session, err := env.sessionRepo.GetByID(contextx.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	return session
+}
+
+func TestReauthenticate(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.registerUser(t, "alice", "alice@example.com")
+	session, _ := env.signIn(t, user)
+	signedInAt := time.Now().Add(-time.Hour)
+	env.db.Model(session).Update("authenticated_at", signedInAt)
+
+	if _, err := env.sessionService.Reauthenticate(contextx.Background(), user, session.ID); err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	if authenticatedAt := env.reloadSession(t, session.ID).AuthenticatedAt; !authenticatedAt.After(signedInAt.Add(59 * time.Minute)) {
+		t.Errorf("authenticated_at = %v, want about now", authenticatedAt)
+	}
+	// auth_time in ID tokens follows the re-authentication
+	if authTime := env.oidcProviderService.authTime(contextx.Background(), session.ID); !authTime.After(signedInAt.Add(59 * time.Minute)) {
+		t.Errorf("oidc auth_time = %v, want the re-authentication", authTime)
+	}
+}
+
+func TestReauthenticateDoesNotRestoreRevokedSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	session, _ := env.signIn(t, user)
+
+	service := env.interleaved(func() {
+		if err := env.sessionService.RevokeAllSessions(ctx, user.ID, 0); err != nil {
+			t.Fatalf("RevokeAllSessions: %v", err)
+		}
+	})
+	if _, err := service.Reauthenticate(ctx, user, session.ID); err == nil || err.Error() != "session revoked" {
+		t.Errorf("Reauthenticate error = %v, want session revoked", err)
+	}
+	if env.reloadSession(t, session.ID).RevokedAt == nil {
+		t.Error("revoked session active again after re-authentication")
+	}
+}
+
+func TestReauthenticateKeepsConcurrentRefresh(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	session, refreshToken := env.signIn(t, user)
+
+	var rotated string
+	service := env.interleaved(func() {
+		resp, err := env.sessionService.Refresh(ctx, refreshToken)
+		if err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		rotated = resp.RefreshToken
+	})
+	if _, err := service.Reauthenticate(ctx, user, session.ID); err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	if _, err := env.sessionService.Refresh(ctx, rotated); err != nil {
+		t.Errorf("refresh token rotated during re-authentication no longer works: %v", err)
+	}
+}
+
+func TestSwitchOrganization(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	session, refreshToken := env.signIn(t, user)
+
+	// A refresh rotates the token between the read and the write
+	var rotated string
+	service := env.interleaved(func() {
+		resp, err := env.sessionService.Refresh(ctx, refreshToken)
+		if err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		rotated = resp.RefreshToken
+	})
+	if _, err := service.SwitchOrganization(ctx, user.ID, session.ID, 7); err != nil {
+		t.Fatalf("SwitchOrganization: %v", err)
+	}
+	if orgID := env.reloadSession(t, session.ID).OrganizationID; orgID == nil || *orgID != 7 {
+		t.Errorf("organization = %v, want 7", orgID)
+	}
+	if _, err := env.sessionService.Refresh(ctx, rotated); err != nil {
+		t.Errorf("refresh token rotated during the switch no longer works: %v", err)
+	}
+
+	if _, err := env.sessionService.SwitchOrganization(ctx, user.ID, session.ID, 0); err != nil {
+		t.Fatalf("SwitchOrganization to none: %v", err)
+	}
+	if orgID := env.reloadSession(t, session.ID).OrganizationID; orgID != nil {
+		t.Errorf("organization = %d, want none", *orgID)
+	}
+}
+
+func TestSwitchOrganizationDoesNotRestoreRevokedSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	user := env.registerUser(t, "alice", "alice@example.com")
+	session, _ := env.signIn(t, user)
+
+	service := env.interleaved(func() {
+		if err := env.sessionService.RevokeSession(ctx, user.ID, session.ID); err != nil {
+			t.Fatalf("RevokeSession: %v", err)
+		}
+	})
+	if _, err := service.SwitchOrganization(ctx, user.ID, session.ID, 7); err == nil || err.Error() != "session revoked" {
+		t.Errorf("SwitchOrganization error = %v, want session revoked", err)
+	}
+	if env.reloadSession(t, session.ID).RevokedAt == nil {
+		t.Error("revoked session active again after switching organization")
+	}
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"errors"
+	"log"
 
 	"bezbase/internal/dto"
 	"bezbase/internal/models"
 	"bezbase/internal/pkg/contextx"
+	"bezbase/internal/pkg/identifier"
 	"bezbase/internal/repository"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,6 +19,9 @@ type UserService struct {
 	userInfoRepo     repository.UserInfoRepository
 	authProviderRepo repository.AuthProviderRepository
 	rbacService      *RBACService
+	sessionService   *SessionService
+	passwordPolicy   *PasswordPolicyService
+	emailChange      *EmailChangeService
 	db               *gorm.DB
 }
 
@@ -25,6 +30,9 @@ func NewUserService(
 	userInfoRepo repository.UserInfoRepository,
 	authProviderRepo repository.AuthProviderRepository,
 	rbacService *RBACService,
+	sessionService *SessionService,
+	passwordPolicy *PasswordPolicyService,
+	emailChange *EmailChangeService,
 	db *gorm.DB,
 ) *UserService {
 	return &UserService{
@@ -32,6 +40,9 @@ func NewUserService(
 		userInfoRepo:     userInfoRepo,
 		authProviderRepo: authProviderRepo,
 		rbacService:      rbacService,
+		sessionService:   sessionService,
+		passwordPolicy:   passwordPolicy,
+		emailChange:      emailChange,
 		db:               db,
 	}
 }
@@ -52,10 +63,12 @@ func (s *UserService) GetProfile(ctx contextx.Contextx, userID uint) (*dto.UserR
 	}
 
 	response := dto.ToUserResponseWithRoles(user, roles)
+	response.PendingEmail = s.emailChange.PendingEmail(ctx, userID)
 	return &response, nil
 }
 
-// UpdateProfile updates user information in UserInfo table
+// UpdateProfile updates user information in UserInfo table. The email is changed separately
+// through EmailChangeService, behind re-authentication.
 func (s *UserService) UpdateProfile(ctx contextx.Contextx, userID uint, req dto.UpdateProfileRequest) (*dto.UserResponse, error) {
 	// Check if user exists
 	_, err := s.userRepo.GetByID(ctx, userID)
@@ -70,6 +83,13 @@ func (s *UserService) UpdateProfile(ctx contextx.Contextx, userID uint, req dto.
 	}
 
 	// Check if username is being changed and if it's already taken
+	if req.Username != "" {
+		username, err := identifier.Username(req.Username)
+		if err != nil {
+			return nil, err
+		}
+		req.Username = username
+	}
 	if req.Username != "" && req.Username != userInfo.Username {
 		taken, err := s.userInfoRepo.IsUsernameTaken(ctx, req.Username, userID)
 		if err != nil {
@@ -80,17 +100,6 @@ func (s *UserService) UpdateProfile(ctx contextx.Contextx, userID uint, req dto.
 		}
 	}
 
-	// Check if email is being changed and if it's already taken
-	if req.Email != "" && req.Email != userInfo.Email {
-		taken, err := s.userInfoRepo.IsEmailTaken(ctx, req.Email, userID)
-		if err != nil {
-			return nil, errors.New("failed to check email availability")
-		}
-		if taken {
-			return nil, errors.New("email already taken")
-		}
-	}
-
 	// Update fields that are provided
 	if req.Username != "" {
 		userInfo.Username = req.Username
@@ -101,9 +110,6 @@ func (s *UserService) UpdateProfile(ctx contextx.Contextx, userID uint, req dto.
 	if req.LastName != "" {
 		userInfo.LastName = req.LastName
 	}
-	if req.Email != "" {
-		userInfo.Email = req.Email
-	}
 	if req.AvatarURL != "" {
 		userInfo.AvatarURL = req.AvatarURL
 	}
@@ -159,6 +165,11 @@ func (s *UserService) ChangePassword(ctx contextx.Contextx, userID uint, current
 		return errors.New("invalid current password")
 	}
 
+	userInputs := []string{authProvider.UserName, authProvider.ProviderID}
+	if err := s.passwordPolicy.Validate(ctx, userID, newPassword, userInputs...); err != nil {
+		return err
+	}
+
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -170,6 +181,14 @@ func (s *UserService) ChangePassword(ctx contextx.Contextx, userID uint, current
 	if err := s.authProviderRepo.Update(ctx, authProvider); err != nil {
 		return err
 	}
+	if err := s.passwordPolicy.Record(ctx, userID, authProvider.Password); err != nil {
+		return err
+	}
+
+	// Sign out everywhere so stolen tokens stop working with the old password
+	if err := s.sessionService.RevokeAllSessions(ctx, userID, 0); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -276,17 +295,30 @@ func (s *UserService) DeleteUser(ctx contextx.Contextx, userID uint) error {
 
 // CreateUser creates a new user with UserInfo
 func (s *UserService) CreateUser(ctx contextx.Contextx, req dto.CreateUserRequest) (*dto.UserResponse, error) {
+	username, err := identifier.Username(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	req.Username = username
+
 	// Check if user with this email already exists
-	var existingUserInfo models.UserInfo
-	if err := ctx.GetTxn(s.db).Where("email = ?", req.Email).First(&existingUserInfo).Error; err == nil {
+	if taken, err := s.userInfoRepo.IsEmailTaken(ctx, req.Email, 0); err != nil {
+		return nil, errors.New("failed to check email availability")
+	} else if taken {
 		return nil, errors.New("user with this email already exists")
 	}
 
 	// Check if username is already taken
-	if err := ctx.GetTxn(s.db).Where("username = ?", req.Username).First(&existingUserInfo).Error; err == nil {
+	if taken, err := s.userInfoRepo.IsUsernameTaken(ctx, req.Username, 0); err != nil {
+		return nil, errors.New("failed to check username availability")
+	} else if taken {
 		return nil, errors.New("username already taken")
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, 0, req.Password, req.Username, req.Email, req.FirstName, req.LastName); err != nil {
+		return nil, err
+	}
+
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -351,6 +383,10 @@ func (s *UserService) CreateUser(ctx contextx.Contextx, req dto.CreateUserReques
 		return nil, errors.New("failed to create user account")
 	}
 
+	if err := s.passwordPolicy.Record(ctx, user.ID, string(hashedPassword)); err != nil {
+		log.Printf("Failed to record password history for user %d: %v", user.ID, err)
+	}
+
 	// Return created user
 	user.UserInfo = &userInfo
 	var roles []string
@@ -383,7 +419,8 @@ func (s *UserService) GetUserByIDDetailed(ctx contextx.Contextx, userID uint) (*
 	return &response, nil
 }
 
-// UpdateUser updates user information
+// UpdateUser updates user information. Email changes go through confirmation by the new address
+// like profile updates do.
 func (s *UserService) UpdateUser(ctx contextx.Contextx, userID uint, req dto.UpdateUserRequest) (*dto.UserResponse, error) {
 	// Check if user exists
 	var user models.User
@@ -391,11 +428,10 @@ func (s *UserService) UpdateUser(ctx contextx.Contextx, userID uint, req dto.Upd
 		return nil, errors.New("user not found")
 	}
 
-	// Check if email is being changed and if it's already taken
+	// Reject a new email the change would refuse before saving the other fields
 	if req.Email != "" && req.Email != user.UserInfo.Email {
-		var existingUser models.User
-		if err := ctx.GetTxn(s.db).Joins("UserInfo").Where("user_info.email = ? AND users.id != ?", req.Email, userID).First(&existingUser).Error; err == nil {
-			return nil, errors.New("email already taken by another user")
+		if err := s.emailChange.ValidateChange(ctx, userID, req.Email); err != nil {
+			return nil, err
 		}
 	}
 
@@ -423,9 +459,6 @@ func (s *UserService) UpdateUser(ctx contextx.Contextx, userID uint, req dto.Upd
 	if req.LastName != "" {
 		user.UserInfo.LastName = req.LastName
 	}
-	if req.Email != "" {
-		user.UserInfo.Email = req.Email
-	}
 	if req.Language != "" {
 		user.UserInfo.Language = req.Language
 	}
@@ -450,19 +483,17 @@ func (s *UserService) UpdateUser(ctx contextx.Contextx, userID uint, req dto.Upd
 		return nil, errors.New("failed to update user info")
 	}
 
-	// Update auth provider email if email was changed
-	if req.Email != "" && req.Email != user.UserInfo.Email {
-		if err := tx.Model(&models.AuthProvider{}).Where("user_id = ? AND provider_type = ?", userID, models.ProviderEmail).Update("provider_id", req.Email).Error; err != nil {
-			tx.Rollback()
-			return nil, errors.New("failed to update auth provider")
-		}
-	}
-
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, errors.New("failed to save user changes")
 	}
 
+	if req.Email != "" && req.Email != user.UserInfo.Email {
+		if err := s.emailChange.RequestChange(ctx, userID, req.Email); err != nil {
+			return nil, err
+		}
+	}
+
 	// Return updated user
 	var roles []string
 	if s.rbacService != nil {
@@ -472,5 +503,6 @@ func (s *UserService) UpdateUser(ctx contextx.Contextx, userID uint, req dto.Upd
 		}
 	}
 	response := dto.ToUserResponseWithRoles(&user, roles)
+	response.PendingEmail = s.emailChange.PendingEmail(ctx, userID)
 	return &response, nil
 }
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+
+	"bezbase/internal/dto"
+	"bezbase/internal/models"
+	"bezbase/internal/pkg/contextx"
+)
+
+// collide turns the user into an account that matched another one when the normalized columns
+// were backfilled, which leaves both of its columns NULL
+func (env *testEnv) collide(t *testing.T, userID uint, username, email string) {
+	t.Helper()
+	if err := env.db.Exec("UPDATE user_info SET username = ?, email = ?, username_normalized = NULL, email_normalized = NULL WHERE user_id = ?",
+		username, email, userID).Error; err != nil {
+		t.Fatalf("collide user %d: %v", userID, err)
+	}
+}
+
+// normalizedIdentifiers returns the stored normalized username and email
+func (env *testEnv) normalizedIdentifiers(t *testing.T, userID uint) (*string, *string) {
+	t.Helper()
+	var info models.UserInfo
+	if err := env.db.Where("user_id = ?", userID).First(&info).Error; err != nil {
+		t.Fatalf("load user info: %v", err)
+	}
+	return info.UsernameNormalized, info.EmailNormalized
+}
+
+func TestSaveCollidedAccount(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	env.registerUser(t, "alice", "alice@example.com")
+	bob := env.registerUser(t, "bob", "bob@example.com")
+	env.collide(t, bob.ID, "ALICE", "ALICE@example.com")
+
+	// Saves that leave the identifiers alone keep the NULLs instead of hitting the unique index
+	if _, err := env.userService.UpdateProfile(ctx, bob.ID, dto.UpdateProfileRequest{Bio: "Hi"}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if _, err := env.userService.UpdateUser(ctx, bob.ID, dto.UpdateUserRequest{FirstName: "Bob"}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if username, email := env.normalizedIdentifiers(t, bob.ID); username != nil || email != nil {
+		t.Errorf("normalized identifiers = %v, %v; want both still NULL", username, email)
+	}
+
+	// A rename fills in the normalized username only
+	if _, err := env.userService.UpdateProfile(ctx, bob.ID, dto.UpdateProfileRequest{Username: "Bobby"}); err != nil {
+		t.Fatalf("UpdateProfile rename: %v", err)
+	}
+	username, email := env.normalizedIdentifiers(t, bob.ID)
+	if username == nil || *username != "bobby" {
+		t.Errorf("normalized username = %v, want bobby", username)
+	}
+	if email != nil {
+		t.Errorf("normalized email = %s, want still NULL", *email)
+	}
+}
+
+func TestSaveKeepsNormalizedIdentifiersInSync(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	alice := env.registerUser(t, "alice", "alice@example.com")
+
+	info, err := env.userInfoRepo.GetByUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	info.Username = "Alicia"
+	info.Email = "Alicia@Example.com"
+	if err := env.userInfoRepo.Update(ctx, info); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	username, email := env.normalizedIdentifiers(t, alice.ID)
+	if username == nil || *username != "alicia" || email == nil || *email != "alicia@example.com" {
+		t.Errorf("normalized identifiers = %v, %v; want alicia, alicia@example.com", username, email)
+	}
+}
+
+func TestUpdateUserRejectsEmailBeforeSaving(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	alice := env.registerUser(t, "alice", "alice@example.com")
+	env.registerUser(t, "bob", "bob@example.com")
+
+	reject := func(email, want string) {
+		t.Helper()
+		req := dto.UpdateUserRequest{Email: email, Bio: "Changed", Status: string(models.UserStatusSuspended)}
+		if _, err := env.userService.UpdateUser(ctx, alice.ID, req); err == nil || err.Error() != want {
+			t.Errorf("UpdateUser(%s) error = %v, want %s", email, err, want)
+		}
+		user, err := env.userRepo.GetByIDWithPreload(ctx, alice.ID, "UserInfo")
+		if err != nil {
+			t.Fatalf("GetByIDWithPreload: %v", err)
+		}
+		if user.UserInfo.Bio != "" || user.Status == models.UserStatusSuspended {
+			t.Errorf("UpdateUser(%s) saved bio %q and status %s although the email was rejected", email, user.UserInfo.Bio, user.Status)
+		}
+	}
+	reject("not an email", "invalid email")
+	reject("BOB@example.com", "email already taken")
+
+	if err := env.emailChangeService.RequestChange(ctx, alice.ID, "alice@example.org"); err != nil {
+		t.Fatalf("RequestChange: %v", err)
+	}
+	reject("alice@example.net", "email change requested recently")
+}
+
+func TestUpdateUserRequestsEmailChange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := contextx.Background()
+	alice := env.registerUser(t, "alice", "alice@example.com")
+
+	resp, err := env.userService.UpdateUser(ctx, alice.ID, dto.UpdateUserRequest{Email: "alice@example.org", Bio: "Changed"})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if resp.Email != "alice@example.com" || resp.PendingEmail != "alice@example.org" || resp.Bio != "Changed" {
+		t.Errorf("user = %s (pending %s), bio %q; want alice@example.com pending alice@example.org, bio Changed", resp.Email, resp.PendingEmail, resp.Bio)
+	}
+}
@@ -83,9 +83,16 @@ const Profile = () => {
     setMessage('');
 
     try {
-      const response = await userService.updateProfile(profileData);
+      // The email has its own endpoint, which requires recent authentication
+      const { email, ...profile } = profileData;
+      const response = await userService.updateProfile(profile);
       updateUser(response.data);
-      setMessage(t('profile.profileUpdated'));
+      if (email && email !== response.data.email && email !== response.data.pending_email) {
+        const emailResponse = await userService.changeEmail(email);
+        setMessage(emailResponse.data.message);
+      } else {
+        setMessage(t('profile.profileUpdated'));
+      }
     } catch (err) {
       setError(err.response?.data?.message || t('profile.errors.updateFailed'));
     } finally {
@@ -229,6 +229,10 @@ export const userService = {
     return apiV1.put('/profile', userData);
   },
   
+  changeEmail: (email: string): Promise<AxiosResponse<any>> => {
+    return apiV1.put('/profile/email', { email });
+  },
+  
   changePassword: (passwordData: PasswordData): Promise<AxiosResponse<any>> => {
     return apiV1.put('/profile/password', passwordData);
   },